@@ -77,3 +77,12 @@ func TestCommand_WrongCommannd(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.True(t, len(err.Error()) != 0)
 }
+
+func TestRunCommand(t *testing.T) {
+	lines, err := RunCommand(context.Background(), "echo", "hello")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hello"}, lines)
+
+	_, err = RunCommand(context.Background(), "ls", "/not/exist/path")
+	assert.NotNil(t, err)
+}
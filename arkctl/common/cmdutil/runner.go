@@ -0,0 +1,52 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Runner runs the command to completion and returns the stdout lines.
+// It's used where the command should be replaced by a fake one in tests.
+type Runner func(ctx context.Context, cmd string, args ...string) ([]string, error)
+
+// RunCommand is the default Runner, the stderr is attached to the error if the command fails.
+// The stdout lines are returned even if the command fails.
+func RunCommand(ctx context.Context, cmd string, args ...string) ([]string, error) {
+	execCmd := exec.CommandContext(ctx, cmd, args...)
+	stderr := &bytes.Buffer{}
+	execCmd.Stderr = stderr
+
+	stdout, err := execCmd.Output()
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if err != nil {
+		if stderr.Len() != 0 {
+			return lines, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return lines, err
+	}
+	return lines, scanner.Err()
+}
@@ -17,20 +17,40 @@ package fileutil
 import (
 	"context"
 	"fmt"
+	neturl "net/url"
 	"strings"
 )
 
 // FileUrl is the url of file
 type FileUrl string
 
-func (url FileUrl) GetFileUrlType() FileUrlType {
-	switch {
-	// start with file:// then it's a local file
-	case strings.HasPrefix(string(url), "file://"):
-		return FileUrlTypeLocal
+// GetFileUrlType return the type of the file url by its scheme, which is case-insensitive.
+// UnsupportedSchemeError is returned if no resolver is registered for the scheme.
+func (url FileUrl) GetFileUrlType() (FileUrlType, error) {
+	u, err := neturl.Parse(strings.TrimSpace(string(url)))
+	if err != nil {
+		return "", fmt.Errorf("invalid file url %s: %w", url, err)
+	}
+
+	switch scheme := strings.ToLower(u.Scheme); scheme {
+	// file:// is a local file
+	case "file":
+		return FileUrlTypeLocal, nil
+
+	// http:// or https:// is a remote file served by http
+	case "http", "https":
+		return FileUrlTypeHttp, nil
+
+	// oss:// is a remote file stored in oss bucket
+	case "oss":
+		return FileUrlTypeOss, nil
 
 	default:
-		panic(fmt.Sprintf("unknown file url type %s", url))
+		// custom schemes are typed by the scheme itself once a resolver is registered
+		if _, ok := getResolver(scheme); ok {
+			return FileUrlType(scheme), nil
+		}
+		return "", &UnsupportedSchemeError{Scheme: u.Scheme, Supported: SupportedSchemes()}
 	}
 }
 
@@ -38,8 +58,109 @@ type FileUrlType string
 
 const (
 	FileUrlTypeLocal FileUrlType = "local"
+	FileUrlTypeHttp  FileUrlType = "http"
+	FileUrlTypeOss   FileUrlType = "oss"
+	FileUrlTypeMaven FileUrlType = "mvn"
 )
 
+// ParsedFileUrl is the structured form of a FileUrl.
+type ParsedFileUrl struct {
+	// Type is the type of the file url.
+	Type FileUrlType
+
+	// Scheme is the url scheme, like file, http, https or oss.
+	Scheme string
+
+	// Host is the host[:port] of http(s) url.
+	// It's empty for file and oss url.
+	Host string
+
+	// Bucket is the bucket name of oss url.
+	// It's empty for file and http(s) url.
+	Bucket string
+
+	// Endpoint is the endpoint of oss url given like oss://{bucket}.{endpoint}/{key}.
+	// It's empty if the url only gives the bucket, or for other urls.
+	Endpoint string
+
+	// Path is the absolute file path of file url, the request path of http(s) url,
+	// or the object key of oss url.
+	Path string
+}
+
+// ParseFileUrl validate the raw url and return it as a FileUrl.
+// Besides file://, http(s):// and oss://, schemes registered by RegisterResolver are supported.
+func ParseFileUrl(raw string) (FileUrl, error) {
+	fileUrl := FileUrl(raw)
+	if _, err := fileUrl.Parse(); err != nil {
+		return "", err
+	}
+	return fileUrl, nil
+}
+
+// Parse decompose the file url into structured fields.
+func (url FileUrl) Parse() (*ParsedFileUrl, error) {
+	raw := strings.TrimSpace(string(url))
+	if raw == "" {
+		return nil, fmt.Errorf("file url is empty")
+	}
+
+	u, err := neturl.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file url %s: %w", raw, err)
+	}
+
+	parsed := &ParsedFileUrl{
+		Scheme: strings.ToLower(u.Scheme),
+	}
+
+	switch parsed.Scheme {
+	case "file":
+		parsed.Type = FileUrlTypeLocal
+		// file url like file://relative/path would take "relative" as host, which is not allowed.
+		if u.Host != "" {
+			return nil, fmt.Errorf("invalid file url %s: path must be absolute", raw)
+		}
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid file url %s: path is empty", raw)
+		}
+		parsed.Path = u.Path
+
+	case "http", "https":
+		parsed.Type = FileUrlTypeHttp
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid http url %s: host is empty", raw)
+		}
+		parsed.Host = u.Host
+		parsed.Path = u.Path
+
+	case "oss":
+		parsed.Type = FileUrlTypeOss
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid oss url %s: bucket is empty", raw)
+		}
+		if strings.TrimPrefix(u.Path, "/") == "" {
+			return nil, fmt.Errorf("invalid oss url %s: object key is empty", raw)
+		}
+		// bucket names never contain dots, the rest of the host is the endpoint
+		parsed.Bucket, parsed.Endpoint, _ = strings.Cut(u.Host, ".")
+		parsed.Path = strings.TrimPrefix(u.Path, "/")
+
+	case "":
+		return nil, fmt.Errorf("invalid file url %s: missing scheme", raw)
+
+	default:
+		if _, ok := getResolver(parsed.Scheme); !ok {
+			return nil, &UnsupportedSchemeError{Scheme: u.Scheme, Supported: SupportedSchemes()}
+		}
+		parsed.Type = FileUrlType(parsed.Scheme)
+		parsed.Host = u.Host
+		parsed.Path = u.Path
+	}
+
+	return parsed, nil
+}
+
 // FileUtils is an interface for all fileutil
 type FileUtils interface {
 	// Download file from fileUrl to local file system.
@@ -58,11 +179,19 @@ func DefaultFileUtil() FileUtils {
 type fileUtil struct {
 }
 
-func (f fileUtil) Download(_ context.Context, fileUrl FileUrl) (string, error) {
-	switch fileUrl.GetFileUrlType() {
+func (f fileUtil) Download(ctx context.Context, fileUrl FileUrl) (string, error) {
+	urlType, err := fileUrl.GetFileUrlType()
+	if err != nil {
+		return "", err
+	}
+	switch urlType {
 	case FileUrlTypeLocal:
 		return (string)(fileUrl), nil
 	default:
-		panic(fmt.Sprintf("unknown download operation for file url type %s", fileUrl))
+		localized, err := Resolve(ctx, fileUrl)
+		if err != nil {
+			return "", err
+		}
+		return "file://" + localized.Path, nil
 	}
 }
@@ -0,0 +1,153 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fileutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFileUrl(t *testing.T) {
+	testCases := []struct {
+		name     string
+		raw      string
+		expected *ParsedFileUrl
+		errMsg   string
+	}{
+		{
+			name: "local file",
+			raw:  "file:///tmp/biz-ark-biz.jar",
+			expected: &ParsedFileUrl{
+				Type:   FileUrlTypeLocal,
+				Scheme: "file",
+				Path:   "/tmp/biz-ark-biz.jar",
+			},
+		},
+		{
+			name: "http file",
+			raw:  "http://127.0.0.1:8080/repo/biz-ark-biz.jar",
+			expected: &ParsedFileUrl{
+				Type:   FileUrlTypeHttp,
+				Scheme: "http",
+				Host:   "127.0.0.1:8080",
+				Path:   "/repo/biz-ark-biz.jar",
+			},
+		},
+		{
+			name: "https file",
+			raw:  "https://example.com/biz-ark-biz.jar",
+			expected: &ParsedFileUrl{
+				Type:   FileUrlTypeHttp,
+				Scheme: "https",
+				Host:   "example.com",
+				Path:   "/biz-ark-biz.jar",
+			},
+		},
+		{
+			name: "oss file",
+			raw:  "oss://bucket/path/to/biz-ark-biz.jar",
+			expected: &ParsedFileUrl{
+				Type:   FileUrlTypeOss,
+				Scheme: "oss",
+				Bucket: "bucket",
+				Path:   "path/to/biz-ark-biz.jar",
+			},
+		},
+		{
+			name: "upper case scheme",
+			raw:  "HTTPS://example.com/biz-ark-biz.jar",
+			expected: &ParsedFileUrl{
+				Type:   FileUrlTypeHttp,
+				Scheme: "https",
+				Host:   "example.com",
+				Path:   "/biz-ark-biz.jar",
+			},
+		},
+		{
+			name: "oss file with endpoint",
+			raw:  "oss://bucket.oss-cn-shanghai.aliyuncs.com/path/to/biz-ark-biz.jar",
+			expected: &ParsedFileUrl{
+				Type:     FileUrlTypeOss,
+				Scheme:   "oss",
+				Bucket:   "bucket",
+				Endpoint: "oss-cn-shanghai.aliyuncs.com",
+				Path:     "path/to/biz-ark-biz.jar",
+			},
+		},
+		{
+			name:   "empty url",
+			raw:    "  ",
+			errMsg: "file url is empty",
+		},
+		{
+			name:   "missing scheme",
+			raw:    "/tmp/biz-ark-biz.jar",
+			errMsg: "invalid file url /tmp/biz-ark-biz.jar: missing scheme",
+		},
+		{
+			name:   "unsupported scheme",
+			raw:    "ftp://example.com/biz-ark-biz.jar",
+			errMsg: "unsupported file url scheme \"ftp\", supported schemes are file, http, https, mvn, oss",
+		},
+		{
+			name:   "relative file path",
+			raw:    "file://tmp/biz-ark-biz.jar",
+			errMsg: "invalid file url file://tmp/biz-ark-biz.jar: path must be absolute",
+		},
+		{
+			name:   "http without host",
+			raw:    "http:///biz-ark-biz.jar",
+			errMsg: "invalid http url http:///biz-ark-biz.jar: host is empty",
+		},
+		{
+			name:   "oss without object key",
+			raw:    "oss://bucket/",
+			errMsg: "invalid oss url oss://bucket/: object key is empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fileUrl, err := ParseFileUrl(tc.raw)
+			if tc.errMsg != "" {
+				assert.NotNil(t, err)
+				assert.Equal(t, tc.errMsg, err.Error())
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, FileUrl(tc.raw), fileUrl)
+			parsed, err := fileUrl.Parse()
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, parsed)
+			urlType, err := fileUrl.GetFileUrlType()
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected.Type, urlType)
+		})
+	}
+}
+
+func TestGetFileUrlType_UnsupportedScheme(t *testing.T) {
+	_, err := FileUrl("ftp://example.com/biz-ark-biz.jar").GetFileUrlType()
+	unsupportedErr := &UnsupportedSchemeError{}
+	assert.True(t, errors.As(err, &unsupportedErr))
+	assert.Equal(t, "ftp", unsupportedErr.Scheme)
+
+	urlType, err := FileUrl("File:///tmp/biz-ark-biz.jar").GetFileUrlType()
+	assert.Nil(t, err)
+	assert.Equal(t, FileUrlTypeLocal, urlType)
+}
@@ -0,0 +1,358 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fileutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/progressutil"
+)
+
+// ErrChecksumMismatch is returned when the downloaded file doesn't match the expected checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// DownloadOptions is the per call options of downloading a remote file.
+type DownloadOptions struct {
+	// ExpectedChecksum is the hex encoded sha256 checksum the downloaded file must match.
+	// The check is skipped if it's empty.
+	ExpectedChecksum string
+
+	// OnProgress is called with the downloaded bytes and the total bytes while downloading.
+	// The total is -1 if the server doesn't tell the content length.
+	//
+	// Deprecated: use Progress, which is also given the total by Start.
+	OnProgress func(downloaded, total int64)
+
+	// Progress is started once the total bytes are known, updated with the downloaded bytes and finished with the download.
+	Progress progressutil.ProgressReporter
+
+	// Proxy overrides the proxy of the http client used to download, the client's own proxy is used if it's nil.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// Username and Password authenticate the download by http basic auth, e.g. for a private maven repository.
+	Username string
+	Password string
+}
+
+// setAuth set the basic auth of options to req, if any.
+func (options DownloadOptions) setAuth(req *http.Request) {
+	if options.Username != "" || options.Password != "" {
+		req.SetBasicAuth(options.Username, options.Password)
+	}
+}
+
+// DownloadError is returned when the server responds the download with a failed status code.
+type DownloadError struct {
+	FileUrl    FileUrl
+	StatusCode int
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("download %s failed with code %d", e.FileUrl, e.StatusCode)
+}
+
+type downloadOptionsKey struct{}
+
+// WithDownloadOptions return a context carrying the download options used by the resolvers.
+func WithDownloadOptions(ctx context.Context, options DownloadOptions) context.Context {
+	return context.WithValue(ctx, downloadOptionsKey{}, options)
+}
+
+// WithDownloadProxy return a context downloading through proxy, other download options in ctx are kept.
+func WithDownloadProxy(ctx context.Context, proxy func(*http.Request) (*url.URL, error)) context.Context {
+	options := getDownloadOptions(ctx)
+	options.Proxy = proxy
+	return WithDownloadOptions(ctx, options)
+}
+
+func getDownloadOptions(ctx context.Context) DownloadOptions {
+	options, _ := ctx.Value(downloadOptionsKey{}).(DownloadOptions)
+	return options
+}
+
+// HttpResolver download http(s) files to local file system.
+// The download is resumed with range requests if the server supports it.
+type HttpResolver struct {
+	// Client is the http client used to download, http.DefaultClient is used if not given.
+	Client *http.Client
+
+	// CacheDir is where the completed downloads are cached by url and etag.
+	// The cache is disabled if it's empty.
+	CacheDir string
+
+	// MaxAttempts is the max attempts to download a file, default to 3.
+	MaxAttempts int
+}
+
+// client return the http client used to download, with the proxy of options applied.
+func (r *HttpResolver) client(options DownloadOptions) *http.Client {
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if options.Proxy == nil {
+		return client
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpTransport, ok := base.(*http.Transport)
+	if !ok {
+		// the proxy of a custom transport is up to itself
+		return client
+	}
+	transport := httpTransport.Clone()
+	transport.Proxy = options.Proxy
+	proxied := *client
+	proxied.Transport = transport
+	return &proxied
+}
+
+func (r *HttpResolver) maxAttempts() int {
+	if r.MaxAttempts <= 0 {
+		return 3
+	}
+	return r.MaxAttempts
+}
+
+// cachePath return the cache file path of given url and etag.
+func (r *HttpResolver) cachePath(fileUrl FileUrl, etag string, baseName string) string {
+	sum := sha256.Sum256([]byte(string(fileUrl) + "\n" + etag))
+	return filepath.Join(r.CacheDir, hex.EncodeToString(sum[:8])+"-"+baseName)
+}
+
+// Resolve download the file to a temp file or the cache dir.
+func (r *HttpResolver) Resolve(ctx context.Context, fileUrl FileUrl) (*LocalizedFile, error) {
+	parsed, err := fileUrl.Parse()
+	if err != nil {
+		return nil, err
+	}
+	options := getDownloadOptions(ctx)
+	baseName := path.Base(parsed.Path)
+
+	// lookup the cache by etag before downloading
+	targetPath := ""
+	if r.CacheDir != "" {
+		if err := os.MkdirAll(r.CacheDir, 0755); err != nil {
+			return nil, err
+		}
+		if etag, err := r.head(ctx, fileUrl, options); err == nil && etag != "" {
+			targetPath = r.cachePath(fileUrl, etag, baseName)
+			if localized, err := localize(targetPath); err == nil {
+				if err := verifyChecksum(fileUrl, localized.Checksum, options.ExpectedChecksum); err != nil {
+					return nil, err
+				}
+				return localized, nil
+			}
+		}
+	}
+
+	partFile, err := os.CreateTemp(r.CacheDir, "arkctl-*-"+baseName+".part")
+	if err != nil {
+		return nil, err
+	}
+	partPath := partFile.Name()
+	defer func() {
+		partFile.Close()
+		os.Remove(partPath)
+	}()
+
+	download := &httpDownload{
+		resolver: r,
+		fileUrl:  fileUrl,
+		file:     partFile,
+		hash:     sha256.New(),
+		options:  options,
+		progress: progressutil.OrNop(progressutil.Multi(options.Progress, progressutil.FromFunc(options.OnProgress))),
+	}
+	if err := download.run(ctx); err != nil {
+		return nil, err
+	}
+
+	checksum := hex.EncodeToString(download.hash.Sum(nil))
+	if err := verifyChecksum(fileUrl, checksum, options.ExpectedChecksum); err != nil {
+		return nil, err
+	}
+	if err := partFile.Close(); err != nil {
+		return nil, err
+	}
+
+	if targetPath == "" && download.etag != "" && r.CacheDir != "" {
+		targetPath = r.cachePath(fileUrl, download.etag, baseName)
+	}
+	temporary := targetPath == ""
+	if temporary {
+		targetPath = strings.TrimSuffix(partPath, ".part")
+	}
+
+	// rename is atomic, so the target file is either absent or complete.
+	if err := os.Rename(partPath, targetPath); err != nil {
+		return nil, err
+	}
+
+	return &LocalizedFile{
+		Path:      targetPath,
+		Size:      download.written,
+		Checksum:  checksum,
+		Temporary: temporary,
+	}, nil
+}
+
+func (r *HttpResolver) head(ctx context.Context, fileUrl FileUrl, options DownloadOptions) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, string(fileUrl), nil)
+	if err != nil {
+		return "", err
+	}
+	options.setAuth(req)
+	resp, err := r.client(options).Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+func verifyChecksum(fileUrl FileUrl, actual, expected string) error {
+	if expected != "" && !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, fileUrl, expected, actual)
+	}
+	return nil
+}
+
+// httpDownload is the state of a single download, which might take several attempts.
+type httpDownload struct {
+	resolver *HttpResolver
+	fileUrl  FileUrl
+	file     *os.File
+	hash     hash.Hash
+	options  DownloadOptions
+	progress progressutil.ProgressReporter
+
+	written      int64
+	total        int64
+	etag         string
+	acceptRanges bool
+}
+
+func (d *httpDownload) run(ctx context.Context) (err error) {
+	defer func() {
+		d.progress.Finish(err)
+	}()
+
+	var lastErr error
+	for attempt := 0; attempt < d.resolver.maxAttempts(); attempt++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if attempt > 0 && !(d.acceptRanges && d.written > 0) {
+			// can't resume, restart from the beginning
+			if err := d.reset(); err != nil {
+				return err
+			}
+		}
+
+		done, err := d.attempt(ctx)
+		if done {
+			return err
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("download %s failed after %d attempts: %w", d.fileUrl, d.resolver.maxAttempts(), lastErr)
+}
+
+func (d *httpDownload) reset() error {
+	if _, err := d.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := d.file.Truncate(0); err != nil {
+		return err
+	}
+	d.hash.Reset()
+	d.written = 0
+	return nil
+}
+
+// attempt download the remaining content, return done=true if there's no need to try again.
+func (d *httpDownload) attempt(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, string(d.fileUrl), nil)
+	if err != nil {
+		return true, err
+	}
+	d.options.setAuth(req)
+
+	resuming := d.written > 0
+	if resuming {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", d.written))
+		if d.etag != "" {
+			req.Header.Set("If-Range", d.etag)
+		}
+	}
+
+	resp, err := d.resolver.client(d.options).Do(req)
+	if err != nil {
+		return ctx.Err() != nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resuming && resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		if resuming {
+			// the server ignored the range or the file changed, restart from the beginning
+			if err := d.reset(); err != nil {
+				return true, err
+			}
+		}
+		d.etag = resp.Header.Get("ETag")
+		d.acceptRanges = resp.Header.Get("Accept-Ranges") == "bytes"
+		d.total = resp.ContentLength
+		d.progress.Start(d.total)
+	case resp.StatusCode >= 500:
+		return false, &DownloadError{FileUrl: d.fileUrl, StatusCode: resp.StatusCode}
+	default:
+		return true, &DownloadError{FileUrl: d.fileUrl, StatusCode: resp.StatusCode}
+	}
+
+	_, err = io.Copy(io.MultiWriter(d.file, d.hash, d), resp.Body)
+	if err != nil {
+		return ctx.Err() != nil, err
+	}
+	if d.total >= 0 && d.written < d.total {
+		return false, fmt.Errorf("download %s truncated at %d of %d bytes", d.fileUrl, d.written, d.total)
+	}
+	return true, nil
+}
+
+// Write count the downloaded bytes and report the progress.
+func (d *httpDownload) Write(p []byte) (int, error) {
+	d.written += int64(len(p))
+	d.progress.Update(d.written, "")
+	return len(p), nil
+}
@@ -0,0 +1,133 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fileutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyServer serves content with range support, the first full download is aborted halfway.
+func flakyServer(content []byte, getCount *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Method == http.MethodGet {
+			if getCount.Add(1) == 1 {
+				w.Header().Set("Accept-Ranges", "bytes")
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(content[:len(content)/2])
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}
+		}
+		http.ServeContent(w, r, "biz.jar", time.Time{}, bytes.NewReader(content))
+	}))
+}
+
+func TestHttpResolver_ResumeDownload(t *testing.T) {
+	content := []byte(strings.Repeat("ark-biz-content;", 4096))
+	sum := sha256.Sum256(content)
+
+	var getCount atomic.Int32
+	var ranges []string
+	server := flakyServer(content, &getCount)
+	defer server.Close()
+	resolver := &HttpResolver{Client: &http.Client{Transport: recordRangeTransport(&ranges)}}
+
+	var lastDownloaded, lastTotal int64
+	ctx := WithDownloadOptions(context.Background(), DownloadOptions{
+		ExpectedChecksum: hex.EncodeToString(sum[:]),
+		OnProgress: func(downloaded, total int64) {
+			assert.True(t, downloaded >= lastDownloaded)
+			lastDownloaded, lastTotal = downloaded, total
+		},
+	})
+
+	localized, err := resolver.Resolve(ctx, FileUrl(server.URL+"/biz-ark-biz.jar"))
+	assert.Nil(t, err)
+	defer os.Remove(localized.Path)
+
+	assert.Equal(t, int32(2), getCount.Load())
+	assert.Equal(t, []string{"", "bytes=" + strconv.Itoa(len(content)/2) + "-"}, ranges)
+	assert.Equal(t, int64(len(content)), localized.Size)
+	assert.Equal(t, hex.EncodeToString(sum[:]), localized.Checksum)
+	assert.Equal(t, int64(len(content)), lastDownloaded)
+	assert.Equal(t, int64(len(content)), lastTotal)
+	assert.False(t, strings.HasSuffix(localized.Path, ".part"))
+
+	downloaded, err := os.ReadFile(localized.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, content, downloaded)
+}
+
+func TestHttpResolver_ChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testContent))
+	}))
+	defer server.Close()
+
+	ctx := WithDownloadOptions(context.Background(), DownloadOptions{ExpectedChecksum: "deadbeef"})
+	_, err := (&HttpResolver{}).Resolve(ctx, FileUrl(server.URL+"/biz-ark-biz.jar"))
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+}
+
+func TestHttpResolver_Cache(t *testing.T) {
+	var getCount atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			getCount.Add(1)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "biz.jar", time.Time{}, strings.NewReader(testContent))
+	}))
+	defer server.Close()
+
+	resolver := &HttpResolver{CacheDir: t.TempDir()}
+	first, err := resolver.Resolve(context.Background(), FileUrl(server.URL+"/biz-ark-biz.jar"))
+	assert.Nil(t, err)
+	second, err := resolver.Resolve(context.Background(), FileUrl(server.URL+"/biz-ark-biz.jar"))
+	assert.Nil(t, err)
+
+	assert.Equal(t, int32(1), getCount.Load())
+	assert.Equal(t, first, second)
+	assert.Equal(t, testChecksum(), second.Checksum)
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func recordRangeTransport(ranges *[]string) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*ranges = append(*ranges, req.Header.Get("Range"))
+		return http.DefaultTransport.RoundTrip(req)
+	})
+}
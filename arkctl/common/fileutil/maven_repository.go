@@ -0,0 +1,142 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fileutil
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrMavenArtifactNotFound is returned when the artifact doesn't exist in the maven repository.
+var ErrMavenArtifactNotFound = errors.New("maven artifact not found")
+
+// ParseMavenCoordinate parse groupId:artifactId:version[:packaging[:classifier]] into MavenCoordinate,
+// which is the format of mvn dependency:get, only the jar packaging is supported.
+func ParseMavenCoordinate(coords string) (*MavenCoordinate, error) {
+	parts := strings.Split(strings.TrimSpace(coords), ":")
+	if len(parts) < 3 || len(parts) > 5 {
+		return nil, fmt.Errorf("invalid maven coordinates %q: expect groupId:artifactId:version[:packaging[:classifier]]", coords)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("invalid maven coordinates %q: expect groupId:artifactId:version[:packaging[:classifier]]", coords)
+		}
+	}
+	if len(parts) > 3 && parts[3] != "jar" {
+		return nil, fmt.Errorf("invalid maven coordinates %q: packaging %s is not supported, expect jar", coords, parts[3])
+	}
+
+	coordinate := &MavenCoordinate{
+		GroupId:    parts[0],
+		ArtifactId: parts[1],
+		Version:    parts[2],
+	}
+	if len(parts) == 5 {
+		coordinate.Classifier = parts[4]
+	}
+	return coordinate, nil
+}
+
+// mavenMetadata is the maven-metadata.xml of a SNAPSHOT version in the remote repository.
+type mavenMetadata struct {
+	Versioning struct {
+		Snapshot struct {
+			Timestamp   string `xml:"timestamp"`
+			BuildNumber string `xml:"buildNumber"`
+		} `xml:"snapshot"`
+		SnapshotVersions []struct {
+			Classifier string `xml:"classifier"`
+			Extension  string `xml:"extension"`
+			Value      string `xml:"value"`
+		} `xml:"snapshotVersions>snapshotVersion"`
+	} `xml:"versioning"`
+}
+
+// snapshotVersion return the timestamped version of the jar with classifier, empty if the metadata doesn't tell.
+func (m *mavenMetadata) snapshotVersion(baseVersion, classifier string) string {
+	for _, version := range m.Versioning.SnapshotVersions {
+		if version.Extension == "jar" && version.Classifier == classifier {
+			return version.Value
+		}
+	}
+	if snapshot := m.Versioning.Snapshot; snapshot.Timestamp != "" && snapshot.BuildNumber != "" {
+		return strings.TrimSuffix(baseVersion, "-SNAPSHOT") + "-" + snapshot.Timestamp + "-" + snapshot.BuildNumber
+	}
+	return ""
+}
+
+// MavenArtifactUrl return the url of the artifact jar in the remote maven repository at repoBaseUrl.
+// A SNAPSHOT version is resolved to the newest timestamped build by the maven-metadata.xml of the version,
+// the credentials of the repository are taken from the DownloadOptions in ctx.
+func MavenArtifactUrl(ctx context.Context, repoBaseUrl string, coordinate *MavenCoordinate) (FileUrl, error) {
+	versionUrl := strings.TrimSuffix(repoBaseUrl, "/") + "/" +
+		strings.ReplaceAll(coordinate.GroupId, ".", "/") + "/" +
+		coordinate.ArtifactId + "/" +
+		coordinate.Version + "/"
+
+	fileVersion := coordinate.Version
+	if strings.HasSuffix(coordinate.Version, "-SNAPSHOT") {
+		metadata, err := fetchMavenMetadata(ctx, versionUrl+"maven-metadata.xml", coordinate)
+		if err != nil {
+			return "", err
+		}
+		if metadata != nil {
+			if version := metadata.snapshotVersion(coordinate.Version, coordinate.Classifier); version != "" {
+				fileVersion = version
+			}
+		}
+	}
+
+	fileName := coordinate.ArtifactId + "-" + fileVersion
+	if coordinate.Classifier != "" {
+		fileName += "-" + coordinate.Classifier
+	}
+	return FileUrl(versionUrl + fileName + ".jar"), nil
+}
+
+// fetchMavenMetadata fetch the maven-metadata.xml, nil if the repository doesn't have it, like the non unique snapshots.
+func fetchMavenMetadata(ctx context.Context, metadataUrl string, coordinate *MavenCoordinate) (*mavenMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	options := getDownloadOptions(ctx)
+	options.setAuth(req)
+	resp, err := (&HttpResolver{}).client(options).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("fetch maven metadata of %s failed with code %d, check the credentials of the maven repository",
+			coordinate, resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("fetch maven metadata of %s failed with code %d", coordinate, resp.StatusCode)
+	}
+
+	metadata := &mavenMetadata{}
+	if err := xml.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		return nil, fmt.Errorf("invalid maven metadata of %s: %w", coordinate, err)
+	}
+	return metadata, nil
+}
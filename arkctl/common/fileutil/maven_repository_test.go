@@ -0,0 +1,54 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fileutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMavenCoordinate(t *testing.T) {
+	coordinate, err := ParseMavenCoordinate("com.alipay.sofa:biz1:1.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, &MavenCoordinate{GroupId: "com.alipay.sofa", ArtifactId: "biz1", Version: "1.0.0"}, coordinate)
+
+	coordinate, err = ParseMavenCoordinate("com.alipay.sofa:biz1:1.0.0:jar:ark-biz")
+	assert.Nil(t, err)
+	assert.Equal(t, "com.alipay.sofa:biz1:1.0.0:jar:ark-biz", coordinate.String())
+
+	for _, invalid := range []string{"com.alipay.sofa:biz1", "com.alipay.sofa::1.0.0", "com.alipay.sofa:biz1:1.0.0:war", "a:b:c:jar:d:e"} {
+		_, err = ParseMavenCoordinate(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestMavenArtifactUrl_SnapshotWithoutClassifierMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<metadata><versioning><snapshot><timestamp>20231011.080000</timestamp><buildNumber>3</buildNumber></snapshot></versioning></metadata>`))
+	}))
+	defer server.Close()
+
+	artifactUrl, err := MavenArtifactUrl(context.Background(), server.URL, &MavenCoordinate{
+		GroupId:    "com.alipay.sofa",
+		ArtifactId: "biz1",
+		Version:    "1.0.0-SNAPSHOT",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, FileUrl(server.URL+"/com/alipay/sofa/biz1/1.0.0-SNAPSHOT/biz1-1.0.0-20231011.080000-3.jar"), artifactUrl)
+}
@@ -0,0 +1,196 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fileutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/cmdutil"
+)
+
+// MavenCoordinate is the GAV coordinate of a maven artifact.
+type MavenCoordinate struct {
+	GroupId    string
+	ArtifactId string
+	Version    string
+	Classifier string
+}
+
+func (c MavenCoordinate) String() string {
+	if c.Classifier == "" {
+		return fmt.Sprintf("%s:%s:%s", c.GroupId, c.ArtifactId, c.Version)
+	}
+	return fmt.Sprintf("%s:%s:%s:jar:%s", c.GroupId, c.ArtifactId, c.Version, c.Classifier)
+}
+
+// ParseMavenFileUrl parse mvn://groupId/artifactId/version[/classifier] into MavenCoordinate.
+func ParseMavenFileUrl(fileUrl FileUrl) (*MavenCoordinate, error) {
+	raw := string(fileUrl)
+	if !strings.HasPrefix(raw, "mvn://") {
+		return nil, fmt.Errorf("invalid maven url %s: scheme must be mvn", raw)
+	}
+
+	parts := strings.Split(strings.Trim(raw[len("mvn://"):], "/"), "/")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, fmt.Errorf("invalid maven url %s: expect mvn://groupId/artifactId/version[/classifier]", raw)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("invalid maven url %s: expect mvn://groupId/artifactId/version[/classifier]", raw)
+		}
+	}
+
+	coordinate := &MavenCoordinate{
+		GroupId:    parts[0],
+		ArtifactId: parts[1],
+		Version:    parts[2],
+	}
+	if len(parts) == 4 {
+		coordinate.Classifier = parts[3]
+	}
+	return coordinate, nil
+}
+
+// MavenResolver locate maven artifacts in the local maven repository.
+type MavenResolver struct {
+	// LocalRepository is the local maven repository, default to ~/.m2/repository.
+	LocalRepository string
+
+	// FetchMissing runs mvn dependency:get to fetch the artifact when it's missing in local repository.
+	FetchMissing bool
+
+	// RunMaven runs mvn with given args, the mvn command in PATH is used if not given.
+	RunMaven func(ctx context.Context, args ...string) error
+}
+
+func (r *MavenResolver) localRepository() (string, error) {
+	if r.LocalRepository != "" {
+		return r.LocalRepository, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".m2", "repository"), nil
+}
+
+func (r *MavenResolver) runMaven(ctx context.Context, args ...string) error {
+	if r.RunMaven != nil {
+		return r.RunMaven(ctx, args...)
+	}
+
+	// mvn reports build errors on stdout, so the output is attached to the exit error
+	output, err := cmdutil.RunCommand(ctx, "mvn", args...)
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, strings.Join(output, "\n"))
+	}
+	return nil
+}
+
+// Resolve find the artifact file in local repository.
+func (r *MavenResolver) Resolve(ctx context.Context, fileUrl FileUrl) (*LocalizedFile, error) {
+	coordinate, err := ParseMavenFileUrl(fileUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	repository, err := r.localRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	artifactPath, err := findMavenArtifact(repository, coordinate)
+	if err != nil && r.FetchMissing {
+		if fetchErr := r.runMaven(ctx,
+			"dependency:get",
+			"-Dartifact="+coordinate.String(),
+			"-Dtransitive=false",
+			"-Dmaven.repo.local="+repository,
+		); fetchErr != nil {
+			return nil, fmt.Errorf("fetch maven artifact %s failed: %w", coordinate, fetchErr)
+		}
+		artifactPath, err = findMavenArtifact(repository, coordinate)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return localize(artifactPath)
+}
+
+// findMavenArtifact return the artifact path in local repository.
+// For SNAPSHOT versions, the newest timestamped build is preferred.
+func findMavenArtifact(repository string, coordinate *MavenCoordinate) (string, error) {
+	versionDir := filepath.Join(
+		repository,
+		filepath.Join(strings.Split(coordinate.GroupId, ".")...),
+		coordinate.ArtifactId,
+		coordinate.Version,
+	)
+
+	suffix := ".jar"
+	if coordinate.Classifier != "" {
+		suffix = "-" + coordinate.Classifier + ".jar"
+	}
+
+	if strings.HasSuffix(coordinate.Version, "-SNAPSHOT") {
+		if newest := findNewestSnapshot(versionDir, coordinate, suffix); newest != "" {
+			return newest, nil
+		}
+	}
+
+	artifactPath := filepath.Join(versionDir, coordinate.ArtifactId+"-"+coordinate.Version+suffix)
+	if _, err := os.Stat(artifactPath); err != nil {
+		return "", fmt.Errorf("maven artifact %s not found in local repository %s", coordinate, repository)
+	}
+	return artifactPath, nil
+}
+
+// findNewestSnapshot return the newest {artifactId}-{baseVersion}-{yyyyMMdd.HHmmss}-{buildNumber}{suffix} in dir.
+func findNewestSnapshot(versionDir string, coordinate *MavenCoordinate, suffix string) string {
+	entries, err := os.ReadDir(versionDir)
+	if err != nil {
+		return ""
+	}
+
+	baseVersion := strings.TrimSuffix(coordinate.Version, "-SNAPSHOT")
+	pattern := regexp.MustCompile("^" +
+		regexp.QuoteMeta(coordinate.ArtifactId+"-"+baseVersion+"-") +
+		`(\d{8}\.\d{6})-(\d+)` +
+		regexp.QuoteMeta(suffix) + "$")
+
+	newest, newestTimestamp, newestBuild := "", "", 0
+	for _, entry := range entries {
+		matches := pattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		build := 0
+		fmt.Sscanf(matches[2], "%d", &build)
+		if matches[1] > newestTimestamp || (matches[1] == newestTimestamp && build > newestBuild) {
+			newest, newestTimestamp, newestBuild = entry.Name(), matches[1], build
+		}
+	}
+
+	if newest == "" {
+		return ""
+	}
+	return filepath.Join(versionDir, newest)
+}
@@ -0,0 +1,120 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fileutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createArtifact(t *testing.T, repository string, relativePath string) string {
+	artifactPath := filepath.Join(repository, relativePath)
+	assert.Nil(t, os.MkdirAll(filepath.Dir(artifactPath), 0755))
+	assert.Nil(t, os.WriteFile(artifactPath, []byte(testContent), 0644))
+	return artifactPath
+}
+
+func TestParseMavenFileUrl(t *testing.T) {
+	coordinate, err := ParseMavenFileUrl("mvn://com.alipay.sofa/biz1/1.0.0/ark-biz")
+	assert.Nil(t, err)
+	assert.Equal(t, &MavenCoordinate{
+		GroupId:    "com.alipay.sofa",
+		ArtifactId: "biz1",
+		Version:    "1.0.0",
+		Classifier: "ark-biz",
+	}, coordinate)
+	assert.Equal(t, "com.alipay.sofa:biz1:1.0.0:jar:ark-biz", coordinate.String())
+
+	_, err = ParseMavenFileUrl("mvn://com.alipay.sofa/biz1")
+	assert.NotNil(t, err)
+}
+
+func TestMavenResolver_Release(t *testing.T) {
+	repository := t.TempDir()
+	expected := createArtifact(t, repository, "com/alipay/sofa/biz1/1.0.0/biz1-1.0.0-ark-biz.jar")
+
+	resolver := &MavenResolver{LocalRepository: repository}
+	localized, err := resolver.Resolve(context.Background(), "mvn://com.alipay.sofa/biz1/1.0.0/ark-biz")
+	assert.Nil(t, err)
+	assert.Equal(t, expected, localized.Path)
+	assert.Equal(t, testChecksum(), localized.Checksum)
+	assert.False(t, localized.Temporary)
+}
+
+func TestMavenResolver_NewestSnapshot(t *testing.T) {
+	repository := t.TempDir()
+	dir := "com/alipay/sofa/biz1/1.0.0-SNAPSHOT/"
+	createArtifact(t, repository, dir+"biz1-1.0.0-SNAPSHOT.jar")
+	createArtifact(t, repository, dir+"biz1-1.0.0-20231010.101010-1.jar")
+	expected := createArtifact(t, repository, dir+"biz1-1.0.0-20231011.080000-2.jar")
+	createArtifact(t, repository, dir+"biz1-1.0.0-20231011.080000-1.jar")
+	createArtifact(t, repository, dir+"biz1-1.0.0-20231012.080000-3-sources.jar")
+
+	resolver := &MavenResolver{LocalRepository: repository}
+	localized, err := resolver.Resolve(context.Background(), "mvn://com.alipay.sofa/biz1/1.0.0-SNAPSHOT")
+	assert.Nil(t, err)
+	assert.Equal(t, expected, localized.Path)
+}
+
+func TestMavenResolver_FetchMissing(t *testing.T) {
+	repository := t.TempDir()
+
+	var fetchedArgs []string
+	resolver := &MavenResolver{
+		LocalRepository: repository,
+		FetchMissing:    true,
+		RunMaven: func(_ context.Context, args ...string) error {
+			fetchedArgs = args
+			createArtifact(t, repository, "com/alipay/biz1/1.0.0/biz1-1.0.0.jar")
+			return nil
+		},
+	}
+
+	localized, err := resolver.Resolve(context.Background(), "mvn://com.alipay/biz1/1.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(repository, "com/alipay/biz1/1.0.0/biz1-1.0.0.jar"), localized.Path)
+	assert.Equal(t, []string{
+		"dependency:get",
+		"-Dartifact=com.alipay:biz1:1.0.0",
+		"-Dtransitive=false",
+		"-Dmaven.repo.local=" + repository,
+	}, fetchedArgs)
+}
+
+func TestMavenResolver_Missing(t *testing.T) {
+	repository := t.TempDir()
+	resolver := &MavenResolver{LocalRepository: repository}
+	_, err := resolver.Resolve(context.Background(), "mvn://com.alipay/biz1/1.0.0")
+	assert.NotNil(t, err)
+	assert.Equal(t, "maven artifact com.alipay:biz1:1.0.0 not found in local repository "+repository, err.Error())
+}
+
+func TestMavenResolver_FetchFailed(t *testing.T) {
+	// a fake mvn which warns on stderr first and fails later
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho 'WARNING: slow' >&2\nsleep 0.2\necho '[ERROR] artifact not found'\nexit 1\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(bin, "mvn"), []byte(script), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	resolver := &MavenResolver{LocalRepository: t.TempDir(), FetchMissing: true}
+	_, err := resolver.Resolve(context.Background(), "mvn://com.alipay/biz1/1.0.0")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "exit status 1")
+	assert.Contains(t, err.Error(), "[ERROR] artifact not found")
+}
@@ -0,0 +1,192 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fileutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	neturl "net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LocalizedFile is a file that is available in local file system.
+type LocalizedFile struct {
+	// Path is the absolute local path of the file.
+	Path string
+
+	// Size is the size of the file in bytes.
+	Size int64
+
+	// Checksum is the hex encoded sha256 checksum of the file.
+	Checksum string
+
+	// Temporary is true if the file is downloaded to a temp file,
+	// which should be cleaned up by the caller after use.
+	Temporary bool
+}
+
+// Cleanup remove the file if it's temporary.
+func (f *LocalizedFile) Cleanup() error {
+	if !f.Temporary {
+		return nil
+	}
+	return os.Remove(f.Path)
+}
+
+// Resolver makes the file given by fileUrl available in local file system.
+type Resolver interface {
+	// Resolve return the localized file of fileUrl.
+	Resolve(ctx context.Context, fileUrl FileUrl) (*LocalizedFile, error)
+}
+
+// ResolverFunc is an adapter to allow the use of ordinary functions as Resolver.
+type ResolverFunc func(ctx context.Context, fileUrl FileUrl) (*LocalizedFile, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, fileUrl FileUrl) (*LocalizedFile, error) {
+	return f(ctx, fileUrl)
+}
+
+// UnsupportedSchemeError is returned when no resolver is registered for the scheme of a file url.
+type UnsupportedSchemeError struct {
+	// Scheme is the scheme of the file url.
+	Scheme string
+
+	// Supported is all the registered schemes.
+	Supported []string
+}
+
+func (e *UnsupportedSchemeError) Error() string {
+	return fmt.Sprintf("unsupported file url scheme %q, supported schemes are %s",
+		e.Scheme, strings.Join(e.Supported, ", "))
+}
+
+var (
+	resolversLock sync.RWMutex
+	resolvers     = map[string]Resolver{}
+)
+
+func init() {
+	RegisterResolver("file", ResolverFunc(resolveLocalFile))
+	RegisterResolver("http", &HttpResolver{})
+	RegisterResolver("https", &HttpResolver{})
+	RegisterResolver("oss", NewOssResolver(""))
+	RegisterResolver("mvn", &MavenResolver{})
+}
+
+// RegisterResolver register a resolver for given scheme, the existing one will be replaced.
+func RegisterResolver(scheme string, resolver Resolver) {
+	resolversLock.Lock()
+	defer resolversLock.Unlock()
+	resolvers[strings.ToLower(scheme)] = resolver
+}
+
+// SupportedSchemes return all the registered schemes in order.
+func SupportedSchemes() []string {
+	resolversLock.RLock()
+	defer resolversLock.RUnlock()
+	schemes := make([]string, 0, len(resolvers))
+	for scheme := range resolvers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+func getResolver(scheme string) (Resolver, bool) {
+	resolversLock.RLock()
+	defer resolversLock.RUnlock()
+	resolver, ok := resolvers[strings.ToLower(scheme)]
+	return resolver, ok
+}
+
+// Resolve find the resolver by the scheme of fileUrl and resolve it to a local file.
+func Resolve(ctx context.Context, fileUrl FileUrl) (*LocalizedFile, error) {
+	u, err := neturl.Parse(strings.TrimSpace(string(fileUrl)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid file url %s: %w", fileUrl, err)
+	}
+
+	resolver, ok := getResolver(u.Scheme)
+	if !ok {
+		return nil, &UnsupportedSchemeError{Scheme: u.Scheme, Supported: SupportedSchemes()}
+	}
+	return resolver.Resolve(ctx, fileUrl)
+}
+
+// localize calculate the size and checksum of a local file.
+func localize(localPath string) (*LocalizedFile, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalizedFile{
+		Path:     localPath,
+		Size:     size,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func resolveLocalFile(_ context.Context, fileUrl FileUrl) (*LocalizedFile, error) {
+	parsed, err := fileUrl.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return localize(parsed.Path)
+}
+
+const (
+	// OssEndpointEnv is the environment variable of the oss endpoint used if neither the url nor the resolver gives one.
+	OssEndpointEnv = "ARKCTL_OSS_ENDPOINT"
+
+	defaultOssEndpoint = "oss-cn-hangzhou.aliyuncs.com"
+)
+
+// NewOssResolver return a resolver that download oss://{bucket}/{key} from
+// the public endpoint https://{bucket}.{endpoint}/{key} with the resolver registered for https.
+// The endpoint given by the url like oss://{bucket}.{endpoint}/{key} wins, then the endpoint of the resolver,
+// then OssEndpointEnv, and oss-cn-hangzhou.aliyuncs.com at last.
+func NewOssResolver(endpoint string) Resolver {
+	return ResolverFunc(func(ctx context.Context, fileUrl FileUrl) (*LocalizedFile, error) {
+		parsed, err := fileUrl.Parse()
+		if err != nil {
+			return nil, err
+		}
+		return Resolve(ctx, FileUrl(fmt.Sprintf("https://%s.%s/%s", parsed.Bucket, ossEndpoint(parsed.Endpoint, endpoint), parsed.Path)))
+	})
+}
+
+// ossEndpoint return the first endpoint given, or the one of OssEndpointEnv, or the default one.
+func ossEndpoint(endpoints ...string) string {
+	for _, endpoint := range append(endpoints, os.Getenv(OssEndpointEnv)) {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			return endpoint
+		}
+	}
+	return defaultOssEndpoint
+}
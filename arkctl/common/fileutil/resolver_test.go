@@ -0,0 +1,134 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fileutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testContent = "hello ark biz"
+
+func testChecksum() string {
+	sum := sha256.Sum256([]byte(testContent))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestResolve_LocalFile(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "biz-ark-biz.jar")
+	assert.Nil(t, os.WriteFile(localPath, []byte(testContent), 0644))
+
+	localized, err := Resolve(context.Background(), FileUrl("file://"+localPath))
+	assert.Nil(t, err)
+	assert.Equal(t, &LocalizedFile{
+		Path:     localPath,
+		Size:     int64(len(testContent)),
+		Checksum: testChecksum(),
+	}, localized)
+}
+
+func TestResolve_HttpFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testContent))
+	}))
+	defer server.Close()
+
+	localized, err := Resolve(context.Background(), FileUrl(server.URL+"/repo/biz-ark-biz.jar"))
+	assert.Nil(t, err)
+	defer os.Remove(localized.Path)
+
+	assert.Equal(t, int64(len(testContent)), localized.Size)
+	assert.Equal(t, testChecksum(), localized.Checksum)
+	content, err := os.ReadFile(localized.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, testContent, string(content))
+}
+
+func TestResolve_HttpFileNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := Resolve(context.Background(), FileUrl(server.URL+"/biz-ark-biz.jar"))
+	assert.NotNil(t, err)
+	assert.Equal(t, "download "+server.URL+"/biz-ark-biz.jar failed with code 404", err.Error())
+}
+
+func TestResolve_CustomScheme(t *testing.T) {
+	RegisterResolver("custom", ResolverFunc(func(_ context.Context, fileUrl FileUrl) (*LocalizedFile, error) {
+		return &LocalizedFile{Path: "/resolved/" + string(fileUrl)[len("custom://"):]}, nil
+	}))
+	defer func() {
+		resolversLock.Lock()
+		delete(resolvers, "custom")
+		resolversLock.Unlock()
+	}()
+
+	fileUrl, err := ParseFileUrl("custom://host/biz-ark-biz.jar")
+	assert.Nil(t, err)
+	urlType, err := fileUrl.GetFileUrlType()
+	assert.Nil(t, err)
+	assert.Equal(t, FileUrlType("custom"), urlType)
+
+	localized, err := Resolve(context.Background(), fileUrl)
+	assert.Nil(t, err)
+	assert.Equal(t, "/resolved/host/biz-ark-biz.jar", localized.Path)
+}
+
+func TestOssResolver_Endpoint(t *testing.T) {
+	var requested []string
+	RegisterResolver("https", ResolverFunc(func(_ context.Context, fileUrl FileUrl) (*LocalizedFile, error) {
+		requested = append(requested, string(fileUrl))
+		return &LocalizedFile{}, nil
+	}))
+	defer RegisterResolver("https", &HttpResolver{})
+
+	ctx := context.Background()
+	t.Setenv(OssEndpointEnv, "")
+	_, err := NewOssResolver("").Resolve(ctx, "oss://bucket/biz-ark-biz.jar")
+	assert.Nil(t, err)
+	t.Setenv(OssEndpointEnv, "oss-cn-beijing.aliyuncs.com")
+	_, err = NewOssResolver("").Resolve(ctx, "oss://bucket/biz-ark-biz.jar")
+	assert.Nil(t, err)
+	_, err = NewOssResolver("oss-us-west-1.aliyuncs.com").Resolve(ctx, "oss://bucket/biz-ark-biz.jar")
+	assert.Nil(t, err)
+	_, err = NewOssResolver("oss-us-west-1.aliyuncs.com").Resolve(ctx, "oss://bucket.oss-cn-shanghai.aliyuncs.com/biz-ark-biz.jar")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{
+		"https://bucket.oss-cn-hangzhou.aliyuncs.com/biz-ark-biz.jar",
+		"https://bucket.oss-cn-beijing.aliyuncs.com/biz-ark-biz.jar",
+		"https://bucket.oss-us-west-1.aliyuncs.com/biz-ark-biz.jar",
+		"https://bucket.oss-cn-shanghai.aliyuncs.com/biz-ark-biz.jar",
+	}, requested)
+}
+
+func TestResolve_UnsupportedScheme(t *testing.T) {
+	_, err := Resolve(context.Background(), "ftp://example.com/biz-ark-biz.jar")
+	assert.NotNil(t, err)
+
+	unsupportedErr := &UnsupportedSchemeError{}
+	assert.True(t, errors.As(err, &unsupportedErr))
+	assert.Equal(t, "ftp", unsupportedErr.Scheme)
+	assert.Equal(t, []string{"file", "http", "https", "mvn", "oss"}, unsupportedErr.Supported)
+}
@@ -0,0 +1,222 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8sutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSource is where the kube config comes from.
+type ConfigSource string
+
+const (
+	ConfigSourceFlag      ConfigSource = "flag"
+	ConfigSourceEnv       ConfigSource = "env"
+	ConfigSourceHome      ConfigSource = "home"
+	ConfigSourceInCluster ConfigSource = "in-cluster"
+)
+
+const (
+	defaultServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// Options are the user provided flags to select the kube config.
+type Options struct {
+	// Kubeconfig is the explicit kubeconfig file, it takes precedence over all other sources.
+	Kubeconfig string
+
+	// Context overrides the current-context of the kubeconfig.
+	Context string
+
+	// Impersonate is the user to act as, like kubectl --as.
+	Impersonate string
+}
+
+// Config is the resolved kube config used by kubectl.
+type Config struct {
+	// Source is where the config comes from.
+	Source ConfigSource
+
+	// KubeconfigPaths are the kubeconfig files, empty for in-cluster config.
+	KubeconfigPaths []string
+
+	// Context is the selected context, empty for in-cluster config.
+	Context string
+
+	// Impersonate is the user to act as.
+	Impersonate string
+}
+
+// KubectlArgs prepend the global flags selecting this config to the kubectl args.
+func (c *Config) KubectlArgs(args ...string) []string {
+	if c == nil {
+		return args
+	}
+
+	var flags []string
+	// kubectl reads and merges $KUBECONFIG itself, and falls back to in-cluster config if no kubeconfig is found
+	if c.Source == ConfigSourceFlag || c.Source == ConfigSourceHome {
+		flags = append(flags, "--kubeconfig", c.KubeconfigPaths[0])
+	}
+	if c.Context != "" {
+		flags = append(flags, "--context", c.Context)
+	}
+	if c.Impersonate != "" {
+		flags = append(flags, "--as", c.Impersonate)
+	}
+	return append(flags, args...)
+}
+
+// ConfigBuilder discovers the kube config, the environment accessors are replaceable for testing.
+type ConfigBuilder struct {
+	// Getenv is os.Getenv if not given.
+	Getenv func(key string) string
+
+	// HomeDir is os.UserHomeDir if not given.
+	HomeDir func() (string, error)
+
+	// ServiceAccountDir is where the service account token is mounted inside a pod.
+	ServiceAccountDir string
+}
+
+// BuildConfig discover the kube config with the default ConfigBuilder.
+func BuildConfig(opts Options) (*Config, error) {
+	return (&ConfigBuilder{}).Build(opts)
+}
+
+// Build discover the kube config in following precedence:
+// 1. the explicit kubeconfig file of Options.
+// 2. the kubeconfig files in $KUBECONFIG.
+// 3. ~/.kube/config if it exists.
+// 4. the in-cluster config if running inside a pod.
+func (b *ConfigBuilder) Build(opts Options) (*Config, error) {
+	config, err := b.discover(opts)
+	if err != nil {
+		return nil, err
+	}
+	config.Impersonate = opts.Impersonate
+
+	if config.Source == ConfigSourceInCluster {
+		if opts.Context != "" {
+			return nil, fmt.Errorf("kube context %q can't be used with in-cluster config", opts.Context)
+		}
+		return config, nil
+	}
+
+	kubeconfig, err := loadKubeconfig(config.KubeconfigPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Context = kubeconfig.CurrentContext
+	if opts.Context != "" {
+		if !kubeconfig.hasContext(opts.Context) {
+			return nil, fmt.Errorf("kube context %q not found in %s", opts.Context, strings.Join(config.KubeconfigPaths, ", "))
+		}
+		config.Context = opts.Context
+	}
+	return config, nil
+}
+
+func (b *ConfigBuilder) discover(opts Options) (*Config, error) {
+	if opts.Kubeconfig != "" {
+		return &Config{Source: ConfigSourceFlag, KubeconfigPaths: []string{opts.Kubeconfig}}, nil
+	}
+
+	getenv := b.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+
+	if env := getenv("KUBECONFIG"); env != "" {
+		var paths []string
+		for _, path := range filepath.SplitList(env) {
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) != 0 {
+			return &Config{Source: ConfigSourceEnv, KubeconfigPaths: paths}, nil
+		}
+	}
+
+	homeDir := b.HomeDir
+	if homeDir == nil {
+		homeDir = os.UserHomeDir
+	}
+	if home, err := homeDir(); err == nil {
+		path := filepath.Join(home, ".kube", "config")
+		if _, err := os.Stat(path); err == nil {
+			return &Config{Source: ConfigSourceHome, KubeconfigPaths: []string{path}}, nil
+		}
+	}
+
+	serviceAccountDir := b.ServiceAccountDir
+	if serviceAccountDir == "" {
+		serviceAccountDir = defaultServiceAccountDir
+	}
+	if getenv("KUBERNETES_SERVICE_HOST") != "" {
+		if _, err := os.Stat(filepath.Join(serviceAccountDir, "token")); err == nil {
+			return &Config{Source: ConfigSourceInCluster}, nil
+		}
+	}
+
+	return nil, errors.New("no kube config found, please set --kubeconfig, $KUBECONFIG or ~/.kube/config")
+}
+
+// kubeconfig is the subset of kubeconfig file used by arkctl.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name string `yaml:"name"`
+	} `yaml:"contexts"`
+}
+
+func (k *kubeconfig) hasContext(name string) bool {
+	for _, context := range k.Contexts {
+		if context.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadKubeconfig merge the kubeconfig files, the first file setting current-context wins like kubectl.
+func loadKubeconfig(paths []string) (*kubeconfig, error) {
+	merged := &kubeconfig{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read kubeconfig %s failed: %w", path, err)
+		}
+
+		current := &kubeconfig{}
+		if err := yaml.Unmarshal(content, current); err != nil {
+			return nil, fmt.Errorf("parse kubeconfig %s failed: %w", path, err)
+		}
+
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = current.CurrentContext
+		}
+		merged.Contexts = append(merged.Contexts, current.Contexts...)
+	}
+	return merged, nil
+}
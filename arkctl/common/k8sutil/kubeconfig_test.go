@@ -0,0 +1,154 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8sutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKubeconfig(t *testing.T, path, currentContext string, contexts ...string) string {
+	content := "apiVersion: v1\nkind: Config\ncurrent-context: " + currentContext + "\ncontexts:\n"
+	for _, context := range contexts {
+		content += "- name: " + context + "\n  context:\n    cluster: " + context + "\n"
+	}
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+// testEnv is an isolated environment with a home dir, a service account dir and env variables.
+type testEnv struct {
+	home              string
+	serviceAccountDir string
+	env               map[string]string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	return &testEnv{
+		home:              t.TempDir(),
+		serviceAccountDir: t.TempDir(),
+		env:               map[string]string{},
+	}
+}
+
+func (e *testEnv) builder() *ConfigBuilder {
+	return &ConfigBuilder{
+		Getenv: func(key string) string {
+			return e.env[key]
+		},
+		HomeDir: func() (string, error) {
+			return e.home, nil
+		},
+		ServiceAccountDir: e.serviceAccountDir,
+	}
+}
+
+func (e *testEnv) enterPod(t *testing.T) {
+	e.env["KUBERNETES_SERVICE_HOST"] = "10.0.0.1"
+	assert.Nil(t, os.WriteFile(filepath.Join(e.serviceAccountDir, "token"), []byte("token"), 0600))
+}
+
+func TestBuild_Precedence(t *testing.T) {
+	env := newTestEnv(t)
+	env.enterPod(t)
+
+	// in-cluster is the last resort
+	config, err := env.builder().Build(Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, ConfigSourceInCluster, config.Source)
+	assert.Equal(t, []string{"get", "pods"}, config.KubectlArgs("get", "pods"))
+
+	// then ~/.kube/config
+	homeConfig := writeKubeconfig(t, filepath.Join(env.home, ".kube", "config"), "home", "home")
+	config, err = env.builder().Build(Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, ConfigSourceHome, config.Source)
+	assert.Equal(t, "home", config.Context)
+	assert.Equal(t, []string{"--kubeconfig", homeConfig, "--context", "home", "get", "pods"}, config.KubectlArgs("get", "pods"))
+
+	// then $KUBECONFIG, multiple files are merged
+	dir := t.TempDir()
+	first := writeKubeconfig(t, filepath.Join(dir, "first"), "", "first")
+	second := writeKubeconfig(t, filepath.Join(dir, "second"), "second", "second")
+	env.env["KUBECONFIG"] = first + string(os.PathListSeparator) + second
+	config, err = env.builder().Build(Options{Context: "first"})
+	assert.Nil(t, err)
+	assert.Equal(t, ConfigSourceEnv, config.Source)
+	assert.Equal(t, []string{first, second}, config.KubeconfigPaths)
+	assert.Equal(t, "first", config.Context)
+
+	// then the explicit file
+	explicit := writeKubeconfig(t, filepath.Join(dir, "explicit"), "explicit", "explicit")
+	config, err = env.builder().Build(Options{Kubeconfig: explicit, Impersonate: "dev"})
+	assert.Nil(t, err)
+	assert.Equal(t, ConfigSourceFlag, config.Source)
+	assert.Equal(t, "explicit", config.Context)
+	assert.Equal(t, []string{"--kubeconfig", explicit, "--context", "explicit", "--as", "dev"}, config.KubectlArgs())
+}
+
+func TestBuild_ContextNotFound(t *testing.T) {
+	env := newTestEnv(t)
+	writeKubeconfig(t, filepath.Join(env.home, ".kube", "config"), "home", "home")
+
+	_, err := env.builder().Build(Options{Context: "not-exist"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "kube context \"not-exist\" not found")
+}
+
+func TestBuild_ContextWithInCluster(t *testing.T) {
+	env := newTestEnv(t)
+	env.enterPod(t)
+
+	_, err := env.builder().Build(Options{Context: "home"})
+	assert.NotNil(t, err)
+}
+
+func TestBuild_NoConfig(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.builder().Build(Options{})
+	assert.NotNil(t, err)
+}
+
+func TestCheckPodAccess(t *testing.T) {
+	config := &Config{Source: ConfigSourceInCluster, Impersonate: "dev"}
+
+	var commands [][]string
+	allowed := map[string]bool{ResourcePodsPortForward: true}
+	runner := func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		commands = append(commands, append([]string{cmd}, args...))
+		if allowed[args[5]] {
+			return []string{"yes"}, nil
+		}
+		return []string{"no"}, errors.New("exit status 1")
+	}
+
+	err := CheckPodAccess(context.Background(), runner, config, "default", ResourcePodsExec, ResourcePodsPortForward)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"kubectl", "--as", "dev", "auth", "can-i", "create", "pods/exec", "-n", "default"}, commands[0])
+
+	allowed = map[string]bool{}
+	err = CheckPodAccess(context.Background(), runner, config, "default", ResourcePodsExec, ResourcePodsPortForward)
+	rbacErr := &RBACError{}
+	assert.True(t, errors.As(err, &rbacErr))
+	assert.Equal(t, "dev is not allowed to create pods/exec or pods/portforward in namespace default, "+
+		"please ask your cluster admin to grant it with a Role and RoleBinding", err.Error())
+}
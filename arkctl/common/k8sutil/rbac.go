@@ -0,0 +1,71 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/cmdutil"
+)
+
+const (
+	ResourcePodsExec        = "pods/exec"
+	ResourcePodsPortForward = "pods/portforward"
+)
+
+// RBACError is returned when the user is not allowed to access the pods.
+type RBACError struct {
+	// User is the impersonated user, empty for the current user.
+	User string
+
+	// Namespace is the namespace of the pods.
+	Namespace string
+
+	// Resources are the denied resources.
+	Resources []string
+}
+
+func (e *RBACError) Error() string {
+	user := e.User
+	if user == "" {
+		user = "current user"
+	}
+	return fmt.Sprintf("%s is not allowed to create %s in namespace %s, "+
+		"please ask your cluster admin to grant it with a Role and RoleBinding",
+		user, strings.Join(e.Resources, " or "), e.Namespace)
+}
+
+// CheckPodAccess check the user is allowed to create any of the pod sub resources, e.g. pods/exec, with kubectl auth can-i.
+// RBACError is returned if none of them is allowed.
+func CheckPodAccess(ctx context.Context, runner cmdutil.Runner, config *Config, namespace string, resources ...string) error {
+	for _, resource := range resources {
+		lines, err := runner(ctx, "kubectl", config.KubectlArgs("auth", "can-i", "create", resource, "-n", namespace)...)
+		// kubectl auth can-i exits with 1 and prints no if it's not allowed
+		if len(lines) != 0 && strings.TrimSpace(lines[0]) == "yes" {
+			return nil
+		}
+		if len(lines) == 0 && err != nil {
+			return fmt.Errorf("check permission of %s failed: %w", resource, err)
+		}
+	}
+
+	rbacErr := &RBACError{Namespace: namespace, Resources: resources}
+	if config != nil {
+		rbacErr.User = config.Impersonate
+	}
+	return rbacErr
+}
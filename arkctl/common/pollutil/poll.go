@@ -0,0 +1,215 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pollutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrBudgetExhausted is returned when the condition isn't done within the budget of polling.
+var ErrBudgetExhausted = errors.New("poll budget exhausted")
+
+// Clock is the source of time, replaced by a fake one in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Config controls how Poll waits between the attempts.
+type Config struct {
+	// Interval is the wait after the first attempt, 1s if not positive.
+	Interval time.Duration
+
+	// Multiplier grows the interval after each attempt, the interval is constant if it's less than 1.
+	Multiplier float64
+
+	// MaxInterval caps the interval, no cap if not positive.
+	MaxInterval time.Duration
+
+	// Jitter randomizes each wait by up to the fraction of the interval, e.g. 0.2 for ±20%.
+	Jitter float64
+
+	// Delay is the wait before the first attempt, the condition is polled immediately if not positive.
+	Delay time.Duration
+
+	// Budget is the max time of polling, no limit except the deadline of ctx if not positive.
+	Budget time.Duration
+
+	// Clock is the source of time, the real clock is used if nil.
+	Clock Clock
+
+	// Rand returns the random number in [0, 1) for jitter, math/rand is used if nil.
+	Rand func() float64
+}
+
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.Multiplier < 1 {
+		c.Multiplier = 1
+	}
+	if c.Clock == nil {
+		c.Clock = realClock{}
+	}
+	if c.Rand == nil {
+		c.Rand = rand.Float64
+	}
+	return c
+}
+
+// next return the interval after interval.
+func (c Config) next(interval time.Duration) time.Duration {
+	next := time.Duration(float64(interval) * c.Multiplier)
+	if c.MaxInterval > 0 && next > c.MaxInterval {
+		return c.MaxInterval
+	}
+	return next
+}
+
+// jittered randomize the interval by the Jitter.
+func (c Config) jittered(interval time.Duration) time.Duration {
+	if c.Jitter <= 0 {
+		return interval
+	}
+	jittered := interval + time.Duration((c.Rand()*2-1)*c.Jitter*float64(interval))
+	if jittered < 0 {
+		return 0
+	}
+	return jittered
+}
+
+// Condition is polled until it's done, the errors are retried unless they're marked by Fatal.
+type Condition func(ctx context.Context) (done bool, err error)
+
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fatalError) Unwrap() error {
+	return e.err
+}
+
+// Fatal mark err to stop polling, Poll returns err as is.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fatalError{err: err}
+}
+
+// Error is returned when Poll gives up because ctx is done or the budget is exhausted.
+type Error struct {
+	// Attempts is how many times the condition is polled.
+	Attempts int
+
+	// Elapsed is the time spent on polling.
+	Elapsed time.Duration
+
+	// LastErr is the error of the last attempt, nil if the condition is just not done.
+	LastErr error
+
+	// Err is why polling gives up, the error of ctx or ErrBudgetExhausted.
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%v after %d attempts in %v", e.Err, e.Attempts, e.Elapsed)
+	if e.LastErr != nil {
+		msg += fmt.Sprintf(", last error: %v", e.LastErr)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Poll call condition until it's done, waiting between the attempts with exponential backoff and jitter.
+// A Fatal error stops polling and is returned as is, other errors are retried.
+// Error is returned if ctx is done or the budget is exhausted before the condition is done.
+func Poll(ctx context.Context, config Config, condition Condition) error {
+	config = config.withDefaults()
+	clock := config.Clock
+	start := clock.Now()
+	attempts := 0
+	var lastErr error
+	giveUp := func(err error) error {
+		return &Error{Attempts: attempts, Elapsed: clock.Now().Sub(start), LastErr: lastErr, Err: err}
+	}
+	// wait return the error if polling should give up before the next attempt
+	wait := func(d time.Duration) error {
+		if config.Budget > 0 {
+			remaining := config.Budget - clock.Now().Sub(start)
+			if remaining <= 0 {
+				return giveUp(ErrBudgetExhausted)
+			}
+			if d > remaining {
+				d = remaining
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return giveUp(ctx.Err())
+		case <-clock.After(d):
+			return nil
+		}
+	}
+
+	if config.Delay > 0 {
+		if err := wait(config.Delay); err != nil {
+			return err
+		}
+	}
+	interval := config.Interval
+	for {
+		attempts++
+		done, err := condition(ctx)
+		fatal := &fatalError{}
+		if errors.As(err, &fatal) {
+			if err == error(fatal) {
+				return fatal.err
+			}
+			return err
+		}
+		if err == nil && done {
+			return nil
+		}
+		lastErr = err
+
+		if err := wait(config.jittered(interval)); err != nil {
+			return err
+		}
+		interval = config.next(interval)
+	}
+}
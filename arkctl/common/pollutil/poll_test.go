@@ -0,0 +1,157 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pollutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock advances the time at once when it's waited.
+type fakeClock struct {
+	now   time.Time
+	waits []time.Duration
+
+	// block makes After never fire.
+	block bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	if c.block {
+		return nil
+	}
+	c.now = c.now.Add(d)
+	fired := make(chan time.Time, 1)
+	fired <- c.now
+	return fired
+}
+
+// doneAt return the condition done at the attempt.
+func doneAt(attempt int) (Condition, *int) {
+	attempts := 0
+	return func(ctx context.Context) (bool, error) {
+		attempts++
+		return attempts >= attempt, nil
+	}, &attempts
+}
+
+func TestPoll_ExponentialBackoff(t *testing.T) {
+	clock := &fakeClock{}
+	condition, attempts := doneAt(6)
+	err := Poll(context.Background(), Config{
+		Interval:    time.Second,
+		Multiplier:  2,
+		MaxInterval: 5 * time.Second,
+		Clock:       clock,
+	}, condition)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, *attempts)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, clock.waits)
+}
+
+func TestPoll_Jitter(t *testing.T) {
+	clock := &fakeClock{}
+	random := []float64{0, 0.5, 1}
+	condition, _ := doneAt(4)
+	err := Poll(context.Background(), Config{
+		Interval: 10 * time.Second,
+		Jitter:   0.2,
+		Clock:    clock,
+		Rand: func() float64 {
+			r := random[0]
+			random = random[1:]
+			return r
+		},
+	}, condition)
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Duration{8 * time.Second, 10 * time.Second, 12 * time.Second}, clock.waits)
+}
+
+func TestPoll_BudgetExhausted(t *testing.T) {
+	clock := &fakeClock{}
+	retried := errors.New("connection refused")
+	attempts := 0
+	err := Poll(context.Background(), Config{
+		Interval: time.Second,
+		Delay:    500 * time.Millisecond,
+		Budget:   3 * time.Second,
+		Clock:    clock,
+	}, func(ctx context.Context) (bool, error) {
+		attempts++
+		if attempts == 1 {
+			return false, retried
+		}
+		return false, nil
+	})
+
+	pollErr := &Error{}
+	assert.True(t, errors.As(err, &pollErr))
+	assert.True(t, errors.Is(err, ErrBudgetExhausted))
+	assert.Equal(t, 4, pollErr.Attempts)
+	assert.Equal(t, 3*time.Second, pollErr.Elapsed)
+	assert.Nil(t, pollErr.LastErr)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second, time.Second, 500 * time.Millisecond}, clock.waits)
+	assert.Equal(t, "poll budget exhausted after 4 attempts in 3s", err.Error())
+}
+
+func TestPoll_LastError(t *testing.T) {
+	clock := &fakeClock{}
+	err := Poll(context.Background(), Config{
+		Interval: time.Second,
+		Budget:   time.Second,
+		Clock:    clock,
+	}, func(ctx context.Context) (bool, error) {
+		return false, errors.New("connection refused")
+	})
+	assert.Equal(t, "poll budget exhausted after 2 attempts in 1s, last error: connection refused", err.Error())
+}
+
+func TestPoll_Fatal(t *testing.T) {
+	clock := &fakeClock{}
+	broken := errors.New("biz is broken")
+	attempts := 0
+	err := Poll(context.Background(), Config{Clock: clock}, func(ctx context.Context) (bool, error) {
+		attempts++
+		if attempts == 2 {
+			return false, Fatal(broken)
+		}
+		return false, nil
+	})
+	assert.Equal(t, broken, err)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestPoll_ContextDone(t *testing.T) {
+	clock := &fakeClock{block: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	condition, attempts := doneAt(2)
+
+	err := Poll(ctx, Config{Clock: clock}, condition)
+	pollErr := &Error{}
+	assert.True(t, errors.As(err, &pollErr))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, *attempts)
+	assert.Equal(t, 1, pollErr.Attempts)
+}
@@ -0,0 +1,120 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progressutil
+
+import (
+	"sync/atomic"
+)
+
+// ProgressReporter reports the progress of a long operation like uploads, downloads, rollouts and batch installs.
+// The implementations must be safe for concurrent Update calls, as the fan-out workers report at once.
+type ProgressReporter interface {
+	// Start begin the operation, total is the bytes or steps to be done, or -1 if it's unknown.
+	Start(total int64)
+
+	// Update report the bytes or steps done so far, message describes what's being done and could be empty.
+	Update(done int64, message string)
+
+	// Finish end the operation, err is nil if it succeeded.
+	Finish(err error)
+}
+
+// NopReporter is a ProgressReporter doing nothing.
+type NopReporter struct{}
+
+func (NopReporter) Start(int64) {}
+
+func (NopReporter) Update(int64, string) {}
+
+func (NopReporter) Finish(error) {}
+
+// OrNop return reporter, or NopReporter if it's nil, so that the progress could be reported without nil checks.
+func OrNop(reporter ProgressReporter) ProgressReporter {
+	if reporter == nil {
+		return NopReporter{}
+	}
+	return reporter
+}
+
+// funcReporter adapts a progress callback to ProgressReporter, the total given by Start is passed to each call.
+type funcReporter struct {
+	onProgress func(done, total int64)
+	total      atomic.Int64
+}
+
+// FromFunc return the ProgressReporter calling onProgress with the done and the total on each Update,
+// for the options still taking a progress callback. It returns nil if onProgress is nil.
+func FromFunc(onProgress func(done, total int64)) ProgressReporter {
+	if onProgress == nil {
+		return nil
+	}
+	return &funcReporter{onProgress: onProgress}
+}
+
+func (r *funcReporter) Start(total int64) {
+	r.total.Store(total)
+}
+
+func (r *funcReporter) Update(done int64, _ string) {
+	r.onProgress(done, r.total.Load())
+}
+
+func (r *funcReporter) Finish(error) {}
+
+// multiReporter reports the progress to all the reporters in order.
+type multiReporter []ProgressReporter
+
+// Multi return the ProgressReporter reporting to all the non-nil reporters, or nil if there's none.
+func Multi(reporters ...ProgressReporter) ProgressReporter {
+	var multi multiReporter
+	for _, reporter := range reporters {
+		if reporter != nil {
+			multi = append(multi, reporter)
+		}
+	}
+	switch len(multi) {
+	case 0:
+		return nil
+	case 1:
+		return multi[0]
+	default:
+		return multi
+	}
+}
+
+func (m multiReporter) Start(total int64) {
+	for _, reporter := range m {
+		reporter.Start(total)
+	}
+}
+
+func (m multiReporter) Update(done int64, message string) {
+	for _, reporter := range m {
+		reporter.Update(done, message)
+	}
+}
+
+func (m multiReporter) Finish(err error) {
+	for _, reporter := range m {
+		reporter.Finish(err)
+	}
+}
+
+var (
+	_ ProgressReporter = NopReporter{}
+	_ ProgressReporter = &TerminalReporter{}
+	_ ProgressReporter = &funcReporter{}
+	_ ProgressReporter = multiReporter{}
+)
@@ -0,0 +1,53 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progressutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromFunc(t *testing.T) {
+	assert.Nil(t, FromFunc(nil))
+
+	var reported [][2]int64
+	reporter := FromFunc(func(done, total int64) {
+		reported = append(reported, [2]int64{done, total})
+	})
+	reporter.Start(10)
+	reporter.Update(4, "")
+	reporter.Update(10, "")
+	reporter.Finish(nil)
+	assert.Equal(t, [][2]int64{{4, 10}, {10, 10}}, reported)
+}
+
+func TestMulti(t *testing.T) {
+	assert.Nil(t, Multi(nil, nil))
+	single := FromFunc(func(int64, int64) {})
+	assert.Equal(t, single, Multi(nil, single))
+
+	var first, second []int64
+	reporter := Multi(
+		FromFunc(func(done, _ int64) { first = append(first, done) }),
+		nil,
+		FromFunc(func(done, _ int64) { second = append(second, done) }),
+	)
+	reporter.Start(-1)
+	reporter.Update(3, "")
+	reporter.Finish(nil)
+	assert.Equal(t, []int64{3}, first)
+	assert.Equal(t, []int64{3}, second)
+}
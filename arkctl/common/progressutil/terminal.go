@@ -0,0 +1,140 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progressutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// DefaultRedrawInterval is the min interval between two redraws of TerminalReporter.
+const DefaultRedrawInterval = 100 * time.Millisecond
+
+// DefaultLogInterval is the min interval between two log lines of TerminalReporter when the output isn't a terminal,
+// so that the logs of CI won't be flooded.
+const DefaultLogInterval = 5 * time.Second
+
+// TerminalReporter render the progress as a single line redrawn in place if the output is a terminal,
+// otherwise it degrades to plain log lines. The redraws are rate limited, Start and Finish are always rendered.
+type TerminalReporter struct {
+	out   io.Writer
+	title string
+	tty   bool
+
+	// interval is the min interval between two renders of Update.
+	interval time.Duration
+	now      func() time.Time
+
+	mu         sync.Mutex
+	total      int64
+	done       int64
+	message    string
+	lastRender time.Time
+	lineWidth  int
+}
+
+// NewTerminalReporter return a TerminalReporter writing to out, the progress line is prefixed by title.
+// The output is taken as a terminal only if it's a terminal file like os.Stdout.
+func NewTerminalReporter(out io.Writer, title string) *TerminalReporter {
+	file, ok := out.(*os.File)
+	tty := ok && term.IsTerminal(int(file.Fd()))
+	interval := DefaultLogInterval
+	if tty {
+		interval = DefaultRedrawInterval
+	}
+	return &TerminalReporter{
+		out:      out,
+		title:    title,
+		tty:      tty,
+		interval: interval,
+		now:      time.Now,
+		total:    -1,
+	}
+}
+
+func (r *TerminalReporter) Start(total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total = total
+	r.done = 0
+	r.message = ""
+	r.render(r.line())
+}
+
+func (r *TerminalReporter) Update(done int64, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// the workers may report out of order, the progress never goes back
+	if done > r.done {
+		r.done = done
+	}
+	if message != "" {
+		r.message = message
+	}
+	if r.now().Sub(r.lastRender) < r.interval {
+		return
+	}
+	r.render(r.line())
+}
+
+func (r *TerminalReporter) Finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	line := r.line() + " done"
+	if err != nil {
+		line = fmt.Sprintf("%s failed: %v", r.line(), err)
+	}
+	r.render(line)
+	if r.tty {
+		_, _ = fmt.Fprintln(r.out)
+		r.lineWidth = 0
+	}
+}
+
+// line describe the current progress, e.g. "upload biz 30/100 (30%) biz.jar".
+func (r *TerminalReporter) line() string {
+	parts := []string{r.title}
+	if r.total > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d (%d%%)", r.done, r.total, r.done*100/r.total))
+	} else {
+		parts = append(parts, fmt.Sprintf("%d", r.done))
+	}
+	if r.message != "" {
+		parts = append(parts, r.message)
+	}
+	return strings.Join(parts, " ")
+}
+
+// render redraw the line in place on terminals, or write it as a new line otherwise. It must be called with mu held.
+func (r *TerminalReporter) render(line string) {
+	r.lastRender = r.now()
+	if !r.tty {
+		_, _ = fmt.Fprintln(r.out, line)
+		return
+	}
+	// pad with spaces to wipe the rest of a longer previous line
+	padding := ""
+	if width := len(line); width < r.lineWidth {
+		padding = strings.Repeat(" ", r.lineWidth-width)
+	}
+	r.lineWidth = len(line)
+	_, _ = fmt.Fprintf(r.out, "\r%s%s", line, padding)
+}
@@ -0,0 +1,120 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progressutil
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is advanced by the tests only.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestReporter(tty bool) (*TerminalReporter, *bytes.Buffer, *fakeClock) {
+	out := &bytes.Buffer{}
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	reporter := NewTerminalReporter(out, "install biz")
+	reporter.tty = tty
+	reporter.now = clock.Now
+	return reporter, out, clock
+}
+
+func TestNewTerminalReporter_NotTerminal(t *testing.T) {
+	reporter := NewTerminalReporter(&bytes.Buffer{}, "install biz")
+	assert.False(t, reporter.tty)
+	assert.Equal(t, DefaultLogInterval, reporter.interval)
+}
+
+func TestTerminalReporter_LogLines(t *testing.T) {
+	reporter, out, clock := newTestReporter(false)
+
+	reporter.Start(4)
+	reporter.Update(1, "biz1")
+	// rate limited
+	reporter.Update(2, "biz2")
+	clock.now = clock.now.Add(DefaultLogInterval)
+	reporter.Update(3, "biz3")
+	reporter.Finish(nil)
+
+	assert.Equal(t, []string{
+		"install biz 0/4 (0%)",
+		"install biz 3/4 (75%) biz3",
+		"install biz 3/4 (75%) biz3 done",
+	}, strings.Split(strings.TrimSpace(out.String()), "\n"))
+}
+
+func TestTerminalReporter_Redraw(t *testing.T) {
+	reporter, out, clock := newTestReporter(true)
+	reporter.interval = DefaultRedrawInterval
+
+	reporter.Start(-1)
+	clock.now = clock.now.Add(DefaultRedrawInterval)
+	reporter.Update(1024, "biz.jar")
+	reporter.Finish(errors.New("connection reset"))
+
+	assert.Equal(t,
+		"\rinstall biz 0"+
+			"\rinstall biz 1024 biz.jar"+
+			"\rinstall biz 1024 biz.jar failed: connection reset\n",
+		out.String())
+
+	// the rest of a longer previous line is wiped, the finished line is kept
+	out.Reset()
+	reporter.Start(-1)
+	clock.now = clock.now.Add(DefaultRedrawInterval)
+	reporter.Update(1, "biz")
+	reporter.Start(-1)
+	assert.Equal(t, "\rinstall biz 0\rinstall biz 1 biz\rinstall biz 0    ", out.String())
+}
+
+func TestTerminalReporter_ConcurrentUpdate(t *testing.T) {
+	reporter, out, clock := newTestReporter(false)
+	reporter.Start(100)
+
+	wg := sync.WaitGroup{}
+	for i := 1; i <= 100; i++ {
+		wg.Add(1)
+		go func(done int64) {
+			defer wg.Done()
+			reporter.Update(done, "")
+		}(int64(i))
+	}
+	wg.Wait()
+	clock.now = clock.now.Add(DefaultLogInterval)
+	// the progress never goes back with the updates out of order
+	reporter.Update(50, "")
+	reporter.Finish(nil)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, "install biz 100/100 (100%) done", lines[len(lines)-1])
+}
+
+func TestOrNop(t *testing.T) {
+	assert.Equal(t, NopReporter{}, OrNop(nil))
+	reporter := NewTerminalReporter(&bytes.Buffer{}, "")
+	assert.Equal(t, reporter, OrNop(reporter))
+}
@@ -0,0 +1,207 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/progressutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/runtime"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/style"
+	"serverless.alipay.com/sofa-serverless/arkctl/v1/cmd/root"
+	"serverless.alipay.com/sofa-serverless/arkctl/v1/service/ark"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	portFlag int = 1238
+
+	descriptorFlag string
+	journalFlag    string
+	resumeFlag     bool
+	groupFlag      string
+)
+
+var (
+	BizCommand = &cobra.Command{
+		Use:   "biz",
+		Short: "manage biz modules in running ark container",
+	}
+
+	DescribeCommand = &cobra.Command{
+		Use:   "describe bizName [bizVersion]",
+		Short: "show the detail of a biz module",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bizName, bizVersion := args[0], ""
+			if len(args) > 1 {
+				bizVersion = args[1]
+			}
+			return execDescribe(context.Background(), bizName, bizVersion)
+		},
+	}
+
+	ActivateCommand = &cobra.Command{
+		Use:   "activate bizName [bizVersion]",
+		Short: "activate a deactivated biz module",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return execChangeState(context.Background(), ark.BizStateActivated, bizModelOf(args))
+		},
+	}
+
+	DeactivateCommand = &cobra.Command{
+		Use:   "deactivate bizName [bizVersion]",
+		Short: "stop a biz module serving traffic but keep it installed, for quick rollbacks",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return execChangeState(context.Background(), ark.BizStateDeactivated, bizModelOf(args))
+		},
+	}
+
+	InstallCommand = &cobra.Command{
+		Use:   "install -f descriptor.yaml",
+		Short: "install the biz modules listed in the descriptor file one by one",
+		Args:  cobra.NoArgs,
+		// the error is printed by execInstall with the status of each biz
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return execInstall(context.Background())
+		},
+	}
+)
+
+func localTarget() ark.ArkContainerRuntimeInfo {
+	return ark.ArkContainerRuntimeInfo{
+		RunType: ark.ArkContainerRunTypeLocal,
+		Port:    &portFlag,
+	}
+}
+
+func execDescribe(ctx context.Context, bizName, bizVersion string) error {
+	arkService, err := ark.NewService()
+	if err != nil {
+		return err
+	}
+	defer arkService.Close()
+
+	detail, err := arkService.QueryBiz(ctx, localTarget(), bizName, bizVersion)
+	if err != nil {
+		return err
+	}
+	style.InfoPrefix("BizDetail").Println(string(runtime.Must(json.MarshalIndent(detail, "", "  "))))
+	return nil
+}
+
+func bizModelOf(args []string) ark.BizModel {
+	bizModel := ark.BizModel{BizName: args[0]}
+	if len(args) > 1 {
+		bizModel.BizVersion = args[1]
+	}
+	return bizModel
+}
+
+func execChangeState(ctx context.Context, desired ark.BizState, bizModel ark.BizModel) error {
+	arkService, err := ark.NewService()
+	if err != nil {
+		return err
+	}
+	defer arkService.Close()
+
+	if desired == ark.BizStateActivated {
+		err = arkService.ActivateBiz(ctx, ark.ActivateBizRequest{BizModel: bizModel, TargetContainer: localTarget()})
+	} else {
+		err = arkService.DeactivateBiz(ctx, ark.DeactivateBizRequest{BizModel: bizModel, TargetContainer: localTarget()})
+	}
+	if err != nil {
+		return err
+	}
+	style.InfoPrefix(string(desired)).Println(bizModel.BizName, bizModel.BizVersion)
+	return nil
+}
+
+func execInstall(ctx context.Context) error {
+	reqs, err := ark.LoadInstallRequestsFromFile(descriptorFlag)
+	if err != nil {
+		return err
+	}
+	groups, err := root.TargetGroups()
+	if err != nil {
+		return err
+	}
+	arkService, err := ark.NewService(ark.WithTargetGroups(groups))
+	if err != nil {
+		return err
+	}
+	defer arkService.Close()
+
+	targets := ark.SingleTarget(localTarget())
+	if groupFlag != "" {
+		targets = ark.GroupTargets(groupFlag)
+	}
+	// the group is expanded once, so that every biz is installed to the same instances
+	resolved, err := arkService.ResolveTargets(ctx, targets)
+	if err != nil {
+		return err
+	}
+	bizReqs := reqs
+	reqs = make([]ark.InstallBizRequest, 0, len(bizReqs)*len(resolved))
+	for _, target := range resolved {
+		for _, req := range bizReqs {
+			req.TargetContainer = target
+			reqs = append(reqs, req)
+		}
+	}
+
+	result, err := ark.InstallBatch(ctx, arkService, reqs, ark.BatchInstallOptions{
+		JournalPath:       journalFlag,
+		ResumeFromJournal: resumeFlag,
+		Progress:          progressutil.NewTerminalReporter(os.Stdout, "install biz"),
+	})
+	if result != nil {
+		for _, key := range result.Skipped {
+			style.InfoPrefix("Skipped").Println(key)
+		}
+		for _, key := range result.Installed {
+			style.InfoPrefix("Installed").Println(key)
+		}
+	}
+	if err != nil {
+		root.PrintError(err)
+	}
+	return err
+}
+
+func init() {
+	root.RootCmd.AddCommand(BizCommand)
+	BizCommand.AddCommand(DescribeCommand)
+	BizCommand.AddCommand(InstallCommand)
+	BizCommand.AddCommand(ActivateCommand)
+	BizCommand.AddCommand(DeactivateCommand)
+	BizCommand.PersistentFlags().IntVar(&portFlag, "port", portFlag, "ark container's port")
+	_ = BizCommand.RegisterFlagCompletionFunc("port", root.CompleteArkletPort)
+
+	InstallCommand.Flags().StringVarP(&descriptorFlag, "file", "f", "", "the YAML or JSON descriptor file listing the biz modules to install")
+	InstallCommand.Flags().StringVar(&journalFlag, "journal", "", "the journal file recording the installs, so that an interrupted install could be resumed")
+	InstallCommand.Flags().BoolVar(&resumeFlag, "resume", false, "skip the biz modules already installed according to the journal")
+	InstallCommand.Flags().StringVar(&groupFlag, "group", "", "install to the target group defined in the config profile instead of the local ark container")
+	_ = InstallCommand.MarkFlagRequired("file")
+}
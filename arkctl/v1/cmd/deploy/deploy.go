@@ -20,6 +20,7 @@ package deploy
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -28,6 +29,7 @@ import (
 	"serverless.alipay.com/sofa-serverless/arkctl/common/cmdutil"
 	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
 	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/k8sutil"
 	"serverless.alipay.com/sofa-serverless/arkctl/common/runtime"
 	"serverless.alipay.com/sofa-serverless/arkctl/common/style"
 	"serverless.alipay.com/sofa-serverless/arkctl/v1/cmd/root"
@@ -49,6 +51,11 @@ var (
 	podFlag      string
 	podNamespace string // pre parsed pod namespace
 	podName      string // pre parsed pod name
+
+	kubeConfig *k8sutil.Config // pre resolved kube config if deploying to pod
+
+	allowMasterBiz bool
+	preflight      bool
 )
 
 const (
@@ -56,8 +63,12 @@ const (
 	ctxKeyArkService                = "ark.Service"
 	ctxKeyBizModel                  = "ark.BizModel"
 	ctxKeyArkContainerRuntimeInfo   = "ark.ContainerRuntimeInfo"
+	ctxKeyArkError                  = "ark.Error"
 )
 
+// errDeployFailed is returned when a step of deploy failed without an error of the ark service, which is printed already.
+var errDeployFailed = errors.New("deploy failed")
+
 var DeployCommand = &cobra.Command{
 	Use:   "deploy [flags] [path/to/your/project/or/bundle]",
 	Short: "deploy your biz module to running containers",
@@ -98,9 +109,21 @@ Scenario 4: Build an maven multi module project and deploy a sub module to a run
 			podNamespace, podName = "default", podFlag
 		}
 
+		if podFlag != "" {
+			config, err := root.KubeConfig()
+			if err != nil {
+				return err
+			}
+			kubeConfig = config
+		} else if err := root.PickLocalArkletPort(cmd, &portFlag); err != nil {
+			return err
+		}
+
 		return nil
 	},
-	Run: executeDeploy,
+	// the errors are printed by the steps
+	SilenceErrors: true,
+	RunE:          executeDeploy,
 }
 
 func execMavenBuild(ctx *contextutil.Context) bool {
@@ -184,7 +207,12 @@ func execParseBizModel(ctx *contextutil.Context) bool {
 func execUploadBizBundle(ctx *contextutil.Context) bool {
 	bizModel := ctx.Value(ctxKeyBizModel).(*ark.BizModel)
 
-	if podFlag != "" && bizModel.BizUrl.GetFileUrlType() == fileutil.FileUrlTypeLocal {
+	urlType, err := bizModel.BizUrl.GetFileUrlType()
+	if err != nil {
+		pterm.Error.PrintOnError(err)
+		return false
+	}
+	if podFlag != "" && urlType == fileutil.FileUrlTypeLocal {
 
 		targetPath := fmt.Sprintf("/tmp/%s",
 			bizModel.BizName+"-"+
@@ -192,14 +220,13 @@ func execUploadBizBundle(ctx *contextutil.Context) bool {
 				runtime.Must(uuid.NewUUID()).String()+"-"+
 				"ark-biz.jar",
 		)
-		kubecpcmd := cmdutil.BuildCommand(ctx,
-			"kubectl",
+		kubecpcmd := cmdutil.BuildCommand(ctx, "kubectl", kubeConfig.KubectlArgs(
 			"-n",
 			podNamespace,
 			"cp",
 			string(bizModel.BizUrl)[7:],
 			podName+":"+targetPath,
-		)
+		)...)
 		style.InfoPrefix("Stage").Println("UploadBizBundle")
 		style.InfoPrefix("Command").Println(kubecpcmd.String())
 
@@ -226,10 +253,23 @@ func execUploadBizBundle(ctx *contextutil.Context) bool {
 	return true
 }
 
+// check the user is allowed to exec in the pod before doing anything
+func execCheckKubeAccess(ctx *contextutil.Context) bool {
+	if podFlag == "" {
+		return true
+	}
+
+	style.InfoPrefix("Stage").Println("CheckKubeAccess")
+	if err := k8sutil.CheckPodAccess(ctx, cmdutil.RunCommand, kubeConfig, podNamespace, k8sutil.ResourcePodsExec); err != nil {
+		pterm.Error.PrintOnError(err)
+		return false
+	}
+	return true
+}
+
 func execInstallInKubePod(ctx *contextutil.Context) bool {
 	bizModel := ctx.Value(ctxKeyBizModel).(*ark.BizModel)
-	kubeuninstallcmd := cmdutil.BuildCommand(ctx,
-		"kubectl",
+	kubeuninstallcmd := cmdutil.BuildCommand(ctx, "kubectl", kubeConfig.KubectlArgs(
 		"-n", podNamespace,
 		"exec", podName, "--",
 		"curl",
@@ -243,7 +283,7 @@ func execInstallInKubePod(ctx *contextutil.Context) bool {
 			BizVersion: bizModel.BizVersion,
 		}))),
 		fmt.Sprintf("http://127.0.0.1:%v/uninstallBiz", portFlag),
-	)
+	)...)
 
 	style.InfoPrefix("Command").Println(kubeuninstallcmd.String())
 	if err := kubeuninstallcmd.Exec(); err != nil {
@@ -276,8 +316,7 @@ func execInstallInKubePod(ctx *contextutil.Context) bool {
 		}
 	}
 
-	kubeinstallcmd := cmdutil.BuildCommand(ctx,
-		"kubectl",
+	kubeinstallcmd := cmdutil.BuildCommand(ctx, "kubectl", kubeConfig.KubectlArgs(
 		"-n", podNamespace,
 		"exec", podName, "--",
 		"curl",
@@ -290,9 +329,12 @@ func execInstallInKubePod(ctx *contextutil.Context) bool {
 			BizName:    bizModel.BizName,
 			BizVersion: bizModel.BizVersion,
 			BizUrl:     fileutil.FileUrl("file://" + ctx.Value(ctxKeyArkBizBundlePathInSidePod).(string)),
+			MainClass:  bizModel.MainClass,
+			Env:        bizModel.Env,
+			Args:       bizModel.Args,
 		}))),
 		fmt.Sprintf("http://127.0.0.1:%v/installBiz", portFlag),
-	)
+	)...)
 	style.InfoPrefix("Command").Println(kubeinstallcmd.String())
 	if err := kubeinstallcmd.Exec(); err != nil {
 		pterm.Error.PrintOnError(err)
@@ -362,8 +404,10 @@ func execUnInstallLocal(ctx *contextutil.Context) bool {
 	if err := arkService.UnInstallBiz(ctx, ark.UnInstallBizRequest{
 		BizModel:        *bizModel,
 		TargetContainer: *arkContainerRuntimeInfo,
+		AllowMasterBiz:  allowMasterBiz,
 	}); err != nil {
-		pterm.Error.PrintOnError(err)
+		ctx.Put(ctxKeyArkError, err)
+		root.PrintError(err)
 		return false
 	}
 
@@ -381,8 +425,11 @@ func execInstallLocal(ctx *contextutil.Context) bool {
 	if err := arkService.InstallBiz(ctx, ark.InstallBizRequest{
 		BizModel:        *bizModel,
 		TargetContainer: *arkContainerRuntimeInfo,
+		AllowMasterBiz:  allowMasterBiz,
+		Preflight:       preflight,
 	}); err != nil {
-		pterm.Error.PrintOnError(err)
+		ctx.Put(ctxKeyArkError, err)
+		root.PrintError(err)
 		return false
 	}
 	return true
@@ -391,7 +438,7 @@ func execInstallLocal(ctx *contextutil.Context) bool {
 func generateContext(cmd *cobra.Command) *contextutil.Context {
 	ctx := contextutil.NewContext(context.Background())
 
-	arkService := ark.BuildService(ctx)
+	arkService := runtime.Must(ark.NewService(ark.WithKubeConfig(kubeConfig)))
 	ctx.Put(ctxKeyArkService, arkService)
 
 	arkContainerRuntimeInfo := &ark.ArkContainerRuntimeInfo{
@@ -414,10 +461,11 @@ func generateContext(cmd *cobra.Command) *contextutil.Context {
 // 2. parse the biz model for further usage
 // 3. uninstall the biz bundle in target ark container to prevent conflict
 // 4. install the biz bundle in target ark container
-func executeDeploy(cobracmd *cobra.Command, _ []string) {
+func executeDeploy(cobracmd *cobra.Command, _ []string) error {
 	c := generateContext(cobracmd)
 
 	todos := []func(context2 *contextutil.Context) bool{
+		execCheckKubeAccess,
 		execMavenBuild,
 		execParseBizModel,
 		execUploadBizBundle,
@@ -426,10 +474,14 @@ func executeDeploy(cobracmd *cobra.Command, _ []string) {
 
 	for _, todo := range todos {
 		if !todo(c) {
-			return
+			// the error of the ark service decides the exit code
+			if err, ok := c.Value(ctxKeyArkError).(error); ok {
+				return err
+			}
+			return errDeployFailed
 		}
 	}
-
+	return nil
 }
 
 func init() {
@@ -444,6 +496,15 @@ If Provided, arkctl will try to build the project at current dir and deploy the
 
 	DeployCommand.Flags().IntVar(&portFlag, "port", 1238, `
 The default port of ark container is 1238 if not provided.
+If not provided in a terminal, arkctl discovers the running ark containers and asks which one to use.
+`)
+	_ = DeployCommand.RegisterFlagCompletionFunc("port", root.CompleteArkletPort)
+
+	DeployCommand.Flags().BoolVar(&allowMasterBiz, "allow-master-biz", false, `
+If Provided, arkctl won't refuse to deploy a bundle with the same name as the master biz. Use with caution.
+`)
+	DeployCommand.Flags().BoolVar(&preflight, "preflight", false, `
+If Provided, arkctl checks the manifest, the layout and the Ark-Version of the bundle before installing it to a local ark container.
 `)
 
 }
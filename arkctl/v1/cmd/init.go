@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	_ "serverless.alipay.com/sofa-serverless/arkctl/v1/cmd/biz"
 	_ "serverless.alipay.com/sofa-serverless/arkctl/v1/cmd/deploy"
 	_ "serverless.alipay.com/sofa-serverless/arkctl/v1/cmd/gen"
 	_ "serverless.alipay.com/sofa-serverless/arkctl/v1/cmd/root"
@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package root
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/v1/service/ark"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var discoverPorts string
+
+// discoverLocalArklets scan the ports given by --discover-ports for local arklets.
+func discoverLocalArklets(cmd *cobra.Command) ([]ark.ArkContainerRuntimeInfo, error) {
+	portRange, err := ark.ParsePortRange(discoverPorts)
+	if err != nil {
+		return nil, err
+	}
+	return ark.DiscoverLocalArklets(cmd.Context(), portRange)
+}
+
+// PickLocalArkletPort let the user pick one of the discovered local arklets if --port is not given.
+// The port is kept if it's given explicitly, or the stdin is not a terminal, or no arklet is discovered.
+func PickLocalArkletPort(cmd *cobra.Command, port *int) error {
+	if cmd.Flags().Changed("port") || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+
+	arklets, err := discoverLocalArklets(cmd)
+	if err != nil {
+		return err
+	}
+
+	switch len(arklets) {
+	case 0:
+		return nil
+	case 1:
+		*port = arklets[0].GetPort()
+		return nil
+	}
+
+	options := make([]string, 0, len(arklets))
+	for _, arklet := range arklets {
+		options = append(options, strconv.Itoa(arklet.GetPort()))
+	}
+	selected, err := pterm.DefaultInteractiveSelect.
+		WithOptions(options).
+		WithDefaultText("Multiple ark containers are running, select the port to use").
+		Show()
+	if err != nil {
+		return err
+	}
+	*port, err = strconv.Atoi(selected)
+	return err
+}
+
+// CompleteArkletPort complete the --port flag with the discovered local arklets.
+func CompleteArkletPort(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	arklets, err := discoverLocalArklets(cmd)
+	if err != nil {
+		cobra.CompErrorln(err.Error())
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	ports := make([]string, 0, len(arklets))
+	for _, arklet := range arklets {
+		ports = append(ports, fmt.Sprintf("%d\tark container on localhost", arklet.GetPort()))
+	}
+	return ports, cobra.ShellCompDirectiveNoFileComp
+}
+
+// isCompletionRequest return true if the process is invoked by the shell completion script.
+func isCompletionRequest() bool {
+	return len(os.Args) > 1 &&
+		(os.Args[1] == cobra.ShellCompRequestCmd || os.Args[1] == cobra.ShellCompNoDescRequestCmd)
+}
@@ -18,9 +18,12 @@
 package root
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/k8sutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/v1/service/ark"
 
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -29,21 +32,87 @@ import (
 
 var cfgFile string
 
+var kubeOptions k8sutil.Options
+
+var verbose bool
+
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 	},
 }
 
+// KubeConfig build the kube config from the global kube flags.
+func KubeConfig() (*k8sutil.Config, error) {
+	return k8sutil.BuildConfig(kubeOptions)
+}
+
+// TargetGroups return the target groups defined under targetGroups of the config profile, keyed by the group name.
+func TargetGroups() (map[string]ark.TargetGroup, error) {
+	groups := map[string]ark.TargetGroup{}
+	if err := viper.UnmarshalKey("targetGroups", &groups); err != nil {
+		return nil, fmt.Errorf("invalid target groups in %s: %w", viper.ConfigFileUsed(), err)
+	}
+	return groups, nil
+}
+
+// PrintError print the error, and the stack trace responded by arklet with --verbose.
+// The errors of multiple targets are printed as a table with the status of each target.
+func PrintError(err error) {
+	multiErr := &ark.MultiTargetError{}
+	if errors.As(err, &multiErr) {
+		printMultiTargetError(multiErr)
+		return
+	}
+
+	pterm.Error.PrintOnError(err)
+
+	responseErr := &ark.ResponseError{}
+	if verbose && errors.As(err, &responseErr) && responseErr.ErrorDetail() != "" {
+		pterm.Println(responseErr.ErrorDetail())
+	}
+}
+
+func printMultiTargetError(multiErr *ark.MultiTargetError) {
+	summary := fmt.Sprintf("%d/%d targets failed", len(multiErr.Failed()), len(multiErr.Results))
+	if multiErr.Operation != "" {
+		summary = multiErr.Operation + ": " + summary
+	}
+	pterm.Error.Println(summary)
+
+	table := pterm.TableData{{"Target", "Status", "Error"}}
+	for _, result := range multiErr.Results {
+		if result.Err == nil {
+			table = append(table, []string{result.Target, pterm.Green("succeeded"), ""})
+			continue
+		}
+		table = append(table, []string{result.Target, pterm.Red("failed"), result.Err.Error()})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the RootCmd.
+// The process exits with the code derived from the error by ark.ExitCode, the error is printed by cobra or the command.
 func Execute() {
-	cobra.CheckErr(RootCmd.Execute())
+	if err := RootCmd.Execute(); err != nil {
+		os.Exit(ark.ExitCode(err))
+	}
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 	contextutil.DisableLogger()
+	RootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "print the detail of errors, like the stack trace responded by arklet")
+	RootCmd.PersistentFlags().StringVar(&kubeOptions.Kubeconfig, "kubeconfig", "", "path to the kubeconfig file, $KUBECONFIG or ~/.kube/config is used if not given")
+	RootCmd.PersistentFlags().StringVar(&kubeOptions.Context, "kube-context", "", "the kubeconfig context to use")
+	RootCmd.PersistentFlags().StringVar(&kubeOptions.Impersonate, "as", "", "the user to impersonate for the kubernetes operations")
+	RootCmd.PersistentFlags().StringVar(&discoverPorts, "discover-ports", ark.DefaultDiscoveryPortRange.String(), "the localhost port range scanned for running ark containers when --port is not given")
+
+	// the output of shell completion must not be polluted
+	if isCompletionRequest() {
+		return
+	}
 	style := pterm.NewStyle(pterm.Italic, pterm.Bold, pterm.FgLightBlue)
 	pterm.DefaultBasicText.
 		Println("Welcome to use " + style.Sprint("ARKCTL") + " to ease your develop experience!")
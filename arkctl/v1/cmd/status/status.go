@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	"serverless.alipay.com/sofa-serverless/arkctl/common/cmdutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/k8sutil"
 	"serverless.alipay.com/sofa-serverless/arkctl/common/runtime"
 	"serverless.alipay.com/sofa-serverless/arkctl/v1/cmd/root"
 	"serverless.alipay.com/sofa-serverless/arkctl/v1/service/ark"
@@ -55,7 +56,12 @@ var (
 )
 
 func execStatusLocal(ctx context.Context) error {
-	arkService := ark.BuildService(ctx)
+	arkService, err := ark.NewService()
+	if err != nil {
+		return err
+	}
+	defer arkService.Close()
+
 	biz, err := arkService.QueryAllBiz(ctx, ark.QueryAllArkBizRequest{
 		HostName: hostFlag,
 		Port:     portFlag,
@@ -68,15 +74,26 @@ func execStatusLocal(ctx context.Context) error {
 }
 
 func execStatusKubePod(ctx context.Context) error {
+	kubeConfig, err := root.KubeConfig()
+	if err != nil {
+		return err
+	}
+	if err := k8sutil.CheckPodAccess(ctx, cmdutil.RunCommand, kubeConfig, podNamespace, k8sutil.ResourcePodsExec); err != nil {
+		pterm.Error.PrintOnError(err)
+		return err
+	}
+
 	kubeQueryCmd := cmdutil.BuildCommand(
 		ctx,
 		"kubectl",
-		"-n", podNamespace,
-		"exec", podName, "--",
-		"curl",
-		"-X",
-		"POST",
-		fmt.Sprintf("http://127.0.0.1:%v/queryAllBiz", portFlag),
+		kubeConfig.KubectlArgs(
+			"-n", podNamespace,
+			"exec", podName, "--",
+			"curl",
+			"-X",
+			"POST",
+			fmt.Sprintf("http://127.0.0.1:%v/queryAllBiz", portFlag),
+		)...,
 	)
 
 	if err := kubeQueryCmd.Exec(); err != nil {
@@ -114,6 +131,7 @@ func execStatus(ctx context.Context) error {
 func init() {
 	root.RootCmd.AddCommand(&StatusCommand)
 	StatusCommand.Flags().IntVar(&portFlag, "port", portFlag, "ark container's port")
+	_ = StatusCommand.RegisterFlagCompletionFunc("port", root.CompleteArkletPort)
 	StatusCommand.Flags().StringVar(&hostFlag, "host", hostFlag, "ark container's host")
 	StatusCommand.Flags().StringVar(&podFlag, "pod", podFlag, "ark container's running pod")
 }
@@ -0,0 +1,125 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// ActivateBiz activate the biz installed in the ark container, it's a no-op if the biz is already activated.
+func (h *service) ActivateBiz(ctx context.Context, req ActivateBizRequest) error {
+	return h.changeBizState(ctx, "activate biz", EndpointActivateBiz, req.BizModel, req.TargetContainer, BizStateActivated)
+}
+
+// DeactivateBiz stop the biz serving traffic but keep it installed, it's a no-op if the biz is already deactivated.
+func (h *service) DeactivateBiz(ctx context.Context, req DeactivateBizRequest) error {
+	return h.changeBizState(ctx, "deactivate biz", EndpointDeactivateBiz, req.BizModel, req.TargetContainer, BizStateDeactivated)
+}
+
+func (h *service) changeBizState(
+	ctx context.Context,
+	operation string,
+	endpoint Endpoint,
+	bizModel BizModel,
+	target ArkContainerRuntimeInfo,
+	desired BizState,
+) (err error) {
+	if target, err = targetOrDefault(ctx, target); err != nil {
+		return err
+	}
+	logger := contextutil.GetLogger(ctx).WithFields(mergeFields(bizFields(bizModel), targetFields(target)))
+	logger.Info(operation + " started")
+	defer func() {
+		if err != nil {
+			logger.Error(err)
+			h.invalidateCapabilities(target, err)
+		} else {
+			logger.Info(operation + " completed")
+		}
+		h.invalidateBiz(target, bizModel)
+	}()
+
+	body := BizModel{BizName: bizModel.BizName, BizVersion: bizModel.BizVersion}
+	var respBody []byte
+	switch target.RunType {
+	case ArkContainerRunTypeLocal:
+		respBody, err = h.postOnLocal(ctx, operation, target, endpoint, body)
+	case ArkContainerRunTypeK8s:
+		respBody, err = h.postInPod(ctx, target, endpoint, body)
+	default:
+		err = fmt.Errorf("%s is not supported for run type: %s", operation, target.RunType)
+	}
+	if err != nil {
+		return err
+	}
+
+	resp := &ArkResponseBase{}
+	if err = json.Unmarshal(respBody, resp); err != nil {
+		return err
+	}
+	recordResponseCode(ctx, resp.Code)
+	if resp.Code.IsSuccess() {
+		return nil
+	}
+
+	if resp.Code == ResponseCodeIllegalStateBiz || resp.Data.Code == ResponseCodeIllegalStateBiz {
+		// the biz might be in the desired state already
+		state, queryErr := h.QueryBizState(WithCacheBypass(ctx), target, bizModel.BizName, bizModel.BizVersion)
+		if queryErr == nil && state == desired {
+			logger.Infof("biz is already %s", state)
+			return nil
+		}
+	}
+	return h.newResponseError(operation, resp.Code, resp.Message, respBody)
+}
+
+// postOnLocal post the body to the endpoint of the local arklet, and return the response body.
+func (h *service) postOnLocal(ctx context.Context, operation string, target ArkContainerRuntimeInfo, endpoint Endpoint, body interface{}) ([]byte, error) {
+	endpointUrl, err := h.localEndpointUrl(target, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.client.R().
+		SetContext(ctx).
+		SetBody(body).
+		Execute(h.endpointMethod(endpoint), endpointUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", errors.ErrUnsupported, operation)
+	}
+
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("%s http failed with code %d", operation, resp.StatusCode())
+	}
+	return resp.Body(), nil
+}
+
+// invalidateBiz drop the cached results of target once the biz in it is changed.
+func (h *service) invalidateBiz(target ArkContainerRuntimeInfo, bizModel BizModel) {
+	h.invalidateQueryAllBiz(target)
+	if h.queryBizCache != nil {
+		h.queryBizCache.remove(bizCacheKey(target, bizModel.BizName, bizModel.BizVersion))
+		h.queryBizCache.remove(bizCacheKey(target, bizModel.BizName, ""))
+	}
+}
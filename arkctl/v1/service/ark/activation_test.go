@@ -0,0 +1,96 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeactivateAndActivateBiz(t *testing.T) {
+	ctx := context.Background()
+	// the arklet responds ILLEGAL_STATE_BIZ if the biz is already in the state
+	arklet := &fakeArklet{biz: []ArkBizInfo{{BizName: "biz", BizVersion: "0.0.1", BizState: BizStateActivated}}}
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	client := BuildService(ctx)
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	bizModel := BizModel{BizName: "biz", BizVersion: "0.0.1"}
+
+	err := client.DeactivateBiz(ctx, DeactivateBizRequest{BizModel: bizModel, TargetContainer: target})
+	assert.Nil(t, err)
+	assert.Equal(t, BizStateDeactivated, arklet.biz[0].BizState)
+	assert.Nil(t, client.WaitBizState(ctx, target, bizModel, BizStateDeactivated, WaitOptions{Timeout: time.Second}))
+
+	// already deactivated
+	arklet.requests = nil
+	err = client.DeactivateBiz(ctx, DeactivateBizRequest{BizModel: bizModel, TargetContainer: target})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/deactivateBiz", "/queryBiz"}, arklet.requestPaths())
+
+	err = client.ActivateBiz(ctx, ActivateBizRequest{BizModel: bizModel, TargetContainer: target})
+	assert.Nil(t, err)
+	assert.Equal(t, BizStateActivated, arklet.biz[0].BizState)
+	assert.Equal(t, []string{"deactivate biz:0.0.1", "deactivate biz:0.0.1", "activate biz:0.0.1"}, arklet.calls)
+}
+
+func TestActivateBiz_Failed(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/activateBiz":
+			_, _ = w.Write([]byte(`{"code":"FAILED","message":"biz is broken","data":{"code":"ILLEGAL_STATE_BIZ"}}`))
+		case "/queryBiz":
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"bizName":"biz","bizVersion":"0.0.1","bizState":"BROKEN"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer cancel()
+
+	client := BuildService(ctx)
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	err := client.ActivateBiz(ctx, ActivateBizRequest{BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"}, TargetContainer: target})
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, "activate biz failed: biz is broken", err.Error())
+
+	// the arklet doesn't support deactivation
+	err = client.DeactivateBiz(ctx, DeactivateBizRequest{BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"}, TargetContainer: target})
+	assert.True(t, errors.Is(err, errors.ErrUnsupported))
+}
+
+func TestDeactivateBiz_Pod(t *testing.T) {
+	ctx := context.Background()
+
+	var command []string
+	client := BuildService(ctx, WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		command = args
+		return []string{`{"code":"SUCCESS"}`}, nil
+	}))
+	err := client.DeactivateBiz(ctx, DeactivateBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://127.0.0.1:1238/deactivateBiz", command[len(command)-1])
+	assert.Contains(t, command, `{"bizName":"biz","bizVersion":"0.0.1"}`)
+}
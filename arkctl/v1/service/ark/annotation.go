@@ -0,0 +1,127 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/runtime"
+)
+
+// BizStateAnnotation is the pod annotation listing the biz installed by arkctl with RecordOnPod,
+// as a compact json list of BizStateRecord from the oldest to the latest.
+const BizStateAnnotation = "serverless.alipay.com/biz-state"
+
+const (
+	// maxBizStateAnnotationSize caps the size of BizStateAnnotation, the oldest records are dropped beyond it.
+	maxBizStateAnnotationSize = 4096
+
+	// maxBizStateConflictRetries is the max retries of patching BizStateAnnotation when the pod is modified meanwhile.
+	maxBizStateConflictRetries = 5
+)
+
+// BizStateRecord is a biz recorded in BizStateAnnotation.
+type BizStateRecord struct {
+	BizName    string    `json:"name"`
+	BizVersion string    `json:"version"`
+	Time       time.Time `json:"time"`
+}
+
+// mergeBizState add the biz to the records if it's installed, otherwise remove it,
+// and drop the oldest records until the encoded records fit in maxBizStateAnnotationSize.
+func mergeBizState(records []BizStateRecord, bizModel BizModel, installed bool, now time.Time) []BizStateRecord {
+	merged := make([]BizStateRecord, 0, len(records)+1)
+	for _, record := range records {
+		if record.BizName != bizModel.BizName || record.BizVersion != bizModel.BizVersion {
+			merged = append(merged, record)
+		}
+	}
+	if installed {
+		merged = append(merged, BizStateRecord{
+			BizName:    bizModel.BizName,
+			BizVersion: bizModel.BizVersion,
+			Time:       now.UTC().Truncate(time.Second),
+		})
+	}
+
+	for len(merged) > 0 {
+		encoded, _ := json.Marshal(merged)
+		if len(encoded) <= maxBizStateAnnotationSize {
+			break
+		}
+		merged = merged[1:]
+	}
+	return merged
+}
+
+// isConflict return true if kubectl failed as the object is modified since it's read.
+func isConflict(err error) bool {
+	return strings.Contains(err.Error(), "the object has been modified")
+}
+
+// patchBizState read BizStateAnnotation of the pod and patch it with the biz merged,
+// the patch is rejected as a conflict if the pod is modified meanwhile.
+func (h *service) patchBizState(ctx context.Context, target ArkContainerRuntimeInfo, bizModel BizModel, installed bool) error {
+	namespace, podName, err := parsePodCoordinate(target.Coordinate)
+	if err != nil {
+		return err
+	}
+
+	pod, err := h.getPod(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	var records []BizStateRecord
+	if existing := pod.Metadata.Annotations[BizStateAnnotation]; existing != "" {
+		if err := json.Unmarshal([]byte(existing), &records); err != nil {
+			// the annotation is overwritten if it's broken, it's only informational
+			contextutil.GetLogger(ctx).WithError(err).Warn("discard malformed biz state annotation")
+			records = nil
+		}
+	}
+	value, err := json.Marshal(mergeBizState(records, bizModel, installed, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	// the resource version makes the api server reject the patch if the pod is modified since it's read
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": pod.Metadata.ResourceVersion,
+			"annotations":     map[string]string{BizStateAnnotation: string(value)},
+		},
+	}
+	_, err = h.kubectl(ctx, target, "-n", namespace, "patch", "pod", podName, "--type=merge", "-p", string(runtime.Must(json.Marshal(patch))))
+	return err
+}
+
+// recordBizOnPod record the installed or uninstalled biz in BizStateAnnotation of the pod, retrying on conflicts.
+// The failure is only logged, as the annotation is informational and the operation on the biz has succeeded.
+func (h *service) recordBizOnPod(ctx context.Context, target ArkContainerRuntimeInfo, bizModel BizModel, installed bool) {
+	var err error
+	for attempt := 0; attempt <= maxBizStateConflictRetries; attempt++ {
+		if err = h.patchBizState(ctx, target, bizModel, installed); err == nil || !isConflict(err) {
+			break
+		}
+	}
+	if err != nil {
+		contextutil.GetLogger(ctx).WithFields(targetFields(target)).WithError(err).Warn("failed to record biz state on pod")
+	}
+}
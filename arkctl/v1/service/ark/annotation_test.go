@@ -0,0 +1,143 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeBizState(t *testing.T) {
+	then := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := then.Add(time.Hour + 500*time.Millisecond)
+	records := []BizStateRecord{
+		{BizName: "biz1", BizVersion: "0.0.1", Time: then},
+		{BizName: "biz2", BizVersion: "0.0.1", Time: then},
+	}
+
+	// the reinstalled biz is moved to the latest
+	merged := mergeBizState(records, BizModel{BizName: "biz1", BizVersion: "0.0.1"}, true, now)
+	assert.Equal(t, []BizStateRecord{
+		{BizName: "biz2", BizVersion: "0.0.1", Time: then},
+		{BizName: "biz1", BizVersion: "0.0.1", Time: then.Add(time.Hour)},
+	}, merged)
+
+	// only the uninstalled version is removed
+	merged = mergeBizState(records, BizModel{BizName: "biz2", BizVersion: "0.0.2"}, false, now)
+	assert.Equal(t, records, merged)
+	merged = mergeBizState(records, BizModel{BizName: "biz2", BizVersion: "0.0.1"}, false, now)
+	assert.Equal(t, records[:1], merged)
+
+	// the oldest records are dropped beyond the size cap
+	records = nil
+	for i := 0; i < 100; i++ {
+		records = mergeBizState(records, BizModel{BizName: strings.Repeat("b", 40), BizVersion: time.Duration(i).String()}, true, now)
+	}
+	encoded, _ := json.Marshal(records)
+	assert.LessOrEqual(t, len(encoded), maxBizStateAnnotationSize)
+	assert.Equal(t, "99ns", records[len(records)-1].BizVersion)
+	assert.NotEqual(t, "0s", records[0].BizVersion)
+}
+
+// mockPodWithAnnotation fake kubectl serving a pod with the annotation, the patches are recorded and fail with patchErrs in order.
+func mockPodWithAnnotation(annotation string, patches *[]string, patchErrs ...error) Option {
+	return WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		switch args[2] {
+		case "exec":
+			return []string{`{"code":"SUCCESS"}`}, nil
+		case "get":
+			pod, _ := json.Marshal(map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"resourceVersion": "42",
+					"annotations":     map[string]string{BizStateAnnotation: annotation},
+				},
+			})
+			return []string{string(pod)}, nil
+		case "patch":
+			*patches = append(*patches, args[len(args)-1])
+			if len(patchErrs) > 0 {
+				err := patchErrs[0]
+				patchErrs = patchErrs[1:]
+				return nil, err
+			}
+			return []string{`pod/base-0 patched`}, nil
+		}
+		return nil, errors.New("unexpected command")
+	})
+}
+
+func TestRecordOnPod_Install(t *testing.T) {
+	ctx := context.Background()
+	var patches []string
+	conflict := errors.New(`exit status 1: Operation cannot be fulfilled on pods "base-0": the object has been modified; please apply your changes to the latest version and try again`)
+	client := BuildService(ctx, mockPodWithAnnotation(`[{"name":"biz1","version":"0.0.1","time":"2024-01-01T00:00:00Z"}]`, &patches, conflict))
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz2", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+		AllowMasterBiz:  true,
+		RecordOnPod:     true,
+	})
+	assert.Nil(t, err)
+	// the conflict is retried
+	assert.Len(t, patches, 2)
+	assert.Equal(t, patches[0], patches[1])
+
+	patch := &podObject{}
+	assert.Nil(t, json.Unmarshal([]byte(patches[1]), patch))
+	assert.Equal(t, "42", patch.Metadata.ResourceVersion)
+	records := []BizStateRecord{}
+	assert.Nil(t, json.Unmarshal([]byte(patch.Metadata.Annotations[BizStateAnnotation]), &records))
+	assert.Len(t, records, 2)
+	assert.Equal(t, "biz1", records[0].BizName)
+	assert.Equal(t, "biz2", records[1].BizName)
+}
+
+func TestRecordOnPod_UnInstall(t *testing.T) {
+	ctx := context.Background()
+	var patches []string
+	client := BuildService(ctx, mockPodWithAnnotation(`[{"name":"biz1","version":"0.0.1","time":"2024-01-01T00:00:00Z"}]`, &patches))
+
+	err := client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz1", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+		AllowMasterBiz:  true,
+		RecordOnPod:     true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`{"metadata":{"annotations":{"serverless.alipay.com/biz-state":"[]"},"resourceVersion":"42"}}`}, patches)
+}
+
+func TestRecordOnPod_PatchFailureIgnored(t *testing.T) {
+	ctx := context.Background()
+	var patches []string
+	client := BuildService(ctx, mockPodWithAnnotation("not json", &patches, errors.New("exit status 1: forbidden")))
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz1", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+		AllowMasterBiz:  true,
+		RecordOnPod:     true,
+	})
+	assert.Nil(t, err)
+	// only the conflicts are retried
+	assert.Len(t, patches, 1)
+}
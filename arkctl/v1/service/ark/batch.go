@@ -0,0 +1,133 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"fmt"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/progressutil"
+)
+
+// BatchInstallOptions controls InstallBatch.
+type BatchInstallOptions struct {
+	// JournalPath is the journal recording the planned and completed installs, no journal is written if empty.
+	JournalPath string
+
+	// ResumeFromJournal skips the installs already marked succeeded in the journal.
+	ResumeFromJournal bool
+
+	// Progress is started with the count of the requests, and updated as each of them is installed or skipped.
+	Progress progressutil.ProgressReporter
+}
+
+// BatchInstallResult is the result of InstallBatch.
+type BatchInstallResult struct {
+	// Installed are the keys of the installed biz in order.
+	Installed []string
+
+	// Skipped are the keys of the biz skipped because they're already installed according to the journal.
+	Skipped []string
+}
+
+// journalKey identify the install of a biz to a container across runs.
+func journalKey(req InstallBizRequest) string {
+	return bizCacheKey(req.TargetContainer, req.BizModel.BizName, req.BizModel.BizVersion)
+}
+
+// InstallBatch install the biz one by one, and stop at the first failure.
+// The failure is reported by MultiTargetError with the installed biz, the biz after the failure are not tried.
+// With a journal, the interrupted batch could be resumed by ResumeFromJournal.
+func InstallBatch(ctx context.Context, svc Service, reqs []InstallBizRequest, opts BatchInstallOptions) (_ *BatchInstallResult, err error) {
+	ctx = WithPodPortCache(ctx)
+	progress := progressutil.OrNop(opts.Progress)
+	defer func() {
+		progress.Finish(err)
+	}()
+
+	if opts.ResumeFromJournal && opts.JournalPath == "" {
+		return nil, fmt.Errorf("resume from journal requires the journal path")
+	}
+
+	succeeded := map[string]bool{}
+	var journal *Journal
+	if opts.JournalPath != "" {
+		if opts.ResumeFromJournal {
+			records, err := ReadJournal(opts.JournalPath)
+			if err != nil {
+				return nil, err
+			}
+			succeeded = SucceededKeys(records)
+		}
+
+		if journal, err = OpenJournal(opts.JournalPath); err != nil {
+			return nil, err
+		}
+		defer journal.Close()
+	}
+
+	record := func(req InstallBizRequest, status JournalStatus, installErr error) error {
+		if journal == nil {
+			return nil
+		}
+		rec := JournalRecord{
+			Operation:  "install",
+			Key:        journalKey(req),
+			BizName:    req.BizModel.BizName,
+			BizVersion: req.BizModel.BizVersion,
+			Status:     status,
+		}
+		if installErr != nil {
+			rec.Error = installErr.Error()
+		}
+		return journal.Append(rec)
+	}
+
+	logger := contextutil.GetLogger(ctx)
+	result := &BatchInstallResult{}
+	targets := []TargetResult{}
+	progress.Start(int64(len(reqs)))
+	for i, req := range reqs {
+		key := journalKey(req)
+		if succeeded[key] {
+			logger.WithField("key", key).Info("skip biz installed according to the journal")
+			result.Skipped = append(result.Skipped, key)
+			progress.Update(int64(i+1), key)
+			continue
+		}
+
+		if err := record(req, JournalPlanned, nil); err != nil {
+			return result, err
+		}
+		installErr := svc.InstallBiz(ctx, req)
+		status := JournalSucceeded
+		if installErr != nil {
+			status = JournalFailed
+		}
+		if err := record(req, status, installErr); err != nil {
+			return result, err
+		}
+		targets = append(targets, TargetResult{Target: key, Err: installErr})
+		if installErr != nil {
+			// the failed item is done too, it's reported before the batch stops
+			progress.Update(int64(i+1), key+" failed")
+			return result, newMultiTargetError("install batch", targets)
+		}
+		result.Installed = append(result.Installed, key)
+		progress.Update(int64(i+1), key)
+	}
+	return result, nil
+}
@@ -0,0 +1,175 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeInstaller record the installed biz, and fails the biz in failing with failErr, or "boom" if it's nil.
+type fakeInstaller struct {
+	Service
+	installed []string
+	failing   map[string]bool
+	failErr   error
+}
+
+func (f *fakeInstaller) InstallBiz(_ context.Context, req InstallBizRequest) error {
+	if f.failing[req.BizModel.BizName] {
+		if f.failErr != nil {
+			return f.failErr
+		}
+		return errors.New("boom")
+	}
+	f.installed = append(f.installed, req.BizModel.BizName)
+	return nil
+}
+
+func batchRequests(names ...string) []InstallBizRequest {
+	port := 1238
+	reqs := []InstallBizRequest{}
+	for _, name := range names {
+		reqs = append(reqs, InstallBizRequest{
+			BizModel: BizModel{BizName: name, BizVersion: "0.0.1"},
+			TargetContainer: ArkContainerRuntimeInfo{
+				RunType: ArkContainerRunTypeLocal,
+				Port:    &port,
+			},
+		})
+	}
+	return reqs
+}
+
+func TestInstallBatch_ResumeAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+	reqs := batchRequests("a", "b", "c")
+
+	installer := &fakeInstaller{failing: map[string]bool{"b": true}}
+	result, err := InstallBatch(ctx, installer, reqs, BatchInstallOptions{JournalPath: journalPath})
+	assert.Equal(t, "install batch: 1/2 targets failed: local//1238/b:0.0.1: boom", err.Error())
+	assert.Equal(t, []string{"a"}, installer.installed)
+	assert.Equal(t, []string{"local//1238/a:0.0.1"}, result.Installed)
+
+	records, err := ReadJournal(journalPath)
+	assert.Nil(t, err)
+	statuses := []JournalStatus{}
+	for _, record := range records {
+		statuses = append(statuses, record.Status)
+	}
+	assert.Equal(t, []JournalStatus{JournalPlanned, JournalSucceeded, JournalPlanned, JournalFailed}, statuses)
+
+	installer = &fakeInstaller{}
+	result, err = InstallBatch(ctx, installer, reqs, BatchInstallOptions{JournalPath: journalPath, ResumeFromJournal: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b", "c"}, installer.installed)
+	assert.Equal(t, []string{"local//1238/a:0.0.1"}, result.Skipped)
+}
+
+func TestInstallBatch_ItemErrorRecoverable(t *testing.T) {
+	ctx := context.Background()
+	installErr := &ResponseError{Operation: "install biz", Code: ResponseCodeFailed, Message: "bad jar"}
+	installer := &fakeInstaller{failing: map[string]bool{"b": true}, failErr: installErr}
+
+	_, err := InstallBatch(ctx, installer, batchRequests("a", "b", "c"), BatchInstallOptions{})
+	multiErr := &MultiError{}
+	assert.True(t, errors.As(err, &multiErr))
+	assert.Equal(t, []error{installErr}, multiErr.Unwrap())
+
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, installErr, responseErr)
+}
+
+func TestInstallBatch_ResumeTruncatedJournal(t *testing.T) {
+	ctx := context.Background()
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+	reqs := batchRequests("a", "b", "c")
+
+	_, err := InstallBatch(ctx, &fakeInstaller{}, reqs, BatchInstallOptions{JournalPath: journalPath})
+	assert.Nil(t, err)
+
+	// simulate being killed while writing the succeeded record of b
+	content, err := os.ReadFile(journalPath)
+	assert.Nil(t, err)
+	lines := 0
+	cut := 0
+	for i, c := range content {
+		if c == '\n' {
+			lines++
+		}
+		if lines == 3 {
+			cut = i + 20
+			break
+		}
+	}
+	assert.Nil(t, os.WriteFile(journalPath, content[:cut], 0644))
+
+	records, err := ReadJournal(journalPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(records))
+
+	installer := &fakeInstaller{}
+	result, err := InstallBatch(ctx, installer, reqs, BatchInstallOptions{JournalPath: journalPath, ResumeFromJournal: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b", "c"}, installer.installed)
+	assert.Equal(t, []string{"local//1238/a:0.0.1"}, result.Skipped)
+
+	// the partial line is dropped on resume instead of being joined with the appended records
+	records, err = ReadJournal(journalPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 3+4, len(records))
+	assert.Equal(t, "local//1238/c:0.0.1", records[len(records)-1].Key)
+	assert.Equal(t, JournalSucceeded, records[len(records)-1].Status)
+}
+
+// recordingReporter record the reported progress as strings.
+type recordingReporter struct {
+	events []string
+}
+
+func (r *recordingReporter) Start(total int64) {
+	r.events = append(r.events, fmt.Sprintf("start %d", total))
+}
+
+func (r *recordingReporter) Update(done int64, message string) {
+	r.events = append(r.events, fmt.Sprintf("update %d %s", done, message))
+}
+
+func (r *recordingReporter) Finish(err error) {
+	r.events = append(r.events, fmt.Sprintf("finish %v", err))
+}
+
+func TestInstallBatch_Progress(t *testing.T) {
+	ctx := context.Background()
+	reporter := &recordingReporter{}
+
+	installer := &fakeInstaller{failing: map[string]bool{"b": true}}
+	_, err := InstallBatch(ctx, installer, batchRequests("a", "b", "c"), BatchInstallOptions{Progress: reporter})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{
+		"start 3",
+		"update 1 local//1238/a:0.0.1",
+		"update 2 local//1238/b:0.0.1 failed",
+		"finish " + err.Error(),
+	}, reporter.events)
+}
@@ -0,0 +1,72 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// BizState is the lifecycle state of a biz module reported by arklet.
+type BizState string
+
+const (
+	BizStateUnresolved  BizState = "UNRESOLVED"
+	BizStateResolved    BizState = "RESOLVED"
+	BizStateActivated   BizState = "ACTIVATED"
+	BizStateDeactivated BizState = "DEACTIVATED"
+	BizStateBroken      BizState = "BROKEN"
+
+	// BizStateUnknown is the state not known by this client.
+	BizStateUnknown BizState = "UNKNOWN"
+)
+
+var knownBizStates = map[BizState]bool{
+	BizStateUnresolved:  true,
+	BizStateResolved:    true,
+	BizStateActivated:   true,
+	BizStateDeactivated: true,
+	BizStateBroken:      true,
+}
+
+// ParseBizState map the raw state of arklet to a BizState regardless of case and spaces.
+// Unknown states are mapped to BizStateUnknown.
+func ParseBizState(raw string) BizState {
+	state := BizState(strings.ToUpper(strings.TrimSpace(raw)))
+	if knownBizStates[state] {
+		return state
+	}
+	return BizStateUnknown
+}
+
+// UnmarshalJSON parse the state while decoding.
+func (state *BizState) UnmarshalJSON(data []byte) error {
+	raw := ""
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*state = ParseBizState(raw)
+	return nil
+}
+
+// QueryBizState return the state of the biz, ErrBizNotFound is returned if the biz doesn't exist.
+func (h *service) QueryBizState(ctx context.Context, target ArkContainerRuntimeInfo, bizName, bizVersion string) (BizState, error) {
+	detail, err := h.QueryBiz(ctx, target, bizName, bizVersion)
+	if err != nil {
+		return BizStateUnknown, err
+	}
+	return detail.BizState, nil
+}
@@ -0,0 +1,79 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBizState(t *testing.T) {
+	for raw, expected := range map[string]BizState{
+		"UNRESOLVED":  BizStateUnresolved,
+		"RESOLVED":    BizStateResolved,
+		"ACTIVATED":   BizStateActivated,
+		"DEACTIVATED": BizStateDeactivated,
+		"BROKEN":      BizStateBroken,
+		" activated ": BizStateActivated,
+		"WAITING":     BizStateUnknown,
+		"":            BizStateUnknown,
+	} {
+		assert.Equal(t, expected, ParseBizState(raw), raw)
+	}
+}
+
+func TestBizState_UnmarshalJSON(t *testing.T) {
+	resp := &QueryAllArkBizResponse{}
+	err := json.Unmarshal([]byte(`{"code":"SUCCESS","data":[
+		{"bizName":"a","bizState":"deactivated"},
+		{"bizName":"b","bizState":"SOMETHING_NEW"}
+	]}`), resp)
+	assert.Nil(t, err)
+	assert.Equal(t, BizStateDeactivated, resp.Data[0].BizState)
+	assert.Equal(t, BizStateUnknown, resp.Data[1].BizState)
+}
+
+func TestQueryBizState(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/queryBiz", func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["bizName"] != "biz" {
+			_, _ = w.Write([]byte(`{"code":"NOT_FOUND_BIZ"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"bizName":"biz","bizVersion":"0.0.1","bizState":"BROKEN"}}`))
+	})
+	defer cancel()
+
+	client := BuildService(ctx)
+	target := ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}
+
+	state, err := client.QueryBizState(ctx, target, "biz", "0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, BizStateBroken, state)
+
+	state, err = client.QueryBizState(ctx, target, "other", "0.0.1")
+	assert.True(t, errors.Is(err, ErrBizNotFound))
+	assert.Equal(t, BizStateUnknown, state)
+}
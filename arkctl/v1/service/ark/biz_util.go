@@ -17,78 +17,281 @@ package ark
 import (
 	"archive/zip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
 	"strings"
+	"unicode"
 
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
 	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
 )
 
 // isJarFile return true if fileUrl provides a jar file.
 func isJarFile(fileUrl fileutil.FileUrl) bool {
+	// maven artifacts are always resolved to jar files
+	if strings.HasPrefix(string(fileUrl), "mvn://") {
+		return true
+	}
 	// end with .jar
 	return strings.HasSuffix(string(fileUrl), ".jar")
 }
 
-// parseJarBizModel parse jar file to BizModel.
-func parseJarBizModel(ctx context.Context, bizUrl fileutil.FileUrl) (*BizModel, error) {
-	fileUtil := fileutil.DefaultFileUtil()
-	localPath, err := fileUtil.Download(ctx, bizUrl)
+// withLocalJar resolve the jar given by bizUrl to a local file and call fn with it.
+// The jar downloaded to a temp file is removed after fn returns unless keepTempFiles is true.
+func withLocalJar(ctx context.Context, bizUrl fileutil.FileUrl, keepTempFiles bool,
+	fn func(localizedFile *fileutil.LocalizedFile, zipReader *zip.ReadCloser) error) error {
+	localizedFile, err := fileutil.Resolve(ctx, bizUrl)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer cleanupLocalizedFile(ctx, localizedFile, keepTempFiles)
 
-	// remove file:// prefix
-	zipReader, err := zip.OpenReader(localPath[7:])
+	zipReader, err := zip.OpenReader(localizedFile.Path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer zipReader.Close()
+	return fn(localizedFile, zipReader)
+}
 
-	bizName := ""
-	bizVersion := ""
+// cleanupLocalizedFile remove the localized file if it's downloaded to a temp file, unless keepTempFiles is true.
+func cleanupLocalizedFile(ctx context.Context, localizedFile *fileutil.LocalizedFile, keepTempFiles bool) {
+	if !localizedFile.Temporary {
+		return
+	}
+	logger := contextutil.GetLogger(ctx)
+	if keepTempFiles {
+		logger.WithField("path", localizedFile.Path).Info("temp file of biz bundle is kept")
+		return
+	}
+	if err := localizedFile.Cleanup(); err != nil {
+		logger.WithField("path", localizedFile.Path).Warn("failed to remove temp file of biz bundle: ", err)
+	}
+}
 
+// readJarManifest return the main attributes of META-INF/MANIFEST.MF, nil if the jar has no manifest.
+func readJarManifest(zipReader *zip.ReadCloser) (map[string]string, error) {
 	for _, fileInfo := range zipReader.File {
-		if fileInfo.Name == "META-INF/MANIFEST.MF" {
-			// open this file
-			file, err := fileInfo.Open()
-			if err != nil {
-				return nil, err
-			}
-			// read all to string
-			buf := make([]byte, fileInfo.UncompressedSize64)
-			_, err = file.Read(buf)
-			if err != nil && err.Error() != "EOF" {
-				return nil, err
+		if fileInfo.Name != "META-INF/MANIFEST.MF" {
+			continue
+		}
+		file, err := fileInfo.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+
+		attributes := map[string]string{}
+		lastKey := ""
+		for _, line := range strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") {
+			// a line starting with a space continues the value of the last attribute
+			if strings.HasPrefix(line, " ") && lastKey != "" {
+				attributes[lastKey] += strings.TrimPrefix(line, " ")
+				continue
 			}
-			// find bizName and bizVersion
-			for _, line := range strings.Split(string(buf), "\n") {
-				// if line contains "Ark-Biz-Name:" then it's bizName
-				if strings.Contains(line, "Ark-Biz-Name:") {
-					bizName = strings.TrimSpace(strings.Split(line, ":")[1])
+			// the main attributes end at the first blank line
+			if strings.TrimSpace(line) == "" {
+				if len(attributes) > 0 {
+					break
 				}
+				continue
+			}
+			key, value, found := strings.Cut(line, ":")
+			if !found {
+				continue
+			}
+			lastKey = strings.TrimSpace(key)
+			attributes[lastKey] = strings.TrimSpace(value)
+		}
+		return attributes, nil
+	}
+	return nil, nil
+}
 
-				// if line contains "Ark-Biz-Version:" then it's bizVersion
-				if strings.Contains(line, "Ark-Biz-Version:") {
-					bizVersion = strings.TrimSpace(strings.Split(line, ":")[1])
-				}
+// parseJarBizModel parse jar file to BizModel.
+// The jar downloaded to a temp file is removed after parsing unless keepTempFiles is true.
+func parseJarBizModel(ctx context.Context, bizUrl fileutil.FileUrl, keepTempFiles bool) (*BizModel, error) {
+	var bizModel *BizModel
+	err := withLocalJar(ctx, bizUrl, keepTempFiles, func(localizedFile *fileutil.LocalizedFile, zipReader *zip.ReadCloser) error {
+		manifest, err := readJarManifest(zipReader)
+		if err != nil {
+			return err
+		}
+		bizName := manifest["Ark-Biz-Name"]
+		bizVersion := manifest["Ark-Biz-Version"]
+
+		if bizName == "" {
+			return fmt.Errorf("%w: %s has no Ark-Biz-Name in META-INF/MANIFEST.MF, "+
+				"make sure it's built with the ark-biz classifier", ErrNotArkBizJar, bizUrl)
+		}
+
+		bizModel = &BizModel{
+			BizName:    bizName,
+			BizVersion: bizVersion,
+			BizUrl:     bizUrl,
+		}
+
+		// only the jar provided locally could have a sidecar properties file next to it
+		if !localizedFile.Temporary {
+			return loadBizProperties(bizPropertiesPath(localizedFile.Path), bizModel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bizModel, nil
+}
+
+// bizPropertiesPath return the sidecar properties file of the jar, e.g. biz.properties for biz.jar.
+func bizPropertiesPath(jarPath string) string {
+	return strings.TrimSuffix(jarPath, ".jar") + ".properties"
+}
+
+// loadBizProperties fill the install defaults of bizModel from the sidecar properties file if it exists.
+// The supported keys are:
+//
+//	mainClass=com.alipay.Main
+//	args=--foo --bar
+//	env.KEY=value
+func loadBizProperties(path string, bizModel *BizModel) error {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			key, value, found = strings.Cut(line, ":")
+		}
+		if !found {
+			return fmt.Errorf("invalid line %d of %s: %q, expected key=value", i+1, path, line)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch {
+		case key == "mainClass":
+			bizModel.MainClass = value
+		case key == "args":
+			bizModel.Args = strings.Fields(value)
+		case strings.HasPrefix(key, "env."):
+			if bizModel.Env == nil {
+				bizModel.Env = map[string]string{}
 			}
-			break
+			bizModel.Env[strings.TrimPrefix(key, "env.")] = value
+		default:
+			return fmt.Errorf("unknown key %q at line %d of %s", key, i+1, path)
 		}
 	}
+	return validateBizEnv(bizModel.Env)
+}
 
-	return &BizModel{
-		BizName:    bizName,
-		BizVersion: bizVersion,
-		BizUrl:     bizUrl,
-	}, nil
+// bizEnvKeyPattern is the env key accepted by arklet.
+var bizEnvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// validateBizEnv reject the env keys arklet can't handle.
+func validateBizEnv(env map[string]string) error {
+	for key := range env {
+		if !bizEnvKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid env key %q, only letters, digits, '_', '.' and '-' are allowed "+
+				"and it can't start with a digit", key)
+		}
+	}
+	return nil
+}
+
+// validateWebContextPath reject the web context path not starting with '/' or containing spaces, empty is accepted.
+func validateWebContextPath(webContextPath string) error {
+	if webContextPath == "" {
+		return nil
+	}
+	if !strings.HasPrefix(webContextPath, "/") || strings.IndexFunc(webContextPath, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("invalid web context path %q, it must start with '/' and contain no spaces", webContextPath)
+	}
+	return nil
+}
+
+// bizVersionPattern is the biz version like 1.0.0, 1.0.0-SNAPSHOT or 1.0.0.RELEASE.
+var bizVersionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*([.\-][A-Za-z0-9]+)*$`)
+
+// snapshotQualifier is the qualifier of snapshot versions, which is normalized to upper case.
+const snapshotQualifier = "-SNAPSHOT"
+
+// NormalizeBizVersion trim the spaces around the version and upper case the SNAPSHOT qualifier,
+// ErrInvalidVersion is returned if the version is malformed, e.g. 0.0.1 SNAPSHOT.
+func NormalizeBizVersion(version string) (string, error) {
+	normalized := strings.TrimSpace(version)
+	if len(normalized) >= len(snapshotQualifier) &&
+		strings.EqualFold(normalized[len(normalized)-len(snapshotQualifier):], snapshotQualifier) {
+		normalized = normalized[:len(normalized)-len(snapshotQualifier)] + snapshotQualifier
+	}
+	if !bizVersionPattern.MatchString(normalized) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidVersion, version)
+	}
+	return normalized, nil
+}
+
+// normalizeBizModel normalize the version of bizModel, the version is optional and left empty if not given.
+func normalizeBizModel(bizModel BizModel) (BizModel, error) {
+	if bizModel.BizVersion == "" {
+		return bizModel, nil
+	}
+	version, err := NormalizeBizVersion(bizModel.BizVersion)
+	if err != nil {
+		return bizModel, err
+	}
+	bizModel.BizVersion = version
+	return bizModel, nil
 }
 
 // ParseBizModel parse biz bundle given by bizUrl to BizModel.
 func ParseBizModel(ctx context.Context, bizUrl fileutil.FileUrl) (*BizModel, error) {
+	return parseBizModel(ctx, bizUrl, false)
+}
+
+func parseBizModel(ctx context.Context, bizUrl fileutil.FileUrl, keepTempFiles bool) (*BizModel, error) {
 	switch {
 	case isJarFile(bizUrl):
-		return parseJarBizModel(ctx, bizUrl)
+		return parseJarBizModel(ctx, bizUrl, keepTempFiles)
 	default:
 		return nil, fmt.Errorf("unknown biz bundle type %s", bizUrl)
 	}
 }
+
+// localizeBizUrl resolve the bizUrl to a local file if arklet can't download it by itself.
+// Arklet only understands file:// and http(s):// urls, others like mvn:// are resolved locally.
+// The returned cleanup removes the file downloaded to a temp file unless keepTempFiles is true,
+// it should be called once arklet has installed the biz.
+func localizeBizUrl(ctx context.Context, bizModel BizModel, keepTempFiles bool) (BizModel, func(), error) {
+	url := string(bizModel.BizUrl)
+	if url == "" ||
+		strings.HasPrefix(url, "file://") ||
+		strings.HasPrefix(url, "http://") ||
+		strings.HasPrefix(url, "https://") {
+		return bizModel, func() {}, nil
+	}
+
+	localizedFile, err := fileutil.Resolve(ctx, bizModel.BizUrl)
+	if err != nil {
+		return bizModel, func() {}, err
+	}
+	bizModel.BizUrl = fileutil.FileUrl("file://" + localizedFile.Path)
+	return bizModel, func() {
+		cleanupLocalizedFile(ctx, localizedFile, keepTempFiles)
+	}, nil
+}
@@ -16,15 +16,21 @@ package ark
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/runtime"
 
 	"github.com/google/uuid"
 	"github.com/magiconair/properties/assert"
@@ -67,6 +73,7 @@ func TestParseBizModel_LocalJar(t *testing.T) {
 	model, err := parseJarBizModel(
 		context.Background(),
 		fileutil.FileUrl("file://"+zipFilePath),
+		false,
 	)
 	if err != nil {
 		panic(err)
@@ -76,3 +83,235 @@ func TestParseBizModel_LocalJar(t *testing.T) {
 	assert.Equal(t, model.BizVersion, "version")
 	assert.Equal(t, model.BizUrl, fileutil.FileUrl("file://"+zipFilePath))
 }
+
+// buildTestJar return the content of a biz jar with given name and version in manifest.
+func buildTestJar(bizName, bizVersion string) []byte {
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+	manifestFile, err := zipWriter.Create("META-INF/MANIFEST.MF")
+	if err != nil {
+		panic(err)
+	}
+	_, _ = io.WriteString(manifestFile, "Ark-Biz-Name: "+bizName+"\nArk-Biz-Version: "+bizVersion+"\n")
+	if err := zipWriter.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// serveTestJar serve the jar by http, and register a "testhttp" scheme recording the downloaded temp files.
+func serveTestJar(t *testing.T, content []byte) (fileutil.FileUrl, *[]string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	downloaded := &[]string{}
+	fileutil.RegisterResolver("testhttp", fileutil.ResolverFunc(func(ctx context.Context, fileUrl fileutil.FileUrl) (*fileutil.LocalizedFile, error) {
+		localized, err := fileutil.Resolve(ctx, fileutil.FileUrl("http"+string(fileUrl)[len("testhttp"):]))
+		if err == nil {
+			*downloaded = append(*downloaded, localized.Path)
+		}
+		return localized, err
+	}))
+
+	return fileutil.FileUrl("test" + server.URL + "/biz-ark-biz.jar"), downloaded
+}
+
+func TestParseBizModel_RemoveTempFileByDefault(t *testing.T) {
+	bizUrl, downloaded := serveTestJar(t, buildTestJar("testName", "version"))
+
+	model, err := BuildService(context.Background()).ParseBizModel(context.Background(), bizUrl)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, model.BizName, "testName")
+	assert.Equal(t, len(*downloaded), 1)
+
+	_, err = os.Stat((*downloaded)[0])
+	assert.Equal(t, os.IsNotExist(err), true)
+}
+
+func TestParseBizModel_KeepTempFiles(t *testing.T) {
+	bizUrl, downloaded := serveTestJar(t, buildTestJar("testName", "version"))
+
+	model, err := BuildService(context.Background(), WithKeepTempFiles(true)).ParseBizModel(context.Background(), bizUrl)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, model.BizVersion, "version")
+	assert.Equal(t, len(*downloaded), 1)
+	defer os.Remove((*downloaded)[0])
+
+	_, err = os.Stat((*downloaded)[0])
+	assert.Equal(t, err, nil)
+}
+
+func TestParseBizModel_NotArkBizJar(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+	manifestFile, _ := zipWriter.Create("META-INF/MANIFEST.MF")
+	_, _ = io.WriteString(manifestFile, "Main-Class: com.alipay.Application\n")
+	_ = zipWriter.Close()
+
+	jarPath := filepath.Join(t.TempDir(), "app.jar")
+	_ = os.WriteFile(jarPath, buf.Bytes(), 0644)
+
+	_, err := ParseBizModel(context.Background(), fileutil.FileUrl("file://"+jarPath))
+	assert.Equal(t, errors.Is(err, ErrNotArkBizJar), true)
+}
+
+func TestParseBizModel_SidecarProperties(t *testing.T) {
+	jarPath := filepath.Join(t.TempDir(), "biz.jar")
+	_ = os.WriteFile(jarPath, buildTestJar("testName", "version"), 0644)
+	_ = os.WriteFile(filepath.Join(filepath.Dir(jarPath), "biz.properties"), []byte(
+		"# install defaults\n"+
+			"mainClass=com.alipay.BizMain\n"+
+			"args=--spring.profiles.active=test --debug\n"+
+			"env.LOG_LEVEL = DEBUG\n"), 0644)
+
+	model, err := ParseBizModel(context.Background(), fileutil.FileUrl("file://"+jarPath))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, model.MainClass, "com.alipay.BizMain")
+	assert.Equal(t, model.Args, []string{"--spring.profiles.active=test", "--debug"})
+	assert.Equal(t, model.Env, map[string]string{"LOG_LEVEL": "DEBUG"})
+}
+
+func TestParseBizModel_SidecarInvalidEnvKey(t *testing.T) {
+	jarPath := filepath.Join(t.TempDir(), "biz.jar")
+	_ = os.WriteFile(jarPath, buildTestJar("testName", "version"), 0644)
+	_ = os.WriteFile(filepath.Join(filepath.Dir(jarPath), "biz.properties"), []byte("env.LOG LEVEL=DEBUG\n"), 0644)
+
+	_, err := ParseBizModel(context.Background(), fileutil.FileUrl("file://"+jarPath))
+	assert.Equal(t, err.Error(), `invalid env key "LOG LEVEL", only letters, digits, '_', '.' and '-' are allowed and it can't start with a digit`)
+}
+
+func TestInstallBizBody_EnvAndArgs(t *testing.T) {
+	body, err := installBizBody(BizModel{BizName: "biz", BizVersion: "0.0.1"}, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(runtime.Must(json.Marshal(body))), `{"bizName":"biz","bizVersion":"0.0.1"}`)
+
+	body, err = installBizBody(BizModel{
+		BizName:    "biz",
+		BizVersion: "0.0.1",
+		MainClass:  "com.alipay.BizMain",
+		Env:        map[string]string{"LOG_LEVEL": "DEBUG"},
+		Args:       []string{"--debug"},
+	}, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(runtime.Must(json.Marshal(body))),
+		`{"bizName":"biz","bizVersion":"0.0.1","mainClass":"com.alipay.BizMain","envs":{"LOG_LEVEL":"DEBUG"},"args":["--debug"]}`)
+
+	_, err = installBizBody(BizModel{BizName: "biz", Env: map[string]string{"1st": "x"}}, nil)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestNormalizeBizVersion(t *testing.T) {
+	valid := []struct {
+		version  string
+		expected string
+	}{
+		{"0.0.1", "0.0.1"},
+		{"1", "1"},
+		{"0.0.1-SNAPSHOT", "0.0.1-SNAPSHOT"},
+		{"0.0.1-SNAPSHOT ", "0.0.1-SNAPSHOT"},
+		{" 0.0.1-snapshot", "0.0.1-SNAPSHOT"},
+		{"1.0.0.RELEASE", "1.0.0.RELEASE"},
+		{"1.0.0-beta-2", "1.0.0-beta-2"},
+	}
+	for _, c := range valid {
+		normalized, err := NormalizeBizVersion(c.version)
+		assert.Equal(t, err, nil, c.version)
+		assert.Equal(t, normalized, c.expected, c.version)
+	}
+
+	invalid := []string{
+		"",
+		" ",
+		"SNAPSHOT",
+		"v1.0.0",
+		"0.0.1 SNAPSHOT",
+		"0.0.1-",
+		"0..1",
+		"0.0.1-SNAPSHOT-",
+		"0.0.1/SNAPSHOT",
+	}
+	for _, version := range invalid {
+		_, err := NormalizeBizVersion(version)
+		assert.Equal(t, errors.Is(err, ErrInvalidVersion), true, version)
+	}
+}
+
+func TestInstallBiz_NormalizeVersion(t *testing.T) {
+	ctx := context.Background()
+	var installed string
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/installBiz" {
+			bizModel := BizModel{}
+			_ = json.NewDecoder(r.Body).Decode(&bizModel)
+			installed = bizModel.BizVersion
+		}
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel()
+	client := BuildService(ctx)
+	req := InstallBizRequest{
+		BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1-snapshot ", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	}
+
+	assert.Equal(t, client.InstallBiz(ctx, req), nil)
+	assert.Equal(t, installed, "0.0.1-SNAPSHOT")
+
+	installed = ""
+	req.BizModel.BizVersion = "0.0.1 SNAPSHOT"
+	assert.Equal(t, errors.Is(client.InstallBiz(ctx, req), ErrInvalidVersion), true)
+	assert.Equal(t, installed, "")
+}
+
+func TestInstallBiz_RemoveLocalizedTempFile(t *testing.T) {
+	ctx := context.Background()
+	var installedUrl string
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/installBiz" {
+			bizModel := BizModel{}
+			_ = json.NewDecoder(r.Body).Decode(&bizModel)
+			installedUrl = string(bizModel.BizUrl)
+		}
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel()
+
+	for _, keep := range []bool{false, true} {
+		bizUrl, downloaded := serveTestJar(t, buildTestJar("biz", "0.0.1"))
+		err := BuildService(ctx, WithKeepTempFiles(keep)).InstallBiz(ctx, InstallBizRequest{
+			BizModel:              BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: bizUrl},
+			TargetContainer:       ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+			AllowMultipleVersions: true,
+		})
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(*downloaded), 1)
+		assert.Equal(t, installedUrl, "file://"+(*downloaded)[0])
+
+		_, err = os.Stat((*downloaded)[0])
+		assert.Equal(t, os.IsNotExist(err), !keep)
+		_ = os.Remove((*downloaded)[0])
+	}
+}
+
+func TestInstallBizBody_WebContextPath(t *testing.T) {
+	body, err := installBizBody(BizModel{BizName: "biz", BizVersion: "0.0.1"}, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(string(runtime.Must(json.Marshal(body))), "webContextPath"), false)
+
+	body, err = installBizBody(BizModel{BizName: "biz", BizVersion: "0.0.1", WebContextPath: "/biz-canary"}, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(runtime.Must(json.Marshal(body))), `{"bizName":"biz","bizVersion":"0.0.1","webContextPath":"/biz-canary"}`)
+
+	for _, invalid := range []string{"biz", "/biz canary", " /biz"} {
+		_, err = installBizBody(BizModel{BizName: "biz", WebContextPath: invalid}, nil)
+		assert.Equal(t, err != nil, true, invalid)
+	}
+
+	_, err = installBizBody(BizModel{BizName: "biz"}, map[string]interface{}{"webContextPath": "/biz"})
+	assert.Equal(t, err != nil, true)
+}
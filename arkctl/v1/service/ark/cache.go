@@ -0,0 +1,166 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass return a context that makes the queries skip the client side cache.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func isCacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+type ttlCacheEntry[V any] struct {
+	key       string
+	value     V
+	expiredAt time.Time
+}
+
+// ttlCache is a concurrent safe cache whose entries expire after ttl.
+// If maxEntries is positive, the least recently used entries are evicted beyond it.
+type ttlCache[V any] struct {
+	lock       sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+	entries    map[string]*list.Element
+
+	// order is the entries from the most recently used to the least
+	order *list.List
+}
+
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	return newLRUCache[V](ttl, 0)
+}
+
+func newLRUCache[V any](ttl time.Duration, maxEntries int) *ttlCache[V] {
+	return &ttlCache[V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+func (c *ttlCache[V]) get(key string) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := element.Value.(*ttlCacheEntry[V])
+	if !c.now().Before(entry.expiredAt) {
+		c.removeElement(element)
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (c *ttlCache[V]) put(key string, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry := &ttlCacheEntry[V]{
+		key:       key,
+		value:     value,
+		expiredAt: c.now().Add(c.ttl),
+	}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *ttlCache[V]) remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+func (c *ttlCache[V]) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*ttlCacheEntry[V]).key)
+}
+
+// containerCacheKey identify an ark container.
+// The pods of the same coordinate in different clusters are different containers.
+func containerCacheKey(target ArkContainerRuntimeInfo) string {
+	coordinate := target.Coordinate
+	if target.Container != "" {
+		coordinate += "/" + target.Container
+	}
+	if target.Kubeconfig != "" || target.KubeContext != "" {
+		coordinate = target.Kubeconfig + "@" + target.KubeContext + "/" + coordinate
+	}
+	if target.SocketPath != "" {
+		return fmt.Sprintf("%s/%s/%s", target.RunType, coordinate, target.SocketPath)
+	}
+	return fmt.Sprintf("%s/%s/%d", target.RunType, coordinate, target.GetPort())
+}
+
+// queryAllBizCacheKey identify the ark container queried by QueryAllBiz, the names of the same host share the key.
+func queryAllBizCacheKey(host string, port int, socketPath string) string {
+	if socketPath != "" {
+		return "unix:" + socketPath
+	}
+	return net.JoinHostPort(canonicalHost(host), strconv.Itoa(port))
+}
+
+// canonicalHost return the same host for its different spellings, like localhost, ::1 and 127.0.0.1.
+func canonicalHost(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(host), "["), "]"))
+	if host == "" || host == "localhost" {
+		return "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.Equal(net.IPv6loopback) {
+			return "127.0.0.1"
+		}
+		return ip.String()
+	}
+	return host
+}
+
+// bizCacheKey identify a biz in an ark container.
+func bizCacheKey(target ArkContainerRuntimeInfo, bizName, bizVersion string) string {
+	return containerCacheKey(target) + "/" + bizName + ":" + bizVersion
+}
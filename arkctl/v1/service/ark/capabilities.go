@@ -0,0 +1,390 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/pollutil"
+)
+
+// baselineEndpoints are the endpoints supported by every arklet.
+var baselineEndpoints = []Endpoint{EndpointInstallBiz, EndpointUnInstallBiz, EndpointQueryAllBiz, EndpointHealth}
+
+// probedEndpoints are the optional endpoints probed when arklet doesn't list its endpoints.
+var probedEndpoints = []Endpoint{
+	EndpointUploadBiz, EndpointQueryBiz, EndpointDrainBiz, EndpointQueryBizOps,
+	EndpointSwitchBiz, EndpointQueryPlugins, EndpointShutdown,
+}
+
+// ArkletCapabilities is what an arklet supports, the features are summarized from its endpoints for tools adapting to it.
+type ArkletCapabilities struct {
+	// ArkVersion is the version of the ark container, empty if it's unknown.
+	ArkVersion string `json:"arkVersion,omitempty"`
+
+	// Endpoints are the supported endpoints in order.
+	Endpoints []Endpoint `json:"endpoints"`
+
+	// Probed is true if arklet doesn't list its endpoints, and the Endpoints are probed one by one.
+	Probed bool `json:"probed"`
+
+	// GzipUpload is true if arklet accepts gzip compressed uploads, which is advertised by the Accept-Encoding header.
+	GzipUpload bool `json:"gzipUpload"`
+
+	// FormBody is true if arklet only accepts form encoded bodies, which is advertised by the Accept header.
+	FormBody bool `json:"formBody"`
+
+	// SwitchBiz is true if the active version of a biz could be switched.
+	SwitchBiz bool `json:"switchBiz"`
+
+	// AsyncInstall is true if biz could be installed in background, whose progress could be polled.
+	AsyncInstall bool `json:"asyncInstall"`
+
+	// Plugins is true if the ark plugins could be queried.
+	Plugins bool `json:"plugins"`
+
+	// Upload is true if biz bundles could be uploaded to the container.
+	Upload bool `json:"upload"`
+
+	// QueryBiz is true if the detail of a single biz could be queried.
+	QueryBiz bool `json:"queryBiz"`
+
+	// Drain is true if the traffic of biz could be drained before uninstall.
+	Drain bool `json:"drain"`
+
+	// Shutdown is true if the container could be shutdown by arklet.
+	Shutdown bool `json:"shutdown"`
+}
+
+// Capabilities is the features supported by an arklet, returned by QueryCapabilities.
+type Capabilities = ArkletCapabilities
+
+// Supports return true if the arklet supports the endpoint.
+func (c *ArkletCapabilities) Supports(endpoint Endpoint) bool {
+	for _, supported := range c.Endpoints {
+		if supported == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeFeatures set the features by the supported endpoints.
+func (c *ArkletCapabilities) summarizeFeatures() {
+	c.SwitchBiz = c.Supports(EndpointSwitchBiz)
+	c.AsyncInstall = c.Supports(EndpointQueryBizOps)
+	c.Plugins = c.Supports(EndpointQueryPlugins)
+	c.Upload = c.Supports(EndpointUploadBiz)
+	c.QueryBiz = c.Supports(EndpointQueryBiz)
+	c.Drain = c.Supports(EndpointDrainBiz)
+	c.Shutdown = c.Supports(EndpointShutdown)
+}
+
+// QueryCapabilities return the features supported by the arklet of target, it's the same as DetectCapabilities.
+func (h *service) QueryCapabilities(ctx context.Context, target ArkContainerRuntimeInfo) (*Capabilities, error) {
+	return h.DetectCapabilities(ctx, target)
+}
+
+// HelpCommand is a command listed by the help endpoint of arklet.
+type HelpCommand struct {
+	Id   string `json:"id"`
+	Desc string `json:"desc"`
+}
+
+// HelpResponse is the response for listing the commands of arklet.
+type HelpResponse struct {
+	GenericArkResponseBase[[]HelpCommand]
+}
+
+// capabilityEntry is the cached capabilities of a container, the mutex dedupes the concurrent detections.
+type capabilityEntry struct {
+	mu           sync.Mutex
+	capabilities *ArkletCapabilities
+}
+
+// DetectCapabilities return what the arklet of target supports, the result is cached per target.
+// The cache of a target is dropped once a request to it fails to connect, e.g. the container restarts.
+func (h *service) DetectCapabilities(ctx context.Context, target ArkContainerRuntimeInfo) (*ArkletCapabilities, error) {
+	target, err := targetOrDefault(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := h.capabilities.LoadOrStore(containerCacheKey(target), &capabilityEntry{})
+	entry := value.(*capabilityEntry)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.capabilities != nil {
+		return entry.capabilities, nil
+	}
+
+	capabilities, err := h.detectCapabilities(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	entry.capabilities = capabilities
+	if capabilities.FormBody {
+		h.formArklets.Store(h.arkletKey(target), true)
+	} else {
+		h.formArklets.Delete(h.arkletKey(target))
+	}
+	return capabilities, nil
+}
+
+// invalidateCapabilities drop the cached capabilities of target if err means the container is unreachable.
+func (h *service) invalidateCapabilities(target ArkContainerRuntimeInfo, err error) {
+	if isConnectionError(err) {
+		h.capabilities.Delete(containerCacheKey(target))
+		h.formArklets.Delete(h.arkletKey(target))
+	}
+}
+
+// isConnectionError return true if err fails to connect the arklet.
+func isConnectionError(err error) bool {
+	opErr := &net.OpError{}
+	return errors.As(err, &opErr)
+}
+
+func (h *service) detectCapabilities(ctx context.Context, target ArkContainerRuntimeInfo) (*ArkletCapabilities, error) {
+	capabilities := &ArkletCapabilities{}
+	var err error
+	switch target.RunType {
+	case ArkContainerRunTypeLocal:
+		err = h.listEndpointsOnLocal(ctx, target, capabilities)
+	case ArkContainerRunTypeK8s:
+		err = h.listEndpointsInPod(ctx, target, capabilities)
+	default:
+		return nil, fmt.Errorf("detect capabilities is not supported for run type: %s", target.RunType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if capabilities.Endpoints == nil {
+		capabilities.Probed = true
+		if err := h.probeEndpoints(ctx, target, capabilities); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(capabilities.Endpoints, func(i, j int) bool {
+		return capabilities.Endpoints[i] < capabilities.Endpoints[j]
+	})
+	capabilities.summarizeFeatures()
+
+	if health, err := h.queryHealth(ctx, target); err == nil {
+		capabilities.ArkVersion = health.HealthData.ArkVersion
+	} else {
+		contextutil.GetLogger(ctx).WithError(err).Warn("failed to query the ark version")
+	}
+	return capabilities, nil
+}
+
+// listEndpointsOnLocal list the endpoints by the help endpoint, the endpoints are left nil if it isn't supported.
+func (h *service) listEndpointsOnLocal(ctx context.Context, target ArkContainerRuntimeInfo, capabilities *ArkletCapabilities) error {
+	endpointUrl, err := h.localEndpointUrl(target, EndpointHelp)
+	if err != nil {
+		return err
+	}
+	resp, err := h.client.R().
+		SetContext(ctx).
+		SetBody(struct{}{}).
+		Post(endpointUrl)
+	if err != nil {
+		return err
+	}
+	if !resp.IsSuccess() {
+		return nil
+	}
+	capabilities.Endpoints = parseHelpEndpoints(resp.Body())
+	capabilities.GzipUpload = acceptsGzip(resp.Header())
+	capabilities.FormBody = acceptsOnlyForm(resp.Header())
+	return nil
+}
+
+// listEndpointsInPod list the endpoints by the help endpoint, the endpoints are left nil if it isn't supported.
+func (h *service) listEndpointsInPod(ctx context.Context, target ArkContainerRuntimeInfo, capabilities *ArkletCapabilities) error {
+	respBody, err := h.postInPod(ctx, target, EndpointHelp, struct{}{})
+	if err != nil {
+		return err
+	}
+	capabilities.Endpoints = parseHelpEndpoints(respBody)
+	return nil
+}
+
+// acceptsGzip return true if the Accept-Encoding advertised by arklet includes gzip.
+func acceptsGzip(header http.Header) bool {
+	for _, value := range header.Values("Accept-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			name, _, _ := strings.Cut(encoding, ";")
+			if strings.EqualFold(strings.TrimSpace(name), "gzip") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowsMethod return true if the Allow header lists method.
+func allowsMethod(header http.Header, method string) bool {
+	for _, value := range header.Values("Allow") {
+		for _, allowed := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(allowed), method) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseHelpEndpoints return the endpoints listed by the help response, nil if it isn't a valid one.
+func parseHelpEndpoints(body []byte) []Endpoint {
+	helpResponse := &HelpResponse{}
+	if err := json.Unmarshal(body, helpResponse); err != nil || !helpResponse.Code.IsSuccess() {
+		return nil
+	}
+	endpoints := []Endpoint{}
+	for _, command := range helpResponse.Data {
+		if command.Id != "" {
+			endpoints = append(endpoints, Endpoint(command.Id))
+		}
+	}
+	return endpoints
+}
+
+// probeEndpoints probe the optional endpoints with OPTIONS requests, an endpoint is supported if it responds 2xx
+// or its Allow header lists the method of the endpoint, e.g. an arklet rejecting OPTIONS with 405 and Allow: POST.
+// The optional endpoints can't be probed in pods, only the baseline endpoints are returned.
+func (h *service) probeEndpoints(ctx context.Context, target ArkContainerRuntimeInfo, capabilities *ArkletCapabilities) error {
+	capabilities.Endpoints = append([]Endpoint{}, baselineEndpoints...)
+	if target.RunType != ArkContainerRunTypeLocal {
+		return nil
+	}
+
+	for _, endpoint := range probedEndpoints {
+		endpointUrl, err := h.localEndpointUrl(target, endpoint)
+		if err != nil {
+			return err
+		}
+		resp, err := h.client.R().
+			SetContext(ctx).
+			Options(endpointUrl)
+		if err != nil {
+			return err
+		}
+		if !resp.IsSuccess() && !allowsMethod(resp.Header(), h.endpointMethod(endpoint)) {
+			continue
+		}
+		capabilities.Endpoints = append(capabilities.Endpoints, endpoint)
+		if endpoint == EndpointUploadBiz {
+			capabilities.GzipUpload = acceptsGzip(resp.Header())
+		}
+	}
+	return nil
+}
+
+// WaitOptions controls how long to wait for a biz to become activated.
+type WaitOptions struct {
+	// Timeout is the max time to wait. If it's not positive, the deadline of the context is used,
+	// or the DefaultWaitTimeout of the client if the context has none.
+	Timeout time.Duration
+
+	// PollInterval is the interval of polling the biz state, default to 1s.
+	PollInterval time.Duration
+}
+
+// InstallBizAndWait install the biz and wait until it's activated.
+// The biz is installed in background and polled if the arklet supports async install, otherwise it's installed synchronously.
+func (h *service) InstallBizAndWait(ctx context.Context, req InstallBizRequest, opts WaitOptions) (err error) {
+	if req.TargetContainer, err = targetOrDefault(ctx, req.TargetContainer); err != nil {
+		return err
+	}
+	capabilities, err := h.DetectCapabilities(ctx, req.TargetContainer)
+	if err != nil {
+		return err
+	}
+	if !capabilities.AsyncInstall {
+		return h.InstallBiz(ctx, req)
+	}
+	if err := h.requireVersion(ctx, req.TargetContainer, operationAsyncInstall); err != nil {
+		contextutil.GetLogger(ctx).WithError(err).Warn("fallback to synchronous install")
+		return h.InstallBiz(ctx, req)
+	}
+
+	extraParams := map[string]interface{}{}
+	for key, value := range req.ExtraParams {
+		extraParams[key] = value
+	}
+	extraParams["async"] = true
+	req.ExtraParams = extraParams
+	if err := h.InstallBiz(ctx, req); err != nil {
+		return err
+	}
+	return h.waitBizState(ctx, req.TargetContainer, req.BizModel, BizStateActivated, "install", opts)
+}
+
+// WaitBizState poll the state of the biz until it's the desired one, e.g. ACTIVATED or DEACTIVATED.
+func (h *service) WaitBizState(ctx context.Context, target ArkContainerRuntimeInfo, bizModel BizModel, desired BizState, opts WaitOptions) error {
+	target, err := targetOrDefault(ctx, target)
+	if err != nil {
+		return err
+	}
+	return h.waitBizState(ctx, target, bizModel, desired, "", opts)
+}
+
+// waitBizState poll the state of the biz until it's desired, the operation changing the state is reported if the biz is broken.
+func (h *service) waitBizState(ctx context.Context, target ArkContainerRuntimeInfo, bizModel BizModel, desired BizState, operation string, opts WaitOptions) error {
+	var cancel context.CancelFunc
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	} else {
+		ctx, cancel = withDefaultTimeout(ctx, h.options.DefaultWaitTimeout)
+	}
+	defer cancel()
+
+	state := BizStateUnknown
+	err := pollutil.Poll(ctx, pollutil.Config{Interval: opts.PollInterval}, func(ctx context.Context) (bool, error) {
+		polled, err := h.QueryBizState(WithCacheBypass(ctx), target, bizModel.BizName, bizModel.BizVersion)
+		switch {
+		case err == nil:
+			state = polled
+		case !errors.Is(err, ErrBizNotFound):
+			return false, pollutil.Fatal(err)
+		}
+
+		switch {
+		case state == BizStateBroken && operation != "":
+			return false, pollutil.Fatal(fmt.Errorf("biz %s:%s is broken after %s", bizModel.BizName, bizModel.BizVersion, operation))
+		case state == BizStateBroken:
+			return false, pollutil.Fatal(fmt.Errorf("biz %s:%s is broken", bizModel.BizName, bizModel.BizVersion))
+		}
+		return state == desired, nil
+	})
+
+	pollErr := &pollutil.Error{}
+	if errors.As(err, &pollErr) {
+		return fmt.Errorf("wait biz %s:%s %s, the last state is %s: %w",
+			bizModel.BizName, bizModel.BizVersion, strings.ToLower(string(desired)), state, err)
+	}
+	return err
+}
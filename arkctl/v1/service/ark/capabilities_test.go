@@ -0,0 +1,266 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// helpArklet return the fake arklet listing commands by help, the help endpoint is missing if commands is nil.
+func helpArklet(commands []string) *fakeArklet {
+	help := http.NotFound
+	if commands != nil {
+		data := []map[string]string{}
+		for _, command := range commands {
+			data = append(data, map[string]string{"id": command, "desc": command})
+		}
+		help = func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "SUCCESS", "data": data})
+		}
+	}
+	return &fakeArklet{handlers: map[string]http.HandlerFunc{
+		"/help":   help,
+		"/health": respondBody(`{"code":"SUCCESS","data":{"healthData":{"arkVersion":"2.2.9"}}}`),
+	}}
+}
+
+// pollBizStates respond the biz polled by queryBiz in the states in order, the last state is kept.
+func pollBizStates(states ...string) http.HandlerFunc {
+	polled := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := states[min(polled, len(states)-1)]
+		polled++
+		_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"bizName":"biz","bizVersion":"0.0.1","bizState":"` + state + `"}}`))
+	}
+}
+
+func TestDetectCapabilities_Help(t *testing.T) {
+	ctx := context.Background()
+	arklet := helpArklet([]string{"installBiz", "uninstallBiz", "queryAllBiz", "health", "queryBizOps"})
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	client := BuildService(ctx)
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			capabilities, err := client.DetectCapabilities(ctx, target)
+			assert.Nil(t, err)
+			assert.False(t, capabilities.Probed)
+			assert.True(t, capabilities.AsyncInstall)
+			assert.Equal(t, "2.2.9", capabilities.ArkVersion)
+			assert.Equal(t, []Endpoint{EndpointHealth, EndpointInstallBiz, EndpointQueryAllBiz, EndpointQueryBizOps, EndpointUnInstallBiz},
+				capabilities.Endpoints)
+		}()
+	}
+	wg.Wait()
+
+	// detected once for concurrent callers
+	assert.Equal(t, 1, arklet.requestCount("POST /help"))
+}
+
+func TestDetectCapabilities_Probe(t *testing.T) {
+	ctx := context.Background()
+	arklet := helpArklet(nil)
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	capabilities, err := BuildService(ctx).DetectCapabilities(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port})
+	assert.Nil(t, err)
+	assert.True(t, capabilities.Probed)
+	assert.False(t, capabilities.AsyncInstall)
+	assert.True(t, capabilities.Supports(EndpointInstallBiz))
+	assert.False(t, capabilities.Supports(EndpointDrainBiz))
+	for _, endpoint := range probedEndpoints {
+		assert.Equal(t, 1, arklet.requestCount("OPTIONS /"+string(endpoint)))
+	}
+}
+
+func TestDetectCapabilities_ProbeStatus(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch Endpoint(strings.TrimPrefix(r.URL.Path, "/")) {
+		case EndpointUploadBiz:
+			w.WriteHeader(http.StatusNoContent)
+		case EndpointQueryBiz:
+			w.WriteHeader(http.StatusOK)
+		case EndpointDrainBiz:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case EndpointSwitchBiz:
+			// OPTIONS is rejected without telling the allowed methods
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case EndpointShutdown:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer cancel()
+
+	capabilities, err := BuildService(ctx, WithRetry(0, 0)).DetectCapabilities(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port})
+	assert.Nil(t, err)
+	assert.True(t, capabilities.Probed)
+	assert.True(t, capabilities.Upload)
+	assert.True(t, capabilities.QueryBiz)
+	assert.True(t, capabilities.Drain)
+	assert.False(t, capabilities.SwitchBiz)
+	assert.False(t, capabilities.Shutdown)
+	assert.False(t, capabilities.AsyncInstall)
+}
+
+func TestQueryCapabilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string
+		expected Capabilities
+	}{
+		{
+			name:     "baseline",
+			commands: []string{"installBiz", "uninstallBiz", "queryAllBiz", "health"},
+			expected: Capabilities{},
+		},
+		{
+			name:     "switch and plugins",
+			commands: []string{"installBiz", "switchBiz", "queryAllPlugin"},
+			expected: Capabilities{SwitchBiz: true, Plugins: true},
+		},
+		{
+			name:     "async install and upload",
+			commands: []string{"installBiz", "queryBizOps", "uploadBiz", "queryBiz", "drainBiz", "shutdown"},
+			expected: Capabilities{AsyncInstall: true, Upload: true, QueryBiz: true, Drain: true, Shutdown: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			port, cancel := mockHttpServer("/", helpArklet(test.commands).serve)
+			defer cancel()
+
+			capabilities, err := BuildService(ctx).QueryCapabilities(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port})
+			assert.Nil(t, err)
+			// only the features are compared
+			features := *capabilities
+			features.ArkVersion, features.Endpoints = "", nil
+			assert.Equal(t, test.expected, features)
+		})
+	}
+}
+
+func TestDetectCapabilities_InvalidatedOnConnectionError(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/", helpArklet([]string{"installBiz"}).serve)
+
+	// the connection must be refused once the server is closed
+	client := BuildService(ctx, WithDisableKeepAlives(true))
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	_, err := client.DetectCapabilities(ctx, target)
+	assert.Nil(t, err)
+	_, cached := client.(*service).capabilities.Load(containerCacheKey(target))
+	assert.True(t, cached)
+
+	cancel()
+	err = client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: target,
+	})
+	assert.NotNil(t, err)
+	_, cached = client.(*service).capabilities.Load(containerCacheKey(target))
+	assert.False(t, cached)
+}
+
+func TestInstallBizAndWait_Async(t *testing.T) {
+	ctx := context.Background()
+	arklet := helpArklet([]string{"installBiz", "queryBiz", "queryBizOps"})
+	arklet.handlers["/queryBiz"] = pollBizStates("RESOLVED", "RESOLVED", "ACTIVATED")
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	err := BuildService(ctx).InstallBizAndWait(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	}, WaitOptions{Timeout: 5 * time.Second, PollInterval: 10 * time.Millisecond})
+	assert.Nil(t, err)
+
+	install, _ := arklet.lastRequest("/installBiz")
+	assert.Contains(t, install.body, `"async":true`)
+	assert.Equal(t, 3, arklet.requestCount("POST /queryBiz"))
+}
+
+func TestInstallBizAndWait_AsyncBroken(t *testing.T) {
+	ctx := context.Background()
+	arklet := helpArklet([]string{"installBiz", "queryBiz", "queryBizOps"})
+	arklet.handlers["/queryBiz"] = pollBizStates("BROKEN")
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	err := BuildService(ctx).InstallBizAndWait(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	}, WaitOptions{PollInterval: 10 * time.Millisecond})
+	assert.Equal(t, "biz biz:0.0.1 is broken after install", err.Error())
+}
+
+func TestInstallBizAndWait_DefaultTimeout(t *testing.T) {
+	ctx := context.Background()
+	arklet := helpArklet([]string{"installBiz", "queryBiz", "queryBizOps"})
+	arklet.handlers["/queryBiz"] = pollBizStates("RESOLVED")
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	// the biz never becomes activated, the wait without a timeout is bounded by the client
+	client := BuildService(ctx, WithDefaultWaitTimeout(100*time.Millisecond))
+	err := client.InstallBizAndWait(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	}, WaitOptions{PollInterval: 10 * time.Millisecond})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "the last state is RESOLVED")
+}
+
+func TestInstallBizAndWait_Sync(t *testing.T) {
+	ctx := context.Background()
+	arklet := helpArklet(nil)
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	err := BuildService(ctx).InstallBizAndWait(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	}, WaitOptions{})
+	assert.Nil(t, err)
+
+	install, _ := arklet.lastRequest("/installBiz")
+	assert.NotContains(t, install.body, "async")
+	assert.Equal(t, 1, arklet.requestCount("POST /installBiz"))
+	assert.Equal(t, 0, arklet.requestCount("POST /queryBiz"))
+}
@@ -0,0 +1,82 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"fmt"
+	"os"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// bizDescriptor is an entry of the biz descriptor file.
+type bizDescriptor struct {
+	BizName        string `yaml:"bizName"`
+	BizVersion     string `yaml:"bizVersion"`
+	BizUrl         string `yaml:"bizUrl"`
+	WebContextPath string `yaml:"webContextPath"`
+}
+
+// LoadInstallRequestsFromFile parse the YAML or JSON descriptor file into install requests.
+// The descriptor is either a single biz or a list of biz with bizName, bizVersion, bizUrl and optional webContextPath.
+// The TargetContainer of the requests are left empty for the caller to fill.
+func LoadInstallRequestsFromFile(path string) ([]InstallBizRequest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is a subset of YAML, so both are parsed as YAML
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(content, root); err != nil {
+		return nil, fmt.Errorf("parse biz descriptor %s failed: %w", path, err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil, fmt.Errorf("biz descriptor %s is empty", path)
+	}
+
+	var descriptors []bizDescriptor
+	switch node := root.Content[0]; node.Kind {
+	case yaml.SequenceNode:
+		err = node.Decode(&descriptors)
+	case yaml.MappingNode:
+		descriptor := bizDescriptor{}
+		err = node.Decode(&descriptor)
+		descriptors = append(descriptors, descriptor)
+	default:
+		err = fmt.Errorf("expected an object or a list at line %d", node.Line)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse biz descriptor %s failed: %w", path, err)
+	}
+
+	requests := make([]InstallBizRequest, 0, len(descriptors))
+	for i, descriptor := range descriptors {
+		if descriptor.BizName == "" || descriptor.BizVersion == "" || descriptor.BizUrl == "" {
+			return nil, fmt.Errorf("biz descriptor %s: entry %d requires bizName, bizVersion and bizUrl", path, i)
+		}
+		requests = append(requests, InstallBizRequest{
+			BizModel: BizModel{
+				BizName:        descriptor.BizName,
+				BizVersion:     descriptor.BizVersion,
+				BizUrl:         fileutil.FileUrl(descriptor.BizUrl),
+				WebContextPath: descriptor.WebContextPath,
+			},
+		})
+	}
+	return requests, nil
+}
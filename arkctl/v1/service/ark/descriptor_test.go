@@ -0,0 +1,75 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeDescriptor(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadInstallRequestsFromFile_YamlList(t *testing.T) {
+	path := writeDescriptor(t, "biz.yaml", `
+- bizName: biz1
+  bizVersion: 0.0.1
+  bizUrl: file:///tmp/biz1.jar
+- bizName: biz2
+  bizVersion: 0.0.2
+  bizUrl: https://example.com/biz2.jar
+`)
+
+	requests, err := LoadInstallRequestsFromFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []InstallBizRequest{
+		{BizModel: BizModel{BizName: "biz1", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz1.jar"}},
+		{BizModel: BizModel{BizName: "biz2", BizVersion: "0.0.2", BizUrl: "https://example.com/biz2.jar"}},
+	}, requests)
+}
+
+func TestLoadInstallRequestsFromFile_JsonObject(t *testing.T) {
+	path := writeDescriptor(t, "biz.json", `{"bizName": "biz1", "bizVersion": "0.0.1", "bizUrl": "file:///tmp/biz1.jar"}`)
+
+	requests, err := LoadInstallRequestsFromFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []InstallBizRequest{
+		{BizModel: BizModel{BizName: "biz1", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz1.jar"}},
+	}, requests)
+}
+
+func TestLoadInstallRequestsFromFile_Malformed(t *testing.T) {
+	_, err := LoadInstallRequestsFromFile(writeDescriptor(t, "broken.json", `[{"bizName": "biz1",`))
+	assert.NotNil(t, err)
+
+	_, err = LoadInstallRequestsFromFile(writeDescriptor(t, "scalar.yaml", `biz1`))
+	assert.NotNil(t, err)
+
+	_, err = LoadInstallRequestsFromFile(writeDescriptor(t, "missing.yaml", `bizName: biz1`))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "requires bizName, bizVersion and bizUrl")
+
+	_, err = LoadInstallRequestsFromFile(writeDescriptor(t, "empty.yaml", ``))
+	assert.NotNil(t, err)
+
+	_, err = LoadInstallRequestsFromFile(filepath.Join(t.TempDir(), "not-exist.yaml"))
+	assert.NotNil(t, err)
+}
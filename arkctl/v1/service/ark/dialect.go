@@ -0,0 +1,154 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Dialect is the json field names spoken by an arklet fork, e.g. name instead of bizName.
+// The request bodies are renamed into the dialect before they're encoded, and the response bodies are renamed back
+// before they're decoded, so the rest of the client only knows the field names of the Ark dialect.
+type Dialect struct {
+	// Name identifies the dialect in the errors.
+	Name string
+
+	// Fields maps the field names of the Ark dialect to the ones of the dialect at any nesting level,
+	// the fields not in it keep their names.
+	Fields map[string]string
+}
+
+var (
+	// DialectArk is the field names of the upstream arklet, the default.
+	DialectArk = Dialect{Name: "ark"}
+
+	// DialectShort is spoken by the forks dropping the biz prefix of the biz fields.
+	DialectShort = Dialect{
+		Name: "short",
+		Fields: map[string]string{
+			"bizName":    "name",
+			"bizVersion": "version",
+			"bizUrl":     "url",
+			"bizState":   "state",
+		},
+	}
+)
+
+// validate make sure the mapping is one to one, so that the responses could be renamed back.
+func (d Dialect) validate() error {
+	arkNames := make([]string, 0, len(d.Fields))
+	for arkName := range d.Fields {
+		arkNames = append(arkNames, arkName)
+	}
+	sort.Strings(arkNames)
+
+	mappedFrom := map[string]string{}
+	for _, arkName := range arkNames {
+		name := d.Fields[arkName]
+		if name == "" {
+			return fmt.Errorf("dialect %s maps %s to an empty field name", d.Name, arkName)
+		}
+		if existing, ok := mappedFrom[name]; ok {
+			return fmt.Errorf("dialect %s maps both %s and %s to %s", d.Name, existing, arkName, name)
+		}
+		mappedFrom[name] = arkName
+	}
+	return nil
+}
+
+// isArk return true if no field is renamed.
+func (d Dialect) isArk() bool {
+	for arkName, name := range d.Fields {
+		if arkName != name {
+			return false
+		}
+	}
+	return true
+}
+
+// fromArk rename the fields of the json body from the Ark dialect to d.
+func (d Dialect) fromArk(body []byte) ([]byte, error) {
+	if d.isArk() {
+		return body, nil
+	}
+	return renameFields(body, d.Fields)
+}
+
+// toArk rename the fields of the json body from d to the Ark dialect, the body is returned as is if it's not json.
+func (d Dialect) toArk(body []byte) []byte {
+	if d.isArk() {
+		return body
+	}
+	reversed := make(map[string]string, len(d.Fields))
+	for arkName, name := range d.Fields {
+		reversed[name] = arkName
+	}
+	renamed, err := renameFields(body, reversed)
+	if err != nil {
+		return body
+	}
+	return renamed
+}
+
+// renameFields rename the object fields of the json body at any nesting level by names.
+// The numbers are kept as they are, but the fields are sorted.
+func renameFields(body []byte, names map[string]string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(renameValue(value, names))
+}
+
+func renameValue(value interface{}, names map[string]string) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(typed))
+		for key, field := range typed {
+			if name, ok := names[key]; ok {
+				key = name
+			}
+			renamed[key] = renameValue(field, names)
+		}
+		return renamed
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = renameValue(item, names)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+// encodeBody encode the request body by encoder in the field names of the dialect.
+func (h *service) encodeBody(encoder Encoder, body interface{}) ([]byte, error) {
+	if !h.options.Dialect.isArk() {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = h.options.Dialect.fromArk(raw); err != nil {
+			return nil, err
+		}
+		body = json.RawMessage(raw)
+	}
+	return encoder.Encode(body)
+}
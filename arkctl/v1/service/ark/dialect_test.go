@@ -0,0 +1,101 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect_RoundTrip(t *testing.T) {
+	arkBody := `{"bizInfos":[{"bizName":"biz","bizState":"ACTIVATED","bizVersion":"0.0.1"}],"bizUrl":"file:///tmp/biz.jar","elapsedSpace":12345678901234567890}`
+
+	for _, test := range []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{dialect: DialectArk, expected: arkBody},
+		{
+			dialect:  DialectShort,
+			expected: `{"bizInfos":[{"name":"biz","state":"ACTIVATED","version":"0.0.1"}],"elapsedSpace":12345678901234567890,"url":"file:///tmp/biz.jar"}`,
+		},
+	} {
+		encoded, err := test.dialect.fromArk([]byte(arkBody))
+		assert.Nil(t, err, test.dialect.Name)
+		assert.Equal(t, test.expected, string(encoded), test.dialect.Name)
+		assert.JSONEq(t, arkBody, string(test.dialect.toArk(encoded)), test.dialect.Name)
+	}
+
+	// the body which isn't json is kept
+	assert.Equal(t, "not json", string(DialectShort.toArk([]byte("not json"))))
+}
+
+func TestDialect_Invalid(t *testing.T) {
+	for _, dialect := range []Dialect{
+		{Name: "ambiguous", Fields: map[string]string{"bizName": "name", "pluginName": "name"}},
+		{Name: "empty", Fields: map[string]string{"bizName": ""}},
+	} {
+		_, err := NewService(WithDialect(dialect))
+		assert.NotNil(t, err, dialect.Name)
+	}
+}
+
+func TestDialect_ShortArklet(t *testing.T) {
+	ctx := context.Background()
+	// the arklet fork speaking DialectShort
+	arklet := &fakeArklet{handlers: map[string]http.HandlerFunc{
+		"/queryAllBiz": respondBody(`{"code":"SUCCESS","data":[{"name":"biz","version":"0.0.1","state":"ACTIVATED"}]}`),
+		"/installBiz": func(w http.ResponseWriter, r *http.Request) {
+			fields := map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&fields)
+			if _, ok := fields["name"]; !ok {
+				_, _ = w.Write([]byte(`{"code":"FAILED","message":"name is required"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"code":"SUCCESS"}}`))
+		},
+	}}
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	installReq := InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.2", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: target,
+		// skip the version conflict check
+		AllowMultipleVersions: true,
+	}
+
+	client := BuildService(ctx, WithDialect(DialectShort))
+	resp, err := client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	assert.Equal(t, "biz", resp.Data[0].BizName)
+	assert.Equal(t, "0.0.1", resp.Data[0].BizVersion)
+	assert.Equal(t, BizStateActivated, resp.Data[0].BizState)
+
+	assert.Nil(t, client.InstallBiz(ctx, installReq))
+	install, _ := arklet.lastRequest("/installBiz")
+	assert.Equal(t, `{"name":"biz","url":"file:///tmp/biz.jar","version":"0.0.2"}`, install.body)
+
+	// the fork doesn't understand the Ark dialect
+	err = BuildService(ctx).InstallBiz(ctx, installReq)
+	assert.NotNil(t, err)
+	install, _ = arklet.lastRequest("/installBiz")
+	assert.True(t, strings.Contains(install.body, `"bizName":"biz"`))
+}
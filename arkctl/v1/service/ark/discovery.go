@@ -0,0 +1,160 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// discoveryConcurrency is the max ports probed at the same time.
+	discoveryConcurrency = 16
+
+	// discoveryTimeout is the timeout of probing a single port.
+	discoveryTimeout = 300 * time.Millisecond
+)
+
+// DefaultDiscoveryPortRange is the ports scanned for local arklets if not specified.
+var DefaultDiscoveryPortRange = PortRange{From: 1238, To: 1258}
+
+// PortRange is a closed range of ports.
+type PortRange struct {
+	From int
+	To   int
+}
+
+// ParsePortRange parse the port range like 1238-1258, a single port like 1238 is also accepted.
+func ParsePortRange(raw string) (PortRange, error) {
+	from, to, found := strings.Cut(strings.TrimSpace(raw), "-")
+	if !found {
+		to = from
+	}
+	fromPort, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q, expected {from}-{to}", raw)
+	}
+	toPort, err := strconv.Atoi(strings.TrimSpace(to))
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q, expected {from}-{to}", raw)
+	}
+
+	portRange := PortRange{From: fromPort, To: toPort}
+	return portRange, portRange.validate()
+}
+
+func (r PortRange) validate() error {
+	if r.From < 1 || r.To > 65535 || r.From > r.To {
+		return fmt.Errorf("invalid port range %s, ports must be within 1-65535 and from <= to", r)
+	}
+	return nil
+}
+
+func (r PortRange) String() string {
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// DiscoverLocalArklets scan the localhost ports in portRange, and return the arklets responding to the health endpoint.
+// The servers responding with something else than an arklet health response are ignored.
+func DiscoverLocalArklets(ctx context.Context, portRange PortRange) ([]ArkContainerRuntimeInfo, error) {
+	if err := portRange.validate(); err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: discoveryTimeout}
+	ports := make(chan int)
+	lock := sync.Mutex{}
+	arklets := []ArkContainerRuntimeInfo{}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < discoveryConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for port := range ports {
+				if !probeArklet(ctx, client, port) {
+					continue
+				}
+				port := port
+				lock.Lock()
+				arklets = append(arklets, ArkContainerRuntimeInfo{
+					RunType:    ArkContainerRunTypeLocal,
+					Coordinate: "localhost",
+					Port:       &port,
+				})
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for port := portRange.From; port <= portRange.To && ctx.Err() == nil; port++ {
+		ports <- port
+	}
+	close(ports)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(arklets, func(i, j int) bool {
+		return arklets[i].GetPort() < arklets[j].GetPort()
+	})
+	return arklets, nil
+}
+
+// probeArklet return true if an arklet is serving at the port.
+func probeArklet(ctx context.Context, client *http.Client, port int) bool {
+	url := DefaultEndpointResolver{}.Resolve("127.0.0.1", port, EndpointHealth)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader("{}"))
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return err == nil && isArkletHealthResponse(body)
+}
+
+// isArkletHealthResponse filter the false positives of other http servers by the shape of the health response.
+func isArkletHealthResponse(body []byte) bool {
+	resp := &struct {
+		Code ResponseCode `json:"code"`
+		Data struct {
+			HealthData map[string]json.RawMessage `json:"healthData"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(unwrapEnvelope(EnvelopeAuto, body), resp); err != nil {
+		return false
+	}
+	return resp.Code.IsKnown() && resp.Data.HealthData != nil
+}
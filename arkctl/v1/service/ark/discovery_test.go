@@ -0,0 +1,90 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePortRange(t *testing.T) {
+	portRange, err := ParsePortRange("1238-1258")
+	assert.Nil(t, err)
+	assert.Equal(t, PortRange{From: 1238, To: 1258}, portRange)
+
+	portRange, err = ParsePortRange("1238")
+	assert.Nil(t, err)
+	assert.Equal(t, PortRange{From: 1238, To: 1238}, portRange)
+
+	for _, raw := range []string{"", "a-b", "1258-1238", "0-10", "1-70000"} {
+		_, err = ParsePortRange(raw)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestDiscoverLocalArklets(t *testing.T) {
+	arklet := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"healthData":{"masterBizInfo":{"bizName":"base"}}}}`))
+	}
+	notArklet := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"UP"}`))
+	}
+	notFound := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}
+
+	ports := []int{}
+	expected := []int{}
+	for i, handler := range []http.HandlerFunc{arklet, notArklet, arklet, notFound} {
+		port, cancel := mockHttpServer("/", handler)
+		defer cancel()
+		ports = append(ports, port)
+		if i%2 == 0 {
+			expected = append(expected, port)
+		}
+	}
+
+	portRange := PortRange{From: ports[0], To: ports[0]}
+	for _, port := range ports {
+		portRange.From = min(portRange.From, port)
+		portRange.To = max(portRange.To, port)
+	}
+
+	arklets, err := DiscoverLocalArklets(context.Background(), portRange)
+	assert.Nil(t, err)
+
+	discovered := []int{}
+	for _, arklet := range arklets {
+		assert.Equal(t, ArkContainerRunTypeLocal, arklet.RunType)
+		discovered = append(discovered, arklet.GetPort())
+	}
+	assert.ElementsMatch(t, expected, discovered)
+	assert.IsIncreasing(t, discovered)
+}
+
+func TestDiscoverLocalArklets_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := DiscoverLocalArklets(ctx, DefaultDiscoveryPortRange)
+	assert.Equal(t, context.Canceled, err)
+}
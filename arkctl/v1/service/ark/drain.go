@@ -0,0 +1,144 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/pollutil"
+)
+
+// errDrainNotSupported is returned when the arklet doesn't expose the drain endpoint.
+var errDrainNotSupported = errors.New("drain endpoint is not supported by arklet")
+
+// postDrainBiz ask the arklet to stop routing traffic to the biz, and return the in flight requests if reported.
+// It's safe to call repeatedly to poll the in flight requests.
+func (h *service) postDrainBiz(ctx context.Context, req UnInstallBizRequest) (*int, error) {
+	endpointUrl, err := h.localEndpointUrl(req.TargetContainer, EndpointDrainBiz)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.client.R().
+		SetContext(ctx).
+		SetBody(req.BizModel).
+		Post(endpointUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, errDrainNotSupported
+	}
+
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("drain biz http failed with code %d", resp.StatusCode())
+	}
+
+	drainResponse := &DrainBizResponse{}
+	if err := json.Unmarshal(resp.Body(), drainResponse); err != nil {
+		return nil, err
+	}
+
+	if !drainResponse.Code.IsSuccess() {
+		return nil, h.newResponseError("drain biz", drainResponse.Code, drainResponse.Message, resp.Body())
+	}
+	return drainResponse.Data.InFlightRequests, nil
+}
+
+// drainBiz drain the traffic of the biz and wait for the in flight requests, the drain phase is appended to the result.
+// The drain phase has its own timeout, the uninstall goes on when it times out or the arklet doesn't support draining.
+func (h *service) drainBiz(ctx context.Context, req UnInstallBizRequest, result *UnInstallResult) error {
+	logger := contextutil.GetLogger(ctx)
+	phase := PhaseResult{Name: PhaseDrain}
+	start := time.Now()
+	defer func() {
+		phase.Duration = time.Since(start)
+		result.Phases = append(result.Phases, phase)
+	}()
+
+	if req.TargetContainer.RunType != ArkContainerRunTypeLocal {
+		phase.Skipped = true
+		phase.Message = fmt.Sprintf("drain is not supported for run type: %s", req.TargetContainer.RunType)
+		logger.Warn(phase.Message)
+		return nil
+	}
+
+	options := h.options.Drain
+	drainCtx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	inFlight, err := h.postDrainBiz(drainCtx, req)
+	if errors.Is(err, errDrainNotSupported) {
+		phase.Skipped = true
+		phase.Message = err.Error()
+		logger.Warn("skip draining biz: ", err)
+		return nil
+	}
+	if err != nil {
+		if drainCtx.Err() != nil && ctx.Err() == nil {
+			phase.Message = "drain timed out"
+			logger.Warn(phase.Message)
+			return nil
+		}
+		phase.Err = err
+		return err
+	}
+
+	// arklet doesn't report the in flight requests, just wait a while
+	if inFlight == nil {
+		select {
+		case <-drainCtx.Done():
+			phase.Message = "drain timed out"
+		case <-time.After(options.Wait):
+			phase.Message = fmt.Sprintf("waited %v", options.Wait)
+		}
+		return ctx.Err()
+	}
+
+	if *inFlight <= 0 {
+		phase.Message = "drained"
+		return nil
+	}
+	pollConfig := pollutil.Config{Interval: options.PollInterval, Delay: options.PollInterval}
+	err = pollutil.Poll(drainCtx, pollConfig, func(ctx context.Context) (bool, error) {
+		polled, err := h.postDrainBiz(ctx, req)
+		if err != nil {
+			return false, pollutil.Fatal(err)
+		}
+		if polled == nil {
+			return true, nil
+		}
+		inFlight = polled
+		return *inFlight <= 0, nil
+	})
+	if err != nil {
+		if drainCtx.Err() != nil {
+			phase.Message = fmt.Sprintf("drain timed out with %d requests in flight", *inFlight)
+			logger.Warn(phase.Message)
+			return ctx.Err()
+		}
+		phase.Err = err
+		return err
+	}
+
+	phase.Message = "drained"
+	return nil
+}
@@ -0,0 +1,151 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnInstallBiz_DrainPollsInFlightRequests(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithDrain(DrainOptions{
+		Timeout:      time.Second,
+		PollInterval: time.Millisecond,
+	}))
+
+	var calls []string
+	inFlight := 2
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		if r.URL.Path == "/drainBiz" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": "SUCCESS",
+				"data": map[string]interface{}{"inFlightRequests": inFlight},
+			})
+			inFlight--
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	result, err := client.UnInstallBizWithResult(ctx, UnInstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		DrainFirst: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/health", "/drainBiz", "/drainBiz", "/drainBiz", "/uninstallBiz"}, calls)
+	assert.Equal(t, 2, len(result.Phases))
+	assert.Equal(t, PhaseDrain, result.Phases[0].Name)
+	assert.Equal(t, "drained", result.Phases[0].Message)
+	assert.Equal(t, PhaseUninstall, result.Phases[1].Name)
+	assert.Nil(t, result.Phases[1].Err)
+}
+
+func TestUnInstallBiz_DrainNotSupported(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	port, cancel := mockHttpServer("/uninstallBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	result, err := client.UnInstallBizWithResult(ctx, UnInstallBizRequest{
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		DrainFirst: true,
+	})
+	assert.Nil(t, err)
+	assert.True(t, result.Phases[0].Skipped)
+	assert.Equal(t, PhaseUninstall, result.Phases[1].Name)
+}
+
+func TestUnInstallBiz_DrainTimeoutIsIndependent(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithDrain(DrainOptions{
+		Wait:    time.Minute,
+		Timeout: 20 * time.Millisecond,
+	}))
+
+	uninstalled := false
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/uninstallBiz" {
+			uninstalled = true
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	start := time.Now()
+	result, err := client.UnInstallBizWithResult(ctx, UnInstallBizRequest{
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		DrainFirst: true,
+	})
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, "drain timed out", result.Phases[0].Message)
+	assert.True(t, uninstalled)
+}
+
+func TestWithDrain_Defaults(t *testing.T) {
+	options := defaultClientOptions()
+	WithDrain(DrainOptions{Wait: 20 * time.Millisecond})(&options)
+	assert.Equal(t, DrainOptions{Wait: 20 * time.Millisecond, Timeout: 30 * time.Second, PollInterval: time.Second}, options.Drain)
+
+	// the drain isn't timed out at once without the timeout
+	ctx := context.Background()
+	client := BuildService(ctx, WithDrain(DrainOptions{Wait: 20 * time.Millisecond}))
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	result, err := client.UnInstallBizWithResult(ctx, UnInstallBizRequest{
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		DrainFirst: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "waited 20ms", result.Phases[0].Message)
+}
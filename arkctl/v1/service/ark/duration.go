@@ -0,0 +1,85 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Milliseconds is a time.Duration reported by arklet in milliseconds, like the elapsed time of an operation.
+// It's decoded from either a json number or a string, as some arklets quote the numbers.
+type Milliseconds time.Duration
+
+// Duration return the milliseconds as time.Duration.
+func (m Milliseconds) Duration() time.Duration {
+	return time.Duration(m)
+}
+
+func (m Milliseconds) String() string {
+	return time.Duration(m).String()
+}
+
+// MarshalJSON encode the duration as the milliseconds number like arklet.
+func (m Milliseconds) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(time.Duration(m).Milliseconds(), 10)), nil
+}
+
+// UnmarshalJSON decode the milliseconds from a number like 1500, or a string like "1500", "1500ms" or "1.5s".
+// Null and the empty string are decoded as 0.
+func (m *Milliseconds) UnmarshalJSON(raw []byte) error {
+	raw = bytes.TrimSpace(raw)
+	if bytes.Equal(raw, []byte("null")) {
+		*m = 0
+		return nil
+	}
+
+	text := string(raw)
+	if len(raw) > 0 && raw[0] == '"' {
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return err
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			*m = 0
+			return nil
+		}
+		// the unit is given, like 1500ms or 1.5s
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			duration, err := time.ParseDuration(text)
+			if err != nil {
+				return fmt.Errorf("invalid milliseconds %s: %w", raw, err)
+			}
+			*m = Milliseconds(duration)
+			return nil
+		}
+	}
+
+	millis, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid milliseconds %s: %w", raw, err)
+	}
+	nanos := millis * float64(time.Millisecond)
+	if math.IsNaN(nanos) || nanos >= math.MaxInt64 || nanos < math.MinInt64 {
+		return fmt.Errorf("invalid milliseconds %s: out of range", raw)
+	}
+	*m = Milliseconds(nanos)
+	return nil
+}
@@ -0,0 +1,73 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMilliseconds(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected time.Duration
+		err      bool
+	}{
+		{raw: `1500`, expected: 1500 * time.Millisecond},
+		{raw: `0`, expected: 0},
+		{raw: `1.5`, expected: 1500 * time.Microsecond},
+		{raw: `"1500"`, expected: 1500 * time.Millisecond},
+		{raw: `" 20 "`, expected: 20 * time.Millisecond},
+		{raw: `"1500ms"`, expected: 1500 * time.Millisecond},
+		{raw: `"1.5s"`, expected: 1500 * time.Millisecond},
+		{raw: `""`, expected: 0},
+		{raw: `null`, expected: 0},
+		{raw: `"soon"`, err: true},
+		{raw: `true`, err: true},
+		{raw: `12345678901234567890`, err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			var m Milliseconds
+			err := json.Unmarshal([]byte(test.raw), &m)
+			if test.err {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, m.Duration())
+		})
+	}
+}
+
+func TestMilliseconds_ResponseData(t *testing.T) {
+	for _, raw := range []string{
+		`{"code":"SUCCESS","data":{"code":"SUCCESS","elapsedSpace":1200}}`,
+		`{"code":"SUCCESS","data":{"code":"SUCCESS","elapsedSpace":"1200"}}`,
+	} {
+		resp := &InstallBizResponse{}
+		assert.Nil(t, json.Unmarshal([]byte(raw), resp))
+		assert.Equal(t, 1200*time.Millisecond, resp.Data.ElapsedSpace.Duration())
+		assert.Equal(t, "{SUCCESS  1200 []}", resp.Data.String())
+	}
+
+	// encoded back as the milliseconds number
+	encoded, err := json.Marshal(ArkResponseData{ElapsedSpace: Milliseconds(1200 * time.Millisecond)})
+	assert.Nil(t, err)
+	assert.Contains(t, string(encoded), `"elapsedSpace":1200`)
+}
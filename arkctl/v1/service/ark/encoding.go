@@ -0,0 +1,193 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// RequestEncoding controls how the request bodies sent to arklet are encoded.
+type RequestEncoding string
+
+const (
+	// EncodingJSON encodes the bodies as application/json, the default.
+	EncodingJSON RequestEncoding = "json"
+
+	// EncodingForm encodes the bodies as application/x-www-form-urlencoded.
+	EncodingForm RequestEncoding = "form"
+
+	// EncodingAuto encodes the bodies as form once DetectCapabilities found the arklet only accepts it, otherwise json.
+	// The arklets in pods can't be sniffed and always get json.
+	EncodingAuto RequestEncoding = "auto"
+)
+
+const formContentType = "application/x-www-form-urlencoded"
+
+// Encoder encodes the request bodies sent to arklet.
+type Encoder interface {
+	// ContentType is the Content-Type of the encoded bodies.
+	ContentType() string
+
+	// Encode return the encoded body.
+	Encode(body interface{}) ([]byte, error)
+}
+
+// JSONEncoder encodes the bodies as json.
+type JSONEncoder struct{}
+
+func (JSONEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (JSONEncoder) Encode(body interface{}) ([]byte, error) {
+	return json.Marshal(body)
+}
+
+// FormEncoder encodes the bodies as form fields by their json names.
+// The arrays of scalars are repeated fields, and the other nested values are json texts, e.g. envs={"k":"v"}.
+type FormEncoder struct{}
+
+func (FormEncoder) ContentType() string {
+	return formContentType
+}
+
+func (FormEncoder) Encode(body interface{}) ([]byte, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("form encoding requires an object body: %w", err)
+	}
+
+	values := url.Values{}
+	for key, field := range fields {
+		var items []json.RawMessage
+		if err := json.Unmarshal(field, &items); err == nil && allScalars(items) {
+			for _, item := range items {
+				values.Add(key, formValue(item))
+			}
+			continue
+		}
+		if string(field) != "null" {
+			values.Set(key, formValue(field))
+		}
+	}
+	return []byte(values.Encode()), nil
+}
+
+// allScalars return true if none of the json values is an object or array.
+func allScalars(items []json.RawMessage) bool {
+	for _, item := range items {
+		if trimmed := strings.TrimSpace(string(item)); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			return false
+		}
+	}
+	return true
+}
+
+// formValue return the string itself for json strings, or the json text for the others.
+func formValue(field json.RawMessage) string {
+	value := ""
+	if err := json.Unmarshal(field, &value); err == nil {
+		return value
+	}
+	return string(field)
+}
+
+// acceptsOnlyForm return true if the Accept advertised by arklet includes form but not json.
+func acceptsOnlyForm(header http.Header) bool {
+	form, jsonAccepted := false, false
+	for _, value := range header.Values("Accept") {
+		for _, mediaType := range strings.Split(value, ",") {
+			parsed, _, err := mime.ParseMediaType(strings.TrimSpace(mediaType))
+			if err != nil {
+				continue
+			}
+			form = form || parsed == formContentType
+			jsonAccepted = jsonAccepted || parsed == "application/json"
+		}
+	}
+	return form && !jsonAccepted
+}
+
+// encoderOf return the encoder of the arklet identified by key, see arkletKey.
+func (h *service) encoderOf(key string) Encoder {
+	switch h.options.RequestEncoding {
+	case EncodingForm:
+		return FormEncoder{}
+	case EncodingAuto:
+		if _, ok := h.formArklets.Load(key); ok {
+			return FormEncoder{}
+		}
+	}
+	return JSONEncoder{}
+}
+
+// arkletKey identify the arklet of target by the host of its urls for local targets, so that it's known by the requests.
+func (h *service) arkletKey(target ArkContainerRuntimeInfo) string {
+	if target.RunType != ArkContainerRunTypeLocal {
+		return containerCacheKey(target)
+	}
+	parsed, err := url.Parse(h.endpointUrl(h.localHost(target), target.GetPort(), EndpointHelp))
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// encodeRequestBody encode the structured request bodies by the encoder of the arklet, the raw bodies are sent as is.
+// The json bodies are left to resty unless their fields are renamed by the dialect.
+func (h *service) encodeRequestBody(_ *resty.Client, req *resty.Request) error {
+	switch req.Body.(type) {
+	case nil, io.Reader, []byte, string:
+		return nil
+	}
+
+	parsed, err := url.Parse(req.URL)
+	if err != nil {
+		return err
+	}
+	encoder := h.encoderOf(parsed.Host)
+	// the Content-Type is set explicitly rather than inferred by resty, as strict gateways reject the others
+	req.SetHeader("Content-Type", h.contentTypeOf(encoder))
+	// the overridden json bodies are encoded here, as resty only encodes the bodies of the content types it knows
+	if _, ok := encoder.(JSONEncoder); ok && h.options.Dialect.isArk() && h.options.JSONContentType == "" {
+		return nil
+	}
+	body, err := h.encodeBody(encoder, req.Body)
+	if err != nil {
+		return err
+	}
+	req.SetBody(body)
+	return nil
+}
+
+// contentTypeOf return the Content-Type of the bodies encoded by encoder, with JSONContentType applied.
+func (h *service) contentTypeOf(encoder Encoder) string {
+	if _, ok := encoder.(JSONEncoder); ok && h.options.JSONContentType != "" {
+		return h.options.JSONContentType
+	}
+	return encoder.ContentType()
+}
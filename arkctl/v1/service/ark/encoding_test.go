@@ -0,0 +1,187 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormEncoder(t *testing.T) {
+	encoded, err := FormEncoder{}.Encode(map[string]interface{}{
+		"bizName":    "biz",
+		"bizVersion": "0.0.1",
+		"envs":       map[string]string{"k": "v"},
+		"args":       []string{"a", "b"},
+		"priority":   1,
+		"mainClass":  nil,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "args=a&args=b&bizName=biz&bizVersion=0.0.1&envs=%7B%22k%22%3A%22v%22%7D&priority=1", string(encoded))
+
+	_, err = FormEncoder{}.Encode([]string{"a"})
+	assert.NotNil(t, err)
+}
+
+// encodingArklet return the fake arklet whose help advertises the form bodies only if formOnly is true.
+func encodingArklet(formOnly bool) *fakeArklet {
+	return &fakeArklet{handlers: map[string]http.HandlerFunc{
+		"/help": func(w http.ResponseWriter, r *http.Request) {
+			if formOnly {
+				w.Header().Set("Accept", "application/x-www-form-urlencoded")
+			}
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[{"id":"installBiz"},{"id":"uninstallBiz"},{"id":"queryAllBiz"}]}`))
+		},
+	}}
+}
+
+// serveAsText serve the arklet responding the json bodies as text/plain.
+func serveAsText(arklet *fakeArklet) (int, func()) {
+	return mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+		arklet.serve(w, r)
+	})
+}
+
+func TestRequestEncoding_Form(t *testing.T) {
+	ctx := context.Background()
+	arklet := encodingArklet(false)
+	port, cancel := serveAsText(arklet)
+	defer cancel()
+
+	client := BuildService(ctx, WithRequestEncoding(EncodingForm))
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	bizModel := BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz.jar"}
+
+	assert.Nil(t, client.InstallBiz(ctx, InstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+	assert.Nil(t, client.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+	_, err := client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+
+	paths := []string{}
+	for _, request := range arklet.requests {
+		paths = append(paths, request.path)
+		assert.Equal(t, "application/x-www-form-urlencoded", request.contentType, request.path)
+		_, err := url.ParseQuery(request.body)
+		assert.Nil(t, err)
+	}
+	assert.Contains(t, paths, "/installBiz")
+	assert.Contains(t, paths, "/uninstallBiz")
+	assert.Contains(t, paths, "/queryAllBiz")
+
+	for _, request := range arklet.requests {
+		if request.path == "/installBiz" {
+			form, _ := url.ParseQuery(request.body)
+			assert.Equal(t, "biz", form.Get("bizName"))
+			assert.Equal(t, "0.0.1", form.Get("bizVersion"))
+			assert.Equal(t, "http://serverless.alipay.com/biz.jar", form.Get("bizUrl"))
+		}
+	}
+}
+
+func TestRequestEncoding_Auto(t *testing.T) {
+	ctx := context.Background()
+	arklet := encodingArklet(true)
+	port, cancel := serveAsText(arklet)
+	defer cancel()
+
+	client := BuildService(ctx, WithRequestEncoding(EncodingAuto))
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	req := UnInstallBizRequest{BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"}, TargetContainer: target}
+
+	// json until the arklet is sniffed
+	assert.Nil(t, client.UnInstallBiz(ctx, req))
+	last := arklet.requests[len(arklet.requests)-1]
+	assert.Equal(t, "/uninstallBiz", last.path)
+	assert.Equal(t, "application/json", last.contentType)
+	assert.JSONEq(t, `{"bizName":"biz","bizVersion":"0.0.1"}`, last.body)
+
+	capabilities, err := client.DetectCapabilities(ctx, target)
+	assert.Nil(t, err)
+	assert.True(t, capabilities.FormBody)
+
+	assert.Nil(t, client.UnInstallBiz(ctx, req))
+	last = arklet.requests[len(arklet.requests)-1]
+	assert.Equal(t, "/uninstallBiz", last.path)
+	assert.Equal(t, "application/x-www-form-urlencoded", last.contentType)
+	assert.Equal(t, "bizName=biz&bizVersion=0.0.1", last.body)
+}
+
+func TestRequestEncoding_Pod(t *testing.T) {
+	ctx := context.Background()
+
+	var command []string
+	client := BuildService(ctx, WithRequestEncoding(EncodingForm), WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		command = args
+		return []string{`{"code":"SUCCESS"}`}, nil
+	}))
+	err := client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, command, "Content-Type: application/x-www-form-urlencoded")
+	assert.Contains(t, command, "bizName=biz&bizVersion=0.0.1")
+}
+
+func TestJSONContentType(t *testing.T) {
+	ctx := context.Background()
+	contentTypes := map[string]string{}
+	bodies := map[string]string{}
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		contentTypes[r.URL.Path] = r.Header.Get("Content-Type")
+		bodies[r.URL.Path] = string(body)
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	bizModel := BizModel{BizName: "biz", BizVersion: "0.0.1"}
+
+	for _, test := range []struct {
+		opts        []Option
+		contentType string
+	}{
+		{contentType: "application/json"},
+		{opts: []Option{WithJSONContentType("application/json;charset=UTF-8")}, contentType: "application/json;charset=UTF-8"},
+		// the body is still json even if the gateway insists on an unusual type
+		{opts: []Option{WithJSONContentType("text/plain")}, contentType: "text/plain"},
+	} {
+		client := BuildService(ctx, test.opts...)
+		assert.Nil(t, client.InstallBiz(ctx, InstallBizRequest{BizModel: bizModel, TargetContainer: target, AllowMultipleVersions: true}))
+		assert.Nil(t, client.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+		for _, path := range []string{"/installBiz", "/uninstallBiz"} {
+			assert.Equal(t, test.contentType, contentTypes[path], path)
+			assert.JSONEq(t, `{"bizName":"biz","bizVersion":"0.0.1"}`, bodies[path], path)
+		}
+	}
+
+	var command []string
+	client := BuildService(ctx, WithJSONContentType("application/json;charset=UTF-8"), WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		command = args
+		return []string{`{"code":"SUCCESS"}`}, nil
+	}))
+	err := client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel:        bizModel,
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, command, "Content-Type: application/json;charset=UTF-8")
+}
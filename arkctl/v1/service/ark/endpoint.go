@@ -0,0 +1,214 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Endpoint is an api of arklet.
+type Endpoint string
+
+const (
+	EndpointInstallBiz    Endpoint = "installBiz"
+	EndpointUnInstallBiz  Endpoint = "uninstallBiz"
+	EndpointUploadBiz     Endpoint = "uploadBiz"
+	EndpointQueryAllBiz   Endpoint = "queryAllBiz"
+	EndpointQueryBiz      Endpoint = "queryBiz"
+	EndpointDrainBiz      Endpoint = "drainBiz"
+	EndpointHealth        Endpoint = "health"
+	EndpointHelp          Endpoint = "help"
+	EndpointQueryBizOps   Endpoint = "queryBizOps"
+	EndpointShutdown      Endpoint = "shutdown"
+	EndpointSwitchBiz     Endpoint = "switchBiz"
+	EndpointQueryPlugins  Endpoint = "queryAllPlugin"
+	EndpointActivateBiz   Endpoint = "activateBiz"
+	EndpointDeactivateBiz Endpoint = "deactivateBiz"
+)
+
+// Operation is an arklet operation whose endpoint could be overridden by EndpointOverrides.
+type Operation string
+
+const (
+	OperationInstall   Operation = "install"
+	OperationUninstall Operation = "uninstall"
+	OperationQueryAll  Operation = "queryAll"
+	OperationSwitch    Operation = "switch"
+	OperationHealth    Operation = "health"
+)
+
+// operationEndpoints are the endpoints called by the operations.
+var operationEndpoints = map[Operation]Endpoint{
+	OperationInstall:   EndpointInstallBiz,
+	OperationUninstall: EndpointUnInstallBiz,
+	OperationQueryAll:  EndpointQueryAllBiz,
+	OperationSwitch:    EndpointSwitchBiz,
+	OperationHealth:    EndpointHealth,
+}
+
+// overridableMethods are the methods an operation could be remapped to, GET isn't allowed as the requests carry a body.
+var overridableMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// EndpointOverride remaps an operation for gateways exposing arklet under their own apis, e.g. POST /api/v1/biz.
+type EndpointOverride struct {
+	// Path replaces the endpoint of the operation, it's still prefixed by BasePath.
+	Path string
+
+	// Method is the http method of the operation, POST if it's empty.
+	Method string
+}
+
+// endpointOverridesOf merge InstallPath and UninstallPath into a copy of the EndpointOverrides,
+// the EndpointOverrides of the same operations win.
+func endpointOverridesOf(options ClientOptions) map[Operation]EndpointOverride {
+	if options.InstallPath == "" && options.UninstallPath == "" {
+		return options.EndpointOverrides
+	}
+
+	overrides := make(map[Operation]EndpointOverride, len(options.EndpointOverrides)+2)
+	for operation, override := range options.EndpointOverrides {
+		overrides[operation] = override
+	}
+	for operation, path := range map[Operation]string{
+		OperationInstall:   options.InstallPath,
+		OperationUninstall: options.UninstallPath,
+	} {
+		if _, ok := overrides[operation]; !ok && path != "" {
+			overrides[operation] = EndpointOverride{Path: path, Method: http.MethodPost}
+		}
+	}
+	return overrides
+}
+
+// resolveEndpointOverrides validate the overrides and key them by the endpoints they replace.
+func resolveEndpointOverrides(overrides map[Operation]EndpointOverride) (map[Endpoint]EndpointOverride, error) {
+	resolved := make(map[Endpoint]EndpointOverride, len(overrides))
+	for operation, override := range overrides {
+		endpoint, ok := operationEndpoints[operation]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidEndpointOverride, operation)
+		}
+		override.Path = strings.TrimSpace(override.Path)
+		if strings.Trim(override.Path, "/") == "" {
+			return nil, fmt.Errorf("%w: empty path of %s", ErrInvalidEndpointOverride, operation)
+		}
+		override.Method = strings.ToUpper(strings.TrimSpace(override.Method))
+		if override.Method == "" {
+			override.Method = http.MethodPost
+		}
+		supported := false
+		for _, method := range overridableMethods {
+			supported = supported || method == override.Method
+		}
+		if !supported {
+			return nil, fmt.Errorf("%w: unsupported method %s of %s", ErrInvalidEndpointOverride, override.Method, operation)
+		}
+		resolved[endpoint] = override
+	}
+	return resolved, nil
+}
+
+// EndpointResolver build the url of an arklet endpoint,
+// implement it to adapt to gateways exposing arklet under non-standard paths or schemes.
+type EndpointResolver interface {
+	// Resolve return the url of the endpoint of the arklet served at host:port.
+	Resolve(host string, port int, endpoint Endpoint) string
+}
+
+// EndpointResolverFunc is an adapter to use a function as an EndpointResolver.
+type EndpointResolverFunc func(host string, port int, endpoint Endpoint) string
+
+func (f EndpointResolverFunc) Resolve(host string, port int, endpoint Endpoint) string {
+	return f(host, port, endpoint)
+}
+
+// DefaultEndpointResolver resolve the endpoint to http://{host}:{port}{basePath}/{endpoint},
+// the IPv6 host is bracketed, e.g. http://[::1]:1238/installBiz.
+type DefaultEndpointResolver struct {
+	// BasePath is prefixed onto every endpoint, e.g. /arklet.
+	BasePath string
+}
+
+func (r DefaultEndpointResolver) Resolve(host string, port int, endpoint Endpoint) string {
+	hostPort := net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), strconv.Itoa(port))
+	return fmt.Sprintf("http://%s%s/%s", hostPort, normalizeBasePath(r.BasePath), strings.TrimLeft(string(endpoint), "/"))
+}
+
+// normalizeBasePath make the base path start with a single slash and end without slash, empty for the root.
+func normalizeBasePath(basePath string) string {
+	segments := []string{}
+	for _, segment := range strings.Split(strings.TrimSpace(basePath), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// endpointUrl return the url of the arklet endpoint served at host:port, the overridden path is resolved instead if any.
+func (h *service) endpointUrl(host string, port int, endpoint Endpoint) string {
+	if override, ok := h.endpointOverrides[endpoint]; ok {
+		endpoint = Endpoint(override.Path)
+	}
+	if h.options.EndpointResolver != nil {
+		return h.options.EndpointResolver.Resolve(host, port, endpoint)
+	}
+	return DefaultEndpointResolver{BasePath: h.options.BasePath}.Resolve(host, port, endpoint)
+}
+
+// localEndpointUrl return the url of the endpoint of the local arklet of target,
+// or ErrMissingPort if it has neither a port nor a unix socket, rather than a url of the default port or :0.
+func (h *service) localEndpointUrl(target ArkContainerRuntimeInfo, endpoint Endpoint) (string, error) {
+	port, err := localPort(target)
+	if err != nil {
+		return "", err
+	}
+	return h.endpointUrl(h.localHost(target), port, endpoint), nil
+}
+
+// localPort return the port of the local arklet of target, the default port is only used along with a unix socket.
+func localPort(target ArkContainerRuntimeInfo) (int, error) {
+	port := 0
+	if target.Port != nil {
+		port = *target.Port
+	}
+	if err := requirePort(port, target.SocketPath); err != nil {
+		return 0, err
+	}
+	return target.GetPort(), nil
+}
+
+// requirePort return ErrMissingPort if port isn't positive and the arklet isn't served on a unix socket.
+func requirePort(port int, socketPath string) error {
+	if port <= 0 && socketPath == "" {
+		return fmt.Errorf("%w: got %d, set the Port of the target to the arklet port like 1238, or its SocketPath", ErrMissingPort, port)
+	}
+	return nil
+}
+
+// endpointMethod return the http method of the arklet endpoint, POST unless it's overridden.
+func (h *service) endpointMethod(endpoint Endpoint) string {
+	if override, ok := h.endpointOverrides[endpoint]; ok {
+		return override.Method
+	}
+	return http.MethodPost
+}
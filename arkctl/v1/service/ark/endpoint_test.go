@@ -0,0 +1,238 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	for basePath, expected := range map[string]string{
+		"":                     "",
+		"/":                    "",
+		"arklet":               "/arklet",
+		"/arklet/":             "/arklet",
+		" //gateway//arklet/ ": "/gateway/arklet",
+	} {
+		assert.Equal(t, expected, normalizeBasePath(basePath), basePath)
+	}
+}
+
+func TestEndpointResolver(t *testing.T) {
+	ctx := context.Background()
+
+	var paths []string
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "SUCCESS"})
+	})
+	defer cancel()
+
+	client := BuildService(ctx, WithEndpointResolver(EndpointResolverFunc(func(host string, port int, endpoint Endpoint) string {
+		if endpoint == EndpointInstallBiz {
+			return fmt.Sprintf("http://%s:%d/v2/biz/install", host, port)
+		}
+		return DefaultEndpointResolver{}.Resolve(host, port, endpoint)
+	})))
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1",
+			BizUrl:     "http://serverless.alipay.com/biz.jar",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		AllowMultipleVersions: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/health", "/v2/biz/install"}, paths)
+}
+
+func TestDefaultEndpointResolver(t *testing.T) {
+	assert.Equal(t, "http://127.0.0.1:1238/installBiz", DefaultEndpointResolver{}.Resolve("127.0.0.1", 1238, EndpointInstallBiz))
+	assert.Equal(t, "http://127.0.0.1:1238/arklet/health", DefaultEndpointResolver{BasePath: "/arklet/"}.Resolve("127.0.0.1", 1238, EndpointHealth))
+	assert.Equal(t, "http://[::1]:1238/installBiz", DefaultEndpointResolver{}.Resolve("::1", 1238, EndpointInstallBiz))
+	assert.Equal(t, "http://[::1]:1238/installBiz", DefaultEndpointResolver{}.Resolve("[::1]", 1238, EndpointInstallBiz))
+}
+
+func TestInstallBiz_IPv6(t *testing.T) {
+	var calls []string
+	host, port, cancel, err := mockHttpServerOn("::1", "/", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	if err != nil {
+		t.Skipf("IPv6 loopback is not available: %v", err)
+	}
+	defer cancel()
+	assert.Equal(t, "::1", host)
+
+	for _, coordinate := range []string{host, "[" + host + "]"} {
+		calls = nil
+		ctx := context.Background()
+		err = BuildService(ctx).InstallBiz(ctx, InstallBizRequest{
+			BizModel: BizModel{
+				BizName:    "biz",
+				BizVersion: "0.0.1",
+				BizUrl:     "http://serverless.alipay.com/biz.jar",
+			},
+			TargetContainer: ArkContainerRuntimeInfo{
+				RunType:    ArkContainerRunTypeLocal,
+				Coordinate: coordinate,
+				Port:       &port,
+			},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"/health", "/queryAllBiz", "/installBiz"}, calls)
+	}
+}
+
+func TestEndpointOverrides(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+	var installed BizModel
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/gateway/api/v1/biz":
+			_ = json.NewDecoder(r.Body).Decode(&installed)
+			_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+		case "/gateway/api/v1/biz/list":
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[]}`))
+		default:
+			_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+		}
+	})
+	defer cancel()
+
+	client, err := NewService(WithBasePath("/gateway"), WithEndpointOverrides(map[Operation]EndpointOverride{
+		OperationInstall:  {Path: "/api/v1/biz", Method: "put"},
+		OperationQueryAll: {Path: "api/v1/biz/list"},
+	}))
+	assert.Nil(t, err)
+
+	bizModel := BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz.jar"}
+	err = client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        bizModel,
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"POST /gateway/health", "POST /gateway/api/v1/biz/list", "PUT /gateway/api/v1/biz"}, calls)
+	assert.Equal(t, bizModel, installed)
+}
+
+func TestInstallPath(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		// the health checks of the master biz are not the concern
+		if !strings.HasSuffix(r.URL.Path, "/health") {
+			calls = append(calls, r.Method+" "+r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[]}`))
+	})
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	bizModel := BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz.jar"}
+
+	run := func(opts ...Option) []string {
+		calls = nil
+		client, err := NewService(opts...)
+		assert.Nil(t, err)
+		assert.Nil(t, client.InstallBiz(ctx, InstallBizRequest{BizModel: bizModel, TargetContainer: target, AllowMultipleVersions: true}))
+		assert.Nil(t, client.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+		return calls
+	}
+
+	// the defaults
+	assert.Equal(t, []string{"POST /installBiz", "POST /uninstallBiz"}, run())
+
+	// overridden independently, the other endpoints are intact
+	assert.Equal(t, []string{"POST /v1/installBiz", "POST /uninstallBiz"},
+		run(WithInstallPath("/v1/installBiz")))
+	assert.Equal(t, []string{"POST /installBiz", "POST /v1/uninstallBiz"},
+		run(WithUninstallPath("v1/uninstallBiz")))
+	assert.Equal(t, []string{"POST /gateway/v1/installBiz", "POST /gateway/v1/uninstallBiz"},
+		run(WithBasePath("/gateway"), WithInstallPath("/v1/installBiz"), WithUninstallPath("/v1/uninstallBiz")))
+
+	// the endpoint overrides win
+	assert.Equal(t, []string{"PUT /api/v1/biz", "POST /v1/uninstallBiz"},
+		run(WithInstallPath("/v1/installBiz"), WithUninstallPath("/v1/uninstallBiz"), WithEndpointOverrides(map[Operation]EndpointOverride{
+			OperationInstall: {Path: "/api/v1/biz", Method: http.MethodPut},
+		})))
+}
+
+func TestEndpointOverrides_Invalid(t *testing.T) {
+	ctx := context.Background()
+	for _, overrides := range []map[Operation]EndpointOverride{
+		{OperationInstall: {Path: ""}},
+		{OperationInstall: {Path: " / "}},
+		{OperationUninstall: {Path: "/api/v1/biz", Method: http.MethodGet}},
+		{OperationHealth: {Path: "/ping", Method: "FETCH"}},
+		{Operation("upload"): {Path: "/api/v1/upload"}},
+	} {
+		_, err := NewService(WithEndpointOverrides(overrides))
+		assert.ErrorIs(t, err, ErrInvalidEndpointOverride, "%v", overrides)
+	}
+
+	// the deprecated builder ignores the invalid overrides
+	client := BuildService(ctx, WithEndpointOverrides(map[Operation]EndpointOverride{OperationInstall: {}}))
+	assert.Empty(t, client.(*service).endpointOverrides)
+}
+
+func TestMissingPort(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	zero, negative := 0, -1
+	for _, port := range []*int{nil, &zero, &negative} {
+		target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: port}
+
+		err := client.InstallBiz(ctx, InstallBizRequest{
+			BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz.jar"},
+			TargetContainer: target,
+		})
+		assert.ErrorIs(t, err, ErrMissingPort)
+
+		err = client.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"}, TargetContainer: target})
+		assert.ErrorIs(t, err, ErrMissingPort)
+
+		_, err = client.QueryBiz(ctx, target, "biz", "0.0.1")
+		assert.ErrorIs(t, err, ErrMissingPort)
+
+		_, err = client.SyncBiz(ctx, target, nil, SyncOptions{})
+		assert.ErrorIs(t, err, ErrMissingPort)
+		assert.ErrorContains(t, err, "set the Port of the target")
+	}
+
+	// the zero value addresses no target
+	err := client.InstallBiz(ctx, InstallBizRequest{BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"}})
+	assert.ErrorIs(t, err, ErrNoTarget)
+
+	_, err = client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1"})
+	assert.ErrorIs(t, err, ErrMissingPort)
+}
@@ -0,0 +1,75 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// EnvelopeMode controls how the arklet responses wrapped by gateways are handled.
+type EnvelopeMode string
+
+const (
+	// EnvelopeAuto unwraps the response like {"data": {"code": ...}} which has no code of its own.
+	EnvelopeAuto EnvelopeMode = "auto"
+
+	// EnvelopeNone never unwraps the response.
+	EnvelopeNone EnvelopeMode = "none"
+
+	// EnvelopeAlways unwraps the data of the response whenever it's an object.
+	EnvelopeAlways EnvelopeMode = "always"
+)
+
+// unwrapEnvelope return the arklet response inside the envelope, or the body itself if it's not wrapped.
+// Note a flat arklet response may have data.code as well, so the outer code tells them apart in auto mode.
+func unwrapEnvelope(mode EnvelopeMode, body []byte) []byte {
+	if mode == EnvelopeNone {
+		return body
+	}
+
+	outer := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &outer); err != nil {
+		return body
+	}
+	data := bytes.TrimSpace(outer["data"])
+	if len(data) == 0 || data[0] != '{' {
+		return body
+	}
+	if mode == EnvelopeAlways {
+		return data
+	}
+
+	if _, hasCode := outer["code"]; hasCode {
+		return body
+	}
+	inner := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &inner); err != nil {
+		return body
+	}
+	if _, hasCode := inner["code"]; !hasCode {
+		return body
+	}
+	return data
+}
+
+// unwrapResponseEnvelope replace the body of the response with the unwrapped one in the Ark dialect,
+// used as a resty response middleware.
+func (h *service) unwrapResponseEnvelope(_ *resty.Client, resp *resty.Response) error {
+	resp.SetBody(h.options.Dialect.toArk(unwrapEnvelope(h.options.ResponseEnvelope, resp.Body())))
+	return nil
+}
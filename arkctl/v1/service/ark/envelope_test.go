@@ -0,0 +1,77 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnwrapEnvelope(t *testing.T) {
+	flat := `{"code":"FAILED","data":{"code":"NOT_FOUND_BIZ"},"message":"biz not found"}`
+	enveloped := `{"data":{"code":"SUCCESS","data":[]},"traceId":"abc"}`
+	notArklet := `{"data":{"name":"foo"}}`
+
+	assert.Equal(t, flat, string(unwrapEnvelope(EnvelopeAuto, []byte(flat))))
+	assert.Equal(t, `{"code":"SUCCESS","data":[]}`, string(unwrapEnvelope(EnvelopeAuto, []byte(enveloped))))
+	assert.Equal(t, notArklet, string(unwrapEnvelope(EnvelopeAuto, []byte(notArklet))))
+	assert.Equal(t, "not json", string(unwrapEnvelope(EnvelopeAuto, []byte("not json"))))
+
+	assert.Equal(t, enveloped, string(unwrapEnvelope(EnvelopeNone, []byte(enveloped))))
+	assert.Equal(t, `{"code":"NOT_FOUND_BIZ"}`, string(unwrapEnvelope(EnvelopeAlways, []byte(flat))))
+}
+
+func TestQueryAllBiz_Envelope(t *testing.T) {
+	ctx := context.Background()
+	for _, body := range []string{
+		`{"code":"SUCCESS","data":[{"bizName":"biz","bizVersion":"0.0.1"}]}`,
+		`{"data":{"code":"SUCCESS","data":[{"bizName":"biz","bizVersion":"0.0.1"}]}}`,
+	} {
+		body := body
+		port, cancel := mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body))
+		})
+
+		resp, err := BuildService(ctx).QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+		assert.Nil(t, err, body)
+		assert.Equal(t, ResponseCodeSuccess, resp.Code, body)
+		assert.Equal(t, "biz", resp.Data[0].BizName, body)
+		cancel()
+	}
+}
+
+func TestInstallBiz_EnvelopedFailure(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"code":"FAILED","message":"install biz failed","data":{"code":"FAILED"}}}`))
+	})
+	defer cancel()
+
+	req := InstallBizRequest{
+		BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	}
+	err := BuildService(ctx).InstallBiz(ctx, req)
+	assert.Equal(t, "install biz failed: install biz failed", err.Error())
+
+	err = BuildService(ctx, WithResponseEnvelope(EnvelopeNone)).InstallBiz(ctx, req)
+	assert.Equal(t, "install biz failed: ", err.Error())
+}
@@ -0,0 +1,401 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotArkBizJar is returned when the jar file is not an ark biz bundle.
+	ErrNotArkBizJar = errors.New("not an ark biz jar")
+
+	// ErrBizNotFound is returned when the biz module doesn't exist in the ark container.
+	ErrBizNotFound = errors.New("biz not found")
+
+	// ErrClientClosed is returned when the Service is used after Close.
+	ErrClientClosed = errors.New("ark client is closed")
+
+	// ErrVersionConflict is returned when another version of the biz is already active.
+	ErrVersionConflict = errors.New("biz version conflict")
+
+	// ErrMasterBizProtected is returned when installing or uninstalling the master biz.
+	ErrMasterBizProtected = errors.New("master biz is protected")
+
+	// ErrConfirmationRequired is returned when a destructive operation isn't confirmed explicitly.
+	ErrConfirmationRequired = errors.New("confirmation required")
+
+	// ErrBizTooLarge is returned when the biz bundle exceeds the size limit.
+	ErrBizTooLarge = errors.New("biz bundle is too large")
+
+	// ErrIncompatibleVersion is returned when an operation requires a newer arklet than the target.
+	ErrIncompatibleVersion = errors.New("incompatible arklet version")
+
+	// ErrInvalidVersion is returned when the biz version is malformed.
+	ErrInvalidVersion = errors.New("invalid biz version")
+
+	// ErrTargetGroupNotFound is returned when the target group isn't registered by WithTargetGroups.
+	ErrTargetGroupNotFound = errors.New("target group not found")
+
+	// ErrInvalidEndpointOverride is returned by NewService when an endpoint override has no path or an unsupported method.
+	ErrInvalidEndpointOverride = errors.New("invalid endpoint override")
+
+	// ErrMissingPort is returned when a local target has neither a port nor a unix socket.
+	ErrMissingPort = errors.New("port of the local ark container is missing")
+
+	// ErrMissingTargetPort is the same error as ErrMissingPort.
+	//
+	// Deprecated: use ErrMissingPort.
+	ErrMissingTargetPort = ErrMissingPort
+
+	// ErrBizUrlUnreachableFromTarget is returned by InstallBiz with ProbeBizUrl when the biz url can't be downloaded
+	// from where the arklet runs, e.g. a host only reachable from the laptop.
+	ErrBizUrlUnreachableFromTarget = errors.New("biz url is unreachable from the target")
+
+	// ErrAmbiguousArkletPort is returned when the arklet port of a pod can't be told from its container ports.
+	ErrAmbiguousArkletPort = errors.New("arklet port of the pod is ambiguous")
+
+	// ErrUnexpectedContentType is returned when the arklet responds something other than json,
+	// e.g. the html login page of an auth proxy in front of it.
+	ErrUnexpectedContentType = errors.New("unexpected content type of the arklet response")
+
+	// ErrInvalidHop is returned by NewService when the SOCKS5 proxy or an ssh jump host is misconfigured.
+	ErrInvalidHop = errors.New("invalid network hop")
+
+	// ErrVersionReusedWithDifferentArtifact is returned when the biz is active with the same version
+	// but another checksum, i.e. the version is reused for a different jar.
+	ErrVersionReusedWithDifferentArtifact = errors.New("biz version is reused with a different artifact")
+
+	// ErrVerifyProbeFailed is returned by InstallBiz with VerifyProbe when the installed biz doesn't pass the probe in time.
+	ErrVerifyProbeFailed = errors.New("biz failed the verify probe")
+
+	// ErrNoTarget is returned when neither the request nor the context set by WithTarget gives the target.
+	ErrNoTarget = errors.New("no target is given")
+)
+
+// maxContentSnippet is the max bytes of the body kept in UnexpectedContentTypeError.
+const maxContentSnippet = 128
+
+// UnexpectedContentTypeError is returned when the arklet responds something other than json.
+type UnexpectedContentTypeError struct {
+	// Operation is the operation, like "install biz".
+	Operation string
+
+	// ContentType is the content type of the response, empty if unknown like the responses read by kubectl exec.
+	ContentType string
+
+	// Snippet is the beginning of the response body.
+	Snippet string
+}
+
+func newUnexpectedContentTypeError(operation, contentType string, body []byte) *UnexpectedContentTypeError {
+	snippet := strings.TrimSpace(string(body))
+	if len(snippet) > maxContentSnippet {
+		snippet = snippet[:maxContentSnippet] + "..."
+	}
+	return &UnexpectedContentTypeError{Operation: operation, ContentType: contentType, Snippet: snippet}
+}
+
+func (e *UnexpectedContentTypeError) Error() string {
+	contentType := e.ContentType
+	if contentType == "" {
+		contentType = "unknown"
+	}
+	return fmt.Sprintf("%s: %s responded %s: %q", ErrUnexpectedContentType, e.Operation, contentType, e.Snippet)
+}
+
+// Is make errors.Is(err, ErrUnexpectedContentType) work.
+func (e *UnexpectedContentTypeError) Is(target error) bool {
+	return target == ErrUnexpectedContentType
+}
+
+// VersionConflictError is returned when installing a biz while another version of it is active.
+type VersionConflictError struct {
+	// BizName is the name of the conflicting biz.
+	BizName string
+
+	// ActiveVersion is the version already active in the ark container.
+	ActiveVersion string
+
+	// Version is the version to install.
+	Version string
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s: biz %s is active with version %s, can't install version %s",
+		ErrVersionConflict, e.BizName, e.ActiveVersion, e.Version)
+}
+
+// Is make errors.Is(err, ErrVersionConflict) work.
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// VersionReusedError is returned when the biz is active with the same version but another checksum.
+type VersionReusedError struct {
+	// BizName is the name of the biz.
+	BizName string
+
+	// BizVersion is the reused version.
+	BizVersion string
+
+	// ActiveChecksum is the checksum of the biz active in the ark container.
+	ActiveChecksum string
+
+	// Checksum is the checksum of the biz to install.
+	Checksum string
+}
+
+func (e *VersionReusedError) Error() string {
+	return fmt.Sprintf("%s: biz %s %s is active with checksum %s, but the checksum to install is %s",
+		ErrVersionReusedWithDifferentArtifact, e.BizName, e.BizVersion, e.ActiveChecksum, e.Checksum)
+}
+
+// Is make errors.Is(err, ErrVersionReusedWithDifferentArtifact) work.
+func (e *VersionReusedError) Is(target error) bool {
+	return target == ErrVersionReusedWithDifferentArtifact
+}
+
+// IncompatibleVersionError is returned when an operation requires a newer arklet than the target.
+type IncompatibleVersionError struct {
+	// Operation is the operation requiring the newer arklet, like "async install".
+	Operation string
+
+	// Required is the min arklet version of the operation.
+	Required string
+
+	// Actual is the arklet version of the target.
+	Actual string
+}
+
+func (e *IncompatibleVersionError) Error() string {
+	return fmt.Sprintf("%s: %s requires arklet %s or newer, but the target runs %s",
+		ErrIncompatibleVersion, e.Operation, e.Required, e.Actual)
+}
+
+// Is make errors.Is(err, ErrIncompatibleVersion) work.
+func (e *IncompatibleVersionError) Is(target error) bool {
+	return target == ErrIncompatibleVersion
+}
+
+// maxListedFailures is the max failures listed in the message of MultiTargetError.
+const maxListedFailures = 3
+
+// TargetResult is the result of an operation on one of the targets, like a container or a biz.
+type TargetResult struct {
+	// Target identifies the target, like the pod name.
+	Target string
+
+	// Err is the error of the failed operation, nil if it succeeded.
+	Err error
+}
+
+// MultiTargetError is returned by the operations on multiple targets when any of them fails.
+// The errors of the failed targets are unwrapped, so errors.Is and errors.As see through it.
+type MultiTargetError struct {
+	// Operation is the operation, like "sync biz".
+	Operation string
+
+	// Results are the results of all targets in order.
+	Results []TargetResult
+}
+
+// MultiError is the aggregate error of the operations on multiple biz or targets, like InstallBatch and
+// UnInstallAllBiz. The error of each item is recoverable by errors.As.
+type MultiError = MultiTargetError
+
+// newMultiTargetError return nil if none of the targets failed.
+func newMultiTargetError(operation string, results []TargetResult) error {
+	multiErr := &MultiTargetError{Operation: operation, Results: results}
+	if len(multiErr.Failed()) == 0 {
+		return nil
+	}
+	return multiErr
+}
+
+func (e *MultiTargetError) Error() string {
+	failed := e.Failed()
+	sb := &strings.Builder{}
+	if e.Operation != "" {
+		sb.WriteString(e.Operation + ": ")
+	}
+	sb.WriteString(fmt.Sprintf("%d/%d targets failed", len(failed), len(e.Results)))
+	for i, result := range failed {
+		if i == maxListedFailures {
+			sb.WriteString(fmt.Sprintf(" (and %d more)", len(failed)-maxListedFailures))
+			break
+		}
+		separator := "; "
+		if i == 0 {
+			separator = ": "
+		}
+		sb.WriteString(fmt.Sprintf("%s%s: %v", separator, result.Target, result.Err))
+	}
+	return sb.String()
+}
+
+// Unwrap return the errors of the failed targets.
+func (e *MultiTargetError) Unwrap() []error {
+	errs := []error{}
+	for _, result := range e.Failed() {
+		errs = append(errs, result.Err)
+	}
+	return errs
+}
+
+// Failed return the results of the failed targets.
+func (e *MultiTargetError) Failed() []TargetResult {
+	failed := []TargetResult{}
+	for _, result := range e.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Succeeded return the results of the succeeded targets.
+func (e *MultiTargetError) Succeeded() []TargetResult {
+	succeeded := []TargetResult{}
+	for _, result := range e.Results {
+		if result.Err == nil {
+			succeeded = append(succeeded, result)
+		}
+	}
+	return succeeded
+}
+
+// AbortedError is returned when an operation is canceled or timed out by its context.
+// The underlying context error is kept, so errors.Is(err, context.Canceled) works.
+type AbortedError struct {
+	// Operation is the aborted operation, like "install biz".
+	Operation string
+
+	// BizName is the name of the biz being operated.
+	BizName string
+
+	// BizVersion is the version of the biz being operated.
+	BizVersion string
+
+	// RequestSent is true if the request had reached arklet before being aborted,
+	// in which case the operation might still complete in the ark container.
+	RequestSent bool
+
+	// Err is the context error.
+	Err error
+}
+
+func (e *AbortedError) Error() string {
+	state := "no request is sent to arklet"
+	if e.RequestSent {
+		state = "the request is sent, the biz state in ark container is unknown"
+	}
+	return fmt.Sprintf("%s %s:%s aborted, %s: %v", e.Operation, e.BizName, e.BizVersion, state, e.Err)
+}
+
+func (e *AbortedError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseError is returned when arklet responds with a non-success code.
+type ResponseError struct {
+	// Operation is the failed operation, like "install biz".
+	Operation string
+
+	// Code is the code responded by arklet, unknown codes are kept verbatim.
+	Code ResponseCode
+
+	// Message describes the failure.
+	Message string
+
+	// StackTrace is the java stack trace of the failure responded by arklet, if any.
+	StackTrace string
+
+	// BizInfos are the biz infos responded with the failure, if any.
+	BizInfos []interface{}
+
+	// includeDetail includes the ErrorDetail in Error.
+	includeDetail bool
+}
+
+func (e *ResponseError) Error() string {
+	summary, _, _ := strings.Cut(e.Message, "\n")
+	if detail := e.ErrorDetail(); e.includeDetail && detail != "" {
+		return fmt.Sprintf("%s failed: %s\n%s", e.Operation, summary, detail)
+	}
+	return fmt.Sprintf("%s failed: %s", e.Operation, summary)
+}
+
+// ErrorDetail return the stack trace of the failure,
+// which is either responded in data.errorStackTrace or following the first line of message.
+func (e *ResponseError) ErrorDetail() string {
+	if e.StackTrace != "" {
+		return e.StackTrace
+	}
+	_, rest, _ := strings.Cut(e.Message, "\n")
+	return strings.TrimSpace(rest)
+}
+
+// Retriable return true if the failure is transient and the operation could be retried.
+func (e *ResponseError) Retriable() bool {
+	return e.Code.IsRetriable()
+}
+
+// IsRetriable return true if err is caused by a transient arklet failure or a truncated response.
+func IsRetriable(err error) bool {
+	truncatedErr := &TruncatedResponseError{}
+	if errors.As(err, &truncatedErr) {
+		return true
+	}
+	responseErr := &ResponseError{}
+	return errors.As(err, &responseErr) && responseErr.Retriable()
+}
+
+// arkErrorDetail is the error detail in the data of a failed arklet response.
+type arkErrorDetail struct {
+	ErrorStackTrace string        `json:"errorStackTrace"`
+	BizInfos        []interface{} `json:"bizInfos"`
+}
+
+// newResponseError build the ResponseError with the error detail peeked from the raw response body.
+func (h *service) newResponseError(operation string, code ResponseCode, message string, body []byte) *ResponseError {
+	responseErr := &ResponseError{
+		Operation:     operation,
+		Code:          code,
+		Message:       message,
+		includeDetail: h.options.IncludeErrorDetail,
+	}
+
+	resp := &struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, resp); err != nil {
+		return responseErr
+	}
+	// some arklets respond the data as the list of biz infos
+	if jsonShapeOf(resp.Data) == '[' {
+		_ = json.Unmarshal(resp.Data, &responseErr.BizInfos)
+		return responseErr
+	}
+	detail := &arkErrorDetail{}
+	if err := json.Unmarshal(resp.Data, detail); err == nil {
+		responseErr.StackTrace = detail.ErrorStackTrace
+		responseErr.BizInfos = detail.BizInfos
+	}
+	return responseErr
+}
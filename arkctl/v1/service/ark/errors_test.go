@@ -0,0 +1,70 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiTargetError(t *testing.T) {
+	conflictErr := &VersionConflictError{BizName: "biz", ActiveVersion: "1.0.0", Version: "2.0.0"}
+	responseErr := &ResponseError{Operation: "install biz", Code: ResponseCodeTimeout, Message: "timeout"}
+
+	err := newMultiTargetError("install", []TargetResult{
+		{Target: "pod-0"},
+		{Target: "pod-1", Err: conflictErr},
+		{Target: "pod-2", Err: fmt.Errorf("wrapped: %w", responseErr)},
+		{Target: "pod-3"},
+	})
+
+	multiErr := &MultiTargetError{}
+	assert.True(t, errors.As(err, &multiErr))
+	assert.Equal(t, []string{"pod-1", "pod-2"}, targetsOf(multiErr.Failed()))
+	assert.Equal(t, []string{"pod-0", "pod-3"}, targetsOf(multiErr.Succeeded()))
+	assert.Equal(t, "install: 2/4 targets failed: pod-1: "+conflictErr.Error()+
+		"; pod-2: wrapped: install biz failed: timeout", err.Error())
+
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	asResponseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &asResponseErr))
+	assert.Equal(t, ResponseCodeTimeout, asResponseErr.Code)
+	assert.True(t, IsRetriable(err))
+}
+
+func TestMultiTargetError_ListFirstFailures(t *testing.T) {
+	results := []TargetResult{}
+	for i := 0; i < 5; i++ {
+		results = append(results, TargetResult{Target: fmt.Sprintf("pod-%d", i), Err: errors.New("boom")})
+	}
+
+	err := newMultiTargetError("", results)
+	assert.Equal(t, "5/5 targets failed: pod-0: boom; pod-1: boom; pod-2: boom (and 2 more)", err.Error())
+}
+
+func TestMultiTargetError_NoFailure(t *testing.T) {
+	assert.Nil(t, newMultiTargetError("install", []TargetResult{{Target: "pod-0"}}))
+}
+
+func targetsOf(results []TargetResult) []string {
+	targets := []string{}
+	for _, result := range results {
+		targets = append(targets, result.Target)
+	}
+	return targets
+}
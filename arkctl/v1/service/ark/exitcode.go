@@ -0,0 +1,117 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// The process exit codes of the CLI derived from the errors by ExitCode, so that scripts could tell the failures apart.
+const (
+	// ExitCodeOK is for the success, and the install of a biz already installed.
+	ExitCodeOK = 0
+
+	// ExitCodeError is for the failures not classified.
+	ExitCodeError = 1
+
+	// ExitCodeTransport is for the failures worth retrying later, like an unreachable arklet or a busy container.
+	ExitCodeTransport = 3
+
+	// ExitCodeValidation is for the requests rejected before reaching arklet, like a malformed biz version.
+	ExitCodeValidation = 4
+
+	// ExitCodeArkletFailed is for the operations arklet genuinely failed.
+	ExitCodeArkletFailed = 5
+
+	// ExitCodePartialFailure is for the operations on multiple targets where some targets succeeded and some failed.
+	ExitCodePartialFailure = 6
+)
+
+// validationErrors are the errors of the requests rejected before reaching arklet.
+var validationErrors = []error{
+	ErrNotArkBizJar,
+	ErrBizNotFound,
+	ErrVersionConflict,
+	ErrVersionReusedWithDifferentArtifact,
+	ErrMasterBizProtected,
+	ErrConfirmationRequired,
+	ErrBizTooLarge,
+	ErrIncompatibleVersion,
+	ErrInvalidVersion,
+	ErrTargetGroupNotFound,
+	ErrNoTarget,
+	ErrInvalidEndpointOverride,
+	ErrInvalidHop,
+	ErrMissingPort,
+	ErrAmbiguousArkletPort,
+}
+
+// transportErrors are the errors worth retrying later.
+var transportErrors = []error{
+	ErrClientClosed,
+	ErrBizUrlUnreachableFromTarget,
+	context.DeadlineExceeded,
+}
+
+// ExitCode map err to the process exit code, the wrapped errors are classified by errors.Is and errors.As.
+// The MultiTargetError is a partial failure if any target succeeded, otherwise it's classified by its first failure.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeOK
+	}
+
+	multiErr := &MultiTargetError{}
+	if errors.As(err, &multiErr) {
+		failed := multiErr.Failed()
+		if len(failed) == 0 {
+			return ExitCodeOK
+		}
+		if len(failed) < len(multiErr.Results) {
+			return ExitCodePartialFailure
+		}
+		return ExitCode(failed[0].Err)
+	}
+
+	responseErr := &ResponseError{}
+	if errors.As(err, &responseErr) {
+		switch {
+		case responseErr.Code == ResponseCodeDuplicateBiz || responseErr.Code == ResponseCodeRepeatBiz:
+			return ExitCodeOK
+		case responseErr.Retriable():
+			return ExitCodeTransport
+		default:
+			return ExitCodeArkletFailed
+		}
+	}
+
+	for _, target := range validationErrors {
+		if errors.Is(err, target) {
+			return ExitCodeValidation
+		}
+	}
+	for _, target := range transportErrors {
+		if errors.Is(err, target) {
+			return ExitCodeTransport
+		}
+	}
+	truncatedErr := &TruncatedResponseError{}
+	netErr := net.Error(nil)
+	if errors.As(err, &truncatedErr) || errors.As(err, &netErr) {
+		return ExitCodeTransport
+	}
+	return ExitCodeError
+}
@@ -0,0 +1,82 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	ctx := context.Background()
+	port := 1
+	_, connErr := BuildService(ctx).QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "success", err: nil, code: ExitCodeOK},
+		{name: "already installed", err: &ResponseError{Operation: "install biz", Code: ResponseCodeDuplicateBiz}, code: ExitCodeOK},
+		{name: "unclassified", err: errors.New("boom"), code: ExitCodeError},
+		{name: "canceled", err: &AbortedError{Operation: "install biz", Err: context.Canceled}, code: ExitCodeError},
+		{name: "connection refused", err: connErr, code: ExitCodeTransport},
+		{name: "net error", err: &net.OpError{Op: "dial", Err: errors.New("no route to host")}, code: ExitCodeTransport},
+		{name: "timeout", err: &AbortedError{Operation: "install biz", Err: context.DeadlineExceeded}, code: ExitCodeTransport},
+		{name: "truncated", err: &TruncatedResponseError{Read: 1, Expected: 2}, code: ExitCodeTransport},
+		{name: "container busy", err: &ResponseError{Operation: "install biz", Code: ResponseCodeContainerBusy}, code: ExitCodeTransport},
+		{name: "closed", err: ErrClientClosed, code: ExitCodeTransport},
+		{name: "unreachable biz url", err: fmt.Errorf("%w: http://repo/biz.jar", ErrBizUrlUnreachableFromTarget), code: ExitCodeTransport},
+		{name: "invalid version", err: fmt.Errorf("%w: 1..0", ErrInvalidVersion), code: ExitCodeValidation},
+		{name: "version reused", err: &VersionReusedError{BizName: "biz", BizVersion: "1", ActiveChecksum: "a", Checksum: "b"}, code: ExitCodeValidation},
+		{name: "version conflict", err: &VersionConflictError{BizName: "biz", ActiveVersion: "1", Version: "2"}, code: ExitCodeValidation},
+		{name: "incompatible arklet", err: &IncompatibleVersionError{Operation: "async install", Required: "1.1", Actual: "1.0"}, code: ExitCodeValidation},
+		{name: "missing port", err: ErrMissingPort, code: ExitCodeValidation},
+		{name: "master biz", err: fmt.Errorf("%w: base", ErrMasterBizProtected), code: ExitCodeValidation},
+		{name: "arklet failed", err: &ResponseError{Operation: "install biz", Code: ResponseCodeInstallationFailed}, code: ExitCodeArkletFailed},
+		{name: "generic arklet failure", err: &ResponseError{Operation: "install biz", Code: ResponseCodeFailed}, code: ExitCodeArkletFailed},
+		{
+			name: "partial failure",
+			err: newMultiTargetError("install biz", []TargetResult{
+				{Target: "pod-0"},
+				{Target: "pod-1", Err: &ResponseError{Code: ResponseCodeFailed}},
+			}),
+			code: ExitCodePartialFailure,
+		},
+		{
+			name: "all targets failed",
+			err: newMultiTargetError("install biz", []TargetResult{
+				{Target: "pod-0", Err: ErrMissingPort},
+				{Target: "pod-1", Err: &ResponseError{Code: ResponseCodeFailed}},
+			}),
+			code: ExitCodeValidation,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.code, ExitCode(test.err))
+			// the wrapped errors are classified the same
+			if test.err != nil {
+				assert.Equal(t, test.code, ExitCode(fmt.Errorf("deploy: %w", test.err)))
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// GCOptions controls GCFailedBiz.
+type GCOptions struct {
+	// MinAge is how long a biz must have been installed to be collected, so that the installs in progress are left alone.
+	// The age is told by the InstalledTime of QueryBiz, the biz is collected regardless of its age if it's unknown.
+	// Every failed biz is collected if it's not positive.
+	MinAge time.Duration
+
+	// BizName limits the collection to the biz of the name, all biz are checked if it's empty.
+	BizName string
+
+	// BizVersion limits the collection to the version of BizName, all versions are checked if it's empty.
+	BizVersion string
+
+	// DryRun only reports the biz to collect without uninstalling them.
+	DryRun bool
+}
+
+// GCReport is the result of GCFailedBiz.
+type GCReport struct {
+	// Collected are the failed biz uninstalled, or to uninstall in the dry run.
+	Collected []BatchResult
+
+	// Kept are the failed biz younger than MinAge.
+	Kept []BizModel
+}
+
+// isFailedBizState return true for the states left by the failed installs. The deactivated biz are kept,
+// as they're deactivated on purpose like by switchBiz, and so is the biz of an unknown or missing state,
+// since the arklet may not report it at all.
+func isFailedBizState(state BizState) bool {
+	switch state {
+	case BizStateBroken, BizStateResolved, BizStateUnresolved:
+		return true
+	default:
+		return false
+	}
+}
+
+// bizAge return how long the biz has been installed, false if the arklet doesn't report it.
+func (h *service) bizAge(ctx context.Context, target ArkContainerRuntimeInfo, bizModel BizModel) (time.Duration, bool) {
+	detail, err := h.QueryBiz(WithCacheBypass(ctx), target, bizModel.BizName, bizModel.BizVersion)
+	if err != nil || detail.InstalledTime <= 0 {
+		return 0, false
+	}
+	return time.Since(time.UnixMilli(detail.InstalledTime)), true
+}
+
+// GCFailedBiz uninstall the biz left in a failed state like BROKEN by the failed installs, which block the later installs
+// of the same version. The master biz is never collected.
+// All the failed biz are tried even if some fail, the failures are reported by MultiTargetError along with the report.
+func (h *service) GCFailedBiz(ctx context.Context, target ArkContainerRuntimeInfo, opts GCOptions) (*GCReport, error) {
+	target, err := targetOrDefault(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	ctx = WithPodPortCache(ctx)
+	logger := contextutil.GetLogger(ctx).WithFields(targetFields(target)).WithField("dryRun", opts.DryRun)
+	logger.Info("gc failed biz started")
+
+	installed, err := h.queryAllBizOf(ctx, target)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	masterBizName := h.masterBizName(ctx, target)
+	report := &GCReport{}
+	targets := []TargetResult{}
+	for _, info := range installed {
+		if !isFailedBizState(info.BizState) ||
+			(opts.BizName != "" && info.BizName != opts.BizName) ||
+			(opts.BizVersion != "" && info.BizVersion != opts.BizVersion) ||
+			(masterBizName != "" && info.BizName == masterBizName) {
+			continue
+		}
+
+		bizModel := BizModel{BizName: info.BizName, BizVersion: info.BizVersion}
+		bizLogger := logger.WithFields(bizFields(bizModel)).WithField("bizState", info.BizState)
+		if opts.MinAge > 0 {
+			if age, ok := h.bizAge(ctx, target, bizModel); ok && age < opts.MinAge {
+				bizLogger.WithField("age", age).Info("keep failed biz younger than the min age")
+				report.Kept = append(report.Kept, bizModel)
+				continue
+			}
+		}
+
+		if opts.DryRun {
+			bizLogger.Info("failed biz would be collected")
+			report.Collected = append(report.Collected, BatchResult{BizModel: bizModel})
+			continue
+		}
+		uninstallErr := h.UnInstallBiz(ctx, UnInstallBizRequest{
+			BizModel:        bizModel,
+			TargetContainer: target,
+			// the master biz is skipped above
+			AllowMasterBiz: true,
+		})
+		report.Collected = append(report.Collected, BatchResult{BizModel: bizModel, Err: uninstallErr})
+		targets = append(targets, TargetResult{Target: info.BizName + ":" + info.BizVersion, Err: uninstallErr})
+	}
+
+	if err := newMultiTargetError("gc failed biz", targets); err != nil {
+		logger.Error(err)
+		return report, err
+	}
+	logger.WithField("count", len(report.Collected)).Info("gc failed biz completed")
+	return report, nil
+}
+
+// retryInstallAfterGC collect the failed biz of the same name and version left by the failed install,
+// and retry the install once if any is collected. The error of the install is returned if nothing is collected.
+func (h *service) retryInstallAfterGC(ctx context.Context, req InstallBizRequest, install func() error, installErr error) error {
+	logger := contextutil.GetLogger(ctx).WithFields(req.loggableRequest())
+	report, err := h.GCFailedBiz(ctx, req.TargetContainer, GCOptions{
+		BizName:    req.BizModel.BizName,
+		BizVersion: req.BizModel.BizVersion,
+	})
+	if err != nil {
+		logger.WithError(err).Warn("skip retrying install biz as the failed biz can't be collected")
+		return installErr
+	}
+	if len(report.Collected) == 0 {
+		return installErr
+	}
+
+	logger.WithError(installErr).Info("retry install biz after collecting the failed biz")
+	return install()
+}
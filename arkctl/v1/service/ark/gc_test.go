@@ -0,0 +1,180 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newGCArklet serve the fake arklet with the failed biz, and the installed time of the biz by queryBiz.
+func newGCArklet(installedTimes map[string]time.Time) (*fakeArklet, int, func()) {
+	arklet := &fakeArklet{
+		biz: []ArkBizInfo{
+			{BizName: "active", BizVersion: "1.0.0", BizState: BizStateActivated},
+			{BizName: "switched", BizVersion: "1.0.0", BizState: BizStateDeactivated},
+			{BizName: "broken", BizVersion: "1.0.0", BizState: BizStateBroken},
+			{BizName: "resolved", BizVersion: "1.0.0", BizState: BizStateResolved},
+		},
+		failNames: map[string]bool{},
+	}
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/queryBiz" {
+			arklet.serve(w, r)
+			return
+		}
+		bizModel := BizModel{}
+		_ = json.NewDecoder(r.Body).Decode(&bizModel)
+		detail := map[string]interface{}{"bizName": bizModel.BizName, "bizVersion": bizModel.BizVersion}
+		if installed, ok := installedTimes[bizModel.BizName]; ok {
+			detail["installedTime"] = installed.UnixMilli()
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+			"data": detail,
+		})
+	})
+	return arklet, port, cancel
+}
+
+func TestGCFailedBiz(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet, port, cancel := newGCArklet(nil)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	report, err := client.GCFailedBiz(ctx, target, GCOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []BatchResult{
+		{BizModel: BizModel{BizName: "broken", BizVersion: "1.0.0"}},
+		{BizModel: BizModel{BizName: "resolved", BizVersion: "1.0.0"}},
+	}, report.Collected)
+	assert.Equal(t, []string{"uninstall broken:1.0.0", "uninstall resolved:1.0.0"}, arklet.calls)
+	// the activated and the deactivated biz are kept
+	assert.Len(t, arklet.biz, 2)
+}
+
+func TestGCFailedBiz_DryRun(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet, port, cancel := newGCArklet(nil)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	report, err := client.GCFailedBiz(ctx, target, GCOptions{DryRun: true, BizName: "broken"})
+	assert.Nil(t, err)
+	assert.Equal(t, []BatchResult{{BizModel: BizModel{BizName: "broken", BizVersion: "1.0.0"}}}, report.Collected)
+	assert.Empty(t, arklet.calls)
+	assert.Len(t, arklet.biz, 4)
+}
+
+func TestGCFailedBiz_MinAge(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet, port, cancel := newGCArklet(map[string]time.Time{
+		"broken": time.Now().Add(-time.Hour),
+		// an install in progress
+		"resolved": time.Now(),
+	})
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	report, err := client.GCFailedBiz(ctx, target, GCOptions{MinAge: 10 * time.Minute})
+	assert.Nil(t, err)
+	assert.Equal(t, []BatchResult{{BizModel: BizModel{BizName: "broken", BizVersion: "1.0.0"}}}, report.Collected)
+	assert.Equal(t, []BizModel{{BizName: "resolved", BizVersion: "1.0.0"}}, report.Kept)
+	assert.Equal(t, []string{"uninstall broken:1.0.0"}, arklet.calls)
+
+	// the biz of unknown age is collected
+	arklet, port, cancel = newGCArklet(nil)
+	defer cancel()
+	target = ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	report, err = client.GCFailedBiz(ctx, target, GCOptions{MinAge: 10 * time.Minute})
+	assert.Nil(t, err)
+	assert.Len(t, report.Collected, 2)
+	assert.Empty(t, report.Kept)
+}
+
+func TestGCFailedBiz_MissingState(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := &fakeArklet{failNames: map[string]bool{}}
+	// the arklet doesn't report bizState
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/queryAllBiz" {
+			arklet.serve(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[{"bizName":"biz1","bizVersion":"1.0.0"},{"bizName":"biz2","bizVersion":"1.0.0","bizState":"STOPPING"}]}`))
+	})
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	report, err := client.GCFailedBiz(ctx, target, GCOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Collected)
+	assert.Empty(t, arklet.calls)
+}
+
+func TestInstallBiz_AutoGC(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	var calls []string
+	biz := []ArkBizInfo{{BizName: "biz", BizVersion: "1.0.0", BizState: BizStateBroken}}
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]interface{}{"code": "SUCCESS"}
+		switch r.URL.Path {
+		case "/queryAllBiz":
+			resp["data"] = biz
+		case "/installBiz":
+			calls = append(calls, "install")
+			// the broken biz blocks the install of the same version
+			if len(biz) > 0 {
+				resp = map[string]interface{}{"code": "FAILED", "message": "biz is broken"}
+			}
+		case "/uninstallBiz":
+			calls = append(calls, "uninstall")
+			biz = nil
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	defer cancel()
+
+	req := InstallBizRequest{
+		BizModel:              BizModel{BizName: "biz", BizVersion: "1.0.0"},
+		TargetContainer:       ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		AllowMultipleVersions: true,
+	}
+	assert.EqualError(t, client.InstallBiz(ctx, req), "install biz failed: biz is broken")
+	assert.Equal(t, []string{"install"}, calls)
+
+	calls = nil
+	req.AutoGC = true
+	assert.Nil(t, client.InstallBiz(ctx, req))
+	assert.Equal(t, []string{"install", "uninstall", "install"}, calls)
+
+	// nothing to collect, the install isn't retried
+	calls = nil
+	biz = []ArkBizInfo{{BizName: "biz", BizVersion: "1.0.0", BizState: BizStateActivated}}
+	assert.NotNil(t, client.InstallBiz(ctx, req))
+	assert.Equal(t, []string{"install"}, calls)
+}
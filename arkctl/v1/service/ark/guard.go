@@ -0,0 +1,94 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// queryHealth query the health of the ark container.
+func (h *service) queryHealth(ctx context.Context, target ArkContainerRuntimeInfo) (*HealthResult, error) {
+	var respBody []byte
+	switch target.RunType {
+	case ArkContainerRunTypeLocal:
+		endpointUrl, err := h.localEndpointUrl(target, EndpointHealth)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := h.client.R().
+			SetContext(ctx).
+			SetBody(struct{}{}).
+			Execute(h.endpointMethod(EndpointHealth), endpointUrl)
+		if err != nil {
+			return nil, err
+		}
+		if !resp.IsSuccess() {
+			return nil, fmt.Errorf("query health http failed with code %d", resp.StatusCode())
+		}
+		respBody = resp.Body()
+	case ArkContainerRunTypeK8s:
+		body, err := h.postInPod(ctx, target, EndpointHealth, struct{}{})
+		if err != nil {
+			return nil, err
+		}
+		respBody = body
+	default:
+		return nil, fmt.Errorf("query health is not supported for run type: %s", target.RunType)
+	}
+
+	healthResponse := &HealthResponse{}
+	if err := json.Unmarshal(respBody, healthResponse); err != nil {
+		return nil, err
+	}
+
+	if !healthResponse.Code.IsSuccess() {
+		return nil, h.newResponseError("query health", healthResponse.Code, healthResponse.Message, respBody)
+	}
+	return &healthResponse.Data, nil
+}
+
+// masterBizName return the master biz name of the container, empty if it's unknown.
+func (h *service) masterBizName(ctx context.Context, target ArkContainerRuntimeInfo) string {
+	key := containerCacheKey(target)
+	if name, ok := h.masterBizNames.Load(key); ok {
+		return name.(string)
+	}
+
+	health, err := h.queryHealth(ctx, target)
+	if err != nil {
+		contextutil.GetLogger(ctx).WithError(err).Warn("failed to discover master biz")
+		return ""
+	}
+	if health.HealthData.MasterBizInfo == nil || health.HealthData.MasterBizInfo.BizName == "" {
+		return ""
+	}
+
+	name := health.HealthData.MasterBizInfo.BizName
+	h.masterBizNames.Store(key, name)
+	return name
+}
+
+// checkMasterBiz return ErrMasterBizProtected if the biz is the master biz of the container.
+// The check is best effort, the operation goes on if the master biz can't be discovered.
+func (h *service) checkMasterBiz(ctx context.Context, target ArkContainerRuntimeInfo, bizName string) error {
+	if masterBizName := h.masterBizName(ctx, target); masterBizName != "" && masterBizName == bizName {
+		return fmt.Errorf("%w: %s is the master biz of the ark container", ErrMasterBizProtected, bizName)
+	}
+	return nil
+}
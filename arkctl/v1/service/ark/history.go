@@ -0,0 +1,122 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultHistoryCapacity is the max operations kept in the history by default.
+const defaultHistoryCapacity = 256
+
+// OperationRecord is an operation done by the client, kept in the history.
+type OperationRecord struct {
+	// Operation is the operation, like "install biz".
+	Operation string
+
+	// Request summarizes the request, with the sensitive parts like the query of biz url redacted.
+	Request map[string]interface{}
+
+	// Start is when the operation started.
+	Start time.Time
+
+	// Duration is how long the operation took.
+	Duration time.Duration
+
+	// Err is the error of the failed operation, nil if it succeeded.
+	Err error
+}
+
+// Succeeded return true if the operation didn't fail.
+func (r OperationRecord) Succeeded() bool {
+	return r.Err == nil
+}
+
+// operationHistory is a ring buffer of the latest operations, the oldest are evicted once it's full.
+// A nil history records nothing.
+type operationHistory struct {
+	lock    sync.Mutex
+	records []OperationRecord
+	// next is the index to write the next record to
+	next int
+	full bool
+}
+
+// newOperationHistory return nil if capacity is not positive.
+func newOperationHistory(capacity int) *operationHistory {
+	if capacity <= 0 {
+		return nil
+	}
+	return &operationHistory{records: make([]OperationRecord, capacity)}
+}
+
+func (h *operationHistory) add(record OperationRecord) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.records[h.next] = record
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list return a copy of the records in the order they are added.
+func (h *operationHistory) list() []OperationRecord {
+	if h == nil {
+		return nil
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if !h.full {
+		return append([]OperationRecord(nil), h.records[:h.next]...)
+	}
+	return append(append(make([]OperationRecord, 0, len(h.records)), h.records[h.next:]...), h.records[:h.next]...)
+}
+
+func (h *operationHistory) reset() {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	clear(h.records)
+	h.next = 0
+	h.full = false
+}
+
+// recordOperation add the operation started at start to the history, err is read when it's called, so it could be deferred.
+func (h *service) recordOperation(operation string, request map[string]interface{}, start time.Time, err *error) {
+	h.history.add(OperationRecord{
+		Operation: operation,
+		Request:   request,
+		Start:     start,
+		Duration:  time.Since(start),
+		Err:       *err,
+	})
+}
+
+// History return the latest operations done by the client in the order they completed.
+func (h *service) History() []OperationRecord {
+	return h.history.list()
+}
+
+// ResetHistory drop all operations in the history.
+func (h *service) ResetHistory() {
+	h.history.reset()
+}
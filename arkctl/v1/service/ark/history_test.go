@@ -0,0 +1,130 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationHistory_Eviction(t *testing.T) {
+	history := newOperationHistory(3)
+	assert.Empty(t, history.list())
+
+	for i := 0; i < 5; i++ {
+		history.add(OperationRecord{Operation: fmt.Sprintf("op-%d", i)})
+	}
+	var operations []string
+	for _, record := range history.list() {
+		operations = append(operations, record.Operation)
+	}
+	assert.Equal(t, []string{"op-2", "op-3", "op-4"}, operations)
+
+	history.reset()
+	assert.Empty(t, history.list())
+	history.add(OperationRecord{Operation: "op-5"})
+	assert.Len(t, history.list(), 1)
+
+	// the disabled history records nothing
+	var disabled *operationHistory
+	disabled.add(OperationRecord{Operation: "op"})
+	assert.Nil(t, disabled.list())
+	assert.Nil(t, newOperationHistory(0))
+}
+
+func TestOperationHistory_ConcurrentWriters(t *testing.T) {
+	history := newOperationHistory(100)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				history.add(OperationRecord{Operation: fmt.Sprintf("op-%d-%d", writer, j)})
+				_ = history.list()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	records := history.list()
+	assert.Len(t, records, 100)
+	seen := map[string]bool{}
+	for _, record := range records {
+		assert.False(t, seen[record.Operation])
+		seen[record.Operation] = true
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		code := "SUCCESS"
+		if r.URL.Path == "/uninstallBiz" {
+			code = "FAILED"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    code,
+			"message": "done",
+		})
+	})
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	client := BuildService(ctx, WithHistoryCapacity(10))
+
+	start := time.Now()
+	assert.Nil(t, client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "https://oss.example.com/biz.jar?token=secret"},
+		TargetContainer: target,
+		// skip the query of the installed biz, which is recorded too
+		AllowMultipleVersions: true,
+	}))
+	assert.NotNil(t, client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: target,
+	}))
+
+	history := client.History()
+	assert.Len(t, history, 2)
+	install := history[0]
+	assert.Equal(t, "install biz", install.Operation)
+	assert.True(t, install.Succeeded())
+	assert.Equal(t, "biz", install.Request["bizName"])
+	assert.Equal(t, "https://oss.example.com", install.Request["bizUrl"])
+	assert.False(t, install.Start.Before(start))
+	assert.True(t, install.Duration > 0)
+
+	uninstall := history[1]
+	assert.Equal(t, "uninstall biz", uninstall.Operation)
+	assert.False(t, uninstall.Succeeded())
+	assert.EqualError(t, uninstall.Err, "uninstall biz failed: {{FAILED {  0 []} done}}")
+
+	client.ResetHistory()
+	assert.Empty(t, client.History())
+
+	// the history is disabled by a non positive capacity
+	disabled := BuildService(ctx, WithHistoryCapacity(0))
+	_, err := disabled.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	assert.Empty(t, disabled.History())
+}
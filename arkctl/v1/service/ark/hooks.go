@@ -0,0 +1,130 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// Hooks are the callbacks around the install lifecycle, nil hooks are skipped.
+// Hooks run synchronously in the order they are registered.
+type Hooks struct {
+	// BeforeInstall is called before installing biz, returning an error vetoes the install.
+	BeforeInstall func(ctx context.Context, req InstallBizRequest) error
+
+	// AfterInstall is called after installing biz with the result and the duration of the install.
+	// It's not called if the install is vetoed.
+	AfterInstall func(ctx context.Context, req InstallBizRequest, err error, duration time.Duration)
+
+	// BeforeUninstall is called before uninstalling biz, returning an error vetoes the uninstall.
+	BeforeUninstall func(ctx context.Context, req UnInstallBizRequest) error
+
+	// AfterUninstall is called after uninstalling biz with the result and the duration of the uninstall.
+	// It's not called if the uninstall is vetoed.
+	AfterUninstall func(ctx context.Context, req UnInstallBizRequest, err error, duration time.Duration)
+}
+
+// HookError is returned when a hook fails or panics.
+type HookError struct {
+	// Hook is the name of the failed hook, like "BeforeInstall".
+	Hook string
+
+	// Err is the error returned by the hook, or the recovered panic.
+	Err error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("hook %s failed: %v", e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// runHook call the hook and convert both its error and panic to HookError.
+func runHook(name string, hook func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &HookError{Hook: name, Err: fmt.Errorf("panic: %v", r)}
+		}
+	}()
+
+	if err := hook(); err != nil {
+		return &HookError{Hook: name, Err: err}
+	}
+	return nil
+}
+
+func (h *service) beforeInstall(ctx context.Context, req InstallBizRequest) error {
+	for _, hooks := range h.options.Hooks {
+		if hooks.BeforeInstall == nil {
+			continue
+		}
+		if err := runHook("BeforeInstall", func() error {
+			return hooks.BeforeInstall(ctx, req)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterInstall run all AfterInstall hooks, failed hooks are logged and won't change the install result.
+func (h *service) afterInstall(ctx context.Context, req InstallBizRequest, installErr error, duration time.Duration) {
+	for _, hooks := range h.options.Hooks {
+		if hooks.AfterInstall == nil {
+			continue
+		}
+		if err := runHook("AfterInstall", func() error {
+			hooks.AfterInstall(ctx, req, installErr, duration)
+			return nil
+		}); err != nil {
+			contextutil.GetLogger(ctx).Error(err)
+		}
+	}
+}
+
+func (h *service) beforeUninstall(ctx context.Context, req UnInstallBizRequest) error {
+	for _, hooks := range h.options.Hooks {
+		if hooks.BeforeUninstall == nil {
+			continue
+		}
+		if err := runHook("BeforeUninstall", func() error {
+			return hooks.BeforeUninstall(ctx, req)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterUninstall run all AfterUninstall hooks, failed hooks are logged and won't change the uninstall result.
+func (h *service) afterUninstall(ctx context.Context, req UnInstallBizRequest, uninstallErr error, duration time.Duration) {
+	for _, hooks := range h.options.Hooks {
+		if hooks.AfterUninstall == nil {
+			continue
+		}
+		if err := runHook("AfterUninstall", func() error {
+			hooks.AfterUninstall(ctx, req, uninstallErr, duration)
+			return nil
+		}); err != nil {
+			contextutil.GetLogger(ctx).Error(err)
+		}
+	}
+}
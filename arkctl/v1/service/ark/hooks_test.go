@@ -0,0 +1,165 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks_RunInOrder(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+	hooks := func(name string) Hooks {
+		return Hooks{
+			BeforeInstall: func(ctx context.Context, req InstallBizRequest) error {
+				calls = append(calls, name+".BeforeInstall")
+				return nil
+			},
+			AfterInstall: func(ctx context.Context, req InstallBizRequest, err error, duration time.Duration) {
+				assert.Nil(t, err)
+				assert.True(t, duration > 0)
+				calls = append(calls, name+".AfterInstall")
+			},
+		}
+	}
+	client := BuildService(ctx, WithHooks(hooks("first")), WithHooks(hooks("second")))
+
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "install")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"first.BeforeInstall",
+		"second.BeforeInstall",
+		"install",
+		"first.AfterInstall",
+		"second.AfterInstall",
+	}, calls)
+}
+
+func TestHooks_BeforeInstallVeto(t *testing.T) {
+	ctx := context.Background()
+	vetoErr := errors.New("install is frozen")
+
+	afterCalled := false
+	client := BuildService(ctx, WithHooks(Hooks{
+		BeforeInstall: func(ctx context.Context, req InstallBizRequest) error {
+			return vetoErr
+		},
+		AfterInstall: func(ctx context.Context, req InstallBizRequest, err error, duration time.Duration) {
+			afterCalled = true
+		},
+	}))
+
+	called := false
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.True(t, errors.Is(err, vetoErr))
+	hookErr := &HookError{}
+	assert.True(t, errors.As(err, &hookErr))
+	assert.Equal(t, "BeforeInstall", hookErr.Hook)
+	assert.False(t, called)
+	assert.False(t, afterCalled)
+}
+
+func TestHooks_PanicIsHookError(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithHooks(Hooks{
+		BeforeUninstall: func(ctx context.Context, req UnInstallBizRequest) error {
+			panic("boom")
+		},
+	}))
+
+	err := client.UnInstallBiz(ctx, UnInstallBizRequest{
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "hook BeforeUninstall failed: panic: boom", err.Error())
+}
+
+func TestHooks_AfterUninstallFailure(t *testing.T) {
+	ctx := context.Background()
+
+	var hookErr error
+	secondCalled := false
+	client := BuildService(ctx,
+		WithHooks(Hooks{
+			AfterUninstall: func(ctx context.Context, req UnInstallBizRequest, err error, duration time.Duration) {
+				hookErr = err
+				panic("audit failed")
+			},
+		}),
+		WithHooks(Hooks{
+			AfterUninstall: func(ctx context.Context, req UnInstallBizRequest, err error, duration time.Duration) {
+				secondCalled = true
+			},
+		}),
+	)
+
+	port, cancel := mockHttpServer("/uninstallBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    "FAILED",
+			"message": "uninstall failed",
+		})
+	})
+	defer cancel()
+
+	err := client.UnInstallBiz(ctx, UnInstallBizRequest{
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+
+	// the failure is passed to the hooks, and a panicking after hook doesn't change the result
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, err, hookErr)
+	assert.True(t, secondCalled)
+}
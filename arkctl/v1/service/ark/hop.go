@@ -0,0 +1,194 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+)
+
+// Socks5Auth is the username and password to authenticate to the SOCKS5 proxy.
+type Socks5Auth struct {
+	User     string
+	Password string
+}
+
+// SSHJumpHost is a hop of the ssh jump host chain, like the bastion in front of the private network of the arklets.
+type SSHJumpHost struct {
+	// Addr is the host:port of the ssh server.
+	Addr string
+
+	// Config authenticates to the ssh server, its HostKeyCallback must be set to verify the server.
+	Config *ssh.ClientConfig
+}
+
+// contextDialer adapts dialFunc to proxy.ContextDialer.
+type contextDialer dialFunc
+
+func (d contextDialer) Dial(network, addr string) (net.Conn, error) {
+	return d(context.Background(), network, addr)
+}
+
+func (d contextDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d(ctx, network, addr)
+}
+
+// hopDialer return the dialer reaching the arklets through the SOCKS5 proxy, then through the ssh jump hosts in order,
+// or next if neither is configured. The SOCKS5 proxy is dialed by next, and the first jump host by the SOCKS5 proxy if any.
+func hopDialer(options ClientOptions, next dialFunc) (dialFunc, error) {
+	dial := next
+	if options.Socks5Addr != "" {
+		if _, _, err := net.SplitHostPort(options.Socks5Addr); err != nil {
+			return nil, fmt.Errorf("%w: socks5 proxy %q: %v", ErrInvalidHop, options.Socks5Addr, err)
+		}
+		var auth *proxy.Auth
+		if options.Socks5Auth != nil {
+			auth = &proxy.Auth{User: options.Socks5Auth.User, Password: options.Socks5Auth.Password}
+		}
+		socks5, err := proxy.SOCKS5("tcp", options.Socks5Addr, auth, contextDialer(next))
+		if err != nil {
+			return nil, fmt.Errorf("%w: socks5 proxy %q: %v", ErrInvalidHop, options.Socks5Addr, err)
+		}
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := socks5.(proxy.ContextDialer).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, fmt.Errorf("socks5 proxy %s: %w", options.Socks5Addr, err)
+			}
+			return conn, nil
+		}
+	}
+
+	if len(options.SSHJumpHosts) == 0 {
+		return dial, nil
+	}
+	for i, hop := range options.SSHJumpHosts {
+		if _, _, err := net.SplitHostPort(hop.Addr); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidHop, jumpHostName(i, hop), err)
+		}
+		if hop.Config == nil {
+			return nil, fmt.Errorf("%w: %s: missing ssh client config", ErrInvalidHop, jumpHostName(i, hop))
+		}
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialThroughJumpHosts(ctx, dial, options.SSHJumpHosts, addr)
+	}, nil
+}
+
+// jumpHostName identify the ssh jump host in the errors, e.g. ssh jump host #1 bastion:22.
+func jumpHostName(index int, hop SSHJumpHost) string {
+	return fmt.Sprintf("ssh jump host #%d %s", index+1, hop.Addr)
+}
+
+// dialThroughJumpHosts dial addr from the last jump host, each jump host is dialed from the previous one.
+// The ssh connections live along with the returned conn, and are closed with it.
+func dialThroughJumpHosts(ctx context.Context, dial dialFunc, hops []SSHJumpHost, addr string) (net.Conn, error) {
+	chain := &jumpConn{}
+	conn, err := dial(ctx, "tcp", hops[0].Addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", jumpHostName(0, hops[0]), err)
+	}
+	for i, hop := range hops {
+		client, err := sshHandshake(ctx, conn, hop)
+		if err != nil {
+			_ = conn.Close()
+			_ = chain.closeClients()
+			return nil, fmt.Errorf("%s: %w", jumpHostName(i, hop), err)
+		}
+		chain.clients = append(chain.clients, client)
+
+		nextAddr := addr
+		if i+1 < len(hops) {
+			nextAddr = hops[i+1].Addr
+		}
+		if conn, err = sshDial(ctx, client, nextAddr); err != nil {
+			_ = chain.closeClients()
+			return nil, fmt.Errorf("%s: dial %s: %w", jumpHostName(i, hop), nextAddr, err)
+		}
+	}
+	chain.Conn = conn
+	return chain, nil
+}
+
+// sshHandshake open the ssh connection to hop over conn, the handshake is aborted once ctx is done.
+func sshHandshake(ctx context.Context, conn net.Conn, hop SSHJumpHost) (*ssh.Client, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, hop.Addr, hop.Config)
+	if !stop() {
+		if err == nil {
+			_ = sshConn.Close()
+		}
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// sshDial dial addr from the ssh server, it returns once ctx is done even if the server doesn't respond.
+func sshDial(ctx context.Context, client *ssh.Client, addr string) (net.Conn, error) {
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	result := make(chan dialResult, 1)
+	go func() {
+		conn, err := client.Dial("tcp", addr)
+		result <- dialResult{conn: conn, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// the conn dialed too late is closed along with the ssh connections by the caller
+		return nil, ctx.Err()
+	case r := <-result:
+		return r.conn, r.err
+	}
+}
+
+// jumpConn is the conn dialed from the last jump host, closing it closes the ssh connections of the chain.
+type jumpConn struct {
+	net.Conn
+	clients []*ssh.Client
+}
+
+func (c *jumpConn) closeClients() error {
+	var firstErr error
+	for i := len(c.clients) - 1; i >= 0; i-- {
+		if err := c.clients[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (c *jumpConn) Close() error {
+	err := c.Conn.Close()
+	if clientsErr := c.closeClients(); err == nil {
+		err = clientsErr
+	}
+	return err
+}
@@ -0,0 +1,328 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// pipeConns copy between the conns until either is closed.
+func pipeConns(a, b net.Conn) {
+	go func() {
+		_, _ = io.Copy(a, b)
+		_ = a.Close()
+	}()
+	_, _ = io.Copy(b, a)
+	_ = b.Close()
+}
+
+// mockSocks5Server serves the CONNECT of SOCKS5 with the no auth or the username and password auth,
+// and counts the connections tunneled.
+func mockSocks5Server(t *testing.T, user, password string) (string, *atomic.Int32, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	tunneled := &atomic.Int32{}
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		methods := make([]byte, header[1])
+		if _, err := io.ReadFull(conn, methods); err != nil {
+			return
+		}
+		if user == "" {
+			_, _ = conn.Write([]byte{5, 0})
+		} else {
+			_, _ = conn.Write([]byte{5, 2})
+			// version, user, password
+			buf := make([]byte, 2)
+			_, _ = io.ReadFull(conn, buf)
+			gotUser := make([]byte, buf[1])
+			_, _ = io.ReadFull(conn, gotUser)
+			_, _ = io.ReadFull(conn, buf[:1])
+			gotPassword := make([]byte, buf[0])
+			_, _ = io.ReadFull(conn, gotPassword)
+			if string(gotUser) != user || string(gotPassword) != password {
+				_, _ = conn.Write([]byte{1, 1})
+				return
+			}
+			_, _ = conn.Write([]byte{1, 0})
+		}
+
+		// version, command, reserved, address type
+		request := make([]byte, 4)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		var host string
+		switch request[3] {
+		case 1:
+			ip := make([]byte, 4)
+			_, _ = io.ReadFull(conn, ip)
+			host = net.IP(ip).String()
+		case 3:
+			length := make([]byte, 1)
+			_, _ = io.ReadFull(conn, length)
+			name := make([]byte, length[0])
+			_, _ = io.ReadFull(conn, name)
+			host = string(name)
+		default:
+			return
+		}
+		port := make([]byte, 2)
+		_, _ = io.ReadFull(conn, port)
+
+		target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+		if err != nil {
+			_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		tunneled.Add(1)
+		_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		pipeConns(conn, target)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return listener.Addr().String(), tunneled, func() {
+		_ = listener.Close()
+	}
+}
+
+// mockSSHJumpHost serves the direct-tcpip channels of ssh, authenticating by password.
+func mockSSHJumpHost(t *testing.T, password string) (string, ssh.PublicKey, *atomic.Int32, func()) {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	assert.Nil(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, got []byte) (*ssh.Permissions, error) {
+			if string(got) != password {
+				return nil, errors.New("wrong password")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	tunneled := &atomic.Int32{}
+
+	serve := func(conn net.Conn) {
+		_, chans, reqs, err := ssh.NewServerConn(conn, config)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for newChannel := range chans {
+			if newChannel.ChannelType() != "direct-tcpip" {
+				_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+				continue
+			}
+			payload := struct {
+				Host       string
+				Port       uint32
+				OriginHost string
+				OriginPort uint32
+			}{}
+			if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+				_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+			if err != nil {
+				_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			channel, channelReqs, err := newChannel.Accept()
+			if err != nil {
+				_ = target.Close()
+				continue
+			}
+			go ssh.DiscardRequests(channelReqs)
+			tunneled.Add(1)
+			go func() {
+				go func() {
+					_, _ = io.Copy(channel, target)
+					_ = channel.Close()
+				}()
+				_, _ = io.Copy(target, channel)
+				_ = target.Close()
+			}()
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return listener.Addr().String(), signer.PublicKey(), tunneled, func() {
+		_ = listener.Close()
+	}
+}
+
+// mockArkletForHops serves queryAllBiz for the clients dialing through the hops.
+func mockArkletForHops() (int, func()) {
+	return mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+}
+
+func TestSocks5(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockArkletForHops()
+	defer cancel()
+	// the loopback targets bypass http proxies only, the hops are always used
+	req := QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port}
+
+	addr, tunneled, stop := mockSocks5Server(t, "", "")
+	defer stop()
+	client := BuildService(ctx, WithSocks5(addr, nil))
+	_, err := client.QueryAllBiz(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), tunneled.Load())
+
+	authAddr, authTunneled, stopAuth := mockSocks5Server(t, "user", "secret")
+	defer stopAuth()
+	client = BuildService(ctx, WithSocks5(authAddr, &Socks5Auth{User: "user", Password: "secret"}))
+	_, err = client.QueryAllBiz(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), authTunneled.Load())
+
+	// the failed auth names the proxy
+	client = BuildService(ctx, WithSocks5(authAddr, &Socks5Auth{User: "user", Password: "wrong"}))
+	_, err = client.QueryAllBiz(ctx, req)
+	assert.ErrorContains(t, err, "socks5 proxy "+authAddr)
+}
+
+func TestHops_DialDeadline(t *testing.T) {
+	// the hop accepts but never responds
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	for _, opt := range []Option{
+		WithSocks5(listener.Addr().String(), nil),
+		WithSSHJumpHosts(SSHJumpHost{Addr: listener.Addr().String(), Config: &ssh.ClientConfig{
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		}}),
+	} {
+		client := BuildService(context.Background(), opt)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		_, err = client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: 1238})
+		cancel()
+		assert.NotNil(t, err)
+		assert.True(t, time.Since(start) < 2*time.Second)
+	}
+}
+
+func TestSSHJumpHosts(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockArkletForHops()
+	defer cancel()
+	req := QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port}
+
+	bastionAddr, bastionKey, bastionTunneled, stopBastion := mockSSHJumpHost(t, "bastion-secret")
+	defer stopBastion()
+	innerAddr, innerKey, innerTunneled, stopInner := mockSSHJumpHost(t, "inner-secret")
+	defer stopInner()
+
+	hop := func(addr string, key ssh.PublicKey, password string) SSHJumpHost {
+		return SSHJumpHost{Addr: addr, Config: &ssh.ClientConfig{
+			User:            "arkctl",
+			Auth:            []ssh.AuthMethod{ssh.Password(password)},
+			HostKeyCallback: ssh.FixedHostKey(key),
+		}}
+	}
+
+	client := BuildService(ctx, WithSSHJumpHosts(
+		hop(bastionAddr, bastionKey, "bastion-secret"),
+		hop(innerAddr, innerKey, "inner-secret"),
+	))
+	_, err := client.QueryAllBiz(ctx, req)
+	assert.Nil(t, err)
+	// the bastion tunnels to the inner jump host, which tunnels to the arklet
+	assert.Equal(t, int32(1), bastionTunneled.Load())
+	assert.Equal(t, int32(1), innerTunneled.Load())
+
+	// the failed hop is named
+	client = BuildService(ctx, WithSSHJumpHosts(
+		hop(bastionAddr, bastionKey, "bastion-secret"),
+		hop(innerAddr, innerKey, "wrong"),
+	))
+	_, err = client.QueryAllBiz(ctx, req)
+	assert.ErrorContains(t, err, "ssh jump host #2 "+innerAddr)
+
+	client = BuildService(ctx, WithSSHJumpHosts(hop(bastionAddr, innerKey, "bastion-secret")))
+	_, err = client.QueryAllBiz(ctx, req)
+	assert.ErrorContains(t, err, "ssh jump host #1 "+bastionAddr)
+}
+
+func TestHops_Invalid(t *testing.T) {
+	_, err := NewService(WithSocks5("proxy.local", nil))
+	assert.ErrorIs(t, err, ErrInvalidHop)
+	assert.ErrorContains(t, err, `socks5 proxy "proxy.local"`)
+
+	_, err = NewService(WithSSHJumpHosts(SSHJumpHost{Addr: "bastion:22"}))
+	assert.ErrorIs(t, err, ErrInvalidHop)
+	assert.ErrorContains(t, err, "ssh jump host #1 bastion:22: missing ssh client config")
+
+	_, err = NewService(WithSSHJumpHosts(SSHJumpHost{Addr: "bastion:22", Config: &ssh.ClientConfig{}}, SSHJumpHost{Addr: "inner"}))
+	assert.ErrorIs(t, err, ErrInvalidHop)
+	assert.ErrorContains(t, err, "ssh jump host #2 inner")
+}
@@ -0,0 +1,177 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/progressutil"
+)
+
+// defaultInlineBufferSize is the default max bytes of the biz bundle InstallBizInline buffers in memory.
+const defaultInlineBufferSize = 8 << 20
+
+// InstallBizInline install the biz by sending the bytes of the biz bundle in the install request, for the arklets
+// accepting multipart install requests, instead of a biz url the arklet downloads.
+// The fields of the install body are sent as form fields followed by the bundle as the file field named "file",
+// which is streamed rather than read into memory. The size of the bundle is -1 if it's unknown.
+// The body is gzip compressed if arklet advertises the support by Accept-Encoding, and sent again without compression
+// if arklet rejects it, which requires the bundle to be seekable or within InlineBufferSize.
+// Only the local run type is supported.
+func (h *service) InstallBizInline(ctx context.Context, req InstallBizRequest, file io.Reader, size int64) (err error) {
+	if req.TargetContainer, err = targetOrDefault(ctx, req.TargetContainer); err != nil {
+		return err
+	}
+	logger := contextutil.GetLogger(ctx).WithFields(req.loggableRequest()).WithField("size", size)
+	logger.Info("install biz inline started")
+	defer h.recordOperation("install biz inline", req.loggableRequest(), time.Now(), &err)
+	defer func() {
+		if err != nil {
+			logger.Error(err)
+		} else {
+			logger.Info("install biz inline completed")
+		}
+		// the biz might be changed even if it fails
+		h.invalidateBiz(req.TargetContainer, req.BizModel)
+	}()
+
+	if req.TargetContainer.RunType != ArkContainerRunTypeLocal {
+		return fmt.Errorf("install biz inline is not supported for run type: %s", req.TargetContainer.RunType)
+	}
+	// the bundle is sent by ourselves, the url isn't downloaded by arklet
+	req.BizModel.BizUrl = ""
+	if req.BizModel, err = normalizeBizModel(req.BizModel); err != nil {
+		return err
+	}
+	if !req.AllowMasterBiz {
+		if err = h.checkMasterBiz(ctx, req.TargetContainer, req.BizModel.BizName); err != nil {
+			return err
+		}
+	}
+	if !req.AllowMultipleVersions {
+		if err = h.checkVersionConflict(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	fields, err := inlineFields(req)
+	if err != nil {
+		return err
+	}
+	content, err := bufferInline(file, size, h.options.InlineBufferSize)
+	if err != nil {
+		return err
+	}
+
+	progress := progressutil.OrNop(h.options.UploadProgress)
+	progress.Start(size)
+	defer func() {
+		progress.Finish(err)
+	}()
+
+	compress := false
+	if capabilities, err := h.DetectCapabilities(ctx, req.TargetContainer); err != nil {
+		logger.WithError(err).Warn("install biz inline without compression")
+	} else {
+		compress = capabilities.GzipUpload
+	}
+	if !compress {
+		return h.postInstallBizInline(ctx, req, fields, content, size, false)
+	}
+
+	rewind := rewinder(content)
+	err = h.postInstallBizInline(ctx, req, fields, content, size, true)
+	if !errors.Is(err, errCompressionNotAccepted) {
+		return err
+	}
+	logger.Warn("arklet doesn't accept compressed install, fall back to raw install")
+	if err := rewind(); err != nil {
+		return fmt.Errorf("%w, and the content can't be sent again: %v", errCompressionNotAccepted, err)
+	}
+	return h.postInstallBizInline(ctx, req, fields, content, size, false)
+}
+
+// inlineFields return the form fields of the install body, encoded like FormEncoder.
+func inlineFields(req InstallBizRequest) (url.Values, error) {
+	body, err := installBizBody(req.BizModel, req.ExtraParams)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := FormEncoder{}.Encode(body)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := url.ParseQuery(string(encoded))
+	if err != nil {
+		return nil, err
+	}
+	// the empty biz url is omitted rather than sent as an empty field
+	if fields.Get("bizUrl") == "" {
+		fields.Del("bizUrl")
+	}
+	return fields, nil
+}
+
+// bufferInline read the content into memory if it's not seekable and its size is known within limit,
+// so that it could be sent again. The content is returned as is otherwise.
+func bufferInline(content io.Reader, size, limit int64) (io.Reader, error) {
+	if _, ok := content.(io.Seeker); ok || size < 0 || size > limit {
+		return content, nil
+	}
+	buffered, err := io.ReadAll(io.LimitReader(content, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(buffered)) > limit {
+		// the size is wrong, stream the rest after the buffered bytes
+		return io.MultiReader(bytes.NewReader(buffered), content), nil
+	}
+	return bytes.NewReader(buffered), nil
+}
+
+// postInstallBizInline send the multipart install request, the body is gzip compressed if compress is true.
+func (h *service) postInstallBizInline(ctx context.Context, req InstallBizRequest, fields url.Values, content io.Reader, size int64, compress bool) error {
+	// resolved before the body, whose writer goroutine is only ended by sending the body
+	endpointUrl, err := h.localEndpointUrl(req.TargetContainer, EndpointInstallBiz)
+	if err != nil {
+		return err
+	}
+	fileName := fmt.Sprintf("%s-%s-ark-biz.jar", req.BizModel.BizName, req.BizModel.BizVersion)
+	body, contentType := newMultipartFormBody(fields, fileName, newProgressReader(content, h.options.UploadProgress))
+
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	if compress {
+		header.Set("Content-Encoding", "gzip")
+		body = newGzipReader(body)
+	}
+	resp, respBody, err := h.postStreaming(ctx, endpointUrl, header, body)
+	if err != nil {
+		return err
+	}
+
+	if compress && resp.StatusCode == http.StatusUnsupportedMediaType {
+		return errCompressionNotAccepted
+	}
+	return decodeArkResponse(ctx, h, "install biz inline", resp.StatusCode, resp.Header.Get("Content-Type"), respBody, &InstallBizResponse{})
+}
@@ -0,0 +1,261 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// inlineInstall is an install request received by mockInlineArklet.
+type inlineInstall struct {
+	encoding string
+	fields   map[string]string
+	fileName string
+	size     int64
+	content  []byte
+}
+
+// mockInlineArklet mock an arklet accepting multipart installs, which advertises gzip if advertiseGzip is true,
+// and rejects the compressed installs with 415 if acceptGzip is false. The file is kept up to keepLimit bytes.
+func mockInlineArklet(t *testing.T, advertiseGzip, acceptGzip bool, keepLimit int64, installs *[]inlineInstall) (int, func()) {
+	lock := sync.Mutex{}
+	return mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/help":
+			if advertiseGzip {
+				w.Header().Set("Accept-Encoding", "gzip")
+			}
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[{"id":"installBiz"}]}`))
+		case "/queryAllBiz":
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[]}`))
+		case "/installBiz":
+			install := inlineInstall{encoding: r.Header.Get("Content-Encoding"), fields: map[string]string{}}
+			if install.encoding == "gzip" {
+				if !acceptGzip {
+					_, _ = io.Copy(io.Discard, r.Body)
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				gzipReader, err := gzip.NewReader(r.Body)
+				assert.Nil(t, err)
+				r.Body = gzipReader
+			}
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			assert.Nil(t, err)
+			assert.Equal(t, "multipart/form-data", mediaType)
+			reader, err := r.MultipartReader()
+			assert.Nil(t, err)
+			for {
+				part, err := reader.NextPart()
+				if err == io.EOF {
+					break
+				}
+				if !assert.Nil(t, err) {
+					return
+				}
+				if part.FileName() == "" {
+					value, _ := io.ReadAll(part)
+					install.fields[part.FormName()] = string(value)
+					continue
+				}
+				assert.Equal(t, "file", part.FormName())
+				install.fileName = part.FileName()
+				kept := &bytes.Buffer{}
+				install.size, err = io.Copy(io.MultiWriter(&limitedWriter{w: kept, n: keepLimit}, io.Discard), part)
+				assert.Nil(t, err)
+				install.content = kept.Bytes()
+			}
+			lock.Lock()
+			*installs = append(*installs, install)
+			lock.Unlock()
+			_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+// limitedWriter keep the first n bytes written, and discard the rest.
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if kept := min(int64(len(p)), w.n); kept > 0 {
+		_, _ = w.w.Write(p[:kept])
+		w.n -= kept
+	}
+	return len(p), nil
+}
+
+// onlyReader hide the other methods of the reader like Seek.
+type onlyReader struct {
+	io.Reader
+}
+
+func TestInstallBizInline(t *testing.T) {
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("biz"), 1024)
+	tests := []struct {
+		name          string
+		advertiseGzip bool
+		acceptGzip    bool
+		encoding      string
+	}{
+		{name: "raw"},
+		{name: "gzip", advertiseGzip: true, acceptGzip: true, encoding: "gzip"},
+		// sent again without compression
+		{name: "gzip rejected", advertiseGzip: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var installs []inlineInstall
+			port, cancel := mockInlineArklet(t, test.advertiseGzip, test.acceptGzip, 1<<20, &installs)
+			defer cancel()
+
+			client := BuildService(ctx)
+			// the content isn't seekable, it's buffered to be sent again
+			err := client.InstallBizInline(ctx, InstallBizRequest{
+				BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", MainClass: "com.example.Main"},
+				TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+				ExtraParams:     map[string]interface{}{"async": true},
+			}, onlyReader{bytes.NewReader(content)}, int64(len(content)))
+			assert.Nil(t, err)
+
+			if assert.Equal(t, 1, len(installs)) {
+				install := installs[0]
+				assert.Equal(t, test.encoding, install.encoding)
+				assert.Equal(t, "biz", install.fields["bizName"])
+				assert.Equal(t, "0.0.1", install.fields["bizVersion"])
+				assert.Equal(t, "com.example.Main", install.fields["mainClass"])
+				assert.Equal(t, "true", install.fields["async"])
+				assert.NotContains(t, install.fields, "bizUrl")
+				assert.Equal(t, "biz-0.0.1-ark-biz.jar", install.fileName)
+				assert.Equal(t, content, install.content)
+			}
+		})
+	}
+}
+
+func TestInstallBizInline_GzipRejectedWithoutBuffer(t *testing.T) {
+	ctx := context.Background()
+	var installs []inlineInstall
+	port, cancel := mockInlineArklet(t, true, false, 0, &installs)
+	defer cancel()
+
+	client := BuildService(ctx, WithInlineBufferSize(0))
+	err := client.InstallBizInline(ctx, InstallBizRequest{
+		BizModel:              BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer:       ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		AllowMultipleVersions: true,
+	}, onlyReader{bytes.NewReader([]byte("biz"))}, 3)
+	assert.ErrorIs(t, err, errCompressionNotAccepted)
+	assert.Empty(t, installs)
+}
+
+func TestInstallBizInline_MissingPortNoLeak(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		err := client.InstallBizInline(ctx, InstallBizRequest{
+			BizModel:              BizModel{BizName: "biz", BizVersion: "0.0.1"},
+			TargetContainer:       ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal},
+			AllowMasterBiz:        true,
+			AllowMultipleVersions: true,
+		}, bytes.NewReader([]byte("biz")), 3)
+		assert.ErrorIs(t, err, ErrMissingPort)
+	}
+	// the writer goroutine of the body isn't started
+	assert.Less(t, runtime.NumGoroutine(), before+5)
+}
+
+// zeroReader read n zero bytes without allocating them.
+type zeroReader struct {
+	n int64
+}
+
+func (r *zeroReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+	n := min(int64(len(p)), r.n)
+	clear(p[:n])
+	r.n -= n
+	return int(n), nil
+}
+
+func TestInstallBizInline_MemoryBounded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("streams 200MB")
+	}
+	ctx := context.Background()
+	var installs []inlineInstall
+	port, cancel := mockInlineArklet(t, false, false, 0, &installs)
+	defer cancel()
+
+	// sample the heap while streaming
+	runtime.GC()
+	stats := runtime.MemStats{}
+	runtime.ReadMemStats(&stats)
+	baseline := stats.HeapAlloc
+	peak := atomic.Uint64{}
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		stats := runtime.MemStats{}
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > peak.Load() {
+				peak.Store(stats.HeapAlloc)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+
+	const size = 200 << 20
+	client := BuildService(ctx)
+	err := client.InstallBizInline(ctx, InstallBizRequest{
+		BizModel:              BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer:       ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		AllowMultipleVersions: true,
+	}, &zeroReader{n: size}, size)
+	close(done)
+	<-sampled
+
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(installs)) {
+		assert.Equal(t, int64(size), installs[0].size)
+	}
+	assert.Less(t, peak.Load()-min(baseline, peak.Load()), uint64(32<<20))
+}
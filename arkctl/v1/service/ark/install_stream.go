@@ -0,0 +1,155 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const eventStreamContentType = "text/event-stream"
+
+// eventResult is the server-sent event carrying the final install response.
+const eventResult = "result"
+
+// InstallStage is the stage of an install reported by InstallEvent.
+type InstallStage string
+
+const (
+	InstallStageDownloading InstallStage = "downloading"
+	InstallStageResolving   InstallStage = "resolving"
+	InstallStageStarting    InstallStage = "starting"
+
+	// InstallStageCompleted and InstallStageFailed are the final stages.
+	InstallStageCompleted InstallStage = "completed"
+	InstallStageFailed    InstallStage = "failed"
+)
+
+// InstallEvent is the progress of an install streamed by arklet.
+type InstallEvent struct {
+	// Stage is the stage of the install, unknown stages are kept verbatim.
+	Stage InstallStage `json:"stage"`
+
+	// Message describes the progress.
+	Message string `json:"message,omitempty"`
+
+	// Percent is the progress of the stage from 0 to 100, nil if arklet doesn't report it.
+	Percent *int `json:"percent,omitempty"`
+
+	// Err is the failure of the install, only set for the failed stage.
+	Err error `json:"-"`
+
+	// Synthetic is true if the event isn't streamed by arklet, e.g. the completion of an arklet not streaming events.
+	Synthetic bool `json:"-"`
+}
+
+type installEventHandlerKey struct{}
+
+// withInstallEventHandler return a context asking the install to stream the progress events to onEvent.
+func withInstallEventHandler(ctx context.Context, onEvent func(InstallEvent)) context.Context {
+	return context.WithValue(ctx, installEventHandlerKey{}, onEvent)
+}
+
+func installEventHandlerOf(ctx context.Context) func(InstallEvent) {
+	onEvent, _ := ctx.Value(installEventHandlerKey{}).(func(InstallEvent))
+	return onEvent
+}
+
+// InstallBizStream install the biz like InstallBiz, and stream the progress events if the arklet supports server-sent events.
+// The channel is closed after a final event of the completed or failed stage,
+// which is the only event if the arklet doesn't stream events or the target isn't local.
+// Cancelling ctx aborts the install and closes the connection to arklet.
+func (h *service) InstallBizStream(ctx context.Context, req InstallBizRequest) (<-chan InstallEvent, error) {
+	events := make(chan InstallEvent, 16)
+	send := func(event InstallEvent) {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(events)
+
+		streamed := false
+		err := h.InstallBiz(withInstallEventHandler(ctx, func(event InstallEvent) {
+			streamed = true
+			send(event)
+		}), req)
+
+		final := InstallEvent{Stage: InstallStageCompleted, Message: "install biz completed", Synthetic: !streamed}
+		if err != nil {
+			final = InstallEvent{Stage: InstallStageFailed, Message: err.Error(), Err: err, Synthetic: !streamed}
+		}
+		send(final)
+	}()
+	return events, nil
+}
+
+// isEventStream return true if the response is server-sent events.
+func isEventStream(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == eventStreamContentType
+}
+
+// readInstallEvents pass the progress events to onEvent, and return the data of the result event, which is the install response.
+func readInstallEvents(body io.Reader, onEvent func(InstallEvent)) ([]byte, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	name := ""
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				name = value
+			case "data":
+				data = append(data, value)
+			}
+			continue
+		}
+
+		// a blank line dispatches the event
+		if len(data) > 0 {
+			payload := strings.Join(data, "\n")
+			if name == eventResult {
+				return []byte(payload), nil
+			}
+			event := InstallEvent{}
+			if err := json.Unmarshal([]byte(payload), &event); err != nil {
+				event.Message = payload
+			}
+			if event.Stage == "" {
+				event.Stage = InstallStage(name)
+			}
+			onEvent(event)
+		}
+		name, data = "", nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("install event stream ended without result")
+}
@@ -0,0 +1,138 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func streamInstallRequest(port int) InstallBizRequest {
+	return InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	}
+}
+
+func collectEvents(events <-chan InstallEvent) []InstallEvent {
+	var collected []InstallEvent
+	for event := range events {
+		collected = append(collected, event)
+	}
+	return collected
+}
+
+func TestInstallBizStream_ServerSentEvents(t *testing.T) {
+	ctx := context.Background()
+	var accept string
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/installBiz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "event: downloading\ndata: {\"message\":\"downloading biz.jar\",\"percent\":50}\n\n")
+		_, _ = fmt.Fprint(w, ": comment\ndata: {\"stage\":\"starting\"}\n\n")
+		_, _ = fmt.Fprint(w, "event: result\ndata: {\"code\":\"SUCCESS\"}\n\n")
+	})
+	defer cancel()
+
+	events, err := BuildService(ctx).InstallBizStream(ctx, streamInstallRequest(port))
+	assert.Nil(t, err)
+	collected := collectEvents(events)
+
+	assert.Contains(t, accept, "text/event-stream")
+	percent := 50
+	assert.Equal(t, []InstallEvent{
+		{Stage: InstallStageDownloading, Message: "downloading biz.jar", Percent: &percent},
+		{Stage: InstallStageStarting},
+		{Stage: InstallStageCompleted, Message: "install biz completed"},
+	}, collected)
+}
+
+func TestInstallBizStream_NotSupported(t *testing.T) {
+	ctx := context.Background()
+	code := "SUCCESS"
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/installBiz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"code":%q,"message":"install biz failed"}`, code)
+	})
+	defer cancel()
+	client := BuildService(ctx)
+
+	events, err := client.InstallBizStream(ctx, streamInstallRequest(port))
+	assert.Nil(t, err)
+	assert.Equal(t, []InstallEvent{
+		{Stage: InstallStageCompleted, Message: "install biz completed", Synthetic: true},
+	}, collectEvents(events))
+
+	code = "FAILED"
+	events, err = client.InstallBizStream(ctx, streamInstallRequest(port))
+	assert.Nil(t, err)
+	collected := collectEvents(events)
+	assert.Equal(t, 1, len(collected))
+	assert.Equal(t, InstallStageFailed, collected[0].Stage)
+	assert.True(t, collected[0].Synthetic)
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(collected[0].Err, &responseErr))
+}
+
+func TestInstallBizStream_Cancel(t *testing.T) {
+	disconnected := make(chan struct{})
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/installBiz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "data: {\"stage\":\"downloading\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(disconnected)
+	})
+	defer cancel()
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	events, err := BuildService(ctx).InstallBizStream(ctx, streamInstallRequest(port))
+	assert.Nil(t, err)
+	assert.Equal(t, InstallStageDownloading, (<-events).Stage)
+	cancelCtx()
+
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the connection isn't closed after cancel")
+	}
+	done := make(chan struct{})
+	go func() {
+		collectEvents(events)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the events aren't closed after cancel")
+	}
+}
@@ -0,0 +1,172 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// JournalStatus is the status of an operation recorded in the journal.
+type JournalStatus string
+
+const (
+	JournalPlanned   JournalStatus = "planned"
+	JournalSucceeded JournalStatus = "succeeded"
+	JournalFailed    JournalStatus = "failed"
+)
+
+// JournalRecord is a line of the journal.
+type JournalRecord struct {
+	// Time is when the record is written.
+	Time time.Time `json:"time"`
+
+	// Operation is the operation, like install.
+	Operation string `json:"operation"`
+
+	// Key identifies the operation across runs, it's the same for the same biz and target container.
+	Key string `json:"key"`
+
+	BizName    string `json:"bizName"`
+	BizVersion string `json:"bizVersion"`
+
+	// Status is the status of the operation when the record is written.
+	Status JournalStatus `json:"status"`
+
+	// Error is the error of the failed operation.
+	Error string `json:"error,omitempty"`
+}
+
+// Journal is an append-only JSON lines file recording the operations of batch deploys,
+// so that an interrupted batch could be resumed. It's safe for concurrent writers, even across processes.
+type Journal struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+// OpenJournal open the journal file for appending, the file is created if it doesn't exist.
+// The partially written last line left by a killed writer is truncated, so that it isn't joined with the next record.
+func OpenJournal(path string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := truncatePartialLine(file); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("repair journal %s failed: %w", path, err)
+	}
+	return &Journal{file: file}, nil
+}
+
+// truncatePartialLine truncate the file after its last newline, the file is locked so that no record is being written.
+func truncatePartialLine(file *os.File) error {
+	if err := lockFile(file); err != nil {
+		return err
+	}
+	defer unlockFile(file)
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	content := make([]byte, info.Size())
+	if _, err := file.ReadAt(content, 0); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if len(content) == 0 || content[len(content)-1] == '\n' {
+		return nil
+	}
+	return file.Truncate(int64(bytes.LastIndexByte(content, '\n') + 1))
+}
+
+// Append write the record as a single line and fsync it, the file is locked while writing.
+func (j *Journal) Append(record JournalRecord) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if err := lockFile(j.file); err != nil {
+		return fmt.Errorf("lock journal %s failed: %w", j.file.Name(), err)
+	}
+	defer unlockFile(j.file)
+
+	if _, err := j.file.Write(line); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// Close close the journal file.
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// ReadJournal read all records of the journal, an empty journal is returned if the file doesn't exist.
+// The last line is ignored if it's truncated, which happens when the writer is killed while writing.
+func ReadJournal(path string) ([]JournalRecord, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines := bytes.Split(content, []byte("\n"))
+	records := []JournalRecord{}
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		record := JournalRecord{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			// only the last line without the trailing newline could be partially written
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, fmt.Errorf("journal %s is corrupted at line %d: %w", path, i+1, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// SucceededKeys return the keys of the operations whose last record is succeeded.
+func SucceededKeys(records []JournalRecord) map[string]bool {
+	succeeded := map[string]bool{}
+	for _, record := range records {
+		succeeded[record.Key] = record.Status == JournalSucceeded
+	}
+	for key, ok := range succeeded {
+		if !ok {
+			delete(succeeded, key)
+		}
+	}
+	return succeeded
+}
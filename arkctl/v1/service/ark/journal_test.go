@@ -0,0 +1,59 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadJournal_Corrupted(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+	assert.Nil(t, os.WriteFile(journalPath, []byte("{\"key\":\"a\"}\nnot json\n{\"key\":\"b\"}\n"), 0644))
+
+	_, err := ReadJournal(journalPath)
+	assert.ErrorContains(t, err, "is corrupted at line 2")
+
+	records, err := ReadJournal(filepath.Join(t.TempDir(), "not-exist.jsonl"))
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+}
+
+func TestJournal_ConcurrentWriters(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		journal, err := OpenJournal(journalPath)
+		assert.Nil(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer journal.Close()
+			for j := 0; j < 25; j++ {
+				assert.Nil(t, journal.Append(JournalRecord{Key: "key", Status: JournalSucceeded}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	records, err := ReadJournal(journalPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 100, len(records))
+}
@@ -0,0 +1,140 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// ArkletJarEnv is the environment variable of the jar launched by the tests requiring a real arklet.
+	ArkletJarEnv = "ARKCTL_ARKLET_JAR"
+
+	// arkletPortProperty is the jvm system property overriding the http port of arklet.
+	arkletPortProperty = "sofa.serverless.arklet.http.port"
+
+	// defaultArkletStartTimeout is the max wait for the launched arklet to be healthy if it's not configured.
+	defaultArkletStartTimeout = 2 * time.Minute
+
+	// arkletStartPollInterval is the interval probing the health of the launched arklet.
+	arkletStartPollInterval = 200 * time.Millisecond
+)
+
+// LocalArkletLauncher launches a local arklet on a free port for tests and development,
+// by running the executable jar of a base application embedding arklet.
+type LocalArkletLauncher struct {
+	// JarPath is the executable jar of the base application.
+	JarPath string
+
+	// JavaPath is the java executable, java in PATH if it's empty.
+	JavaPath string
+
+	// JVMArgs are passed to java before -jar, e.g. -Xmx512m.
+	JVMArgs []string
+
+	// StartTimeout bounds the wait for the arklet to be healthy, 2 minutes if it's not positive.
+	StartTimeout time.Duration
+
+	// Output receives the stdout and stderr of the process, which are discarded if it's nil.
+	Output io.Writer
+
+	// command builds the process, exec.Command by default.
+	command func(name string, args ...string) *exec.Cmd
+}
+
+// freePort return a local port free at the moment.
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Launch start the arklet and wait until it's healthy, the cleanup stops the process and is safe to call more than once.
+// The process isn't bound to ctx, which only bounds the wait.
+func (l *LocalArkletLauncher) Launch(ctx context.Context) (ArkContainerRuntimeInfo, func() error, error) {
+	if l.JarPath == "" {
+		return ArkContainerRuntimeInfo{}, nil, errors.New("jar path of the arklet to launch is missing")
+	}
+	port, err := freePort()
+	if err != nil {
+		return ArkContainerRuntimeInfo{}, nil, err
+	}
+
+	java := l.JavaPath
+	if java == "" {
+		java = "java"
+	}
+	command := l.command
+	if command == nil {
+		command = exec.Command
+	}
+	args := append(append([]string{}, l.JVMArgs...), "-D"+arkletPortProperty+"="+strconv.Itoa(port), "-jar", l.JarPath)
+	cmd := command(java, args...)
+	cmd.Stdout = l.Output
+	cmd.Stderr = l.Output
+	if err := cmd.Start(); err != nil {
+		return ArkContainerRuntimeInfo{}, nil, fmt.Errorf("launch arklet failed: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	var once sync.Once
+	cleanup := func() error {
+		once.Do(func() {
+			_ = cmd.Process.Kill()
+			<-exited
+		})
+		return nil
+	}
+
+	timeout := l.StartTimeout
+	if timeout <= 0 {
+		timeout = defaultArkletStartTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(arkletStartPollInterval)
+	defer ticker.Stop()
+	for !probeArklet(ctx, http.DefaultClient, port) {
+		select {
+		case err := <-exited:
+			return ArkContainerRuntimeInfo{}, nil, fmt.Errorf("arklet exited before being healthy: %v", err)
+		case <-ctx.Done():
+			_ = cleanup()
+			return ArkContainerRuntimeInfo{}, nil, fmt.Errorf("wait for arklet on port %d to be healthy: %w", port, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	return ArkContainerRuntimeInfo{
+		RunType:    ArkContainerRunTypeLocal,
+		Coordinate: "127.0.0.1",
+		Port:       &port,
+	}, cleanup, nil
+}
@@ -0,0 +1,104 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// launcherHelperEnv makes the test binary act as the launched arklet, see TestLocalArkletLauncher_HelperProcess.
+const launcherHelperEnv = "ARKCTL_LAUNCHER_HELPER"
+
+// TestLocalArkletLauncher_HelperProcess isn't a real test, it's the stub arklet launched by the other tests,
+// serving the health on the port of the jvm system property, or exiting at once if the helper mode is exit.
+func TestLocalArkletLauncher_HelperProcess(t *testing.T) {
+	mode := os.Getenv(launcherHelperEnv)
+	if mode == "" {
+		return
+	}
+	if mode == "exit" {
+		os.Exit(3)
+	}
+
+	port := ""
+	for _, arg := range os.Args {
+		if value, ok := strings.CutPrefix(arg, "-D"+arkletPortProperty+"="); ok {
+			port = value
+		}
+	}
+	_ = http.ListenAndServe("127.0.0.1:"+port, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"healthData":{"arkVersion":"2.2.5"}}}`))
+	}))
+	os.Exit(0)
+}
+
+// stubArkletLauncher launch the test binary in place of java.
+func stubArkletLauncher(mode string) *LocalArkletLauncher {
+	return &LocalArkletLauncher{
+		JarPath:      "base.jar",
+		StartTimeout: 10 * time.Second,
+		command: func(name string, args ...string) *exec.Cmd {
+			cmd := exec.Command(os.Args[0], append([]string{"-test.run=TestLocalArkletLauncher_HelperProcess", "--", name}, args...)...)
+			cmd.Env = append(os.Environ(), launcherHelperEnv+"="+mode)
+			return cmd
+		},
+	}
+}
+
+func TestLocalArkletLauncher_Stub(t *testing.T) {
+	ctx := context.Background()
+	target, cleanup, err := stubArkletLauncher("serve").Launch(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, ArkContainerRunTypeLocal, target.RunType)
+	assert.True(t, probeArklet(ctx, http.DefaultClient, target.GetPort()))
+
+	version, err := BuildService(ctx).QueryVersion(ctx, target)
+	assert.Nil(t, err)
+	assert.Equal(t, "2.2.5", version)
+
+	assert.Nil(t, cleanup())
+	assert.False(t, probeArklet(ctx, http.DefaultClient, target.GetPort()))
+	// cleanup is idempotent
+	assert.Nil(t, cleanup())
+}
+
+func TestLocalArkletLauncher_ExitedBeforeHealthy(t *testing.T) {
+	_, cleanup, err := stubArkletLauncher("exit").Launch(context.Background())
+	assert.Nil(t, cleanup)
+	assert.ErrorContains(t, err, "arklet exited before being healthy: exit status 3")
+}
+
+func TestLocalArkletLauncher_Jar(t *testing.T) {
+	jarPath := os.Getenv(ArkletJarEnv)
+	if jarPath == "" {
+		t.Skipf("%s is not set", ArkletJarEnv)
+	}
+
+	ctx := context.Background()
+	target, cleanup, err := (&LocalArkletLauncher{JarPath: jarPath, Output: os.Stderr}).Launch(ctx)
+	assert.Nil(t, err)
+	defer cleanup()
+
+	_, err = BuildService(ctx).QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: target.GetPort()})
+	assert.Nil(t, err)
+}
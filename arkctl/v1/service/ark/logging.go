@@ -0,0 +1,150 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redactedUrl return the url safe to log, only the scheme and host are kept for remote urls,
+// as the userinfo, the path and the query of them may carry credentials like presigned tokens.
+// The local urls like file:// are kept without the query.
+func redactedUrl(fileUrl fileutil.FileUrl) string {
+	if fileUrl == "" {
+		return ""
+	}
+	parsed, err := url.Parse(string(fileUrl))
+	if err != nil {
+		return "<invalid url>"
+	}
+	if parsed.Host == "" {
+		parsed.RawQuery = ""
+		parsed.Fragment = ""
+		return parsed.String()
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// targetString return the identity of the ark container, e.g. 127.0.0.1:1238, default/base-0:1238, unix:/run/arklet.sock,
+// or default/base-0/sidecar:1239 for the arklet in the sidecar container of the pod.
+func targetString(target ArkContainerRuntimeInfo) string {
+	host := target.Coordinate
+	if target.RunType == ArkContainerRunTypeK8s && target.Container != "" {
+		host += "/" + target.Container
+	}
+	if target.RunType == ArkContainerRunTypeLocal {
+		host = localHostName(target)
+		if target.SocketPath != "" {
+			return "unix:" + target.SocketPath
+		}
+	}
+	if target.SocketPath != "" {
+		return host + " unix:" + target.SocketPath
+	}
+	return net.JoinHostPort(host, strconv.Itoa(target.GetPort()))
+}
+
+// targetFields return the log fields of the ark container.
+func targetFields(target ArkContainerRuntimeInfo) logrus.Fields {
+	fields := logrus.Fields{
+		"runType": target.RunType,
+		"target":  targetString(target),
+	}
+	if target.RunType == ArkContainerRunTypeK8s && target.Container != "" {
+		fields["container"] = target.Container
+	}
+	return fields
+}
+
+// bizFields return the log fields of the biz, the values of env and extra params are left out as they may be secrets.
+func bizFields(bizModel BizModel) logrus.Fields {
+	fields := logrus.Fields{
+		"bizName":    bizModel.BizName,
+		"bizVersion": bizModel.BizVersion,
+	}
+	if bizModel.BizUrl != "" {
+		fields["bizUrl"] = redactedUrl(bizModel.BizUrl)
+	}
+	if bizModel.MainClass != "" {
+		fields["mainClass"] = bizModel.MainClass
+	}
+	if len(bizModel.Env) > 0 {
+		fields["envKeys"] = sortedKeys(bizModel.Env)
+	}
+	if len(bizModel.Args) > 0 {
+		fields["args"] = len(bizModel.Args)
+	}
+	return fields
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeFields merge the fields into one, the later ones win.
+func mergeFields(fields ...logrus.Fields) logrus.Fields {
+	merged := logrus.Fields{}
+	for _, f := range fields {
+		for key, value := range f {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// loggableRequest return the fields of the request safe to log.
+func (req InstallBizRequest) loggableRequest() logrus.Fields {
+	fields := mergeFields(bizFields(req.BizModel), targetFields(req.TargetContainer))
+	if len(req.ExtraParams) > 0 {
+		fields["extraParams"] = sortedKeys(req.ExtraParams)
+	}
+	return fields
+}
+
+// loggableRequest return the fields of the request safe to log.
+func (req UnInstallBizRequest) loggableRequest() logrus.Fields {
+	fields := mergeFields(bizFields(req.BizModel), targetFields(req.TargetContainer))
+	fields["drainFirst"] = req.DrainFirst
+	return fields
+}
+
+// loggableRequest return the fields of the request safe to log.
+func (req UploadBizRequest) loggableRequest() logrus.Fields {
+	fields := mergeFields(bizFields(req.BizModel), targetFields(req.TargetContainer))
+	fields["fileName"] = req.FileName
+	fields["size"] = req.Size
+	return fields
+}
+
+// loggableRequest return the fields of the request safe to log.
+func (req QueryAllArkBizRequest) loggableRequest() logrus.Fields {
+	if req.SocketPath != "" {
+		return logrus.Fields{"target": "unix:" + req.SocketPath}
+	}
+	return logrus.Fields{"target": fmt.Sprintf("%s:%d", req.HostName, req.Port)}
+}
@@ -0,0 +1,95 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// tailFile return the last n lines of the file.
+func tailFile(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// Read the configured log file of local arklet.
+func (h *service) tailArkletLogsOnLocal(_ context.Context, _ ArkContainerRuntimeInfo, lines int) ([]string, error) {
+	if h.options.ArkletLogFile == "" {
+		return nil, errors.New("arklet log file is not configured")
+	}
+	return tailFile(h.options.ArkletLogFile, lines)
+}
+
+// Use kubectl logs to tail the logs of the pod.
+func (h *service) tailArkletLogsInPod(ctx context.Context, target ArkContainerRuntimeInfo, lines int) ([]string, error) {
+	namespace, podName, err := parsePodCoordinate(target.Coordinate)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"-n", namespace, "logs", podName, fmt.Sprintf("--tail=%d", lines)}
+	if target.Container != "" {
+		args = append(args, "-c", target.Container)
+	}
+	return h.kubectl(ctx, target, args...)
+}
+
+func (h *service) TailArkletLogs(ctx context.Context, target ArkContainerRuntimeInfo, lines int) (logs []string, err error) {
+	if target, err = targetOrDefault(ctx, target); err != nil {
+		return nil, err
+	}
+	logger := contextutil.GetLogger(ctx).WithFields(targetFields(target))
+	logger.WithField("lines", lines).Info("tail arklet logs started")
+	defer func() {
+		if err != nil {
+			logger.Error(err)
+		} else {
+			logger.Info("tail arklet logs completed")
+		}
+	}()
+
+	if lines <= 0 {
+		return nil, nil
+	}
+
+	switch target.RunType {
+	case ArkContainerRunTypeLocal:
+		logs, err = h.tailArkletLogsOnLocal(ctx, target, lines)
+	case ArkContainerRunTypeK8s:
+		logs, err = h.tailArkletLogsInPod(ctx, target, lines)
+	default:
+		err = fmt.Errorf("tail arklet logs is not supported for run type: %s", target.RunType)
+	}
+	return
+}
@@ -0,0 +1,74 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTailArkletLogs_Pod(t *testing.T) {
+	ctx := context.Background()
+
+	var command []string
+	client := BuildService(ctx, WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		command = append([]string{cmd}, args...)
+		return []string{"install biz started", "install biz failed"}, nil
+	}))
+
+	logs, err := client.TailArkletLogs(ctx, ArkContainerRuntimeInfo{
+		RunType:    ArkContainerRunTypeK8s,
+		Coordinate: "default/base-0",
+	}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"install biz started", "install biz failed"}, logs)
+	assert.Equal(t, []string{"kubectl", "-n", "default", "logs", "base-0", "--tail=2"}, command)
+}
+
+func TestTailArkletLogs_InvalidPodCoordinate(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		t.Fatal("should not run command")
+		return nil, nil
+	}))
+
+	_, err := client.TailArkletLogs(ctx, ArkContainerRuntimeInfo{
+		RunType:    ArkContainerRunTypeK8s,
+		Coordinate: "base-0",
+	}, 10)
+	assert.NotNil(t, err)
+}
+
+func TestTailArkletLogs_Local(t *testing.T) {
+	ctx := context.Background()
+	logFile := filepath.Join(t.TempDir(), "arklet.log")
+	assert.Nil(t, os.WriteFile(logFile, []byte("line1\nline2\nline3\n"), 0644))
+
+	client := BuildService(ctx, WithArkletLogFile(logFile))
+	logs, err := client.TailArkletLogs(ctx, ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+	}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"line2", "line3"}, logs)
+
+	_, err = BuildService(ctx).TailArkletLogs(ctx, ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+	}, 2)
+	assert.NotNil(t, err)
+}
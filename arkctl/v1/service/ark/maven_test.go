@@ -0,0 +1,109 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockMavenRepository(t *testing.T, files map[string][]byte, username, password string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username != "" {
+			if user, pass, ok := r.BasicAuth(); !ok || user != username || pass != password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		content, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveFromMaven_Release(t *testing.T) {
+	server := mockMavenRepository(t, map[string][]byte{
+		"/maven2/com/alipay/sofa/biz1/1.0.0/biz1-1.0.0-ark-biz.jar": buildTestJar("biz1", "1.0.0"),
+	}, "", "")
+
+	req, err := ResolveFromMaven(context.Background(), "com.alipay.sofa:biz1:1.0.0:jar:ark-biz", server.URL+"/maven2/")
+	assert.Nil(t, err)
+	assert.Equal(t, BizModel{
+		BizName:    "biz1",
+		BizVersion: "1.0.0",
+		BizUrl:     fileutil.FileUrl(server.URL + "/maven2/com/alipay/sofa/biz1/1.0.0/biz1-1.0.0-ark-biz.jar"),
+	}, req.BizModel)
+}
+
+func TestResolveFromMaven_AuthenticatedSnapshot(t *testing.T) {
+	server := mockMavenRepository(t, map[string][]byte{
+		"/com/alipay/sofa/biz1/1.0.0-SNAPSHOT/maven-metadata.xml": []byte(`<metadata><versioning>
+<snapshot><timestamp>20231011.080000</timestamp><buildNumber>2</buildNumber></snapshot>
+<snapshotVersions>
+<snapshotVersion><extension>pom</extension><value>1.0.0-20231011.080000-2</value></snapshotVersion>
+<snapshotVersion><classifier>ark-biz</classifier><extension>jar</extension><value>1.0.0-20231011.080000-2</value></snapshotVersion>
+</snapshotVersions></versioning></metadata>`),
+		"/com/alipay/sofa/biz1/1.0.0-SNAPSHOT/biz1-1.0.0-20231011.080000-2-ark-biz.jar": buildTestJar("biz1", "1.0.0-SNAPSHOT"),
+	}, "deployer", "s3cret")
+	repoBaseUrl := strings.Replace(server.URL, "http://", "http://deployer:s3cret@", 1)
+
+	req, err := ResolveFromMaven(context.Background(), "com.alipay.sofa:biz1:1.0.0-SNAPSHOT:jar:ark-biz", repoBaseUrl)
+	assert.Nil(t, err)
+	assert.Equal(t, "biz1", req.BizModel.BizName)
+	assert.Equal(t, "1.0.0-SNAPSHOT", req.BizModel.BizVersion)
+
+	// the credentials don't leave arkctl, the jar fetched is installed
+	assert.True(t, strings.HasPrefix(string(req.BizModel.BizUrl), "file://"))
+	localPath := strings.TrimPrefix(string(req.BizModel.BizUrl), "file://")
+	_, err = os.Stat(localPath)
+	assert.Nil(t, err)
+	_ = os.Remove(localPath)
+
+	_, err = ResolveFromMaven(context.Background(), "com.alipay.sofa:biz1:1.0.0-SNAPSHOT:jar:ark-biz",
+		strings.Replace(server.URL, "http://", "http://deployer:wrong@", 1))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "check the credentials")
+	assert.NotContains(t, err.Error(), "wrong")
+}
+
+func TestResolveFromMaven_NotFound(t *testing.T) {
+	server := mockMavenRepository(t, map[string][]byte{}, "", "")
+
+	_, err := ResolveFromMaven(context.Background(), "com.alipay.sofa:biz1:1.0.0", server.URL)
+	assert.True(t, errors.Is(err, fileutil.ErrMavenArtifactNotFound))
+
+	// non unique snapshots have no maven-metadata.xml
+	_, err = ResolveFromMaven(context.Background(), "com.alipay.sofa:biz1:1.0.0-SNAPSHOT", server.URL)
+	assert.True(t, errors.Is(err, fileutil.ErrMavenArtifactNotFound))
+
+	_, err = ResolveFromMaven(context.Background(), "com.alipay.sofa:biz1", server.URL)
+	assert.NotNil(t, err)
+	_, err = ResolveFromMaven(context.Background(), "com.alipay.sofa:biz1:1.0.0", "ftp://repo")
+	assert.NotNil(t, err)
+}
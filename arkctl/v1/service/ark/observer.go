@@ -0,0 +1,59 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// Observer receives the duration and outcome of operations, e.g. to export them as prometheus metrics.
+// Observers are called synchronously after the operation, they should return quickly.
+type Observer interface {
+	// ObserveInstall is called after installing biz, err is nil if the install succeeded.
+	ObserveInstall(bizName string, duration time.Duration, err error)
+
+	// ObserveUninstall is called after uninstalling biz, err is nil if the uninstall succeeded.
+	ObserveUninstall(bizName string, duration time.Duration, err error)
+}
+
+// NopObserver is the default Observer which does nothing.
+type NopObserver struct{}
+
+func (NopObserver) ObserveInstall(string, time.Duration, error) {}
+
+func (NopObserver) ObserveUninstall(string, time.Duration, error) {}
+
+// observeInstall report the install to the observer, a panicking observer is logged and won't change the result.
+func (h *service) observeInstall(ctx context.Context, bizName string, duration time.Duration, installErr error) {
+	if err := runHook("ObserveInstall", func() error {
+		h.options.Observer.ObserveInstall(bizName, duration, installErr)
+		return nil
+	}); err != nil {
+		contextutil.GetLogger(ctx).Error(err)
+	}
+}
+
+// observeUninstall report the uninstall to the observer, a panicking observer is logged and won't change the result.
+func (h *service) observeUninstall(ctx context.Context, bizName string, duration time.Duration, uninstallErr error) {
+	if err := runHook("ObserveUninstall", func() error {
+		h.options.Observer.ObserveUninstall(bizName, duration, uninstallErr)
+		return nil
+	}); err != nil {
+		contextutil.GetLogger(ctx).Error(err)
+	}
+}
@@ -0,0 +1,93 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingObserver record the observations by duration bucket like a prometheus histogram.
+type recordingObserver struct {
+	buckets      []time.Duration
+	observations []string
+}
+
+func (o *recordingObserver) bucket(duration time.Duration) time.Duration {
+	for _, bucket := range o.buckets {
+		if duration <= bucket {
+			return bucket
+		}
+	}
+	return -1
+}
+
+func (o *recordingObserver) record(operation, bizName string, duration time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	o.observations = append(o.observations, operation+" "+bizName+" "+outcome+" le="+o.bucket(duration).String())
+}
+
+func (o *recordingObserver) ObserveInstall(bizName string, duration time.Duration, err error) {
+	o.record("install", bizName, duration, err)
+}
+
+func (o *recordingObserver) ObserveUninstall(bizName string, duration time.Duration, err error) {
+	o.record("uninstall", bizName, duration, err)
+}
+
+func TestObserver(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/installBiz":
+			time.Sleep(300 * time.Millisecond)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "SUCCESS"})
+		case "/uninstallBiz":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "FAILED", "message": "biz not found"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer cancel()
+
+	observer := &recordingObserver{buckets: []time.Duration{200 * time.Millisecond, 5 * time.Second}}
+	client := BuildService(ctx, WithObserver(observer))
+	target := ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}
+	bizModel := BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz.jar"}
+
+	assert.Nil(t, client.InstallBiz(ctx, InstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+	assert.NotNil(t, client.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+	assert.Equal(t, []string{
+		"install biz success le=5s",
+		"uninstall biz failure le=200ms",
+	}, observer.observations)
+}
+
+func TestObserver_Nil(t *testing.T) {
+	options := defaultClientOptions()
+	WithObserver(nil)(&options)
+	assert.Equal(t, NopObserver{}, options.Observer)
+}
@@ -0,0 +1,597 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/cmdutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/k8sutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/progressutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/v1/constant"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ClientOptions is the options used to build a Service.
+type ClientOptions struct {
+	// RetryCount is the max retry times of a failed request, 0 means no retry.
+	RetryCount int
+
+	// RetryWaitTime is the wait time before the first retry, it grows exponentially for later retries.
+	RetryWaitTime time.Duration
+
+	// DefaultTimeout bounds each request whose context has no deadline, so that it never hangs on a dead connection.
+	// The deadline of the context always wins, and the timeout is disabled if it's not positive.
+	DefaultTimeout time.Duration
+
+	// DefaultWaitTimeout bounds the waits for a biz state whose WaitOptions.Timeout isn't positive and whose context
+	// has no deadline, so that a biz never reaching the state isn't polled forever. It's disabled if it's not positive.
+	DefaultWaitTimeout time.Duration
+
+	// EnableIdempotencyKey will send an Idempotency-Key header with install requests,
+	// so that arklet or a proxy can dedupe the retries of the same install.
+	EnableIdempotencyKey bool
+
+	// KeepTempFiles keeps the biz bundles downloaded to temp files for debugging,
+	// otherwise they are removed once used.
+	KeepTempFiles bool
+
+	// OnUploadProgress is called periodically with the sent bytes and the total bytes while uploading biz bundles.
+	// The total is -1 if the size of bundle is unknown.
+	//
+	// Deprecated: use UploadProgress, the callback is reported through it along with UploadProgress.
+	OnUploadProgress func(bytesSent, total int64)
+
+	// UploadProgress is started with the size of each biz bundle uploaded, and updated with the sent bytes.
+	UploadProgress progressutil.ProgressReporter
+
+	// QueryBizCacheTTL is how long a QueryBiz result is reused for the same biz in the same container.
+	// The cache is disabled if it's not positive.
+	QueryBizCacheTTL time.Duration
+
+	// QueryAllBizCacheTTL is how long the biz listed in the same container are reused, by QueryAllBiz or in pods,
+	// the result is dropped once the container is mutated by the client. The cache is disabled if it's not positive.
+	QueryAllBizCacheTTL time.Duration
+
+	// QueryAllBizCacheSize is the max containers whose QueryAllBiz results are cached, the least recently used are evicted.
+	QueryAllBizCacheSize int
+
+	// RateLimitQPS is the max requests per second sent by the client, the limit is disabled if it's not positive.
+	RateLimitQPS float64
+
+	// RateLimitBurst is the max requests could be sent at once when the client is idle.
+	RateLimitBurst int
+
+	// HistoryCapacity is the max operations kept in the history of Service.History, the history is disabled if it's not positive.
+	HistoryCapacity int
+
+	// Hooks are called around install and uninstall in order.
+	Hooks []Hooks
+
+	// ArkletLogFile is the log file of the local arklet, used to tail arklet logs of local containers.
+	ArkletLogFile string
+
+	// CommandRunner runs the external commands like kubectl.
+	CommandRunner cmdutil.Runner
+
+	// KubeConfig selects the cluster for the pod run type, kubectl defaults are used if not given.
+	KubeConfig *k8sutil.Config
+
+	// KubeConfigLoader resolves the kube config of the targets with their own Kubeconfig or KubeContext,
+	// k8sutil.BuildConfig by default.
+	KubeConfigLoader func(opts k8sutil.Options) (*k8sutil.Config, error)
+
+	// DisableKeepAlives opens a fresh connection per request,
+	// which helps when the idle connections are silently dropped by middleboxes.
+	DisableKeepAlives bool
+
+	// Drain controls how biz are drained before uninstall when DrainFirst is requested.
+	Drain DrainOptions
+
+	// IncludeErrorDetail includes the stack trace responded by arklet in the message of ResponseError,
+	// otherwise it's only available via ResponseError.ErrorDetail.
+	IncludeErrorDetail bool
+
+	// BasePath is prefixed onto every arklet endpoint, for arklets mounted behind a gateway like /arklet.
+	BasePath string
+
+	// EndpointResolver builds the urls of arklet endpoints, BasePath is ignored if it's given.
+	EndpointResolver EndpointResolver
+
+	// EndpointOverrides remaps the path and method of the operations, the overridden paths are passed
+	// to EndpointResolver in place of the endpoints. NewService fails if any of them is invalid.
+	EndpointOverrides map[Operation]EndpointOverride
+
+	// InstallPath replaces the installBiz endpoint, e.g. /v1/installBiz, the default one is used if it's empty.
+	// It's a shorthand of the EndpointOverrides of OperationInstall with POST, which wins if both are given.
+	InstallPath string
+
+	// UninstallPath replaces the uninstallBiz endpoint, e.g. /v1/uninstallBiz, the default one is used if it's empty.
+	// It's a shorthand of the EndpointOverrides of OperationUninstall with POST, which wins if both are given.
+	UninstallPath string
+
+	// Observer receives the duration and outcome of install and uninstall.
+	Observer Observer
+
+	// Tracer starts a span per install and uninstall, tracing is disabled if it's nil.
+	Tracer trace.Tracer
+
+	// ResponseEnvelope controls how the arklet responses wrapped by gateways are unwrapped.
+	ResponseEnvelope EnvelopeMode
+
+	// UserAgent is sent with every request to arklet, arkctl/{version} by default.
+	UserAgent string
+
+	// ProxyURL is the proxy of both arklet requests and biz bundle downloads, overriding HTTP_PROXY and HTTPS_PROXY.
+	ProxyURL string
+
+	// RawMethods are the http methods allowed by Raw, only GET and POST are allowed if it's nil.
+	RawMethods []string
+
+	// TargetGroups are the named target groups addressed by GroupTargets.
+	TargetGroups map[string]TargetGroup
+
+	// EndpointProvider provides the targets of the operations on AllTargets, which address no target or group.
+	EndpointProvider EndpointProvider
+
+	// RequestEncoding controls how the request bodies are encoded, json by default.
+	RequestEncoding RequestEncoding
+
+	// DetectPodPort detects the arklet port of the pod targets without a port from the pod,
+	// by ArkletPortAnnotation or the container ports named ArkletPortName. It requires the permission to get the pods.
+	DetectPodPort bool
+
+	// JSONContentType is the Content-Type sent explicitly with the json bodies, application/json if it's empty.
+	// Some gateways insist on a variant like application/json;charset=UTF-8.
+	JSONContentType string
+
+	// Dialect is the json field names spoken by the arklets, DialectArk by default.
+	Dialect Dialect
+
+	// BizUrlProbeTimeout bounds the probe of InstallBizRequest.ProbeBizUrl, 5s if it's not positive.
+	BizUrlProbeTimeout time.Duration
+
+	// UploadCompression controls whether the biz bundles are gzip compressed when uploading.
+	UploadCompression CompressionMode
+
+	// InlineBufferSize is the max bytes of the biz bundle InstallBizInline buffers in memory, 8MiB by default.
+	// The bundles not seekable and within the size are buffered, so that they could be sent again without compression
+	// if arklet rejects the compressed one. The larger bundles are streamed once, and nothing is buffered if it's not positive.
+	InlineBufferSize int64
+
+	// Socks5Addr is the host:port of the SOCKS5 proxy every connection is dialed through, e.g. the one of the VPC.
+	// It's dialed before the ssh jump hosts, and the http proxy if any is dialed through it too.
+	Socks5Addr string
+
+	// Socks5Auth authenticates to the SOCKS5 proxy, no auth is done if it's nil.
+	Socks5Auth *Socks5Auth
+
+	// SSHJumpHosts are the ssh servers every connection is tunneled through in order, e.g. a bastion in front of the VPC.
+	// The connections to the unix sockets are dialed directly.
+	SSHJumpHosts []SSHJumpHost
+
+	// NoProxy is the hosts bypassing the proxy in the format of NO_PROXY, overriding NO_PROXY if it's not nil.
+	// The loopback targets like 127.0.0.1 always bypass the proxy.
+	NoProxy []string
+
+	// Redirect controls how the redirects issued by arklet gateways are followed.
+	Redirect RedirectOptions
+
+	// CommandQueue submits install and uninstall through a message queue instead of calling arklet directly,
+	// it's disabled by default. The other operations still call arklet directly.
+	CommandQueue CommandQueueOptions
+
+	// BaseContext bounds the lifetime of the Service, which is closed once the context is done.
+	// Nil means the Service lives until Close.
+	BaseContext context.Context
+}
+
+// DrainOptions controls the drain phase of uninstall.
+type DrainOptions struct {
+	// Wait is how long to wait after draining if arklet doesn't report the in flight requests, 5s by default.
+	Wait time.Duration
+
+	// Timeout is the max time of the drain phase, independent of the uninstall timeout, 30s by default.
+	Timeout time.Duration
+
+	// PollInterval is the interval of polling the in flight requests, 1s by default.
+	PollInterval time.Duration
+}
+
+// RedirectOptions controls the redirects followed by the client.
+type RedirectOptions struct {
+	// MaxRedirects is the max redirects followed per request, redirects are not followed if it's not positive,
+	// in which case the redirect response fails the request.
+	MaxRedirects int
+
+	// PreservePost keeps the method and body of POST requests redirected by 301 and 302, as every arklet endpoint
+	// accepts POST only. Otherwise they are switched to GET without body following the browsers.
+	// The method and body are always kept for 307 and 308.
+	PreservePost bool
+}
+
+// defaultUserAgent identifies the requests sent by arkctl in the access logs of arklet.
+var defaultUserAgent = "arkctl/" + constant.Version
+
+// Option configures the ClientOptions.
+type Option func(options *ClientOptions)
+
+// defaultDrainOptions are used for the fields of DrainOptions which are not positive.
+var defaultDrainOptions = DrainOptions{
+	Wait:         5 * time.Second,
+	Timeout:      30 * time.Second,
+	PollInterval: time.Second,
+}
+
+func defaultClientOptions() ClientOptions {
+	return ClientOptions{
+		RetryWaitTime:        100 * time.Millisecond,
+		DefaultTimeout:       5 * time.Minute,
+		DefaultWaitTimeout:   10 * time.Minute,
+		QueryAllBizCacheSize: 128,
+		HistoryCapacity:      defaultHistoryCapacity,
+		CommandRunner:        cmdutil.RunCommand,
+		KubeConfigLoader:     k8sutil.BuildConfig,
+		Observer:             NopObserver{},
+		ResponseEnvelope:     EnvelopeAuto,
+		UserAgent:            defaultUserAgent,
+		UploadCompression:    CompressionOff,
+		InlineBufferSize:     defaultInlineBufferSize,
+		RequestEncoding:      EncodingJSON,
+		Dialect:              DialectArk,
+		Drain:                defaultDrainOptions,
+		Redirect: RedirectOptions{
+			MaxRedirects: 10,
+			PreservePost: true,
+		},
+	}
+}
+
+// WithRetry enables retrying failed requests up to count times.
+func WithRetry(count int, waitTime time.Duration) Option {
+	return func(options *ClientOptions) {
+		options.RetryCount = count
+		options.RetryWaitTime = waitTime
+	}
+}
+
+// WithDefaultTimeout set the timeout of the requests whose context has no deadline, 0 disables it.
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(options *ClientOptions) {
+		options.DefaultTimeout = timeout
+	}
+}
+
+// WithDefaultWaitTimeout set the timeout of the waits for a biz state without their own timeout, 0 disables it.
+func WithDefaultWaitTimeout(timeout time.Duration) Option {
+	return func(options *ClientOptions) {
+		options.DefaultWaitTimeout = timeout
+	}
+}
+
+// WithIdempotencyKey enables sending Idempotency-Key header with install requests.
+func WithIdempotencyKey(enable bool) Option {
+	return func(options *ClientOptions) {
+		options.EnableIdempotencyKey = enable
+	}
+}
+
+// WithKeepTempFiles keeps the downloaded temp files of biz bundles.
+func WithKeepTempFiles(keep bool) Option {
+	return func(options *ClientOptions) {
+		options.KeepTempFiles = keep
+	}
+}
+
+// WithUploadProgress reports the progress of uploading biz bundles to onProgress.
+//
+// Deprecated: use WithUploadProgressReporter, progressutil.FromFunc adapts onProgress to a ProgressReporter.
+func WithUploadProgress(onProgress func(bytesSent, total int64)) Option {
+	return func(options *ClientOptions) {
+		options.OnUploadProgress = onProgress
+	}
+}
+
+// WithUploadProgressReporter reports the progress of uploading biz bundles to reporter.
+func WithUploadProgressReporter(reporter progressutil.ProgressReporter) Option {
+	return func(options *ClientOptions) {
+		options.UploadProgress = reporter
+	}
+}
+
+// WithQueryBizCache caches QueryBiz results for ttl, use WithCacheBypass to skip the cache per call.
+func WithQueryBizCache(ttl time.Duration) Option {
+	return func(options *ClientOptions) {
+		options.QueryBizCacheTTL = ttl
+	}
+}
+
+// WithQueryAllBizCache caches QueryAllBiz results for ttl in at most maxTargets containers,
+// use QueryAllArkBizRequest.ForceRefresh or WithCacheBypass to skip the cache per call.
+func WithQueryAllBizCache(ttl time.Duration, maxTargets int) Option {
+	return func(options *ClientOptions) {
+		options.QueryAllBizCacheTTL = ttl
+		options.QueryAllBizCacheSize = maxTargets
+	}
+}
+
+// WithRateLimit limits the outgoing requests to qps with burst, shared by all goroutines using the client.
+func WithRateLimit(qps float64, burst int) Option {
+	return func(options *ClientOptions) {
+		options.RateLimitQPS = qps
+		options.RateLimitBurst = burst
+	}
+}
+
+// WithHistoryCapacity keeps at most capacity operations in the history, the oldest are evicted first.
+// The history is disabled if capacity is not positive.
+func WithHistoryCapacity(capacity int) Option {
+	return func(options *ClientOptions) {
+		options.HistoryCapacity = capacity
+	}
+}
+
+// WithHooks registers hooks around install and uninstall, hooks registered earlier run first.
+func WithHooks(hooks Hooks) Option {
+	return func(options *ClientOptions) {
+		options.Hooks = append(options.Hooks, hooks)
+	}
+}
+
+// WithArkletLogFile sets the log file of the local arklet.
+func WithArkletLogFile(path string) Option {
+	return func(options *ClientOptions) {
+		options.ArkletLogFile = path
+	}
+}
+
+// WithCommandRunner replaces the runner of external commands, mostly used in tests.
+func WithCommandRunner(runner cmdutil.Runner) Option {
+	return func(options *ClientOptions) {
+		options.CommandRunner = runner
+	}
+}
+
+// WithKubeConfig sets the kube config used to access pods.
+func WithKubeConfig(config *k8sutil.Config) Option {
+	return func(options *ClientOptions) {
+		options.KubeConfig = config
+	}
+}
+
+// WithKubeConfigLoader replaces the loader of the per target kube config, mostly used in tests.
+func WithKubeConfigLoader(loader func(opts k8sutil.Options) (*k8sutil.Config, error)) Option {
+	return func(options *ClientOptions) {
+		options.KubeConfigLoader = loader
+	}
+}
+
+// WithEndpointProvider set the provider of the targets addressed by AllTargets, see EndpointProvider.
+func WithEndpointProvider(provider EndpointProvider) Option {
+	return func(options *ClientOptions) {
+		options.EndpointProvider = provider
+	}
+}
+
+// WithTargetGroups registers the named target groups, e.g. the groups defined in the config profile.
+func WithTargetGroups(groups map[string]TargetGroup) Option {
+	return func(options *ClientOptions) {
+		options.TargetGroups = groups
+	}
+}
+
+// WithRawMethods sets the http methods allowed by Raw, e.g. DELETE for the plugin endpoints requiring it.
+func WithRawMethods(methods ...string) Option {
+	return func(options *ClientOptions) {
+		options.RawMethods = methods
+	}
+}
+
+// WithRequestEncoding sets how the request bodies are encoded, e.g. form for the arklets expecting form bodies.
+func WithRequestEncoding(encoding RequestEncoding) Option {
+	return func(options *ClientOptions) {
+		options.RequestEncoding = encoding
+	}
+}
+
+// WithPodPortDetection enables detecting the arklet port of the pod targets without a port, see DetectPodPort.
+func WithPodPortDetection(enable bool) Option {
+	return func(options *ClientOptions) {
+		options.DetectPodPort = enable
+	}
+}
+
+// WithJSONContentType overrides the Content-Type of the json bodies, e.g. application/json;charset=UTF-8 for strict gateways.
+func WithJSONContentType(contentType string) Option {
+	return func(options *ClientOptions) {
+		options.JSONContentType = contentType
+	}
+}
+
+// WithDialect sets the json field names spoken by the arklets, e.g. DialectShort for the forks using name over bizName.
+func WithDialect(dialect Dialect) Option {
+	return func(options *ClientOptions) {
+		options.Dialect = dialect
+	}
+}
+
+// WithBizUrlProbeTimeout bounds the probe of InstallBizRequest.ProbeBizUrl by timeout.
+func WithBizUrlProbeTimeout(timeout time.Duration) Option {
+	return func(options *ClientOptions) {
+		options.BizUrlProbeTimeout = timeout
+	}
+}
+
+// WithDisableKeepAlives disables the connection reuse between requests.
+func WithDisableKeepAlives(disable bool) Option {
+	return func(options *ClientOptions) {
+		options.DisableKeepAlives = disable
+	}
+}
+
+// WithDrain sets how biz are drained before uninstall, the fields which are not positive keep the defaults.
+func WithDrain(drain DrainOptions) Option {
+	return func(options *ClientOptions) {
+		if drain.Wait <= 0 {
+			drain.Wait = defaultDrainOptions.Wait
+		}
+		if drain.Timeout <= 0 {
+			drain.Timeout = defaultDrainOptions.Timeout
+		}
+		if drain.PollInterval <= 0 {
+			drain.PollInterval = defaultDrainOptions.PollInterval
+		}
+		options.Drain = drain
+	}
+}
+
+// WithErrorDetail includes the stack trace responded by arklet in the error message.
+func WithErrorDetail(include bool) Option {
+	return func(options *ClientOptions) {
+		options.IncludeErrorDetail = include
+	}
+}
+
+// WithBasePath prefixes every arklet endpoint with basePath, e.g. /arklet/installBiz.
+func WithBasePath(basePath string) Option {
+	return func(options *ClientOptions) {
+		options.BasePath = basePath
+	}
+}
+
+// WithEndpointResolver builds the urls of arklet endpoints with resolver instead of the DefaultEndpointResolver.
+func WithEndpointResolver(resolver EndpointResolver) Option {
+	return func(options *ClientOptions) {
+		options.EndpointResolver = resolver
+	}
+}
+
+// WithEndpointOverrides remaps the path and method of the operations, e.g. install to PUT /api/v1/biz.
+func WithEndpointOverrides(overrides map[Operation]EndpointOverride) Option {
+	return func(options *ClientOptions) {
+		options.EndpointOverrides = overrides
+	}
+}
+
+// WithInstallPath replaces the installBiz endpoint with path, e.g. /v1/installBiz.
+func WithInstallPath(path string) Option {
+	return func(options *ClientOptions) {
+		options.InstallPath = path
+	}
+}
+
+// WithUninstallPath replaces the uninstallBiz endpoint with path, e.g. /v1/uninstallBiz.
+func WithUninstallPath(path string) Option {
+	return func(options *ClientOptions) {
+		options.UninstallPath = path
+	}
+}
+
+// WithObserver reports the duration and outcome of install and uninstall to observer.
+func WithObserver(observer Observer) Option {
+	return func(options *ClientOptions) {
+		if observer == nil {
+			observer = NopObserver{}
+		}
+		options.Observer = observer
+	}
+}
+
+// WithTracer starts a span per install and uninstall with tracer.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(options *ClientOptions) {
+		options.Tracer = tracer
+	}
+}
+
+// WithResponseEnvelope controls how the arklet responses wrapped by gateways are unwrapped, EnvelopeAuto by default.
+func WithResponseEnvelope(mode EnvelopeMode) Option {
+	return func(options *ClientOptions) {
+		options.ResponseEnvelope = mode
+	}
+}
+
+// WithUserAgent overrides the User-Agent header sent to arklet, the default is used if userAgent is empty.
+func WithUserAgent(userAgent string) Option {
+	return func(options *ClientOptions) {
+		if userAgent == "" {
+			userAgent = defaultUserAgent
+		}
+		options.UserAgent = userAgent
+	}
+}
+
+// WithSocks5 dials every connection through the SOCKS5 proxy at addr, auth is optional.
+func WithSocks5(addr string, auth *Socks5Auth) Option {
+	return func(options *ClientOptions) {
+		options.Socks5Addr = addr
+		options.Socks5Auth = auth
+	}
+}
+
+// WithSSHJumpHosts tunnels every connection through the ssh jump hosts in order, like ssh -J.
+func WithSSHJumpHosts(hosts ...SSHJumpHost) Option {
+	return func(options *ClientOptions) {
+		options.SSHJumpHosts = hosts
+	}
+}
+
+// WithProxy sends requests through proxyURL except the noProxy hosts, overriding the proxy env vars.
+// An empty proxyURL keeps the proxy of env vars and only overrides NO_PROXY.
+// The loopback arklets like 127.0.0.1 and localhost, which are the default host of local targets, are always
+// requested directly, only the arklets on other hosts like a remote Coordinate go through the proxy.
+func WithProxy(proxyURL string, noProxy ...string) Option {
+	return func(options *ClientOptions) {
+		options.ProxyURL = proxyURL
+		options.NoProxy = noProxy
+	}
+}
+
+// WithUploadCompression controls whether the biz bundles are gzip compressed when uploading, CompressionOff by default.
+func WithUploadCompression(mode CompressionMode) Option {
+	return func(options *ClientOptions) {
+		options.UploadCompression = mode
+	}
+}
+
+// WithInlineBufferSize set the max bytes of the biz bundle InstallBizInline buffers in memory, see InlineBufferSize.
+func WithInlineBufferSize(size int64) Option {
+	return func(options *ClientOptions) {
+		options.InlineBufferSize = size
+	}
+}
+
+// WithRedirect set how the redirects issued by arklet gateways are followed, see RedirectOptions.
+func WithRedirect(redirect RedirectOptions) Option {
+	return func(options *ClientOptions) {
+		options.Redirect = redirect
+	}
+}
+
+// WithBaseContext close the Service once ctx is done, the calls afterwards fail with ErrClientClosed.
+func WithBaseContext(ctx context.Context) Option {
+	return func(options *ClientOptions) {
+		options.BaseContext = ctx
+	}
+}
+
+// WithCommandQueue submit install and uninstall through the queue of the publisher, see CommandQueueOptions.
+func WithCommandQueue(queue CommandQueueOptions) Option {
+	return func(options *ClientOptions) {
+		options.CommandQueue = queue
+	}
+}
@@ -0,0 +1,234 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/k8sutil"
+)
+
+// parsePodCoordinate split the {namespace}/{podName} coordinate of pod.
+func parsePodCoordinate(coordinate string) (namespace, podName string, err error) {
+	namespace, podName, found := strings.Cut(coordinate, "/")
+	if !found || namespace == "" || podName == "" {
+		return "", "", fmt.Errorf("invalid pod coordinate %q, expected {namespace}/{podName}", coordinate)
+	}
+	return namespace, podName, nil
+}
+
+// podExecArgs return the args of kubectl exec running command in the container of target.
+func podExecArgs(target ArkContainerRuntimeInfo, namespace, podName string, command ...string) []string {
+	args := []string{"-n", namespace, "exec", podName}
+	if target.Container != "" {
+		args = append(args, "-c", target.Container)
+	}
+	return append(append(args, "--"), command...)
+}
+
+// kubeConfigOf return the kube config of the cluster the pod of target is running in,
+// which is loaded from the Kubeconfig and KubeContext of target if given, otherwise the client's kube config.
+func (h *service) kubeConfigOf(target ArkContainerRuntimeInfo) (*k8sutil.Config, error) {
+	if target.Kubeconfig == "" && target.KubeContext == "" {
+		return h.options.KubeConfig, nil
+	}
+
+	opts := k8sutil.Options{
+		Kubeconfig: target.Kubeconfig,
+		Context:    target.KubeContext,
+	}
+	if base := h.options.KubeConfig; base != nil {
+		opts.Impersonate = base.Impersonate
+		// only switch the context in the kubeconfig explicitly selected by the client
+		if opts.Kubeconfig == "" && (base.Source == k8sutil.ConfigSourceFlag || base.Source == k8sutil.ConfigSourceHome) {
+			opts.Kubeconfig = base.KubeconfigPaths[0]
+		}
+	}
+	config, err := h.options.KubeConfigLoader(opts)
+	if err != nil {
+		return nil, fmt.Errorf("load kube config of %s failed: %w", target.Coordinate, err)
+	}
+	return config, nil
+}
+
+// podObject is the part of the pod object read by kubectl get.
+type podObject struct {
+	Metadata struct {
+		Name            string            `json:"name"`
+		ResourceVersion string            `json:"resourceVersion"`
+		Annotations     map[string]string `json:"annotations"`
+	} `json:"metadata"`
+
+	Spec struct {
+		Containers []struct {
+			Name  string `json:"name"`
+			Ports []struct {
+				Name          string `json:"name"`
+				ContainerPort int    `json:"containerPort"`
+			} `json:"ports"`
+		} `json:"containers"`
+	} `json:"spec"`
+}
+
+// getPod read the pod of target by kubectl get.
+func (h *service) getPod(ctx context.Context, target ArkContainerRuntimeInfo) (*podObject, error) {
+	namespace, podName, err := parsePodCoordinate(target.Coordinate)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := h.kubectl(ctx, target, "-n", namespace, "get", "pod", podName, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	pod := &podObject{}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), pod); err != nil {
+		return nil, fmt.Errorf("decode pod %s failed: %w", target.Coordinate, err)
+	}
+	return pod, nil
+}
+
+// kubectl run kubectl against the cluster the pod of target is running in.
+func (h *service) kubectl(ctx context.Context, target ArkContainerRuntimeInfo, args ...string) ([]string, error) {
+	if h.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	config, err := h.kubeConfigOf(target)
+	if err != nil {
+		return nil, err
+	}
+	return h.options.CommandRunner(ctx, "kubectl", config.KubectlArgs(args...)...)
+}
+
+// postInPod use kubectl exec to call curl inside the pod, and return the response body.
+// In this way, the implementation won't be overwhelmed with complicated 7 layers of k8s service
+// The constraint is that user requires with CA or token to access k8s cluster exec.
+func (h *service) postInPod(ctx context.Context, target ArkContainerRuntimeInfo, endpoint Endpoint, body interface{}) ([]byte, error) {
+	namespace, podName, err := parsePodCoordinate(target.Coordinate)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := h.podPort(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := h.encoderOf(h.arkletKey(target))
+	encoded, err := h.encodeBody(encoder, body)
+	if err != nil {
+		return nil, err
+	}
+	curlArgs := []string{
+		"curl", "-s",
+		"-X", h.endpointMethod(endpoint),
+		"-H", "Content-Type: " + h.contentTypeOf(encoder),
+		"-A", h.options.UserAgent,
+		"-d", string(encoded),
+	}
+	if target.SocketPath != "" {
+		curlArgs = append(curlArgs, "--unix-socket", target.SocketPath)
+	}
+	curlArgs = append(curlArgs, h.endpointUrl("127.0.0.1", port, endpoint))
+
+	ctx, cancel := withDefaultTimeout(ctx, h.options.DefaultTimeout)
+	defer cancel()
+
+	args := podExecArgs(target, namespace, podName, curlArgs...)
+	start := time.Now()
+	lines, err := h.kubectl(ctx, target, args...)
+	respBody := []byte(strings.Join(lines, "\n"))
+	recordStats(ctx, time.Since(start), int64(len(respBody)))
+	if err != nil {
+		return nil, err
+	}
+	return h.options.Dialect.toArk(unwrapEnvelope(h.options.ResponseEnvelope, respBody)), nil
+}
+
+// Use kubectl exec to install biz in pod, the biz url must be accessible inside the pod.
+func (h *service) installBizInPod(ctx context.Context, req InstallBizRequest) error {
+	body, err := installBizBody(req.BizModel, req.ExtraParams)
+	if err != nil {
+		return err
+	}
+
+	respBody, err := h.postInPod(ctx, req.TargetContainer, EndpointInstallBiz, body)
+	if err != nil {
+		return err
+	}
+
+	return decodeArkResponse(ctx, h, "install biz", 0, "", respBody, &InstallBizResponse{})
+}
+
+// Use kubectl exec to uninstall biz in pod, existed is false if the biz was already absent
+func (h *service) unInstallBizInPod(ctx context.Context, req UnInstallBizRequest) (existed bool, err error) {
+	respBody, err := h.postInPod(ctx, req.TargetContainer, EndpointUnInstallBiz, req.BizModel)
+	if err != nil {
+		return false, err
+	}
+
+	uninstallResponse := &UnInstallBizResponse{}
+	err = decodeArkResponse(ctx, h, "uninstall biz", 0, "", respBody, uninstallResponse)
+	if IsNotFound(uninstallResponse.ArkResponseBase) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Use kubectl exec to query all biz in pod
+func (h *service) queryAllBizInPod(ctx context.Context, target ArkContainerRuntimeInfo) ([]ArkBizInfo, error) {
+	cacheKey := containerCacheKey(target)
+	if h.queryAllBizCache != nil && !isCacheBypassed(ctx) {
+		if cached, ok := h.queryAllBizCache.get(cacheKey); ok {
+			return cached.Data, nil
+		}
+	}
+
+	respBody, err := h.postInPod(ctx, target, EndpointQueryAllBiz, struct{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	queryAllBizResponse := &QueryAllArkBizResponse{}
+	if err := json.Unmarshal(respBody, queryAllBizResponse); err != nil {
+		return nil, err
+	}
+
+	if !queryAllBizResponse.Code.IsSuccess() {
+		return nil, h.newResponseError("query all biz", queryAllBizResponse.Code, queryAllBizResponse.Message, respBody)
+	}
+	if h.queryAllBizCache != nil {
+		h.queryAllBizCache.put(cacheKey, queryAllBizResponse)
+	}
+	return queryAllBizResponse.Data, nil
+}
+
+// Use kubectl exec to query all biz in pod, and find the biz from the result.
+func (h *service) queryBizInPod(ctx context.Context, target ArkContainerRuntimeInfo, bizName, bizVersion string) (*BizDetail, error) {
+	allBiz, err := h.queryAllBizInPod(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range allBiz {
+		if info.BizName == bizName && (bizVersion == "" || info.BizVersion == bizVersion) {
+			return &BizDetail{ArkBizInfo: info}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s:%s", ErrBizNotFound, bizName, bizVersion)
+}
@@ -0,0 +1,155 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// ArkletPortAnnotation is the pod annotation declaring the arklet port, which wins over the container ports.
+	ArkletPortAnnotation = "serverless.alipay.com/arklet-port"
+
+	// ArkletContainerPortAnnotationPrefix prefixes the container name to the pod annotation declaring the arklet port
+	// of the container, e.g. serverless.alipay.com/arklet-port.sidecar, for the pods running an arklet in each of
+	// their containers. It wins over ArkletPortAnnotation for the container.
+	ArkletContainerPortAnnotationPrefix = ArkletPortAnnotation + "."
+
+	// ArkletPortName is the name of the container port of arklet, used to detect the port from the pod spec.
+	ArkletPortName = "arklet"
+
+	// defaultArkletPort is the port arklet listens on by default.
+	defaultArkletPort = 1238
+)
+
+type podPortCacheKey struct{}
+
+// podPortCache caches the detected arklet ports by pod.
+type podPortCache struct {
+	ports sync.Map
+}
+
+// WithPodPortCache return a context caching the arklet ports detected from the pods,
+// so that a batch of operations inspects each pod only once. The batch operations of the Service apply it by themselves.
+func WithPodPortCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(podPortCacheKey{}).(*podPortCache); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, podPortCacheKey{}, &podPortCache{})
+}
+
+// parseAnnotatedPort parse the port declared by the annotation.
+func parseAnnotatedPort(annotation, value string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid %s annotation %q", annotation, value)
+	}
+	return port, nil
+}
+
+// containerArkletPorts return the arklet ports of the containers declared by ArkletContainerPortAnnotationPrefix.
+func containerArkletPorts(pod *podObject) (map[string]int, error) {
+	ports := map[string]int{}
+	for annotation, value := range pod.Metadata.Annotations {
+		container, ok := strings.CutPrefix(annotation, ArkletContainerPortAnnotationPrefix)
+		if !ok || container == "" {
+			continue
+		}
+		port, err := parseAnnotatedPort(annotation, value)
+		if err != nil {
+			return nil, err
+		}
+		ports[container] = port
+	}
+	return ports, nil
+}
+
+// detectArkletPort return the arklet port of the container, the default container if it's empty,
+// declared by the annotations or the container ports named ArkletPortName, the default port if none is declared.
+// The annotation of the container wins over ArkletPortAnnotation, which wins over the container ports.
+// Different ports named ArkletPortName are ambiguous, only the ports of the container are checked if it's given.
+func detectArkletPort(pod *podObject, containerName string) (int, error) {
+	if containerName != "" {
+		annotation := ArkletContainerPortAnnotationPrefix + containerName
+		if annotated, ok := pod.Metadata.Annotations[annotation]; ok {
+			return parseAnnotatedPort(annotation, annotated)
+		}
+	}
+	if annotated, ok := pod.Metadata.Annotations[ArkletPortAnnotation]; ok {
+		return parseAnnotatedPort(ArkletPortAnnotation, annotated)
+	}
+
+	candidates := map[int][]string{}
+	for _, container := range pod.Spec.Containers {
+		if containerName != "" && container.Name != containerName {
+			continue
+		}
+		for _, port := range container.Ports {
+			if port.Name == ArkletPortName {
+				candidates[port.ContainerPort] = append(candidates[port.ContainerPort], container.Name)
+			}
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return defaultArkletPort, nil
+	case 1:
+		for port := range candidates {
+			return port, nil
+		}
+	}
+
+	listed := make([]string, 0, len(candidates))
+	for port, containers := range candidates {
+		listed = append(listed, fmt.Sprintf("%d (%s)", port, strings.Join(containers, ",")))
+	}
+	sort.Strings(listed)
+	return 0, fmt.Errorf("%w: ports named %s: %s, set the port or the %s annotation",
+		ErrAmbiguousArkletPort, ArkletPortName, strings.Join(listed, ", "), ArkletPortAnnotation)
+}
+
+// podPort return the arklet port of the pod target, which is detected from the pod if the port isn't given
+// and DetectPodPort is enabled, otherwise the given or the default port.
+func (h *service) podPort(ctx context.Context, target ArkContainerRuntimeInfo) (int, error) {
+	if target.Port != nil || target.SocketPath != "" || !h.options.DetectPodPort {
+		return target.GetPort(), nil
+	}
+
+	cache, _ := ctx.Value(podPortCacheKey{}).(*podPortCache)
+	key := target.Kubeconfig + "|" + target.KubeContext + "|" + target.Coordinate + "|" + target.Container
+	if cache != nil {
+		if port, ok := cache.ports.Load(key); ok {
+			return port.(int), nil
+		}
+	}
+
+	pod, err := h.getPod(ctx, target)
+	if err != nil {
+		return 0, fmt.Errorf("detect arklet port of %s failed: %w", target.Coordinate, err)
+	}
+	port, err := detectArkletPort(pod, target.Container)
+	if err != nil {
+		return 0, fmt.Errorf("detect arklet port of %s failed: %w", target.Coordinate, err)
+	}
+	if cache != nil {
+		cache.ports.Store(key, port)
+	}
+	return port, nil
+}
@@ -0,0 +1,175 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePod decode the pod object in the json of kubectl get.
+func fakePod(t *testing.T, raw string) *podObject {
+	pod := &podObject{}
+	assert.Nil(t, json.Unmarshal([]byte(raw), pod))
+	return pod
+}
+
+func TestDetectArkletPort(t *testing.T) {
+	tests := []struct {
+		name      string
+		pod       string
+		container string
+		port      int
+		err       string
+	}{
+		{
+			name: "named port",
+			pod:  `{"spec":{"containers":[{"name":"base","ports":[{"name":"http","containerPort":8080},{"name":"arklet","containerPort":1239}]}]}}`,
+			port: 1239,
+		},
+		{
+			name: "annotation wins over named port",
+			pod: `{"metadata":{"annotations":{"serverless.alipay.com/arklet-port":"1240"}},` +
+				`"spec":{"containers":[{"name":"base","ports":[{"name":"arklet","containerPort":1239}]}]}}`,
+			port: 1240,
+		},
+		{
+			name: "same port named in sidecar",
+			pod: `{"spec":{"containers":[{"name":"base","ports":[{"name":"arklet","containerPort":1239}]},` +
+				`{"name":"sidecar","ports":[{"name":"arklet","containerPort":1239}]}]}}`,
+			port: 1239,
+		},
+		{
+			name: "default port",
+			pod:  `{"spec":{"containers":[{"name":"base","ports":[{"name":"http","containerPort":8080}]}]}}`,
+			port: 1238,
+		},
+		{
+			name: "ambiguous",
+			pod: `{"spec":{"containers":[{"name":"base","ports":[{"name":"arklet","containerPort":1239}]},` +
+				`{"name":"canary","ports":[{"name":"arklet","containerPort":1240}]}]}}`,
+			err: "arklet port of the pod is ambiguous: ports named arklet: 1239 (base), 1240 (canary), " +
+				"set the port or the serverless.alipay.com/arklet-port annotation",
+		},
+		{
+			name: "annotation of the container",
+			pod: `{"metadata":{"annotations":{"serverless.alipay.com/arklet-port":"1238","serverless.alipay.com/arklet-port.sidecar":"1239"}},` +
+				`"spec":{"containers":[{"name":"base"},{"name":"sidecar"}]}}`,
+			container: "sidecar",
+			port:      1239,
+		},
+		{
+			name: "named port of the container",
+			pod: `{"spec":{"containers":[{"name":"base","ports":[{"name":"arklet","containerPort":1239}]},` +
+				`{"name":"canary","ports":[{"name":"arklet","containerPort":1240}]}]}}`,
+			container: "canary",
+			port:      1240,
+		},
+		{
+			name:      "invalid annotation of the container",
+			pod:       `{"metadata":{"annotations":{"serverless.alipay.com/arklet-port.sidecar":"0"}}}`,
+			container: "sidecar",
+			err:       `invalid serverless.alipay.com/arklet-port.sidecar annotation "0"`,
+		},
+		{
+			name: "invalid annotation",
+			pod:  `{"metadata":{"annotations":{"serverless.alipay.com/arklet-port":"arklet"}}}`,
+			err:  `invalid serverless.alipay.com/arklet-port annotation "arklet"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			port, err := detectArkletPort(fakePod(t, test.pod), test.container)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.port, port)
+		})
+	}
+}
+
+// mockPodWithPorts fake kubectl serving the pod, the urls curled by exec and the count of get are recorded.
+func mockPodWithPorts(pod string, urls *[]string, gets *int) Option {
+	return WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		switch args[2] {
+		case "get":
+			*gets++
+			return strings.Split(pod, "\n"), nil
+		case "exec":
+			*urls = append(*urls, args[len(args)-1])
+			return []string{`{"code":"SUCCESS"}`}, nil
+		}
+		return nil, errors.New("unexpected command")
+	})
+}
+
+func TestPodPortDetection(t *testing.T) {
+	ctx := context.Background()
+	pod := `{"spec":{"containers":[{"name":"base","ports":[{"name":"arklet","containerPort":1239}]}]}}`
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"}
+
+	var urls []string
+	gets := 0
+	client := BuildService(ctx, WithPodPortDetection(true), mockPodWithPorts(pod, &urls, &gets))
+	reqs := []InstallBizRequest{
+		{BizModel: BizModel{BizName: "biz1", BizVersion: "0.0.1"}, TargetContainer: target, AllowMasterBiz: true},
+		{BizModel: BizModel{BizName: "biz2", BizVersion: "0.0.1"}, TargetContainer: target, AllowMasterBiz: true},
+	}
+	_, err := InstallBatch(ctx, client, reqs, BatchInstallOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"http://127.0.0.1:1239/installBiz", "http://127.0.0.1:1239/installBiz"}, urls)
+	// the pod is inspected once for the batch
+	assert.Equal(t, 1, gets)
+
+	// the given port isn't detected
+	urls, gets = nil, 0
+	port := 1238
+	target.Port = &port
+	assert.Nil(t, client.InstallBiz(ctx, InstallBizRequest{BizModel: reqs[0].BizModel, TargetContainer: target, AllowMasterBiz: true}))
+	assert.Equal(t, []string{"http://127.0.0.1:1238/installBiz"}, urls)
+	assert.Equal(t, 0, gets)
+
+	// the detection is disabled by default
+	urls = nil
+	client = BuildService(ctx, mockPodWithPorts(pod, &urls, &gets))
+	target.Port = nil
+	assert.Nil(t, client.InstallBiz(ctx, InstallBizRequest{BizModel: reqs[0].BizModel, TargetContainer: target, AllowMasterBiz: true}))
+	assert.Equal(t, []string{"http://127.0.0.1:1238/installBiz"}, urls)
+	assert.Equal(t, 0, gets)
+}
+
+func TestPodPortDetection_Ambiguous(t *testing.T) {
+	ctx := context.Background()
+	pod := `{"spec":{"containers":[{"name":"base","ports":[{"name":"arklet","containerPort":1239}]},` +
+		`{"name":"canary","ports":[{"name":"arklet","containerPort":1240}]}]}}`
+	var urls []string
+	gets := 0
+	client := BuildService(ctx, WithPodPortDetection(true), mockPodWithPorts(pod, &urls, &gets))
+
+	err := client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+		AllowMasterBiz:  true,
+	})
+	assert.True(t, errors.Is(err, ErrAmbiguousArkletPort))
+	assert.Empty(t, urls)
+}
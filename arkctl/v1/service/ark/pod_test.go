@@ -0,0 +1,197 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/k8sutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallBiz_Pod(t *testing.T) {
+	ctx := context.Background()
+
+	var command []string
+	client := BuildService(ctx, WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		command = append([]string{cmd}, args...)
+		return []string{`{"code":"SUCCESS"}`}, nil
+	}))
+
+	port := 1239
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1",
+			BizUrl:     "file:///tmp/biz.jar",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType:    ArkContainerRunTypeK8s,
+			Coordinate: "default/base-0",
+			Port:       &port,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"kubectl", "-n", "default", "exec", "base-0", "--",
+		"curl", "-s", "-X", "POST", "-H", "Content-Type: application/json", "-A", "arkctl/0.0.1",
+		"-d", `{"bizName":"biz","bizVersion":"0.0.1","bizUrl":"file:///tmp/biz.jar"}`,
+		"http://127.0.0.1:1239/installBiz",
+	}, command)
+}
+
+func TestQueryBiz_Pod(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		return []string{`{"code":"SUCCESS","data":[{"bizName":"biz","bizVersion":"0.0.1","bizState":"ACTIVATED"}]}`}, nil
+	}))
+	target := ArkContainerRuntimeInfo{
+		RunType:    ArkContainerRunTypeK8s,
+		Coordinate: "default/base-0",
+	}
+
+	detail, err := client.QueryBiz(ctx, target, "biz", "0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, BizStateActivated, detail.BizState)
+
+	_, err = client.QueryBiz(ctx, target, "biz", "0.0.2")
+	assert.True(t, errors.Is(err, ErrBizNotFound))
+}
+
+func TestQueryBiz_PodCachedUntilMutated(t *testing.T) {
+	ctx := context.Background()
+
+	queries := 0
+	client := BuildService(ctx, WithQueryAllBizCache(time.Minute, 8), WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		if strings.HasSuffix(args[len(args)-1], "/queryAllBiz") {
+			queries++
+			return []string{`{"code":"SUCCESS","data":[{"bizName":"biz","bizVersion":"0.0.1","bizState":"ACTIVATED"}]}`}, nil
+		}
+		return []string{`{"code":"SUCCESS"}`}, nil
+	}))
+	port := 1239
+	target := ArkContainerRuntimeInfo{
+		RunType:    ArkContainerRunTypeK8s,
+		Coordinate: "default/base-0",
+		Port:       &port,
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.QueryBiz(ctx, target, "biz", "0.0.1")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 1, queries)
+
+	err := client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: target,
+	})
+	assert.Nil(t, err)
+	_, err = client.QueryBiz(ctx, target, "biz", "0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, queries)
+}
+
+func TestQueryAllBiz_PodWithBasePath(t *testing.T) {
+	ctx := context.Background()
+
+	var url string
+	client := BuildService(ctx, WithBasePath("/arklet"), WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		url = args[len(args)-1]
+		return []string{`{"code":"SUCCESS","data":[]}`}, nil
+	}))
+
+	_, err := client.QueryBiz(ctx, ArkContainerRuntimeInfo{
+		RunType:    ArkContainerRunTypeK8s,
+		Coordinate: "default/base-0",
+	}, "biz", "0.0.1")
+	assert.True(t, errors.Is(err, ErrBizNotFound))
+	assert.Equal(t, "http://127.0.0.1:1238/arklet/queryAllBiz", url)
+}
+
+func TestPod_TargetKubeConfig(t *testing.T) {
+	ctx := context.Background()
+
+	var loaded []k8sutil.Options
+	loader := func(opts k8sutil.Options) (*k8sutil.Config, error) {
+		loaded = append(loaded, opts)
+		if opts.Context == "missing" {
+			return nil, errors.New("kube context not found")
+		}
+		return &k8sutil.Config{
+			Source:          k8sutil.ConfigSourceFlag,
+			KubeconfigPaths: []string{opts.Kubeconfig},
+			Context:         opts.Context,
+			Impersonate:     opts.Impersonate,
+		}, nil
+	}
+
+	var commands [][]string
+	client := BuildService(ctx,
+		WithKubeConfig(&k8sutil.Config{
+			Source:          k8sutil.ConfigSourceHome,
+			KubeconfigPaths: []string{"/root/.kube/config"},
+			Context:         "default-cluster",
+			Impersonate:     "admin",
+		}),
+		WithKubeConfigLoader(loader),
+		WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+			commands = append(commands, args)
+			return []string{`{"code":"SUCCESS","data":[]}`}, nil
+		}))
+
+	tests := []struct {
+		target         ArkContainerRuntimeInfo
+		expectedLoaded []k8sutil.Options
+		expectedFlags  []string
+	}{
+		{
+			// the client's kube config
+			target:        ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+			expectedFlags: []string{"--kubeconfig", "/root/.kube/config", "--context", "default-cluster", "--as", "admin"},
+		},
+		{
+			target: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0",
+				Kubeconfig: "/etc/cluster-b.yaml", KubeContext: "cluster-b"},
+			expectedLoaded: []k8sutil.Options{{Kubeconfig: "/etc/cluster-b.yaml", Context: "cluster-b", Impersonate: "admin"}},
+			expectedFlags:  []string{"--kubeconfig", "/etc/cluster-b.yaml", "--context", "cluster-b", "--as", "admin"},
+		},
+		{
+			// switch the context in the kubeconfig of the client
+			target:         ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0", KubeContext: "cluster-c"},
+			expectedLoaded: []k8sutil.Options{{Kubeconfig: "/root/.kube/config", Context: "cluster-c", Impersonate: "admin"}},
+			expectedFlags:  []string{"--kubeconfig", "/root/.kube/config", "--context", "cluster-c", "--as", "admin"},
+		},
+	}
+	for _, test := range tests {
+		loaded, commands = nil, nil
+		_, err := client.(*service).queryAllBizInPod(ctx, test.target)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedLoaded, loaded)
+		assert.Equal(t, 1, len(commands))
+		assert.Equal(t, test.expectedFlags, commands[0][:len(test.expectedFlags)])
+		assert.Equal(t, []string{"-n", "default", "exec", "base-0", "--"}, commands[0][len(test.expectedFlags):len(test.expectedFlags)+5])
+	}
+
+	commands = nil
+	_, err := client.(*service).queryAllBizInPod(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0", KubeContext: "missing"})
+	assert.NotNil(t, err)
+	assert.Empty(t, commands)
+}
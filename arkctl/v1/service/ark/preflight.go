@@ -0,0 +1,193 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"strings"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+)
+
+// PreflightReport is the result of the static checks of a biz jar.
+// Errors make the install fail for sure, while Warnings are suspicious but may be fine.
+type PreflightReport struct {
+	// BizName is the Ark-Biz-Name declared in the manifest.
+	BizName string
+
+	// BizVersion is the Ark-Biz-Version declared in the manifest.
+	BizVersion string
+
+	// ArkVersion is the Ark-Version declared in the manifest, empty if it's not declared.
+	ArkVersion string
+
+	// ContainerArkVersion is the ark version of the target container, empty if it's unknown.
+	ContainerArkVersion string
+
+	// Errors are the problems that the biz can't be installed with.
+	Errors []string
+
+	// Warnings are the problems that the biz may not work with.
+	Warnings []string
+}
+
+// OK return true if the report has no errors.
+func (r *PreflightReport) OK() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *PreflightReport) errorf(format string, args ...interface{}) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+func (r *PreflightReport) warnf(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// PreflightError is returned by InstallBiz if the preflight check of the biz jar fails.
+type PreflightError struct {
+	BizUrl fileutil.FileUrl
+	Report *PreflightReport
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("preflight check of %s failed: %s", e.BizUrl, strings.Join(e.Report.Errors, "; "))
+}
+
+// PreflightCheck inspects the biz jar given by bizUrl without installing it.
+// If target is given, the Ark-Version declared by the jar is checked against the ark version of the container.
+// The returned error is only about failing to run the checks, the problems found are listed in the report.
+func (h *service) PreflightCheck(ctx context.Context, bizUrl fileutil.FileUrl, target *ArkContainerRuntimeInfo) (*PreflightReport, error) {
+	report := &PreflightReport{}
+	err := withLocalJar(h.withDownloadProxy(ctx), bizUrl, h.options.KeepTempFiles, func(_ *fileutil.LocalizedFile, zipReader *zip.ReadCloser) error {
+		manifest, err := readJarManifest(zipReader)
+		if err != nil {
+			return err
+		}
+		checkJarManifest(report, manifest)
+		checkJarLayout(report, zipReader)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if target != nil {
+		health, err := h.queryHealth(ctx, *target)
+		if err != nil {
+			report.warnf("failed to query the ark version of the container: %v", err)
+		} else {
+			report.ContainerArkVersion = health.HealthData.ArkVersion
+		}
+		checkArkVersion(report)
+	}
+	return report, nil
+}
+
+// checkJarManifest checks the entries required by arklet exist in the manifest.
+func checkJarManifest(report *PreflightReport, manifest map[string]string) {
+	if manifest == nil {
+		report.errorf("META-INF/MANIFEST.MF is missing")
+		return
+	}
+	report.BizName = manifest["Ark-Biz-Name"]
+	report.BizVersion = manifest["Ark-Biz-Version"]
+	report.ArkVersion = manifest["Ark-Version"]
+
+	if report.BizName == "" {
+		report.errorf("Ark-Biz-Name is missing in META-INF/MANIFEST.MF, make sure it's built with the ark-biz classifier")
+	}
+	if report.BizVersion == "" {
+		report.errorf("Ark-Biz-Version is missing in META-INF/MANIFEST.MF")
+	}
+	if manifest["Main-Class"] == "" && manifest["Start-Class"] == "" {
+		report.warnf("neither Main-Class nor Start-Class is declared in META-INF/MANIFEST.MF")
+	}
+}
+
+// checkJarLayout flags the jars missing the classes or the nested libs, which are usually packaged wrongly.
+func checkJarLayout(report *PreflightReport, zipReader *zip.ReadCloser) {
+	hasClasses := false
+	hasLibs := false
+	for _, fileInfo := range zipReader.File {
+		name := fileInfo.Name
+		switch {
+		case strings.HasPrefix(name, "classes/") && strings.HasSuffix(name, ".class"):
+			hasClasses = true
+		case strings.HasPrefix(name, "lib/") && strings.HasSuffix(name, ".jar"):
+			hasLibs = true
+		}
+	}
+	if !hasClasses {
+		report.warnf("no classes found under classes/, the biz may be packaged without its own code")
+	}
+	if !hasLibs {
+		report.warnf("no nested jars found under lib/, the biz may be packaged without its dependencies")
+	}
+}
+
+// checkArkVersion checks the ark version declared by the biz is compatible with the container.
+// The major versions must be the same, and a biz built with a newer minor version may use missing features.
+func checkArkVersion(report *PreflightReport) {
+	if report.ArkVersion == "" {
+		return
+	}
+	if report.ContainerArkVersion == "" {
+		report.warnf("ark version of the container is unknown, can't check the compatibility with Ark-Version %s", report.ArkVersion)
+		return
+	}
+
+	bizMajor, bizMinor, bizOk := parseMajorMinor(report.ArkVersion)
+	containerMajor, containerMinor, containerOk := parseMajorMinor(report.ContainerArkVersion)
+	switch {
+	case !bizOk || !containerOk:
+		report.warnf("can't compare Ark-Version %s with the container ark version %s",
+			report.ArkVersion, report.ContainerArkVersion)
+	case bizMajor != containerMajor:
+		report.errorf("Ark-Version %s is incompatible with the container ark version %s",
+			report.ArkVersion, report.ContainerArkVersion)
+	case bizMinor > containerMinor:
+		report.warnf("Ark-Version %s is newer than the container ark version %s",
+			report.ArkVersion, report.ContainerArkVersion)
+	}
+}
+
+// parseMajorMinor parse the major and minor numbers of a version like 2.2.3-SNAPSHOT.
+func parseMajorMinor(version string) (major, minor int, ok bool) {
+	numbers, ok := parseVersion(version)
+	if !ok || len(numbers) < 2 {
+		return 0, 0, false
+	}
+	return numbers[0], numbers[1], true
+}
+
+// preflightInstall run PreflightCheck for the install request, the warnings are only logged.
+func (h *service) preflightInstall(ctx context.Context, req InstallBizRequest) error {
+	report, err := h.PreflightCheck(ctx, req.BizModel.BizUrl, &req.TargetContainer)
+	if err != nil {
+		return fmt.Errorf("preflight check of %s: %w", req.BizModel.BizUrl, err)
+	}
+	logger := contextutil.GetLogger(ctx)
+	for _, warning := range report.Warnings {
+		logger.Warn("preflight: ", warning)
+	}
+	if !report.OK() {
+		return &PreflightError{BizUrl: req.BizModel.BizUrl, Report: report}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writePreflightJar write a jar with given manifest and entries to a temp dir, and return its url.
+func writePreflightJar(t *testing.T, manifest string, entries ...string) fileutil.FileUrl {
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+	manifestFile, err := zipWriter.Create("META-INF/MANIFEST.MF")
+	assert.Nil(t, err)
+	_, _ = io.WriteString(manifestFile, manifest)
+	for _, entry := range entries {
+		_, err := zipWriter.Create(entry)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zipWriter.Close())
+
+	jarPath := filepath.Join(t.TempDir(), "biz-ark-biz.jar")
+	assert.Nil(t, os.WriteFile(jarPath, buf.Bytes(), 0644))
+	return fileutil.FileUrl("file://" + jarPath)
+}
+
+const preflightManifest = "Ark-Biz-Name: biz\r\nArk-Biz-Version: 0.0.1\r\nArk-Version: 2.2.\r\n 3\r\nMain-Class: com.alipay.Main\r\n\r\n"
+
+func TestPreflightCheck_Clean(t *testing.T) {
+	bizUrl := writePreflightJar(t, preflightManifest, "classes/com/alipay/Main.class", "lib/foo.jar")
+
+	report, err := BuildService(context.Background()).PreflightCheck(context.Background(), bizUrl, nil)
+	assert.Nil(t, err)
+	assert.True(t, report.OK())
+	assert.Empty(t, report.Warnings)
+	assert.Equal(t, "biz", report.BizName)
+	assert.Equal(t, "0.0.1", report.BizVersion)
+	assert.Equal(t, "2.2.3", report.ArkVersion)
+}
+
+func TestPreflightCheck_MissingManifestEntriesAndLayout(t *testing.T) {
+	bizUrl := writePreflightJar(t, "Manifest-Version: 1.0\n", "BOOT-INF/classes/Main.class")
+
+	report, err := BuildService(context.Background()).PreflightCheck(context.Background(), bizUrl, nil)
+	assert.Nil(t, err)
+	assert.False(t, report.OK())
+	assert.Len(t, report.Errors, 2)
+	assert.Contains(t, report.Errors[0], "Ark-Biz-Name is missing")
+	assert.Contains(t, report.Errors[1], "Ark-Biz-Version is missing")
+	assert.Len(t, report.Warnings, 3)
+}
+
+func TestPreflightCheck_ArkVersion(t *testing.T) {
+	tests := []struct {
+		containerVersion string
+		wantErrors       int
+		wantWarnings     int
+	}{
+		{containerVersion: "2.2.5", wantErrors: 0, wantWarnings: 0},
+		{containerVersion: "2.1.0", wantErrors: 0, wantWarnings: 1},
+		{containerVersion: "1.9.0", wantErrors: 1, wantWarnings: 0},
+		{containerVersion: "", wantErrors: 0, wantWarnings: 1},
+	}
+	bizUrl := writePreflightJar(t, preflightManifest, "classes/com/alipay/Main.class", "lib/foo.jar")
+
+	for _, test := range tests {
+		t.Run(test.containerVersion, func(t *testing.T) {
+			port, cancel := mockHttpServer("/health", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"healthData":{"arkVersion":"` + test.containerVersion + `"}}}`))
+			})
+			defer cancel()
+
+			report, err := BuildService(context.Background()).PreflightCheck(context.Background(), bizUrl,
+				&ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port})
+			assert.Nil(t, err)
+			assert.Equal(t, test.containerVersion, report.ContainerArkVersion)
+			assert.Len(t, report.Errors, test.wantErrors)
+			assert.Len(t, report.Warnings, test.wantWarnings)
+		})
+	}
+}
+
+func TestInstallBiz_PreflightFailed(t *testing.T) {
+	bizUrl := writePreflightJar(t, "Manifest-Version: 1.0\n")
+
+	var installed bool
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/installBiz" {
+			installed = true
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer cancel()
+
+	err := BuildService(context.Background()).InstallBiz(context.Background(), InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: bizUrl},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		Preflight:       true,
+	})
+
+	var preflightErr *PreflightError
+	assert.True(t, errors.As(err, &preflightErr))
+	assert.False(t, preflightErr.Report.OK())
+	assert.False(t, installed)
+}
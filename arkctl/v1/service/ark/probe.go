@@ -0,0 +1,123 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+)
+
+// defaultBizUrlProbeTimeout bounds the probe of the biz url, which is a single HEAD request or a stat.
+const defaultBizUrlProbeTimeout = 5 * time.Second
+
+// probeBizUrl verify the biz url is downloadable from where the arklet of target runs,
+// ErrBizUrlUnreachableFromTarget is returned with the probe output if it isn't.
+// Only the file and http(s) urls are probed, the others are left to arklet.
+func (h *service) probeBizUrl(ctx context.Context, target ArkContainerRuntimeInfo, bizUrl fileutil.FileUrl) error {
+	parsed, err := bizUrl.Parse()
+	if err != nil {
+		return err
+	}
+	if parsed.Type != fileutil.FileUrlTypeLocal && parsed.Type != fileutil.FileUrlTypeHttp {
+		return nil
+	}
+
+	timeout := h.options.BizUrlProbeTimeout
+	if timeout <= 0 {
+		timeout = defaultBizUrlProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output string
+	switch target.RunType {
+	case ArkContainerRunTypeLocal:
+		output, err = probeBizUrlOnLocal(ctx, bizUrl, parsed)
+	case ArkContainerRunTypeK8s:
+		output, err = h.probeBizUrlInPod(ctx, target, bizUrl, parsed)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s from %s: %v", ErrBizUrlUnreachableFromTarget, redactedUrl(bizUrl), targetString(target), err)
+	}
+	contextutil.GetLogger(ctx).WithField("output", output).Debug("biz url probed")
+	return nil
+}
+
+// probeBizUrlOnLocal probe the biz url from this host, where the local arklet runs.
+func probeBizUrlOnLocal(ctx context.Context, bizUrl fileutil.FileUrl, parsed *fileutil.ParsedFileUrl) (string, error) {
+	if parsed.Type == fileutil.FileUrlTypeLocal {
+		info, err := os.Stat(parsed.Path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %d bytes", parsed.Path, info.Size()), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, string(bizUrl), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Status, checkProbeStatus(resp.StatusCode)
+}
+
+// probeBizUrlInPod probe the biz url inside the pod by kubectl exec, with curl for http(s) urls and ls for file urls.
+func (h *service) probeBizUrlInPod(ctx context.Context, target ArkContainerRuntimeInfo, bizUrl fileutil.FileUrl, parsed *fileutil.ParsedFileUrl) (string, error) {
+	namespace, podName, err := parsePodCoordinate(target.Coordinate)
+	if err != nil {
+		return "", err
+	}
+
+	probeArgs := []string{"ls", "-l", parsed.Path}
+	if parsed.Type == fileutil.FileUrlTypeHttp {
+		probeArgs = []string{"curl", "-sS", "-I", "-L", "-o", "/dev/null", "-w", "%{http_code}", string(bizUrl)}
+	}
+	args := podExecArgs(target, namespace, podName, probeArgs...)
+	lines, err := h.kubectl(ctx, target, args...)
+	output := strings.TrimSpace(strings.Join(lines, "\n"))
+	if err != nil {
+		return output, err
+	}
+	if parsed.Type == fileutil.FileUrlTypeHttp {
+		statusCode, err := strconv.Atoi(output)
+		if err != nil {
+			return output, fmt.Errorf("unexpected curl output %q", output)
+		}
+		return output, checkProbeStatus(statusCode)
+	}
+	return output, nil
+}
+
+// checkProbeStatus accept the success status, and the ones only telling HEAD isn't allowed as the server is reachable.
+func checkProbeStatus(statusCode int) error {
+	if statusCode < 400 || statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented {
+		return nil
+	}
+	return fmt.Errorf("HEAD responded with code %d", statusCode)
+}
@@ -0,0 +1,111 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallBiz_ProbeBizUrlOnLocal(t *testing.T) {
+	ctx := context.Background()
+	var installed int
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/biz.jar":
+			w.WriteHeader(http.StatusOK)
+		case "/slow.jar":
+			time.Sleep(time.Second)
+		case "/installBiz":
+			installed++
+			_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer cancel()
+	baseUrl := "http://127.0.0.1:" + strconv.Itoa(port)
+	client := BuildService(ctx, WithBizUrlProbeTimeout(100*time.Millisecond))
+
+	for bizUrl, reachable := range map[string]bool{
+		baseUrl + "/biz.jar":                                 true,
+		baseUrl + "/missing.jar":                             false,
+		baseUrl + "/slow.jar":                                false,
+		"file://" + filepath.Join(t.TempDir(), "absent.jar"): false,
+	} {
+		installed = 0
+		err := client.InstallBiz(ctx, InstallBizRequest{
+			BizModel:              BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: fileutil.FileUrl(bizUrl)},
+			TargetContainer:       ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+			AllowMultipleVersions: true,
+			ProbeBizUrl:           true,
+		})
+		if reachable {
+			assert.Nil(t, err, bizUrl)
+			assert.Equal(t, 1, installed, bizUrl)
+		} else {
+			assert.ErrorIs(t, err, ErrBizUrlUnreachableFromTarget, bizUrl)
+			assert.Equal(t, 0, installed, bizUrl)
+		}
+	}
+}
+
+func TestInstallBiz_ProbeBizUrlInPod(t *testing.T) {
+	ctx := context.Background()
+
+	var probes [][]string
+	curlOutput, curlErr := "200", error(nil)
+	client := BuildService(ctx, WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		if args[6] == "-sS" {
+			probes = append(probes, args)
+			return []string{curlOutput}, curlErr
+		}
+		return []string{`{"code":"SUCCESS"}`}, nil
+	}))
+	req := InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://10.0.0.1/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+		ProbeBizUrl:     true,
+	}
+
+	assert.Nil(t, client.InstallBiz(ctx, req))
+	assert.Equal(t, [][]string{{"-n", "default", "exec", "base-0", "--",
+		"curl", "-sS", "-I", "-L", "-o", "/dev/null", "-w", "%{http_code}", "http://10.0.0.1/biz.jar"}}, probes)
+
+	curlOutput, curlErr = "000", errors.New("exit status 28: curl: (28) Connection timed out")
+	err := client.InstallBiz(ctx, req)
+	assert.ErrorIs(t, err, ErrBizUrlUnreachableFromTarget)
+	assert.Contains(t, err.Error(), "Connection timed out")
+
+	curlOutput, curlErr = "403", nil
+	err = client.InstallBiz(ctx, req)
+	assert.ErrorIs(t, err, ErrBizUrlUnreachableFromTarget)
+	assert.Contains(t, err.Error(), "code 403")
+
+	// the probe is skipped unless it's asked
+	probes = nil
+	req.ProbeBizUrl = false
+	assert.Nil(t, client.InstallBiz(ctx, req))
+	assert.Empty(t, probes)
+}
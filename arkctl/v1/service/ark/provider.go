@@ -0,0 +1,50 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"fmt"
+)
+
+// EndpointProvider is the discovery source of the arklets, like a registry or a config service,
+// resolved at call time instead of a fixed host and port.
+type EndpointProvider interface {
+	// Endpoints return the ark containers currently known by the provider.
+	Endpoints(ctx context.Context) ([]ArkContainerRuntimeInfo, error)
+}
+
+// StaticEndpoints is the EndpointProvider of a fixed list of ark containers.
+type StaticEndpoints []ArkContainerRuntimeInfo
+
+func (e StaticEndpoints) Endpoints(context.Context) ([]ArkContainerRuntimeInfo, error) {
+	endpoints := make([]ArkContainerRuntimeInfo, 0, len(e))
+	for _, endpoint := range e {
+		endpoints = append(endpoints, endpoint.Clone())
+	}
+	return endpoints, nil
+}
+
+// providedTargets return the endpoints of the provider, an error is returned if the provider has none.
+func providedTargets(ctx context.Context, provider EndpointProvider) ([]ArkContainerRuntimeInfo, error) {
+	endpoints, err := provider.Endpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("resolve endpoints of the provider failed: %w", err)
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("endpoint provider has no targets")
+	}
+	return endpoints, nil
+}
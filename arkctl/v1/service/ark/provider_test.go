@@ -0,0 +1,120 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// funcProvider is the EndpointProvider of a function.
+type funcProvider func(ctx context.Context) ([]ArkContainerRuntimeInfo, error)
+
+func (f funcProvider) Endpoints(ctx context.Context) ([]ArkContainerRuntimeInfo, error) {
+	return f(ctx)
+}
+
+func TestEndpointProvider_FanOut(t *testing.T) {
+	ctx := context.Background()
+	installs := map[int]*atomic.Int32{}
+	endpoints := StaticEndpoints{}
+	for i := 0; i < 3; i++ {
+		installed := &atomic.Int32{}
+		port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/installBiz" {
+				installed.Add(1)
+			}
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[]}`))
+		})
+		defer cancel()
+		installs[port] = installed
+		endpoints = append(endpoints, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port})
+	}
+	installedPorts := func() []int {
+		ports := []int{}
+		for _, endpoint := range endpoints {
+			if installs[*endpoint.Port].Swap(0) > 0 {
+				ports = append(ports, *endpoint.Port)
+			}
+		}
+		return ports
+	}
+	bizModel := BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"}
+
+	client := BuildService(ctx, WithEndpointProvider(endpoints))
+	result, err := client.InstallBizOnTargets(ctx, AllTargets(), bizModel)
+	assert.Nil(t, err)
+	assert.Equal(t, []ArkContainerRuntimeInfo(endpoints), result.Targets)
+	assert.Equal(t, 3, len(result.Results))
+	assert.Equal(t, []int{*endpoints[0].Port, *endpoints[1].Port, *endpoints[2].Port}, installedPorts())
+
+	// a subset of the endpoints
+	skipped := *endpoints[1].Port
+	targets := AllTargets()
+	targets.Filter = func(target ArkContainerRuntimeInfo) bool {
+		return target.GetPort() != skipped
+	}
+	result, err = client.InstallBizOnTargets(ctx, targets, bizModel)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result.Targets))
+	assert.Equal(t, []int{*endpoints[0].Port, *endpoints[2].Port}, installedPorts())
+
+	// the provider is asked at each call
+	calls := 0
+	provider := funcProvider(func(ctx context.Context) ([]ArkContainerRuntimeInfo, error) {
+		calls++
+		return endpoints[calls-1 : calls], nil
+	})
+	client = BuildService(ctx, WithTargetGroups(map[string]TargetGroup{"registry": {Provider: provider}}))
+	for i := 0; i < 2; i++ {
+		_, err = client.InstallBizOnTargets(ctx, GroupTargets("registry"), bizModel)
+		assert.Nil(t, err)
+		assert.Equal(t, []int{*endpoints[i].Port}, installedPorts())
+	}
+}
+
+func TestEndpointProvider_Invalid(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	// no provider of the client
+	_, err := client.ResolveTargets(ctx, AllTargets())
+	assert.NotNil(t, err)
+
+	failed := funcProvider(func(ctx context.Context) ([]ArkContainerRuntimeInfo, error) {
+		return nil, errors.New("registry unavailable")
+	})
+	_, err = client.ResolveTargets(ctx, ProviderTargets(failed))
+	assert.ErrorContains(t, err, "registry unavailable")
+
+	_, err = client.ResolveTargets(ctx, ProviderTargets(StaticEndpoints{}))
+	assert.ErrorContains(t, err, "no targets")
+
+	port := 1238
+	targets := ProviderTargets(StaticEndpoints{{RunType: ArkContainerRunTypeLocal, Port: &port}})
+	targets.Filter = func(ArkContainerRuntimeInfo) bool { return false }
+	_, err = client.ResolveTargets(ctx, targets)
+	assert.ErrorContains(t, err, "none of the 1 targets of provider is selected")
+
+	targets = ProviderTargets(StaticEndpoints{})
+	targets.Group = "staging"
+	_, err = client.ResolveTargets(ctx, targets)
+	assert.ErrorContains(t, err, "only one of")
+}
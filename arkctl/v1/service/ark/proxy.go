@@ -0,0 +1,58 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxyConfig return the proxy config of the client.
+// The HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars are used unless they are overridden by options.
+// The loopback targets like 127.0.0.1 and localhost always bypass the proxy.
+func proxyConfig(options ClientOptions) *httpproxy.Config {
+	config := httpproxy.FromEnvironment()
+	if options.ProxyURL != "" {
+		config.HTTPProxy = options.ProxyURL
+		config.HTTPSProxy = options.ProxyURL
+	}
+	if options.NoProxy != nil {
+		config.NoProxy = strings.Join(options.NoProxy, ",")
+	}
+	return config
+}
+
+// proxyFunc return the proxy function for http.Transport built from options.
+func proxyFunc(options ClientOptions) func(*http.Request) (*url.URL, error) {
+	proxy := proxyConfig(options).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
+// withDownloadProxy apply the proxy overridden by options to the downloads of biz bundles.
+// The downloads follow the env vars by themselves if the proxy isn't overridden.
+func (h *service) withDownloadProxy(ctx context.Context) context.Context {
+	if h.options.ProxyURL == "" && h.options.NoProxy == nil {
+		return ctx
+	}
+	return fileutil.WithDownloadProxy(ctx, h.proxy)
+}
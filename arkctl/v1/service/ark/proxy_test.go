@@ -0,0 +1,146 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newRecordingProxy start a proxy answering every request like arklet, and record the hosts requested through it.
+func newRecordingProxy(t *testing.T) (string, *[]string) {
+	hosts := &[]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hosts = append(*hosts, r.Host)
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, hosts
+}
+
+// clearProxyEnv unset the proxy env vars of the test environment.
+func clearProxyEnv(t *testing.T) {
+	for _, key := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(key, "")
+	}
+}
+
+// remoteArklet resolve every endpoint to the non-loopback host arklet.test, the port is ignored.
+var remoteArklet = WithEndpointResolver(EndpointResolverFunc(func(_ string, _ int, endpoint Endpoint) string {
+	return "http://arklet.test/" + string(endpoint)
+}))
+
+func queryAllBizWithTimeout(client Service, port int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	return err
+}
+
+func TestProxy_ExplicitProxyURL(t *testing.T) {
+	clearProxyEnv(t)
+	proxyURL, hosts := newRecordingProxy(t)
+
+	err := queryAllBizWithTimeout(BuildService(context.Background(), WithProxy(proxyURL), remoteArklet), 1238)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"arklet.test"}, *hosts)
+}
+
+func TestProxy_RemoteCoordinate(t *testing.T) {
+	clearProxyEnv(t)
+	proxyURL, hosts := newRecordingProxy(t)
+	client := BuildService(context.Background(), WithProxy(proxyURL))
+
+	// the local target on a non-loopback host goes through the proxy
+	port := 1238
+	err := client.InstallBiz(context.Background(), InstallBizRequest{
+		BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://artifacts.test/biz-ark-biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType:    ArkContainerRunTypeLocal,
+			Coordinate: "arklet.test",
+			Port:       &port,
+		},
+		AllowMultipleVersions: true,
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, *hosts)
+	for _, host := range *hosts {
+		assert.Equal(t, "arklet.test:1238", host)
+	}
+}
+
+func TestProxy_NoProxyOverride(t *testing.T) {
+	clearProxyEnv(t)
+	proxyURL, hosts := newRecordingProxy(t)
+
+	// arklet.test can't be resolved without the proxy
+	err := queryAllBizWithTimeout(BuildService(context.Background(), WithProxy(proxyURL, "arklet.test"), remoteArklet), 1238)
+	assert.NotNil(t, err)
+	assert.Empty(t, *hosts)
+}
+
+func TestProxy_LocalBypass(t *testing.T) {
+	clearProxyEnv(t)
+	proxyURL, hosts := newRecordingProxy(t)
+
+	var served bool
+	port, cancel := mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		served = true
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel()
+
+	err := queryAllBizWithTimeout(BuildService(context.Background(), WithProxy(proxyURL)), port)
+	assert.Nil(t, err)
+	assert.True(t, served)
+	assert.Empty(t, *hosts)
+}
+
+func TestProxy_Env(t *testing.T) {
+	clearProxyEnv(t)
+	proxyURL, hosts := newRecordingProxy(t)
+	t.Setenv("HTTP_PROXY", proxyURL)
+
+	err := queryAllBizWithTimeout(BuildService(context.Background(), remoteArklet), 1238)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"arklet.test"}, *hosts)
+
+	t.Setenv("NO_PROXY", "arklet.test")
+	err = queryAllBizWithTimeout(BuildService(context.Background(), remoteArklet), 1238)
+	assert.NotNil(t, err)
+	assert.Len(t, *hosts, 1)
+}
+
+func TestProxy_Download(t *testing.T) {
+	clearProxyEnv(t)
+	content := buildTestJar("biz", "0.0.1")
+	hosts := []string{}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		_, _ = w.Write(content)
+	}))
+	defer proxy.Close()
+
+	model, err := BuildService(context.Background(), WithProxy(proxy.URL)).
+		ParseBizModel(context.Background(), "http://artifacts.test/biz-ark-biz.jar")
+	assert.Nil(t, err)
+	assert.Equal(t, "biz", model.BizName)
+	assert.Contains(t, hosts, "artifacts.test")
+}
@@ -0,0 +1,221 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultCommandPollInterval is the interval polling the result of a submitted command if it's not configured.
+const defaultCommandPollInterval = time.Second
+
+// ArkCommand is an install or uninstall submitted to arklet through a message queue,
+// for the environments where arklet isn't reachable by http or kubectl exec.
+type ArkCommand struct {
+	// ID identifies the command, the result of the command is correlated by it.
+	ID string `json:"id"`
+
+	// Operation is either OperationInstall or OperationUninstall.
+	Operation Operation `json:"operation"`
+
+	// Target is the ark container to run the command.
+	Target ArkContainerRuntimeInfo `json:"target"`
+
+	// Body is the json body of the arklet endpoint of the operation, in the field names of the Dialect.
+	Body json.RawMessage `json:"body"`
+}
+
+// CommandPublisher publishes the commands to the queue consumed by the arklets.
+type CommandPublisher interface {
+	// Publish submit the command, it returns once the queue accepted the command.
+	Publish(ctx context.Context, command ArkCommand) error
+}
+
+// CommandResultSource reads the results of the commands, like a consumer of the result topic.
+type CommandResultSource interface {
+	// Result return the arklet response of the command, nil if the command isn't done yet.
+	Result(ctx context.Context, commandID string) ([]byte, error)
+}
+
+// CommandQueueOptions routes install and uninstall through a message queue instead of calling arklet directly.
+type CommandQueueOptions struct {
+	// Publisher publishes the commands, the queue is disabled if it's nil.
+	Publisher CommandPublisher
+
+	// Results is polled for the result of each command, if it's nil the operations return once the command is published,
+	// in which case the uninstall is reported as if the biz existed.
+	Results CommandResultSource
+
+	// PollInterval is the interval polling Results, 1s if it's not positive.
+	PollInterval time.Duration
+}
+
+// HTTPCommandPublisher is the default CommandPublisher talking to a queue through its http gateway.
+// The commands are posted to URL as json, and the result of each is read from {URL}/{id},
+// where 404 means the command isn't done yet, so it serves as the CommandResultSource as well.
+type HTTPCommandPublisher struct {
+	// URL is the endpoint of the queue accepting the commands.
+	URL string
+
+	// Client sends the requests, http.DefaultClient if it's nil.
+	Client *http.Client
+}
+
+func (p *HTTPCommandPublisher) client() *http.Client {
+	if p.Client == nil {
+		return http.DefaultClient
+	}
+	return p.Client
+}
+
+func (p *HTTPCommandPublisher) Publish(ctx context.Context, command ArkCommand) error {
+	body, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("publish command %s failed with code %d", command.ID, resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *HTTPCommandPublisher) Result(ctx context.Context, commandID string) ([]byte, error) {
+	resultUrl, err := url.JoinPath(p.URL, commandID)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resultUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("read result of command %s failed with code %d", commandID, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// commandQueueEnabled return true if install and uninstall are submitted through the command queue.
+func (h *service) commandQueueEnabled() bool {
+	return h.options.CommandQueue.Publisher != nil
+}
+
+// submitCommand publish the command of operation, and wait for its result if the result source is given.
+// The result is nil if the command is only published.
+func (h *service) submitCommand(ctx context.Context, operation Operation, target ArkContainerRuntimeInfo, body interface{}) ([]byte, error) {
+	if h.closed.Load() {
+		return nil, ErrClientClosed
+	}
+	encoded, err := h.encodeBody(JSONEncoder{}, body)
+	if err != nil {
+		return nil, err
+	}
+	command := ArkCommand{
+		ID:        uuid.NewString(),
+		Operation: operation,
+		Target:    target,
+		Body:      encoded,
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx, h.options.DefaultTimeout)
+	defer cancel()
+
+	queue := h.options.CommandQueue
+	if err := queue.Publisher.Publish(ctx, command); err != nil {
+		return nil, fmt.Errorf("submit %s command failed: %w", operation, err)
+	}
+	if queue.Results == nil {
+		return nil, nil
+	}
+
+	interval := queue.PollInterval
+	if interval <= 0 {
+		interval = defaultCommandPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := queue.Results.Result(ctx, command.ID)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			return h.options.Dialect.toArk(unwrapEnvelope(h.options.ResponseEnvelope, result)), nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for result of %s command %s: %w", operation, command.ID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// installBizByCommand install biz by submitting the command to the queue.
+func (h *service) installBizByCommand(ctx context.Context, req InstallBizRequest) error {
+	body, err := installBizBody(req.BizModel, req.ExtraParams)
+	if err != nil {
+		return err
+	}
+
+	respBody, err := h.submitCommand(ctx, OperationInstall, req.TargetContainer, body)
+	if err != nil || respBody == nil {
+		return err
+	}
+	return decodeArkResponse(ctx, h, "install biz", 0, "", respBody, &InstallBizResponse{})
+}
+
+// unInstallBizByCommand uninstall biz by submitting the command to the queue, existed is false if the biz was already absent.
+func (h *service) unInstallBizByCommand(ctx context.Context, req UnInstallBizRequest) (existed bool, err error) {
+	respBody, err := h.submitCommand(ctx, OperationUninstall, req.TargetContainer, req.BizModel)
+	if err != nil {
+		return false, err
+	}
+	if respBody == nil {
+		return true, nil
+	}
+
+	uninstallResponse := &UnInstallBizResponse{}
+	err = decodeArkResponse(ctx, h, "uninstall biz", 0, "", respBody, uninstallResponse)
+	if IsNotFound(uninstallResponse.ArkResponseBase) {
+		return false, nil
+	}
+	return err == nil, err
+}
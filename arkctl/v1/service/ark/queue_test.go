@@ -0,0 +1,180 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryQueue is an in-memory CommandPublisher and CommandResultSource,
+// the result of each command is computed by respond when it's published.
+type memoryQueue struct {
+	mu       sync.Mutex
+	commands []ArkCommand
+	results  map[string][]byte
+	respond  func(command ArkCommand) []byte
+}
+
+func (q *memoryQueue) Publish(_ context.Context, command ArkCommand) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.commands = append(q.commands, command)
+	if q.respond != nil {
+		if q.results == nil {
+			q.results = map[string][]byte{}
+		}
+		q.results[command.ID] = q.respond(command)
+	}
+	return nil
+}
+
+func (q *memoryQueue) Result(_ context.Context, commandID string) ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.results[commandID], nil
+}
+
+func TestCommandQueue_Install(t *testing.T) {
+	ctx := context.Background()
+	queue := &memoryQueue{respond: func(ArkCommand) []byte { return []byte(`{"code":"SUCCESS"}`) }}
+	client := BuildService(ctx,
+		WithCommandQueue(CommandQueueOptions{Publisher: queue, Results: queue, PollInterval: time.Millisecond}),
+		WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+			t.Fatal("arklet is called directly")
+			return nil, nil
+		}))
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"}
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://repo/biz.jar"},
+		TargetContainer: target,
+		AllowMasterBiz:  true,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, queue.commands, 1)
+	command := queue.commands[0]
+	assert.NotEmpty(t, command.ID)
+	assert.Equal(t, OperationInstall, command.Operation)
+	assert.Equal(t, target, command.Target)
+	assert.JSONEq(t, `{"bizName":"biz","bizVersion":"0.0.1","bizUrl":"http://repo/biz.jar"}`, string(command.Body))
+
+	// the command is serialized as it's published to the queue
+	raw, err := json.Marshal(command)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"id":"`+command.ID+`","operation":"install","target":{"runType":"pod","coordinate":"default/base-0","port":null},`+
+		`"body":{"bizName":"biz","bizVersion":"0.0.1","bizUrl":"http://repo/biz.jar"}}`, string(raw))
+}
+
+func TestCommandQueue_UnInstall(t *testing.T) {
+	ctx := context.Background()
+	respond := `{"code":"FAILED","data":{"code":"NOT_FOUND_BIZ"}}`
+	queue := &memoryQueue{respond: func(ArkCommand) []byte { return []byte(respond) }}
+	client := BuildService(ctx, WithCommandQueue(CommandQueueOptions{Publisher: queue, Results: queue, PollInterval: time.Millisecond}))
+	req := UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+		AllowMasterBiz:  true,
+	}
+
+	result, err := client.UnInstallBizWithResult(ctx, req)
+	assert.Nil(t, err)
+	assert.False(t, result.Existed)
+	assert.Equal(t, OperationUninstall, queue.commands[0].Operation)
+
+	respond = `{"code":"FAILED","message":"uninstall failed"}`
+	err = client.UnInstallBiz(ctx, req)
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, "uninstall biz failed: uninstall failed", err.Error())
+}
+
+func TestCommandQueue_SubmitOnly(t *testing.T) {
+	ctx := context.Background()
+	queue := &memoryQueue{}
+	client := BuildService(ctx, WithCommandQueue(CommandQueueOptions{Publisher: queue}))
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"}
+
+	result, err := client.UnInstallBizWithResult(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: target,
+		AllowMasterBiz:  true,
+	})
+	assert.Nil(t, err)
+	assert.True(t, result.Existed)
+	assert.Len(t, queue.commands, 1)
+}
+
+func TestCommandQueue_ResultTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	queue := &memoryQueue{}
+	client := BuildService(ctx, WithCommandQueue(CommandQueueOptions{Publisher: queue, Results: queue, PollInterval: time.Millisecond}))
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+		AllowMasterBiz:  true,
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestHTTPCommandPublisher(t *testing.T) {
+	ctx := context.Background()
+	var published ArkCommand
+	polls := 0
+	port, cancel := mockHttpServer("/commands/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/commands/":
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &published)
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodGet && r.URL.Path == "/commands/"+published.ID:
+			// the result is ready on the second poll
+			if polls++; polls == 1 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"code":"FAILED","message":"install failed"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer cancel()
+
+	publisher := &HTTPCommandPublisher{URL: "http://127.0.0.1:" + strconv.Itoa(port) + "/commands/"}
+	client := BuildService(ctx, WithCommandQueue(CommandQueueOptions{Publisher: publisher, Results: publisher, PollInterval: time.Millisecond}))
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+		AllowMasterBiz:  true,
+	})
+	assert.Equal(t, "install biz failed: install failed", err.Error())
+	assert.Equal(t, OperationInstall, published.Operation)
+	assert.Equal(t, 2, polls)
+
+	// the publish is rejected by the gateway
+	err = (&HTTPCommandPublisher{URL: "http://127.0.0.1:" + strconv.Itoa(port) + "/commands/unknown"}).Publish(ctx, ArkCommand{ID: "1"})
+	assert.Equal(t, "publish command 1 failed with code 400", err.Error())
+}
@@ -0,0 +1,74 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+
+	"golang.org/x/time/rate"
+)
+
+// clock is the source of time, replaced by a fake one in tests.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// requestLimiter limits the rate of outgoing requests, it's shared by all goroutines using the same client.
+type requestLimiter struct {
+	clock   clock
+	limiter *rate.Limiter
+}
+
+func newRequestLimiter(qps float64, burst int) *requestLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &requestLimiter{clock: realClock{}, limiter: rate.NewLimiter(rate.Limit(qps), burst)}
+}
+
+// wait block until a token is available or ctx is done, the token is given back if ctx is done first.
+// Unlike rate.Limiter.Wait, it doesn't fail early if the deadline of ctx is before the token is available,
+// so the error is always the one of ctx.
+func (l *requestLimiter) wait(ctx context.Context) error {
+	now := l.clock.Now()
+	reservation := l.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	contextutil.GetLogger(ctx).WithField("wait", delay).Debug("request is rate limited")
+	select {
+	case <-ctx.Done():
+		reservation.CancelAt(l.clock.Now())
+		return ctx.Err()
+	case <-l.clock.After(delay):
+		return nil
+	}
+}
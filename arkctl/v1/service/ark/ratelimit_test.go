@@ -0,0 +1,88 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock advance the time immediately when someone waits on it.
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestRateLimit_Spacing(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithRateLimit(2, 1))
+
+	start := time.Now()
+	fake := &fakeClock{now: start}
+	client.(*service).limiter.clock = fake
+
+	port, cancel := mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		_, err := client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port, ForceRefresh: true})
+		assert.Nil(t, err)
+	}
+
+	// the first call takes the burst token, the other 4 calls wait 0.5s each
+	assert.Equal(t, 2*time.Second, fake.Now().Sub(start))
+}
+
+func TestRateLimit_WaitRespectsContext(t *testing.T) {
+	limiter := newRequestLimiter(0.001, 1)
+	assert.Nil(t, limiter.wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := limiter.wait(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// the wait fails at once if ctx is already done
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	assert.Equal(t, context.Canceled, limiter.wait(canceled))
+}
@@ -0,0 +1,104 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// defaultRawMethods are the methods allowed by Raw unless WithRawMethods is given.
+var defaultRawMethods = []string{http.MethodGet, http.MethodPost}
+
+// RawResponse is the response of a custom arklet endpoint.
+type RawResponse struct {
+	// StatusCode is the http status code.
+	StatusCode int
+
+	// Header is the response headers.
+	Header http.Header
+
+	// Body is the raw response body.
+	Body []byte
+}
+
+// Raw call a custom arklet endpoint like /flushCache added by arklet plugins, and return the raw response.
+// It shares the transport, base path, retries and logging with the typed methods, which should be preferred when exist.
+// Only GET and POST are allowed unless WithRawMethods is given, the body is encoded like the typed methods if not nil.
+func (h *service) Raw(ctx context.Context, target ArkContainerRuntimeInfo, method, path string, body any) (resp *RawResponse, err error) {
+	if target, err = targetOrDefault(ctx, target); err != nil {
+		return nil, err
+	}
+	method = strings.ToUpper(method)
+	logger := contextutil.GetLogger(ctx).
+		WithFields(targetFields(target)).
+		WithField("method", method).
+		WithField("path", path)
+	logger.Info("raw request started")
+	defer func() {
+		if err != nil {
+			logger.Error(err)
+			h.invalidateCapabilities(target, err)
+		} else {
+			logger.WithField("statusCode", resp.StatusCode).Info("raw request completed")
+		}
+	}()
+
+	if !h.rawMethodAllowed(method) {
+		return nil, fmt.Errorf("raw request method %s is not allowed, allowed methods are %v", method, h.rawMethods())
+	}
+	if target.RunType != ArkContainerRunTypeLocal {
+		return nil, fmt.Errorf("raw request is not supported for run type: %s", target.RunType)
+	}
+
+	endpointUrl, err := h.localEndpointUrl(target, Endpoint(path))
+	if err != nil {
+		return nil, err
+	}
+
+	request := h.client.R().SetContext(ctx)
+	if body != nil {
+		request.SetBody(body)
+	}
+	restyResp, err := request.Execute(method, endpointUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &RawResponse{
+		StatusCode: restyResp.StatusCode(),
+		Header:     restyResp.Header(),
+		Body:       restyResp.Body(),
+	}, nil
+}
+
+func (h *service) rawMethods() []string {
+	if h.options.RawMethods != nil {
+		return h.options.RawMethods
+	}
+	return defaultRawMethods
+}
+
+func (h *service) rawMethodAllowed(method string) bool {
+	for _, allowed := range h.rawMethods() {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
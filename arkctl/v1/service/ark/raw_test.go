@@ -0,0 +1,84 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRaw(t *testing.T) {
+	ctx := context.Background()
+	var methods, bodies []string
+	calls := 0
+	port, cancel := mockHttpServer("/arklet/flushCache", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		methods = append(methods, r.Method)
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		assert.Equal(t, "arkctl/0.0.1", r.Header.Get("User-Agent"))
+		if calls == 1 {
+			// retried like the typed methods
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Flushed", "3")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("flushed"))
+	})
+	defer cancel()
+
+	client := BuildService(ctx, WithBasePath("/arklet"), WithRetry(1, time.Millisecond))
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	resp, err := client.Raw(ctx, target, "post", "/flushCache", map[string]string{"cache": "biz"})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "3", resp.Header.Get("X-Flushed"))
+	assert.Equal(t, []byte("flushed"), resp.Body)
+	assert.Equal(t, []string{"POST", "POST"}, methods)
+	assert.JSONEq(t, `{"cache":"biz"}`, bodies[1])
+
+	resp, err = client.Raw(ctx, target, http.MethodGet, "flushCache", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "GET", methods[len(methods)-1])
+	assert.Equal(t, "", bodies[len(bodies)-1])
+}
+
+func TestRaw_MethodNotAllowed(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	port, cancel := mockHttpServer("/flushCache", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, http.MethodDelete, r.Method)
+	})
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	_, err := BuildService(ctx).Raw(ctx, target, http.MethodDelete, "flushCache", nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, calls)
+
+	resp, err := BuildService(ctx, WithRawMethods(http.MethodDelete)).Raw(ctx, target, http.MethodDelete, "flushCache", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,88 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// arkResponse is the response of arklet embedding ArkResponseBase.
+type arkResponse interface {
+	responseBase() ArkResponseBase
+}
+
+func (resp ArkResponseBase) responseBase() ArkResponseBase {
+	return resp
+}
+
+// isHtmlResponse tell whether the response is a html page rather than json,
+// by the content type if known, or the leading '<' of the body.
+func isHtmlResponse(contentType string, body []byte) bool {
+	if contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "text/html" {
+			return true
+		}
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("<"))
+}
+
+// decodeArkResponse decode the response body of operation into target, and check the http status and the response code.
+// The status code is 0 and the content type is empty for the responses read by kubectl exec, which are not checked.
+// The failed code is returned as ResponseError, so the callers could still inspect target for the codes they tolerate.
+func decodeArkResponse[T arkResponse](ctx context.Context, h *service, operation string, statusCode int, contentType string, body []byte, target T) error {
+	return decodeArkResponseWithMessage(ctx, h, operation, statusCode, contentType, body, target, nil)
+}
+
+// decodeArkResponseWithMessage is decodeArkResponse reporting the failed code with the message returned by message,
+// which is called after target is decoded. The Message of the response is reported if message is nil.
+func decodeArkResponseWithMessage[T arkResponse](
+	ctx context.Context,
+	h *service,
+	operation string,
+	statusCode int,
+	contentType string,
+	body []byte,
+	target T,
+	message func() string,
+) error {
+	// an auth proxy may respond its login page with any status, report it rather than the status
+	if isHtmlResponse(contentType, body) {
+		return newUnexpectedContentTypeError(operation, contentType, body)
+	}
+	if statusCode != 0 && (statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices) {
+		return fmt.Errorf("%s http failed with code %d", operation, statusCode)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return fmt.Errorf("%s responded an empty body", operation)
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("decode %s response failed: %w", operation, err)
+	}
+
+	base := target.responseBase()
+	recordResponseCode(ctx, base.Code)
+	if !base.Code.IsSuccess() {
+		if message == nil {
+			return h.newResponseError(operation, base.Code, base.Message, body)
+		}
+		return h.newResponseError(operation, base.Code, message(), body)
+	}
+	return nil
+}
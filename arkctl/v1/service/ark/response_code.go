@@ -0,0 +1,148 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ResponseCode is the code returned by arklet.
+type ResponseCode string
+
+const (
+	ResponseCodeSuccess              ResponseCode = "SUCCESS"
+	ResponseCodeFailed               ResponseCode = "FAILED"
+	ResponseCodeTimeout              ResponseCode = "TIMEOUT"
+	ResponseCodeNotFoundBiz          ResponseCode = "NOT_FOUND_BIZ"
+	ResponseCodeNotFoundDifferentBiz ResponseCode = "NOT_FOUND_DIFFERENT_BIZ"
+	ResponseCodeDuplicateBiz         ResponseCode = "DUPLICATE_BIZ"
+	ResponseCodeRepeatBiz            ResponseCode = "REPEAT_BIZ"
+	ResponseCodeIllegalStateBiz      ResponseCode = "ILLEGAL_STATE_BIZ"
+	ResponseCodeInstallationFailed   ResponseCode = "INSTALLATION_FAILED"
+	ResponseCodeContainerBusy        ResponseCode = "CONTAINER_BUSY"
+)
+
+var knownResponseCodes = map[ResponseCode]bool{
+	ResponseCodeSuccess:              true,
+	ResponseCodeFailed:               true,
+	ResponseCodeTimeout:              true,
+	ResponseCodeNotFoundBiz:          true,
+	ResponseCodeNotFoundDifferentBiz: true,
+	ResponseCodeDuplicateBiz:         true,
+	ResponseCodeRepeatBiz:            true,
+	ResponseCodeIllegalStateBiz:      true,
+	ResponseCodeInstallationFailed:   true,
+	ResponseCodeContainerBusy:        true,
+}
+
+// retriableResponseCodes classify the failure codes to transient ones worth retrying and permanent ones.
+// The codes not listed, like the generic FAILED, are not classified.
+var retriableResponseCodes = map[ResponseCode]bool{
+	ResponseCodeTimeout:              true,
+	ResponseCodeContainerBusy:        true,
+	ResponseCodeInstallationFailed:   false,
+	ResponseCodeNotFoundBiz:          false,
+	ResponseCodeNotFoundDifferentBiz: false,
+	ResponseCodeDuplicateBiz:         false,
+	ResponseCodeRepeatBiz:            false,
+	ResponseCodeIllegalStateBiz:      false,
+}
+
+// NormalizeResponseCode map the raw code to a known ResponseCode regardless of case and spaces.
+// Unknown codes are preserved verbatim.
+func NormalizeResponseCode(raw string) ResponseCode {
+	normalized := ResponseCode(strings.ToUpper(strings.TrimSpace(raw)))
+	if knownResponseCodes[normalized] {
+		return normalized
+	}
+	return ResponseCode(raw)
+}
+
+// UnmarshalJSON normalize the code while decoding.
+func (code *ResponseCode) UnmarshalJSON(data []byte) error {
+	raw := ""
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*code = NormalizeResponseCode(raw)
+	return nil
+}
+
+// IsKnown return true if the code is one of the known codes.
+func (code ResponseCode) IsKnown() bool {
+	return knownResponseCodes[code]
+}
+
+// IsSuccess return true only for SUCCESS, unknown codes are treated as failures.
+func (code ResponseCode) IsSuccess() bool {
+	return code == ResponseCodeSuccess
+}
+
+// IsRetriable return true if the failure is transient and the operation could be retried.
+func (code ResponseCode) IsRetriable() bool {
+	return retriableResponseCodes[code]
+}
+
+// isClassified return true if the code is known to be either transient or permanent.
+func (code ResponseCode) isClassified() bool {
+	_, classified := retriableResponseCodes[code]
+	return classified
+}
+
+// IsNotFound return true if arklet reports the biz doesn't exist.
+func IsNotFound(resp ArkResponseBase) bool {
+	return resp.Code == ResponseCodeNotFoundBiz ||
+		(!resp.Code.IsSuccess() && resp.Data.Code == ResponseCodeNotFoundBiz)
+}
+
+// IsDuplicate return true if arklet reports the biz is already installed.
+func IsDuplicate(resp ArkResponseBase) bool {
+	isDuplicate := func(code ResponseCode) bool {
+		return code == ResponseCodeDuplicateBiz || code == ResponseCodeRepeatBiz
+	}
+	return isDuplicate(resp.Code) || (!resp.Code.IsSuccess() && isDuplicate(resp.Data.Code))
+}
+
+// responseCodeOf peek the code of a raw arklet response body, empty if the body is not a valid response.
+func responseCodeOf(body []byte) ResponseCode {
+	resp := &struct {
+		Code ResponseCode `json:"code"`
+	}{}
+	if err := json.Unmarshal(body, resp); err != nil {
+		return ""
+	}
+	return resp.Code
+}
+
+// failureCodeOf peek the most specific failure code of a raw arklet response body,
+// which is data.code if the response fails with a classified one, e.g. {"code":"FAILED","data":{"code":"CONTAINER_BUSY"}}.
+// It's empty if the body is not a valid response.
+func failureCodeOf(body []byte) ResponseCode {
+	resp := &struct {
+		Code ResponseCode `json:"code"`
+		Data struct {
+			Code ResponseCode `json:"code"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, resp); err != nil {
+		// data might not be an object, peek the code only
+		return responseCodeOf(body)
+	}
+	if !resp.Code.IsSuccess() && resp.Data.Code.isClassified() {
+		return resp.Data.Code
+	}
+	return resp.Code
+}
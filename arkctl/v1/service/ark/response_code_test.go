@@ -0,0 +1,70 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeResponseCode(t *testing.T) {
+	testCases := []struct {
+		raw      string
+		expected ResponseCode
+		known    bool
+	}{
+		{raw: "SUCCESS", expected: ResponseCodeSuccess, known: true},
+		{raw: "success", expected: ResponseCodeSuccess, known: true},
+		{raw: " Failed ", expected: ResponseCodeFailed, known: true},
+		{raw: "timeout", expected: ResponseCodeTimeout, known: true},
+		{raw: "not_found_biz", expected: ResponseCodeNotFoundBiz, known: true},
+		{raw: "Duplicate_Biz", expected: ResponseCodeDuplicateBiz, known: true},
+		{raw: "Some_New_Code", expected: ResponseCode("Some_New_Code"), known: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.raw, func(t *testing.T) {
+			code := NormalizeResponseCode(tc.raw)
+			assert.Equal(t, tc.expected, code)
+			assert.Equal(t, tc.known, code.IsKnown())
+
+			decoded := ResponseCode("")
+			assert.Nil(t, json.Unmarshal([]byte(`"`+tc.raw+`"`), &decoded))
+			assert.Equal(t, tc.expected, decoded)
+		})
+	}
+}
+
+func TestResponseCode_Predicates(t *testing.T) {
+	assert.True(t, ResponseCodeSuccess.IsSuccess())
+	assert.False(t, ResponseCode("Some_New_Code").IsSuccess())
+	assert.True(t, ResponseCodeTimeout.IsRetriable())
+	assert.False(t, ResponseCodeFailed.IsRetriable())
+	assert.True(t, ResponseCodeContainerBusy.IsRetriable())
+	assert.False(t, ResponseCodeInstallationFailed.IsRetriable())
+
+	assert.Equal(t, ResponseCodeContainerBusy, failureCodeOf([]byte(`{"code":"FAILED","data":{"code":"CONTAINER_BUSY"}}`)))
+	assert.Equal(t, ResponseCodeFailed, failureCodeOf([]byte(`{"code":"FAILED","data":{"code":"FOO"}}`)))
+	assert.Equal(t, ResponseCodeFailed, failureCodeOf([]byte(`{"code":"FAILED","data":[]}`)))
+	assert.Equal(t, ResponseCodeSuccess, failureCodeOf([]byte(`{"code":"SUCCESS","data":{"code":"CONTAINER_BUSY"}}`)))
+
+	assert.True(t, IsNotFound(ArkResponseBase{Code: ResponseCodeNotFoundBiz}))
+	assert.True(t, IsNotFound(ArkResponseBase{Code: ResponseCodeFailed, Data: ArkResponseData{Code: ResponseCodeNotFoundBiz}}))
+	assert.False(t, IsNotFound(ArkResponseBase{Code: ResponseCodeFailed, Data: ArkResponseData{Code: "FOO"}}))
+	assert.True(t, IsDuplicate(ArkResponseBase{Code: ResponseCodeFailed, Data: ArkResponseData{Code: ResponseCodeRepeatBiz}}))
+	assert.False(t, IsDuplicate(ArkResponseBase{Code: ResponseCodeSuccess}))
+}
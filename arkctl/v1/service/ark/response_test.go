@@ -0,0 +1,105 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeArkResponse(t *testing.T) {
+	ctx := context.Background()
+	h := BuildService(ctx).(*service)
+
+	tests := []struct {
+		name        string
+		statusCode  int
+		contentType string
+		body        string
+		err         string
+	}{
+		{name: "non 2xx", statusCode: http.StatusBadGateway, body: `{"code":"SUCCESS"}`, err: "install biz http failed with code 502"},
+		{name: "empty body", statusCode: http.StatusOK, body: " \n", err: "install biz responded an empty body"},
+		{name: "malformed json", statusCode: http.StatusOK, body: `{"code":`, err: "decode install biz response failed: unexpected end of JSON input"},
+		{name: "html content type", statusCode: http.StatusUnauthorized, contentType: "text/html; charset=utf-8", body: "login", err: `unexpected content type of the arklet response: install biz responded text/html; charset=utf-8: "login"`},
+		{name: "html body of kubectl exec", statusCode: 0, body: "\n<!DOCTYPE html>", err: `unexpected content type of the arklet response: install biz responded unknown: "<!DOCTYPE html>"`},
+		{name: "failed code", statusCode: http.StatusOK, body: `{"code":"FAILED","message":"boom"}`, err: "install biz failed: boom"},
+		{name: "unchecked status of kubectl exec", statusCode: 0, body: `{"code":"SUCCESS"}`},
+		{name: "success", statusCode: http.StatusOK, body: `{"code":"SUCCESS","message":"ok"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &InstallBizResponse{}
+			err := decodeArkResponse(ctx, h, "install biz", test.statusCode, test.contentType, []byte(test.body), resp)
+			if test.err == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, ResponseCodeSuccess, resp.Code)
+				return
+			}
+			assert.EqualError(t, err, test.err)
+		})
+	}
+
+	// the failed code is still decoded for the callers tolerating it
+	resp := &UnInstallBizResponse{}
+	err := decodeArkResponse(ctx, h, "uninstall biz", http.StatusOK, "application/json", []byte(`{"code":"FAILED","data":{"code":"NOT_FOUND_BIZ"}}`), resp)
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, ResponseCodeFailed, responseErr.Code)
+	assert.True(t, IsNotFound(resp.ArkResponseBase))
+}
+
+func TestDecodeArkResponse_SharedByOperations(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+	client := BuildService(ctx)
+
+	err := client.InstallBiz(ctx, InstallBizRequest{BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"}, TargetContainer: target})
+	assert.EqualError(t, err, "install biz responded an empty body")
+
+	err = client.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"}, TargetContainer: target})
+	assert.EqualError(t, err, "uninstall biz responded an empty body")
+}
+
+func TestDecodeArkResponse_HtmlLoginPage(t *testing.T) {
+	ctx := context.Background()
+	page := "<html><body>" + strings.Repeat("please login ", 20) + "</body></html>"
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(page))
+	})
+	defer cancel()
+
+	err := BuildService(ctx).InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	})
+	assert.True(t, errors.Is(err, ErrUnexpectedContentType))
+	contentTypeErr := &UnexpectedContentTypeError{}
+	assert.True(t, errors.As(err, &contentTypeErr))
+	assert.Equal(t, "install biz", contentTypeErr.Operation)
+	assert.Equal(t, "text/html", contentTypeErr.ContentType)
+	assert.Equal(t, page[:maxContentSnippet]+"...", contentTypeErr.Snippet)
+}
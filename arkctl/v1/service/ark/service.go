@@ -16,16 +16,45 @@ package ark
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
 	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/progressutil"
 	"serverless.alipay.com/sofa-serverless/arkctl/common/runtime"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
 )
 
+const (
+	headerIdempotencyKey = "Idempotency-Key"
+)
+
+// reservedInstallParams are the core fields of the install body which can't be set by ExtraParams.
+var reservedInstallParams = map[string]bool{
+	"bizName":        true,
+	"bizVersion":     true,
+	"bizUrl":         true,
+	"mainClass":      true,
+	"envs":           true,
+	"args":           true,
+	"checksum":       true,
+	"webContextPath": true,
+}
+
 // Service is responsible for interacting with ark container.
 type Service interface {
 	// ParseBizModel parse the biz file and return the biz model.
@@ -35,19 +64,210 @@ type Service interface {
 	// The precondition is that the biz file is already uploaded to the ark container or file hosting service (e.g. oss).
 	InstallBiz(ctx context.Context, req InstallBizRequest) error
 
+	// UploadBiz upload the biz bundle to the ark container, and return the url of the uploaded bundle
+	// inside the ark container, which could be used to install biz later.
+	UploadBiz(ctx context.Context, req UploadBizRequest) (fileutil.FileUrl, error)
+
+	// InstallBizFromReader stage the biz bundle read from content to a temp file, then upload and install it in one step.
+	InstallBizFromReader(ctx context.Context, bizModel BizModel, content io.Reader, target ArkContainerRuntimeInfo, opts ReaderInstallOptions) error
+
+	// InstallBizInline install the biz by streaming the biz bundle in a multipart install request,
+	// for the arklets accepting it instead of a biz url to download.
+	InstallBizInline(ctx context.Context, req InstallBizRequest, file io.Reader, size int64) error
+
 	// UnInstallBiz call the remote ark container to install biz.
 	// The precondition is that the biz file is already uploaded to the ark container or file hosting service (e.g. oss).
 	UnInstallBiz(ctx context.Context, req UnInstallBizRequest) error
 
 	// QueryAllBiz call the remote ark container to query biz.
 	QueryAllBiz(ctx context.Context, req QueryAllArkBizRequest) (*QueryAllArkBizResponse, error)
+
+	// Close release the idle connections held by the Service, it's called as well once the base context is done.
+	// Any call after Close returns ErrClientClosed.
+	Close() error
+
+	// QueryBiz call the remote ark container to query the detail of a single biz.
+	// If the bizVersion is empty, the first biz with given name is returned.
+	// ErrBizNotFound is returned if the biz doesn't exist.
+	QueryBiz(ctx context.Context, target ArkContainerRuntimeInfo, bizName, bizVersion string) (*BizDetail, error)
+
+	// QueryBizState return the state of a single biz, ErrBizNotFound is returned if the biz doesn't exist.
+	QueryBizState(ctx context.Context, target ArkContainerRuntimeInfo, bizName, bizVersion string) (BizState, error)
+
+	// PreflightCheck inspects the biz jar without installing it, the ark version is checked against target if it's given.
+	PreflightCheck(ctx context.Context, bizUrl fileutil.FileUrl, target *ArkContainerRuntimeInfo) (*PreflightReport, error)
+
+	// TailArkletLogs return the last lines of the arklet logs, which helps to diagnose install failures.
+	TailArkletLogs(ctx context.Context, target ArkContainerRuntimeInfo, lines int) ([]string, error)
+
+	// SyncBiz reconcile the biz in the ark container to the desired biz set.
+	// The report lists every action with its outcome, an error is returned if any action fails.
+	SyncBiz(ctx context.Context, target ArkContainerRuntimeInfo, desired []BizModel, opts SyncOptions) (*SyncReport, error)
+
+	// Reconcile compute the plan reconciling the biz in the ark container to the desired biz set without applying it,
+	// the plan could be reviewed and then applied by Apply.
+	Reconcile(ctx context.Context, target ArkContainerRuntimeInfo, desired []BizModel, opts SyncOptions) (*Plan, error)
+
+	// Apply apply the actions of the plan in order, the report lists every action with its outcome,
+	// an error is returned if any action fails.
+	Apply(ctx context.Context, plan *Plan) (*SyncReport, error)
+
+	// SnapshotState capture the biz state and the metadata of the ark container into a StateSnapshot.
+	SnapshotState(ctx context.Context, target ArkContainerRuntimeInfo) (*StateSnapshot, error)
+
+	// ApplySnapshot re-create the biz set of the snapshot on the ark container by the sync engine,
+	// the biz whose url isn't reachable from the target are reported as unapplicable instead.
+	ApplySnapshot(ctx context.Context, target ArkContainerRuntimeInfo, snapshot *StateSnapshot, opts ApplyOptions) (*SnapshotApplyResult, error)
+
+	// UnInstallBizWithResult is UnInstallBiz reporting the result of each phase, e.g. drain and uninstall,
+	// and whether the biz existed before.
+	UnInstallBizWithResult(ctx context.Context, req UnInstallBizRequest) (*UnInstallResult, error)
+
+	// ShutdownContainer shutdown the whole ark container, which is destructive and must be confirmed by opts.Confirm.
+	ShutdownContainer(ctx context.Context, target ArkContainerRuntimeInfo, opts ShutdownOptions) error
+
+	// QueryVersion return the arklet version of target, empty if arklet doesn't report it.
+	QueryVersion(ctx context.Context, target ArkContainerRuntimeInfo) (string, error)
+
+	// DetectCapabilities return what the arklet of target supports, the result is cached per target.
+	DetectCapabilities(ctx context.Context, target ArkContainerRuntimeInfo) (*ArkletCapabilities, error)
+
+	// QueryCapabilities return the features supported by the arklet of target, e.g. switch, async install, plugins.
+	QueryCapabilities(ctx context.Context, target ArkContainerRuntimeInfo) (*Capabilities, error)
+
+	// InstallBizStream install the biz and stream its progress events, ending with a completed or failed event.
+	InstallBizStream(ctx context.Context, req InstallBizRequest) (<-chan InstallEvent, error)
+
+	// InstallBizAndWait install the biz and wait until it's activated, async install is used if the arklet supports it.
+	InstallBizAndWait(ctx context.Context, req InstallBizRequest, opts WaitOptions) error
+
+	// Raw call a custom arklet endpoint not covered by the typed methods, which should be preferred when exist.
+	Raw(ctx context.Context, target ArkContainerRuntimeInfo, method, path string, body any) (*RawResponse, error)
+
+	// UnInstallAllBiz uninstall every biz in the ark container, the master biz is skipped unless opts.IncludeMasterBiz.
+	UnInstallAllBiz(ctx context.Context, target ArkContainerRuntimeInfo, opts UnInstallAllOptions) ([]BatchResult, error)
+
+	// ActivateBiz activate the installed biz, it succeeds if the biz is already activated.
+	ActivateBiz(ctx context.Context, req ActivateBizRequest) error
+
+	// DeactivateBiz stop the biz serving traffic while keeping it installed, it succeeds if the biz is already deactivated.
+	DeactivateBiz(ctx context.Context, req DeactivateBizRequest) error
+
+	// WaitBizState wait until the state of the biz is desired, e.g. DEACTIVATED after DeactivateBiz.
+	WaitBizState(ctx context.Context, target ArkContainerRuntimeInfo, bizModel BizModel, desired BizState, opts WaitOptions) error
+
+	// ResolveTargets expand the targets into ark containers, the pods selected by a target group are listed at call time.
+	ResolveTargets(ctx context.Context, targets Targets) ([]ArkContainerRuntimeInfo, error)
+
+	// InstallBizOnTargets install the biz to each target, the result reports the expanded targets and their outcome.
+	InstallBizOnTargets(ctx context.Context, targets Targets, bizModel BizModel) (*TargetsResult, error)
+
+	// UnInstallBizOnTargets uninstall the biz from each target, the result reports the expanded targets and their outcome.
+	UnInstallBizOnTargets(ctx context.Context, targets Targets, bizModel BizModel) (*TargetsResult, error)
+
+	// GCFailedBiz uninstall the biz left in a failed state like BROKEN by the failed installs.
+	GCFailedBiz(ctx context.Context, target ArkContainerRuntimeInfo, opts GCOptions) (*GCReport, error)
+
+	// History return the latest install, uninstall, upload and query all biz operations done by this client
+	// in the order they completed, including the ones done internally like the queries before install.
+	// The number of operations kept is set by WithHistoryCapacity.
+	History() []OperationRecord
+
+	// ResetHistory drop all operations in the history.
+	ResetHistory()
 }
 
-// BuildService return a new Service.
-func BuildService(_ context.Context) Service {
-	return &service{
-		client: resty.New(),
+// BuildService return a new Service. If the options are invalid, the error is logged with the logger of ctx
+// and the Service is built with the default options instead.
+//
+// Deprecated: ctx doesn't bound the lifetime of the Service and the invalid options are only logged,
+// use NewService with WithBaseContext instead.
+func BuildService(ctx context.Context, opts ...Option) Service {
+	svc, err := NewService(opts...)
+	if err != nil {
+		contextutil.GetLogger(ctx).WithError(err).Error("invalid options of ark service, fallback to the default options")
+		svc, _ = NewService()
 	}
+	return svc
+}
+
+// NewService return a new Service configured by opts, or an error if the options are invalid.
+// The options are applied in order, so the later ones win over the earlier ones setting the same field.
+// The Service lives until Close, or until the context of WithBaseContext is done.
+func NewService(opts ...Option) (Service, error) {
+	options := defaultClientOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+	endpointOverrides, err := resolveEndpointOverrides(endpointOverridesOf(options))
+	if err != nil {
+		return nil, err
+	}
+	if err := options.Dialect.validate(); err != nil {
+		return nil, err
+	}
+	// the deprecated callback is adapted, so that the uploads report to UploadProgress only
+	options.UploadProgress = progressutil.Multi(options.UploadProgress, progressutil.FromFunc(options.OnUploadProgress))
+
+	client := resty.New().SetHeader("User-Agent", options.UserAgent)
+	sockets := &socketRegistry{}
+	proxy := sockets.bypassProxy(proxyFunc(options))
+	if transport, ok := client.GetClient().Transport.(*http.Transport); ok {
+		dial, err := hopDialer(options, transport.DialContext)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = proxy
+		transport.DisableKeepAlives = options.DisableKeepAlives
+		transport.DialContext = sockets.dialer(dial)
+	}
+	client.SetTransport(&lengthCheckingTransport{
+		next: &deadlineTransport{next: client.GetClient().Transport, timeout: options.DefaultTimeout},
+	})
+	client.SetRedirectPolicy(resty.RedirectPolicyFunc(redirectPolicy(options.Redirect)))
+	if options.RetryCount > 0 {
+		client.SetRetryCount(options.RetryCount).
+			SetRetryWaitTime(options.RetryWaitTime).
+			SetRetryAfter(retryBackoff).
+			AddRetryCondition(shouldRetry)
+	}
+
+	svc := &service{
+		client:            client,
+		options:           options,
+		endpointOverrides: endpointOverrides,
+		proxy:             proxy,
+		sockets:           sockets,
+		history:           newOperationHistory(options.HistoryCapacity),
+	}
+	if options.RateLimitQPS > 0 {
+		svc.limiter = newRequestLimiter(options.RateLimitQPS, options.RateLimitBurst)
+	}
+	client.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		if svc.closed.Load() {
+			return ErrClientClosed
+		}
+		if svc.limiter != nil {
+			return svc.limiter.wait(req.Context())
+		}
+		return nil
+	})
+	client.OnBeforeRequest(svc.encodeRequestBody)
+	client.AddRetryHook(svc.logRetry)
+	client.OnAfterResponse(recordStatusCode)
+	client.OnAfterResponse(recordResponseStats)
+	client.OnError(recordErrorStats)
+	client.OnAfterResponse(svc.unwrapResponseEnvelope)
+	if options.QueryBizCacheTTL > 0 {
+		svc.queryBizCache = newTTLCache[*BizDetail](options.QueryBizCacheTTL)
+	}
+	if options.QueryAllBizCacheTTL > 0 {
+		svc.queryAllBizCache = newLRUCache[*QueryAllArkBizResponse](options.QueryAllBizCacheTTL, options.QueryAllBizCacheSize)
+	}
+	if options.BaseContext != nil {
+		context.AfterFunc(options.BaseContext, func() { _ = svc.Close() })
+	}
+	return svc, nil
 }
 
 var (
@@ -56,137 +276,597 @@ var (
 
 type service struct {
 	client    *resty.Client
+	options   ClientOptions
 	fileUtils fileutil.FileUtils
+
+	// endpointOverrides are the validated EndpointOverrides keyed by the endpoints they replace
+	endpointOverrides map[Endpoint]EndpointOverride
+
+	// queryBizCache is nil if the cache is disabled
+	queryBizCache *ttlCache[*BizDetail]
+
+	// queryAllBizCache is nil if the cache is disabled
+	queryAllBizCache *ttlCache[*QueryAllArkBizResponse]
+
+	// limiter is nil if the rate limit is disabled
+	limiter *requestLimiter
+
+	// history is nil if it's disabled
+	history *operationHistory
+
+	// proxy selects the proxy of each request
+	proxy func(*http.Request) (*url.URL, error)
+
+	// sockets routes the requests of arklets served on unix sockets
+	sockets *socketRegistry
+
+	// capabilities caches the *capabilityEntry per container.
+	capabilities sync.Map
+
+	// formArklets are the arklets only accepting form bodies by arkletKey, sniffed by DetectCapabilities.
+	formArklets sync.Map
+
+	// masterBizNames caches the master biz name per container, it doesn't change during the container's life.
+	masterBizNames sync.Map
+
+	closed atomic.Bool
+}
+
+// Close release the idle connections, requests after Close fail with ErrClientClosed.
+func (h *service) Close() error {
+	if h.closed.CompareAndSwap(false, true) {
+		h.client.GetClient().CloseIdleConnections()
+	}
+	return nil
+}
+
+// shouldRetry retry on transport errors, server side errors and transient arklet failures.
+// The classified failure codes decide by themselves, e.g. a permanent INSTALLATION_FAILED is never retried even with 5xx,
+// otherwise the server errors are retried.
+func shouldRetry(resp *resty.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	if code := failureCodeOf(resp.Body()); code.isClassified() {
+		return code.IsRetriable()
+	}
+	return resp.StatusCode() >= http.StatusInternalServerError
+}
+
+// retryBackoff return the wait before retrying the attempt of resp, which doubles from the retry wait time
+// for each attempt and is capped by the max retry wait time of the client.
+func retryBackoff(client *resty.Client, resp *resty.Response) (time.Duration, error) {
+	wait := client.RetryWaitTime
+	for attempt := 1; attempt < resp.Request.Attempt && wait < client.RetryMaxWaitTime; attempt++ {
+		wait *= 2
+	}
+	return min(wait, client.RetryMaxWaitTime), nil
+}
+
+// logRetry log the failed attempt to be retried with why it failed and the backoff wait,
+// or that the retries are exhausted after the last attempt.
+func (h *service) logRetry(resp *resty.Response, err error) {
+	if resp == nil || resp.Request == nil {
+		return
+	}
+	req := resp.Request
+	logger := contextutil.GetLogger(req.Context()).
+		WithField("method", req.Method).
+		WithField("attempt", req.Attempt).
+		WithField("maxAttempts", h.client.RetryCount+1)
+	if req.RawRequest != nil {
+		logger = logger.WithField("path", req.RawRequest.URL.Path)
+	}
+	if err != nil {
+		logger = logger.WithError(err)
+	} else {
+		logger = logger.WithField("status", resp.StatusCode())
+		if code := failureCodeOf(resp.Body()); code != "" {
+			logger = logger.WithField("code", code)
+		}
+	}
+	if req.Attempt > h.client.RetryCount {
+		logger.Warn("request failed, retries exhausted")
+		return
+	}
+	wait, _ := retryBackoff(h.client, resp)
+	logger.WithField("wait", wait).Warn("request failed, retrying")
+}
+
+// idempotencyKey derive a stable key for the logical install request,
+// the same key is sent with every retry of the request.
+func idempotencyKey(req InstallBizRequest) string {
+	sum := sha256.Sum256([]byte(req.BizModel.BizName + ":" + req.BizModel.BizVersion + ":" + req.IdempotencyNonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// installBizBody merge the extra params into the install body, the core fields of BizModel are reserved.
+func installBizBody(bizModel BizModel, extraParams map[string]interface{}) (interface{}, error) {
+	if err := validateBizEnv(bizModel.Env); err != nil {
+		return nil, err
+	}
+	if err := validateWebContextPath(bizModel.WebContextPath); err != nil {
+		return nil, err
+	}
+	if len(extraParams) == 0 {
+		return bizModel, nil
+	}
+
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(runtime.Must(json.Marshal(bizModel)), &body); err != nil {
+		return nil, err
+	}
+	for key, value := range extraParams {
+		if reservedInstallParams[key] {
+			return nil, fmt.Errorf("extra param %q is reserved and can't be overridden", key)
+		}
+		body[key] = value
+	}
+	return body, nil
 }
 
 // ParseBizModel parse the biz file and return the biz model.
 func (h *service) ParseBizModel(ctx context.Context, bizUrl fileutil.FileUrl) (*BizModel, error) {
-	return ParseBizModel(ctx, bizUrl)
+	return parseBizModel(h.withDownloadProxy(ctx), bizUrl, h.options.KeepTempFiles)
 }
 
 // Use http client to install biz on local
 // The implementation is simple, just copy file to local dir.
 func (h *service) installBizOnLocal(ctx context.Context, req InstallBizRequest) error {
-	resp, err := h.client.R().
-		SetContext(ctx).
-		SetBody(req.BizModel).
-		Post(fmt.Sprintf("http://127.0.0.1:%d/installBiz", req.TargetContainer.GetPort()))
+	// the installed biz isn't queried for the reuse check if there's no checksum to compare
+	if !req.AllowMultipleVersions || (!req.AllowVersionReuse && req.BizModel.Checksum != "") {
+		if err := h.checkVersionConflict(ctx, req); err != nil {
+			return err
+		}
+	}
 
+	// the biz installed in background may still read the file after the response, so it's kept
+	keepTempFiles := h.options.KeepTempFiles || req.ExtraParams["async"] == true
+	bizModel, cleanup, err := localizeBizUrl(h.withDownloadProxy(ctx), req.BizModel, keepTempFiles)
 	if err != nil {
 		return err
 	}
+	defer cleanup()
 
-	if !resp.IsSuccess() {
-		return fmt.Errorf("install biz http failed with code %d", resp.StatusCode())
+	body, err := installBizBody(bizModel, req.ExtraParams)
+	if err != nil {
+		return err
 	}
 
-	installResponse := &InstallBizResponse{}
-	if err := json.Unmarshal(resp.Body(), installResponse); err != nil {
+	// track whether the request reached arklet, to report the partial state if aborted
+	requestSent := atomic.Bool{}
+	request := h.client.R().
+		SetContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			WroteRequest: func(info httptrace.WroteRequestInfo) {
+				if info.Err == nil {
+					requestSent.Store(true)
+				}
+			},
+		})).
+		SetBody(body)
+
+	if h.options.EnableIdempotencyKey {
+		if req.IdempotencyNonce == "" {
+			req.IdempotencyNonce = uuid.NewString()
+		}
+		request.SetHeader(headerIdempotencyKey, idempotencyKey(req))
+	}
+
+	onEvent := installEventHandlerOf(ctx)
+	if onEvent != nil {
+		// the arklet streams the progress events if it supports, the body is read by ourselves
+		request.SetHeader("Accept", eventStreamContentType+", application/json").SetDoNotParseResponse(true)
+	}
+
+	endpointUrl, err := h.localEndpointUrl(req.TargetContainer, EndpointInstallBiz)
+	if err != nil {
 		return err
 	}
+	resp, err := request.Execute(h.endpointMethod(EndpointInstallBiz), endpointUrl)
 
-	if installResponse.Code != "SUCCESS" {
-		return fmt.Errorf("install biz failed: %s", installResponse.Message)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return &AbortedError{
+				Operation:   "install biz",
+				BizName:     req.BizModel.BizName,
+				BizVersion:  req.BizModel.BizVersion,
+				RequestSent: requestSent.Load(),
+				Err:         ctxErr,
+			}
+		}
+		return err
+	}
+
+	respBody := resp.Body()
+	if onEvent != nil {
+		defer resp.RawBody().Close()
+		if resp.IsSuccess() && isEventStream(resp.Header()) {
+			respBody, err = readInstallEvents(resp.RawBody(), onEvent)
+		} else {
+			respBody, err = io.ReadAll(resp.RawBody())
+			respBody = unwrapEnvelope(h.options.ResponseEnvelope, respBody)
+		}
+		if err != nil {
+			return err
+		}
+		respBody = h.options.Dialect.toArk(respBody)
 	}
 
+	return decodeArkResponse(ctx, h, "install biz", resp.StatusCode(), resp.Header().Get("Content-Type"), respBody, &InstallBizResponse{})
+}
+
+// checkVersionConflict return VersionConflictError if another version of the biz is active unless AllowMultipleVersions,
+// or VersionReusedError if the same version is active with another checksum unless AllowVersionReuse.
+// The check is best effort, the install goes on if the existing biz can't be queried.
+func (h *service) checkVersionConflict(ctx context.Context, req InstallBizRequest) error {
+	allBiz, err := h.QueryAllBiz(ctx, QueryAllArkBizRequest{
+		HostName:     localHostName(req.TargetContainer),
+		Port:         req.TargetContainer.GetPort(),
+		SocketPath:   req.TargetContainer.SocketPath,
+		ForceRefresh: true,
+	})
+	if err != nil {
+		contextutil.GetLogger(ctx).WithError(err).Warn("skip version conflict check")
+		return nil
+	}
+
+	for _, info := range allBiz.Data {
+		if info.BizState != BizStateActivated {
+			continue
+		}
+		if !req.AllowVersionReuse {
+			if err := checkArtifactReused(info, req.BizModel); err != nil {
+				return err
+			}
+		}
+		if !req.AllowMultipleVersions &&
+			info.BizName == req.BizModel.BizName &&
+			info.BizVersion != req.BizModel.BizVersion {
+			return &VersionConflictError{
+				BizName:       info.BizName,
+				ActiveVersion: info.BizVersion,
+				Version:       req.BizModel.BizVersion,
+			}
+		}
+	}
 	return nil
 }
 
-// Use kubectl exec to install biz in pod
-// In this way, the implementation won't be overwhelmed with complicated 7 layers of k8s service
-// The constraint is that user requires with CA or token to access k8s cluster exec.
-// However, this is not a big problem, because this command is using in local DEV phase, not in production.
-func (h *service) installBizInPod(_ context.Context, _ InstallBizRequest) error {
-	panic("not implemented")
+// checkArtifactReused return VersionReusedError if the installed biz of the same version has another checksum.
+// The check is skipped if either checksum is unknown.
+func checkArtifactReused(info ArkBizInfo, bizModel BizModel) error {
+	if info.BizName != bizModel.BizName || info.BizVersion != bizModel.BizVersion ||
+		info.Checksum == "" || bizModel.Checksum == "" || strings.EqualFold(info.Checksum, bizModel.Checksum) {
+		return nil
+	}
+	return &VersionReusedError{
+		BizName:        info.BizName,
+		BizVersion:     info.BizVersion,
+		ActiveChecksum: info.Checksum,
+		Checksum:       bizModel.Checksum,
+	}
 }
 
 func (h *service) InstallBiz(ctx context.Context, req InstallBizRequest) (err error) {
+	if req.TargetContainer, err = targetOrDefault(ctx, req.TargetContainer); err != nil {
+		return err
+	}
+	ctx = WithPodPortCache(ctx)
 	logger := contextutil.GetLogger(ctx)
-	logger.WithField("req", string(runtime.Must(json.Marshal(req)))).Info("install biz started")
+	logger = logger.WithFields(req.loggableRequest())
+	logger.Info("install biz started")
+	defer h.recordOperation("install biz", req.loggableRequest(), time.Now(), &err)
 	defer func() {
 		if err != nil {
 			logger.Error(err)
+			h.invalidateCapabilities(req.TargetContainer, err)
 		} else {
 			logger.Info("install biz completed")
 		}
+		// the biz might be changed even if it fails
+		h.invalidateBiz(req.TargetContainer, req.BizModel)
 	}()
 
-	switch req.TargetContainer.RunType {
-	case ArkContainerRunTypeLocal:
-		err = h.installBizOnLocal(ctx, req)
-	case ArkContainerRunTypeK8s:
-		err = h.installBizInPod(ctx, req)
-	default:
-		err = fmt.Errorf("unknown run type: %s", req.TargetContainer.RunType)
+	if req.BizModel, err = normalizeBizModel(req.BizModel); err != nil {
+		return
+	}
+
+	ctx, span := h.startSpan(ctx, "InstallBiz", req.BizModel, req.TargetContainer)
+	defer func() {
+		h.endSpan(span, err)
+	}()
+
+	if err = h.beforeInstall(ctx, req); err != nil {
+		return
+	}
+	start := time.Now()
+	defer func() {
+		duration := time.Since(start)
+		h.observeInstall(ctx, req.BizModel.BizName, duration, err)
+		h.afterInstall(ctx, req, err, duration)
+	}()
+
+	if !req.AllowMasterBiz {
+		if err = h.checkMasterBiz(ctx, req.TargetContainer, req.BizModel.BizName); err != nil {
+			return
+		}
+	}
+
+	if req.Preflight {
+		if err = h.preflightInstall(ctx, req); err != nil {
+			return
+		}
+	}
+
+	if req.ProbeBizUrl {
+		if err = h.probeBizUrl(ctx, req.TargetContainer, req.BizModel.BizUrl); err != nil {
+			return
+		}
+	}
+
+	if async, _ := req.ExtraParams["async"].(bool); async {
+		if err = h.requireVersion(ctx, req.TargetContainer, operationAsyncInstall); err != nil {
+			return
+		}
+	}
+
+	install := func() error {
+		switch {
+		case h.commandQueueEnabled():
+			return h.installBizByCommand(ctx, req)
+		case req.TargetContainer.RunType == ArkContainerRunTypeLocal:
+			return h.installBizOnLocal(ctx, req)
+		case req.TargetContainer.RunType == ArkContainerRunTypeK8s:
+			return h.installBizInPod(ctx, req)
+		default:
+			return fmt.Errorf("unknown run type: %s", req.TargetContainer.RunType)
+		}
+	}
+	err = install()
+	if err != nil && req.AutoGC && ctx.Err() == nil {
+		err = h.retryInstallAfterGC(ctx, req, install, err)
+	}
+	if err == nil && req.RecordOnPod && req.TargetContainer.RunType == ArkContainerRunTypeK8s {
+		h.recordBizOnPod(ctx, req.TargetContainer, req.BizModel, true)
+	}
+	if err == nil && req.VerifyProbe != nil {
+		err = h.verifyInstall(ctx, req)
 	}
 	return
 }
 
-// Use http client to uninstall biz on local
-func (h *service) unInstallBizOnLocal(_ context.Context, req UnInstallBizRequest) error {
-	resp, err := h.client.R().
-		SetContext(context.Background()).
-		SetBody(req.BizModel).
-		Post(fmt.Sprintf("http://127.0.0.1:%d/uninstallBiz", req.TargetContainer.GetPort()))
+// Use http client to upload biz on local, the bundle is streamed as a multipart body.
+// The body is gzip compressed if it's enabled, and uploaded again without compression if arklet doesn't accept it.
+func (h *service) uploadBizOnLocal(ctx context.Context, req UploadBizRequest) (_ fileutil.FileUrl, err error) {
+	progress := progressutil.OrNop(h.options.UploadProgress)
+	progress.Start(req.Size)
+	defer func() {
+		progress.Finish(err)
+	}()
+
+	if !h.shouldCompressUpload(ctx, req) {
+		return h.postUploadBiz(ctx, req, false)
+	}
+
+	rewind := rewinder(req.Content)
+	bizUrl, err := h.postUploadBiz(ctx, req, true)
+	if !errors.Is(err, errCompressionNotAccepted) {
+		return bizUrl, err
+	}
+	contextutil.GetLogger(ctx).Warn("arklet doesn't accept compressed upload, fall back to raw upload")
+	if err := rewind(); err != nil {
+		return "", fmt.Errorf("%w, and the content can't be uploaded again: %v", errCompressionNotAccepted, err)
+	}
+	return h.postUploadBiz(ctx, req, false)
+}
+
+// postUploadBiz send the upload request, the body is gzip compressed if compress is true.
+func (h *service) postUploadBiz(ctx context.Context, req UploadBizRequest, compress bool) (fileutil.FileUrl, error) {
+	// resolved before the body, whose writer goroutine is only ended by sending the body
+	endpointUrl, err := h.localEndpointUrl(req.TargetContainer, EndpointUploadBiz)
 	if err != nil {
-		return err
+		return "", err
+	}
+	parsed, err := url.Parse(endpointUrl)
+	if err != nil {
+		return "", err
+	}
+	query := parsed.Query()
+	query.Set("bizName", req.BizModel.BizName)
+	query.Set("bizVersion", req.BizModel.BizVersion)
+	parsed.RawQuery = query.Encode()
+
+	content := newProgressReader(req.Content, h.options.UploadProgress)
+	body, contentType := newMultipartBody(req.FileName, content)
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	if compress {
+		header.Set("Content-Encoding", "gzip")
+		body = newGzipReader(body)
+	}
+	// the bundle is streamed, so that it isn't held in memory and the progress follows the bytes actually sent
+	resp, respBody, err := h.postStreaming(ctx, parsed.String(), header, body)
+	if err != nil {
+		return "", err
 	}
 
-	if !resp.IsSuccess() {
-		return fmt.Errorf("uninstall biz http failed with code %d", resp.StatusCode())
+	if compress && resp.StatusCode == http.StatusUnsupportedMediaType {
+		return "", errCompressionNotAccepted
 	}
 
-	uninstallResponse := &UnInstallBizResponse{}
-	if err := json.Unmarshal(resp.Body(), uninstallResponse); err != nil {
-		return err
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return "", fmt.Errorf("upload biz http failed with code %d", resp.StatusCode)
 	}
 
-	if uninstallResponse.Code == "FAILED" && uninstallResponse.Data.Code == "NOT_FOUND_BIZ" {
-		return nil
+	uploadResponse := &UploadBizResponse{}
+	if err := json.Unmarshal(respBody, uploadResponse); err != nil {
+		return "", err
 	}
 
-	if uninstallResponse.Code == "SUCCESS" {
-		return nil
+	if !uploadResponse.Code.IsSuccess() {
+		return "", h.newResponseError("upload biz", uploadResponse.Code, uploadResponse.Message, respBody)
 	}
 
-	return fmt.Errorf("uninstall biz failed: %v", *uninstallResponse)
+	return uploadResponse.Data.BizUrl, nil
 }
 
-// Use kubectl exec to uninstall biz in pod
-func (h *service) unInstallBizInPod(_ context.Context, _ UnInstallBizRequest) error {
-	panic("not implemented")
+func (h *service) UploadBiz(ctx context.Context, req UploadBizRequest) (bizUrl fileutil.FileUrl, err error) {
+	if req.TargetContainer, err = targetOrDefault(ctx, req.TargetContainer); err != nil {
+		return "", err
+	}
+	logger := contextutil.GetLogger(ctx)
+	logger = logger.WithFields(req.loggableRequest())
+	logger.Info("upload biz started")
+	defer h.recordOperation("upload biz", req.loggableRequest(), time.Now(), &err)
+	defer func() {
+		if err != nil {
+			logger.Error(err)
+		} else {
+			logger.WithField("bizUrl", redactedUrl(bizUrl)).Info("upload biz completed")
+		}
+	}()
+
+	switch req.TargetContainer.RunType {
+	case ArkContainerRunTypeLocal:
+		bizUrl, err = h.uploadBizOnLocal(ctx, req)
+	default:
+		err = fmt.Errorf("upload biz is not supported for run type: %s", req.TargetContainer.RunType)
+	}
+	return
+}
+
+// Use http client to uninstall biz on local, existed is false if the biz was already absent
+func (h *service) unInstallBizOnLocal(ctx context.Context, req UnInstallBizRequest) (existed bool, err error) {
+	endpointUrl, err := h.localEndpointUrl(req.TargetContainer, EndpointUnInstallBiz)
+	if err != nil {
+		return false, err
+	}
+	resp, err := h.client.R().
+		SetContext(ctx).
+		SetBody(req.BizModel).
+		Execute(h.endpointMethod(EndpointUnInstallBiz), endpointUrl)
+	if err != nil {
+		return false, err
+	}
+
+	uninstallResponse := &UnInstallBizResponse{}
+	// the whole response is reported as the message of the failed uninstall, as it's always been
+	err = decodeArkResponseWithMessage(ctx, h, "uninstall biz", resp.StatusCode(), resp.Header().Get("Content-Type"), resp.Body(), uninstallResponse,
+		func() string {
+			return fmt.Sprintf("%v", *uninstallResponse)
+		})
+	if IsNotFound(uninstallResponse.ArkResponseBase) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *service) UnInstallBiz(ctx context.Context, req UnInstallBizRequest) error {
+	_, err := h.UnInstallBizWithResult(ctx, req)
+	return err
 }
 
-func (h *service) UnInstallBiz(ctx context.Context, req UnInstallBizRequest) (err error) {
+func (h *service) UnInstallBizWithResult(ctx context.Context, req UnInstallBizRequest) (result *UnInstallResult, err error) {
+	if req.TargetContainer, err = targetOrDefault(ctx, req.TargetContainer); err != nil {
+		return nil, err
+	}
+	ctx = WithPodPortCache(ctx)
 	logger := contextutil.GetLogger(ctx)
-	logger.WithField("req", string(runtime.Must(json.Marshal(req)))).Info("uninstall biz started")
+	logger = logger.WithFields(req.loggableRequest())
+	logger.Info("uninstall biz started")
+	defer h.recordOperation("uninstall biz", req.loggableRequest(), time.Now(), &err)
 	defer func() {
 		if err != nil {
 			logger.Error(err)
+			h.invalidateCapabilities(req.TargetContainer, err)
 		} else {
 			logger.Info("uninstall biz completed")
 		}
+		// the biz might be changed even if it fails
+		h.invalidateBiz(req.TargetContainer, req.BizModel)
 	}()
 
-	switch req.TargetContainer.RunType {
-	case ArkContainerRunTypeLocal:
-		err = h.unInstallBizOnLocal(ctx, req)
-	case ArkContainerRunTypeK8s:
-		err = h.unInstallBizInPod(ctx, req)
+	if req.BizModel, err = normalizeBizModel(req.BizModel); err != nil {
+		return
+	}
+
+	ctx, span := h.startSpan(ctx, "UnInstallBiz", req.BizModel, req.TargetContainer)
+	defer func() {
+		h.endSpan(span, err)
+	}()
+
+	if err = h.beforeUninstall(ctx, req); err != nil {
+		return
+	}
+	start := time.Now()
+	defer func() {
+		duration := time.Since(start)
+		h.observeUninstall(ctx, req.BizModel.BizName, duration, err)
+		h.afterUninstall(ctx, req, err, duration)
+	}()
+
+	if !req.AllowMasterBiz {
+		if err = h.checkMasterBiz(ctx, req.TargetContainer, req.BizModel.BizName); err != nil {
+			return
+		}
+	}
+
+	result = &UnInstallResult{}
+	if req.DrainFirst {
+		if err = h.drainBiz(ctx, req, result); err != nil {
+			return
+		}
+	}
+
+	uninstallStart := time.Now()
+	switch {
+	case h.commandQueueEnabled():
+		result.Existed, err = h.unInstallBizByCommand(ctx, req)
+	case req.TargetContainer.RunType == ArkContainerRunTypeLocal:
+		result.Existed, err = h.unInstallBizOnLocal(ctx, req)
+	case req.TargetContainer.RunType == ArkContainerRunTypeK8s:
+		result.Existed, err = h.unInstallBizInPod(ctx, req)
 	default:
 		err = fmt.Errorf("unknown run type: %s", req.TargetContainer.RunType)
 	}
+	result.Phases = append(result.Phases, PhaseResult{
+		Name:     PhaseUninstall,
+		Duration: time.Since(uninstallStart),
+		Err:      err,
+	})
+	if err == nil && req.RecordOnPod && req.TargetContainer.RunType == ArkContainerRunTypeK8s {
+		h.recordBizOnPod(ctx, req.TargetContainer, req.BizModel, false)
+	}
 	return
 }
 
-func (h *service) QueryAllBiz(ctx context.Context, req QueryAllArkBizRequest) (*QueryAllArkBizResponse, error) {
+func (h *service) QueryAllBiz(ctx context.Context, req QueryAllArkBizRequest) (resp *QueryAllArkBizResponse, err error) {
+	defer h.recordOperation("query all biz", req.loggableRequest(), time.Now(), &err)
+	return h.queryAllBiz(ctx, req)
+}
+
+func (h *service) queryAllBiz(ctx context.Context, req QueryAllArkBizRequest) (*QueryAllArkBizResponse, error) {
 	logger := contextutil.GetLogger(ctx)
-	logger.WithField("req", string(runtime.Must(json.Marshal(req)))).Info("query all biz started")
+	logger = logger.WithFields(req.loggableRequest())
+	logger.Info("query all biz started")
+
+	if err := requirePort(req.Port, req.SocketPath); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	cacheKey := queryAllBizCacheKey(req.HostName, req.Port, req.SocketPath)
+	if h.queryAllBizCache != nil && !req.ForceRefresh && !isCacheBypassed(ctx) {
+		if cached, ok := h.queryAllBizCache.get(cacheKey); ok {
+			logger.Info("query all biz completed from cache")
+			return cached, nil
+		}
+	}
 
 	resp, err := h.client.R().
-		SetContext(context.Background()).
+		SetContext(ctx).
 		SetBody(req).
-		Post(fmt.Sprintf("http://%s:%d/queryAllBiz", req.HostName, req.Port))
+		Execute(h.endpointMethod(EndpointQueryAllBiz), h.endpointUrl(h.hostOf(req.HostName, req.SocketPath), req.Port, EndpointQueryAllBiz))
 
 	if err != nil {
 		logger.Error(err)
@@ -205,12 +885,129 @@ func (h *service) QueryAllBiz(ctx context.Context, req QueryAllArkBizRequest) (*
 		return nil, err
 	}
 
-	if queryAllBizResponse.Code != "SUCCESS" {
-		err = fmt.Errorf("query all biz failed: %s", queryAllBizResponse.Message)
+	if !queryAllBizResponse.Code.IsSuccess() {
+		err = h.newResponseError("query all biz", queryAllBizResponse.Code, queryAllBizResponse.Message, resp.Body())
 		logger.Error(err)
 		return nil, err
 	}
 
+	if h.queryAllBizCache != nil {
+		h.queryAllBizCache.put(cacheKey, queryAllBizResponse)
+	}
 	logger.Info("query all biz completed")
 	return queryAllBizResponse, nil
 }
+
+// invalidateQueryAllBiz drop the cached query all biz result of target of any run type once it's mutated.
+func (h *service) invalidateQueryAllBiz(target ArkContainerRuntimeInfo) {
+	if h.queryAllBizCache == nil {
+		return
+	}
+	if target.RunType == ArkContainerRunTypeLocal {
+		h.queryAllBizCache.remove(queryAllBizCacheKey(localHostName(target), target.GetPort(), target.SocketPath))
+	}
+	// the containers queried by other means, like kubectl exec, are cached by the target itself
+	h.queryAllBizCache.remove(containerCacheKey(target))
+}
+
+// queryBizByQueryAllBiz is the fallback of QueryBiz for arklets without the detail endpoint.
+func (h *service) queryBizByQueryAllBiz(ctx context.Context, target ArkContainerRuntimeInfo, bizName, bizVersion string) (*BizDetail, error) {
+	allBiz, err := h.QueryAllBiz(ctx, QueryAllArkBizRequest{
+		HostName:   localHostName(target),
+		Port:       target.GetPort(),
+		SocketPath: target.SocketPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range allBiz.Data {
+		if info.BizName == bizName && (bizVersion == "" || info.BizVersion == bizVersion) {
+			return &BizDetail{ArkBizInfo: info}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s:%s", ErrBizNotFound, bizName, bizVersion)
+}
+
+// Use http client to query biz detail on local, fallback to query all biz if the detail endpoint is absent.
+func (h *service) queryBizOnLocal(ctx context.Context, target ArkContainerRuntimeInfo, bizName, bizVersion string) (*BizDetail, error) {
+	endpointUrl, err := h.localEndpointUrl(target, EndpointQueryBiz)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.client.R().
+		SetContext(ctx).
+		SetBody(BizModel{
+			BizName:    bizName,
+			BizVersion: bizVersion,
+		}).
+		Post(endpointUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == http.StatusNotFound {
+		return h.queryBizByQueryAllBiz(ctx, target, bizName, bizVersion)
+	}
+
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("query biz http failed with code %d", resp.StatusCode())
+	}
+
+	queryBizResponse := &QueryBizResponse{}
+	if err := json.Unmarshal(resp.Body(), queryBizResponse); err != nil {
+		return nil, err
+	}
+
+	if queryBizResponse.Code == ResponseCodeNotFoundBiz ||
+		(queryBizResponse.Code.IsSuccess() && queryBizResponse.Data == nil) {
+		return nil, fmt.Errorf("%w: %s:%s", ErrBizNotFound, bizName, bizVersion)
+	}
+
+	if !queryBizResponse.Code.IsSuccess() {
+		return nil, h.newResponseError("query biz", queryBizResponse.Code, queryBizResponse.Message, resp.Body())
+	}
+
+	return queryBizResponse.Data, nil
+}
+
+func (h *service) QueryBiz(ctx context.Context, target ArkContainerRuntimeInfo, bizName, bizVersion string) (detail *BizDetail, err error) {
+	if target, err = targetOrDefault(ctx, target); err != nil {
+		return nil, err
+	}
+	logger := contextutil.GetLogger(ctx).
+		WithFields(targetFields(target)).
+		WithField("bizName", bizName).
+		WithField("bizVersion", bizVersion)
+	logger.Info("query biz started")
+	defer func() {
+		if err != nil {
+			logger.Error(err)
+			h.invalidateCapabilities(target, err)
+		} else {
+			logger.Info("query biz completed")
+		}
+	}()
+
+	cacheKey := bizCacheKey(target, bizName, bizVersion)
+	if h.queryBizCache != nil && !isCacheBypassed(ctx) {
+		if cached, ok := h.queryBizCache.get(cacheKey); ok {
+			logger.Debug("query biz hit cache")
+			return cached, nil
+		}
+	}
+
+	switch target.RunType {
+	case ArkContainerRunTypeLocal:
+		detail, err = h.queryBizOnLocal(ctx, target, bizName, bizVersion)
+	case ArkContainerRunTypeK8s:
+		detail, err = h.queryBizInPod(ctx, target, bizName, bizVersion)
+	default:
+		err = fmt.Errorf("query biz is not supported for run type: %s", target.RunType)
+	}
+
+	if err == nil && h.queryBizCache != nil {
+		h.queryBizCache.put(cacheKey, detail)
+	}
+	return
+}
@@ -17,9 +17,17 @@ package ark
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/v1/constant"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -29,16 +37,29 @@ func mockHttpServer(
 	path string,
 	handler func(w http.ResponseWriter, r *http.Request),
 ) (int, func()) {
-	// Create a listener on a random port.
-	listener, err := net.Listen("tcp", ":0")
+	_, port, cancel, err := mockHttpServerOn("127.0.0.1", path, handler)
 	if err != nil {
 		panic(err)
 	}
+	return port, cancel
+}
+
+// mockHttpServerOn serve the handler on a random port of host, and return the host and port it's actually bound to.
+func mockHttpServerOn(
+	host string,
+	path string,
+	handler func(w http.ResponseWriter, r *http.Request),
+) (string, int, func(), error) {
+	// Create a listener on a random port.
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return "", 0, nil, err
+	}
 
 	mux := http.NewServeMux()
 
-	// Retrieve the port.
-	port := listener.Addr().(*net.TCPAddr).Port
+	// Retrieve the host and port.
+	addr := listener.Addr().(*net.TCPAddr)
 	mux.Handle(path, http.HandlerFunc(handler))
 
 	server := &http.Server{
@@ -51,9 +72,9 @@ func mockHttpServer(
 		}
 	}()
 
-	return port, func() {
+	return addr.IP.String(), addr.Port, func() {
 		listener.Close()
-	}
+	}, nil
 }
 
 func TestInstallBiz_Success(t *testing.T) {
@@ -293,3 +314,1258 @@ func TestQueryAllBiz_HappyPath(t *testing.T) {
 		},
 	}, info)
 }
+
+func TestInstallBiz_IdempotencyKeyStableAcrossRetries(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithRetry(3, time.Millisecond), WithIdempotencyKey(true))
+
+	var keys []string
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(headerIdempotencyKey))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    "SUCCESS",
+			"message": "install biz success!",
+		})
+	})
+	defer cancel()
+
+	req := InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		IdempotencyNonce: "nonce",
+	}
+	err := client.InstallBiz(ctx, req)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 3, len(keys))
+	assert.Equal(t, idempotencyKey(req), keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+
+	// different nonce means a different logical request
+	anotherReq := req
+	anotherReq.IdempotencyNonce = "another"
+	assert.NotEqual(t, idempotencyKey(req), idempotencyKey(anotherReq))
+}
+
+func TestInstallBiz_IdempotencyKeyDisabledByDefault(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	key := "unset"
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		key = r.Header.Get(headerIdempotencyKey)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "", key)
+}
+
+func TestInstallBiz_TimeoutIsRetried(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithRetry(2, time.Millisecond))
+
+	calls := 0
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		code := "TIMEOUT"
+		if calls > 1 {
+			code = "success"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": code,
+		})
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestInstallBiz_RetryClassifiedByCode(t *testing.T) {
+	tests := []struct {
+		code          string
+		status        int
+		expectedCalls int
+	}{
+		// transient, retried until success
+		{code: "CONTAINER_BUSY", status: http.StatusOK, expectedCalls: 2},
+		// permanent, never retried even with a server error
+		{code: "INSTALLATION_FAILED", status: http.StatusOK, expectedCalls: 1},
+		{code: "INSTALLATION_FAILED", status: http.StatusInternalServerError, expectedCalls: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			ctx := context.Background()
+			client := BuildService(ctx, WithRetry(2, time.Millisecond))
+
+			calls := 0
+			port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls > 1 {
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "SUCCESS"})
+					return
+				}
+				w.WriteHeader(test.status)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"code":    "FAILED",
+					"message": "install biz failed",
+					"data":    map[string]interface{}{"code": test.code},
+				})
+			})
+			defer cancel()
+
+			err := client.InstallBiz(ctx, InstallBizRequest{
+				BizModel: BizModel{
+					BizName:    "biz",
+					BizVersion: "0.0.1-SNAPSHOT",
+				},
+				TargetContainer: ArkContainerRuntimeInfo{
+					RunType: ArkContainerRunTypeLocal,
+					Port:    &port,
+				},
+			})
+			assert.Equal(t, test.expectedCalls, calls)
+			assert.Equal(t, test.expectedCalls == 2, err == nil)
+		})
+	}
+}
+
+func TestInstallBiz_RetryLogged(t *testing.T) {
+	logs := captureLogs(t)
+	ctx := context.Background()
+	client := BuildService(ctx, WithRetry(3, 10*time.Millisecond))
+
+	calls := 0
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			_, _ = w.Write([]byte(`{"code":"FAILED","data":{"code":"CONTAINER_BUSY"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+		}
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+
+	var retries []string
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, "request failed, retrying") {
+			retries = append(retries, line)
+		}
+	}
+	if assert.Equal(t, 2, len(retries)) {
+		for _, field := range []string{"attempt=1", "maxAttempts=4", "path=/installBiz", "status=503", "wait=10ms"} {
+			assert.Contains(t, retries[0], field)
+		}
+		for _, field := range []string{"attempt=2", "status=200", "code=CONTAINER_BUSY", "wait=20ms"} {
+			assert.Contains(t, retries[1], field)
+		}
+	}
+	assert.NotContains(t, logs.String(), "retries exhausted")
+}
+
+func TestInstallBiz_TimeoutIsRetriableError(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    "TIMEOUT",
+			"message": "install biz timeout",
+		})
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.NotNil(t, err)
+	assert.True(t, IsRetriable(err))
+	assert.Equal(t, "install biz failed: install biz timeout", err.Error())
+}
+
+func TestInstallBiz_UnknownCodeIsFailure(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    "Some_New_Code",
+			"message": "something new",
+		})
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, ResponseCode("Some_New_Code"), responseErr.Code)
+	assert.False(t, IsRetriable(err))
+}
+
+func TestUnInstallBiz_NotInstalledCaseInsensitive(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	port, cancel := mockHttpServer("/uninstallBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "failed",
+			"data": map[string]interface{}{
+				"code": "not_found_biz",
+			},
+		})
+	})
+	defer cancel()
+
+	err := client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.Nil(t, err)
+}
+
+func TestUnInstallBizWithResult_Existed(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	code := "SUCCESS"
+	port, cancel := mockHttpServer("/uninstallBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": code,
+			"data": map[string]interface{}{
+				"code": "NOT_FOUND_BIZ",
+			},
+		})
+	})
+	defer cancel()
+	req := UnInstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	}
+
+	result, err := client.UnInstallBizWithResult(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, result.Existed)
+
+	code = "FAILED"
+	result, err = client.UnInstallBizWithResult(ctx, req)
+	assert.Nil(t, err)
+	assert.False(t, result.Existed)
+}
+
+func TestUnInstallBiz_DataShapes(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	body := ""
+	port, cancel := mockHttpServer("/uninstallBiz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	})
+	defer cancel()
+	req := UnInstallBizRequest{
+		BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	}
+
+	// the data is the list of the uninstalled biz
+	body = `{"code":"SUCCESS","data":[{"bizName":"biz","bizVersion":"0.0.1","bizState":"DEACTIVATED"}],"message":"Uninstall biz: biz:0.0.1 success."}`
+	result, err := client.UnInstallBizWithResult(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, result.Existed)
+
+	body = `{"code":"NOT_FOUND_BIZ","data":[],"message":"biz not found"}`
+	result, err = client.UnInstallBizWithResult(ctx, req)
+	assert.Nil(t, err)
+	assert.False(t, result.Existed)
+
+	body = `{"code":"FAILED","data":{"code":"NOT_FOUND_BIZ","message":"biz not found","bizInfos":[]}}`
+	result, err = client.UnInstallBizWithResult(ctx, req)
+	assert.Nil(t, err)
+	assert.False(t, result.Existed)
+
+	body = `{"code":"FAILED","data":[{"bizName":"biz","bizVersion":"0.0.1","bizState":"BROKEN"}],"message":"uninstall biz failed"}`
+	_, err = client.UnInstallBizWithResult(ctx, req)
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, ResponseCodeFailed, responseErr.Code)
+	assert.Equal(t, 1, len(responseErr.BizInfos))
+}
+
+func TestArkResponseBase_DataShapes(t *testing.T) {
+	resp := ArkResponseBase{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"code":"SUCCESS","data":[{"bizName":"biz","bizVersion":"0.0.1","bizState":"ACTIVATED"}]}`), &resp))
+	_, isObject := resp.DataAsObject()
+	assert.False(t, isObject)
+	bizList, err := resp.DataAsBizList()
+	assert.Nil(t, err)
+	assert.Equal(t, []ArkBizInfo{{BizName: "biz", BizVersion: "0.0.1", BizState: BizStateActivated}}, bizList)
+
+	resp = ArkResponseBase{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"code":"FAILED","data":{"code":"NOT_FOUND_BIZ","bizInfos":[{"bizName":"biz","bizVersion":"0.0.1"}]}}`), &resp))
+	data, isObject := resp.DataAsObject()
+	assert.True(t, isObject)
+	assert.Equal(t, ResponseCodeNotFoundBiz, data.Code)
+	assert.True(t, IsNotFound(resp))
+	bizList, err = resp.DataAsBizList()
+	assert.Nil(t, err)
+	assert.Equal(t, []ArkBizInfo{{BizName: "biz", BizVersion: "0.0.1"}}, bizList)
+
+	resp = ArkResponseBase{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"code":"SUCCESS","data":"ok"}`), &resp))
+	_, err = resp.DataAsBizList()
+	assert.NotNil(t, err)
+}
+
+func TestQueryBiz_DetailEndpoint(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	port, cancel := mockHttpServer("/queryBiz", func(w http.ResponseWriter, r *http.Request) {
+		req := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		assert.Equal(t, map[string]string{"bizName": "biz1", "bizVersion": "0.0.1-SNAPSHOT"}, req)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+			"data": map[string]interface{}{
+				"bizName":        "biz1",
+				"bizState":       "ACTIVATED",
+				"bizVersion":     "0.0.1-SNAPSHOT",
+				"webContextPath": "biz1",
+				"classLoader":    "BizClassLoader(bizIdentity=biz1:0.0.1-SNAPSHOT)",
+				"dependencies":   []string{"com.alipay:common:1.0.0"},
+				"activatedTime":  1700000000000,
+				"futureField":    map[string]interface{}{"foo": "bar"},
+			},
+		})
+	})
+	defer cancel()
+
+	detail, err := client.QueryBiz(ctx, ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}, "biz1", "0.0.1-SNAPSHOT")
+	assert.Nil(t, err)
+	assert.Equal(t, BizStateActivated, detail.BizState)
+	assert.Equal(t, "biz1", detail.WebContextPath)
+	assert.Equal(t, "BizClassLoader(bizIdentity=biz1:0.0.1-SNAPSHOT)", detail.ClassLoader)
+	assert.Equal(t, []string{"com.alipay:common:1.0.0"}, detail.Dependencies)
+	assert.Equal(t, int64(1700000000000), detail.ActivatedTime)
+	assert.Equal(t, 1, len(detail.Extra))
+	assert.JSONEq(t, `{"foo":"bar"}`, string(detail.Extra["futureField"]))
+
+	// unknown fields survive a round trip
+	data, err := json.Marshal(detail)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"futureField":{"foo":"bar"}`)
+}
+
+func TestQueryBiz_FallbackToQueryAllBiz(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	port, cancel := mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+			"data": []map[string]interface{}{
+				{"bizName": "biz1", "bizState": "ACTIVATED", "bizVersion": "0.0.1"},
+				{"bizName": "biz2", "bizState": "DEACTIVATED", "bizVersion": "0.0.2"},
+			},
+		})
+	})
+	defer cancel()
+
+	target := ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}
+
+	detail, err := client.QueryBiz(ctx, target, "biz2", "")
+	assert.Nil(t, err)
+	assert.Equal(t, &BizDetail{ArkBizInfo: ArkBizInfo{
+		BizName:    "biz2",
+		BizState:   "DEACTIVATED",
+		BizVersion: "0.0.2",
+	}}, detail)
+
+	_, err = client.QueryBiz(ctx, target, "biz1", "0.0.2")
+	assert.True(t, errors.Is(err, ErrBizNotFound))
+}
+
+func TestQueryBiz_Cache(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithQueryBizCache(time.Minute))
+	now := time.Now()
+	client.(*service).queryBizCache.now = func() time.Time {
+		return now
+	}
+
+	calls := 0
+	port, cancel := mockHttpServer("/queryBiz", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+			"data": map[string]interface{}{
+				"bizName":    "biz1",
+				"bizVersion": "0.0.1",
+				"bizState":   "ACTIVATED",
+			},
+		})
+	})
+	defer cancel()
+
+	target := ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}
+
+	first, err := client.QueryBiz(ctx, target, "biz1", "0.0.1")
+	assert.Nil(t, err)
+	second, err := client.QueryBiz(ctx, target, "biz1", "0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, first, second)
+
+	// bypass the cache per call
+	_, err = client.QueryBiz(WithCacheBypass(ctx), target, "biz1", "0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+	// expired entries are refreshed
+	now = now.Add(2 * time.Minute)
+	_, err = client.QueryBiz(ctx, target, "biz1", "0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+	_, err = client.QueryBiz(ctx, target, "biz1", "0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestQueryBiz_CacheInvalidatedByMutations(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithQueryBizCache(time.Minute))
+
+	var queries int32
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/queryBiz":
+			atomic.AddInt32(&queries, 1)
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"bizName":"biz1","bizVersion":"0.0.1","bizState":"ACTIVATED"}}`))
+		case "/queryAllBiz":
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[]}`))
+		default:
+			_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+		}
+	})
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	query := func() {
+		_, err := client.QueryBiz(ctx, target, "biz1", "0.0.1")
+		assert.Nil(t, err)
+		_, err = client.QueryBiz(ctx, target, "biz1", "")
+		assert.Nil(t, err)
+	}
+	query()
+	query()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&queries))
+
+	// install drops the cached biz of both the exact and any version
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz1", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz1.jar"},
+		TargetContainer: target,
+	})
+	assert.Nil(t, err)
+	query()
+	assert.Equal(t, int32(4), atomic.LoadInt32(&queries))
+
+	// so does uninstall
+	err = client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz1", BizVersion: "0.0.1"},
+		TargetContainer: target,
+	})
+	assert.Nil(t, err)
+	query()
+	assert.Equal(t, int32(6), atomic.LoadInt32(&queries))
+}
+
+func TestQueryAllBiz_Cache(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithQueryAllBizCache(time.Minute, 8))
+
+	var queries int32
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/queryAllBiz":
+			atomic.AddInt32(&queries, 1)
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[{"bizName":"biz1","bizVersion":"0.0.1","bizState":"ACTIVATED"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+		}
+	})
+	defer cancel()
+
+	req := QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port}
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.QueryAllBiz(ctx, req)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	_, err := client.QueryAllBiz(ctx, req)
+	assert.Nil(t, err)
+	cached := atomic.LoadInt32(&queries)
+	// concurrent misses may all reach the arklet, later queries are served from the cache
+	assert.True(t, cached >= 1 && cached <= 8)
+
+	// bypass the cache per call
+	_, err = client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port, ForceRefresh: true})
+	assert.Nil(t, err)
+	assert.Equal(t, cached+1, atomic.LoadInt32(&queries))
+	_, err = client.QueryAllBiz(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, cached+1, atomic.LoadInt32(&queries))
+
+	// install invalidates the cache of the target, the version conflict check always queries the arklet
+	err = client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz2", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz2.jar"},
+		TargetContainer: target,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, cached+2, atomic.LoadInt32(&queries))
+	_, err = client.QueryAllBiz(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, cached+3, atomic.LoadInt32(&queries))
+
+	// so does uninstall
+	_, err = client.UnInstallBizWithResult(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz2", BizVersion: "0.0.1"},
+		TargetContainer: target,
+	})
+	assert.Nil(t, err)
+	_, err = client.QueryAllBiz(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, cached+4, atomic.LoadInt32(&queries))
+}
+
+func TestQueryAllBiz_CacheKeyOfLoopback(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx, WithQueryAllBizCache(time.Minute, 8))
+
+	var queries int32
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/queryAllBiz" {
+			atomic.AddInt32(&queries, 1)
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel()
+
+	// the names of the loopback host share the cache
+	for _, host := range []string{"localhost", "127.0.0.1", "LOCALHOST"} {
+		_, err := client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: host, Port: port})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&queries))
+
+	// so the entry queried by localhost is dropped by the uninstall on the default host
+	_, err := client.UnInstallBizWithResult(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "biz1", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	})
+	assert.Nil(t, err)
+	_, err = client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "localhost", Port: port})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&queries))
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache[int](time.Minute, 2)
+	cache.put("a", 1)
+	cache.put("b", 2)
+	_, _ = cache.get("a")
+	// b is the least recently used
+	cache.put("c", 3)
+
+	_, ok := cache.get("b")
+	assert.False(t, ok)
+	value, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	value, ok = cache.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+
+	cache.remove("a")
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+}
+
+func TestClose_SubsequentCallsFail(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	req := InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	}
+	assert.Nil(t, client.InstallBiz(ctx, req))
+
+	assert.Nil(t, client.Close())
+	// close is idempotent
+	assert.Nil(t, client.Close())
+
+	err := client.InstallBiz(ctx, req)
+	assert.True(t, errors.Is(err, ErrClientClosed))
+
+	_, err = client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.True(t, errors.Is(err, ErrClientClosed))
+}
+
+func TestClose_BaseContext(t *testing.T) {
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	executed := false
+	client, err := NewService(WithBaseContext(baseCtx), WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		executed = true
+		return []string{`{"code":"SUCCESS"}`}, nil
+	}))
+	assert.Nil(t, err)
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"}
+	req := UnInstallBizRequest{BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"}, TargetContainer: target}
+
+	// the calls are bound to their own context, not the base context
+	ctx := context.Background()
+	assert.Nil(t, client.UnInstallBiz(ctx, req))
+	assert.True(t, executed)
+
+	cancelBase()
+	assert.Eventually(t, func() bool { return client.(*service).closed.Load() }, time.Second, time.Millisecond)
+
+	// kubectl isn't run by a closed client either
+	executed = false
+	err = client.UnInstallBiz(ctx, req)
+	assert.True(t, errors.Is(err, ErrClientClosed))
+	assert.False(t, executed)
+
+	_, err = client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: 1238})
+	assert.True(t, errors.Is(err, ErrClientClosed))
+	assert.Nil(t, client.Close())
+}
+
+func TestNewService_OptionPrecedence(t *testing.T) {
+	ctx := context.Background()
+	userAgent := ""
+	port, cancel := mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel()
+
+	// the later options win over the earlier ones, and the untouched fields keep their defaults
+	client, err := NewService(WithUserAgent("first"), WithRetry(3, time.Second), WithUserAgent("second"), WithRetry(1, time.Millisecond))
+	assert.Nil(t, err)
+	defer client.Close()
+	options := client.(*service).options
+	assert.Equal(t, "second", options.UserAgent)
+	assert.Equal(t, 1, options.RetryCount)
+	assert.Equal(t, time.Millisecond, options.RetryWaitTime)
+	assert.Equal(t, defaultClientOptions().DefaultTimeout, options.DefaultTimeout)
+
+	_, err = client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	assert.Equal(t, "second", userAgent)
+
+	// an invalid option is reported instead of being overridden silently
+	_, err = NewService(WithDialect(Dialect{Name: "broken", Fields: map[string]string{"bizName": ""}}), WithUserAgent("third"))
+	assert.NotNil(t, err)
+}
+
+func TestBuildService_InvalidOptions(t *testing.T) {
+	logs := captureLogs(t)
+
+	// the deprecated builder falls back to the default options instead of panicking
+	client := BuildService(context.Background(), WithDialect(Dialect{Name: "broken", Fields: map[string]string{"bizName": ""}}), WithUserAgent("third"))
+	assert.NotNil(t, client)
+	defer client.Close()
+	assert.Equal(t, defaultClientOptions().UserAgent, client.(*service).options.UserAgent)
+	assert.Contains(t, logs.String(), "invalid options of ark service, fallback to the default options")
+}
+
+func TestInstallBiz_ExtraParams(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	body := map[string]interface{}{}
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+			BizUrl:     "file:///tmp/biz.jar",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		ExtraParams: map[string]interface{}{
+			"async":           true,
+			"installStrategy": "serial",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"bizName":         "biz",
+		"bizVersion":      "0.0.1-SNAPSHOT",
+		"bizUrl":          "file:///tmp/biz.jar",
+		"async":           true,
+		"installStrategy": "serial",
+	}, body)
+}
+
+func TestInstallBiz_ExtraParamsCannotOverrideCoreFields(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	called := false
+	port, cancel := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+			BizUrl:     "file:///tmp/biz.jar",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		ExtraParams: map[string]interface{}{
+			"bizName": "other",
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "extra param \"bizName\" is reserved and can't be overridden", err.Error())
+	assert.False(t, called)
+}
+
+func mockArkletWithActiveBiz(t *testing.T, installed *bool, bizInfos ...ArkBizInfo) (int, func()) {
+	return mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": "SUCCESS",
+			})
+		case "/queryAllBiz":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": "SUCCESS",
+				"data": bizInfos,
+			})
+		case "/installBiz":
+			*installed = true
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": "SUCCESS",
+			})
+		default:
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+	})
+}
+
+func TestInstallBiz_VersionConflict(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	installed := false
+	port, cancel := mockArkletWithActiveBiz(t, &installed, ArkBizInfo{
+		BizName:    "biz",
+		BizVersion: "0.0.1",
+		BizState:   BizStateActivated,
+	})
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.2",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	conflictErr := &VersionConflictError{}
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, "0.0.1", conflictErr.ActiveVersion)
+	assert.False(t, installed)
+}
+
+func TestInstallBiz_VersionConflictAllowed(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	installed := false
+	port, cancel := mockArkletWithActiveBiz(t, &installed,
+		ArkBizInfo{
+			BizName:    "biz",
+			BizVersion: "0.0.1",
+			BizState:   BizStateActivated,
+		},
+		ArkBizInfo{
+			BizName:    "other",
+			BizVersion: "0.0.1",
+			BizState:   BizStateActivated,
+		},
+	)
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.2",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		AllowMultipleVersions: true,
+	})
+	assert.Nil(t, err)
+	assert.True(t, installed)
+}
+
+func TestInstallBiz_VersionReused(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	tests := []struct {
+		name           string
+		activeChecksum string
+		checksum       string
+		allowReuse     bool
+		reused         bool
+	}{
+		{name: "same artifact", activeChecksum: "ABC", checksum: "abc"},
+		{name: "different artifact", activeChecksum: "abc", checksum: "def", reused: true},
+		{name: "different artifact allowed", activeChecksum: "abc", checksum: "def", allowReuse: true},
+		{name: "active checksum unavailable", checksum: "def"},
+		{name: "checksum unavailable", activeChecksum: "abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			installed := false
+			port, cancel := mockArkletWithActiveBiz(t, &installed, ArkBizInfo{
+				BizName:    "biz",
+				BizVersion: "1.2.0",
+				BizState:   BizStateActivated,
+				Checksum:   test.activeChecksum,
+			})
+			defer cancel()
+
+			err := client.InstallBiz(ctx, InstallBizRequest{
+				BizModel: BizModel{
+					BizName:    "biz",
+					BizVersion: "1.2.0",
+					Checksum:   test.checksum,
+				},
+				TargetContainer: ArkContainerRuntimeInfo{
+					RunType: ArkContainerRunTypeLocal,
+					Port:    &port,
+				},
+				AllowVersionReuse: test.allowReuse,
+			})
+			if !test.reused {
+				assert.Nil(t, err)
+				assert.True(t, installed)
+				return
+			}
+			assert.True(t, errors.Is(err, ErrVersionReusedWithDifferentArtifact))
+			reusedErr := &VersionReusedError{}
+			assert.True(t, errors.As(err, &reusedErr))
+			assert.Equal(t, "abc", reusedErr.ActiveChecksum)
+			assert.Equal(t, "def", reusedErr.Checksum)
+			assert.False(t, installed)
+		})
+	}
+}
+
+func TestDisableKeepAlives(t *testing.T) {
+	ctx := context.Background()
+
+	transport := BuildService(ctx).(*service).client.GetClient().Transport.(*lengthCheckingTransport).next.(*deadlineTransport).next.(*http.Transport)
+	assert.False(t, transport.DisableKeepAlives)
+
+	client := BuildService(ctx, WithDisableKeepAlives(true))
+	transport = client.(*service).client.GetClient().Transport.(*lengthCheckingTransport).next.(*deadlineTransport).next.(*http.Transport)
+	assert.True(t, transport.DisableKeepAlives)
+
+	connectionClosed := false
+	port, cancel := mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		connectionClosed = r.Close
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+	defer cancel()
+
+	_, err := client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	assert.True(t, connectionClosed)
+}
+
+func mockArkletWithMasterBiz(masterBizName string, calls *[]string) (int, func()) {
+	return mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, r.URL.Path)
+		if r.URL.Path == "/health" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": "SUCCESS",
+				"data": map[string]interface{}{
+					"healthData": map[string]interface{}{
+						"masterBizInfo": map[string]interface{}{
+							"bizName":    masterBizName,
+							"bizVersion": "1.0.0",
+							"bizState":   "ACTIVATED",
+						},
+					},
+				},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+		})
+	})
+}
+
+func TestInstallBiz_MasterBizProtected(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	var calls []string
+	port, cancel := mockArkletWithMasterBiz("base", &calls)
+	defer cancel()
+
+	target := ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "base", BizVersion: "1.0.0"},
+		TargetContainer: target,
+	})
+	assert.True(t, errors.Is(err, ErrMasterBizProtected))
+
+	err = client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "base", BizVersion: "1.0.0"},
+		TargetContainer: target,
+	})
+	assert.True(t, errors.Is(err, ErrMasterBizProtected))
+
+	// the master biz is discovered only once, and nothing is installed or uninstalled
+	assert.Equal(t, []string{"/health"}, calls)
+
+	err = client.UnInstallBiz(ctx, UnInstallBizRequest{
+		BizModel:        BizModel{BizName: "base", BizVersion: "1.0.0"},
+		TargetContainer: target,
+		AllowMasterBiz:  true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/health", "/uninstallBiz"}, calls)
+}
+
+func TestInstallBiz_NotMasterBiz(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	var calls []string
+	port, cancel := mockArkletWithMasterBiz("base", &calls)
+	defer cancel()
+
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{BizName: "biz", BizVersion: "1.0.0"},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, calls, "/installBiz")
+}
+
+func mockInstallFailedWithStackTrace() (int, func()) {
+	return mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    "FAILED",
+			"message": "install biz failed",
+			"data": map[string]interface{}{
+				"code":            "FAILED",
+				"errorStackTrace": "java.lang.IllegalStateException: boom\n\tat com.alipay.Foo.bar(Foo.java:1)",
+				"bizInfos":        []interface{}{map[string]interface{}{"bizName": "biz"}},
+			},
+		})
+	})
+}
+
+func TestInstallBiz_ErrorDetail(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockInstallFailedWithStackTrace()
+	defer cancel()
+
+	req := InstallBizRequest{
+		BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	}
+
+	err := BuildService(ctx).InstallBiz(ctx, req)
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, "install biz failed: install biz failed", err.Error())
+	assert.Equal(t, "java.lang.IllegalStateException: boom\n\tat com.alipay.Foo.bar(Foo.java:1)", responseErr.ErrorDetail())
+	assert.Equal(t, 1, len(responseErr.BizInfos))
+
+	err = BuildService(ctx, WithErrorDetail(true)).InstallBiz(ctx, req)
+	assert.Equal(t, "install biz failed: install biz failed\n"+
+		"java.lang.IllegalStateException: boom\n\tat com.alipay.Foo.bar(Foo.java:1)", err.Error())
+}
+
+func TestResponseError_StackTraceInMessage(t *testing.T) {
+	err := &ResponseError{
+		Operation: "install biz",
+		Message:   "boom\njava.lang.RuntimeException: boom\n\tat Foo.bar(Foo.java:1)\n",
+	}
+	assert.Equal(t, "install biz failed: boom", err.Error())
+	assert.Equal(t, "java.lang.RuntimeException: boom\n\tat Foo.bar(Foo.java:1)", err.ErrorDetail())
+}
+
+func TestInstallBiz_CancelInFlight(t *testing.T) {
+	aborted := make(chan struct{})
+	port, cancelServer := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		// the server only notices the closed connection after the body is consumed
+		_, _ = io.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+			close(aborted)
+		case <-time.After(10 * time.Second):
+		}
+	})
+	defer cancelServer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := BuildService(ctx).InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1",
+			BizUrl:     "http://serverless.alipay.com/biz.jar",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	abortedErr := &AbortedError{}
+	assert.True(t, errors.As(err, &abortedErr))
+	assert.True(t, abortedErr.RequestSent)
+	assert.Equal(t, "install biz biz:0.0.1 aborted, the request is sent, "+
+		"the biz state in ark container is unknown: context canceled", err.Error())
+
+	select {
+	case <-aborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the http request is not aborted on the server side")
+	}
+}
+
+func TestInstallBiz_CanceledBeforeSent(t *testing.T) {
+	port, cancelServer := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request should not be sent")
+	})
+	defer cancelServer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := BuildService(ctx).InstallBiz(ctx, InstallBizRequest{
+		BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+	abortedErr := &AbortedError{}
+	assert.True(t, errors.As(err, &abortedErr))
+	assert.False(t, abortedErr.RequestSent)
+}
+
+func TestBasePath(t *testing.T) {
+	ctx := context.Background()
+
+	var paths []string
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/gateway/arklet/queryAllBiz" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "SUCCESS", "data": []interface{}{}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "SUCCESS"})
+	})
+	defer cancel()
+
+	client := BuildService(ctx, WithBasePath("gateway/arklet/"))
+	target := ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}
+	bizModel := BizModel{
+		BizName:    "biz",
+		BizVersion: "0.0.1",
+		BizUrl:     "http://serverless.alipay.com/biz.jar",
+	}
+
+	assert.Nil(t, client.InstallBiz(ctx, InstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+	assert.Nil(t, client.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+	_, err := client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{
+		"/gateway/arklet/health",
+		"/gateway/arklet/queryAllBiz",
+		"/gateway/arklet/installBiz",
+		"/gateway/arklet/health",
+		"/gateway/arklet/uninstallBiz",
+		"/gateway/arklet/queryAllBiz",
+	}, paths)
+}
+
+func TestUserAgent(t *testing.T) {
+	ctx := context.Background()
+
+	var userAgent string
+	port, cancel := mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel()
+
+	_, err := BuildService(ctx).QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	assert.Equal(t, "arkctl/"+constant.Version, userAgent)
+
+	_, err = BuildService(ctx, WithUserAgent("my-platform/1.0")).QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	assert.Equal(t, "my-platform/1.0", userAgent)
+}
@@ -0,0 +1,153 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// errShutdownNotSupported is returned when the arklet doesn't expose the shutdown endpoint.
+var errShutdownNotSupported = errors.New("shutdown endpoint is not supported by arklet")
+
+// ShutdownOptions controls how the ark container is shutdown.
+type ShutdownOptions struct {
+	// Confirm must be true, as the shutdown stops all the biz in the container.
+	Confirm bool
+
+	// Immediate stops the container without waiting for the biz to stop gracefully.
+	Immediate bool
+
+	// DeletePodFallback deletes the pod if the arklet doesn't support shutdown, only for the pod run type.
+	// The pod is usually recreated by its controller, so it's effectively a restart.
+	DeletePodFallback bool
+}
+
+// shutdownBody is the body sent to the shutdown endpoint.
+type shutdownBody struct {
+	Graceful bool `json:"graceful"`
+}
+
+// Use http client to shutdown the local ark container.
+func (h *service) shutdownOnLocal(ctx context.Context, target ArkContainerRuntimeInfo, opts ShutdownOptions) error {
+	endpointUrl, err := h.localEndpointUrl(target, EndpointShutdown)
+	if err != nil {
+		return err
+	}
+	resp, err := h.client.R().
+		SetContext(ctx).
+		SetBody(shutdownBody{Graceful: !opts.Immediate}).
+		Post(endpointUrl)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode() == http.StatusNotFound {
+		return errShutdownNotSupported
+	}
+
+	if !resp.IsSuccess() {
+		return fmt.Errorf("shutdown http failed with code %d", resp.StatusCode())
+	}
+	return h.checkShutdownResponse(resp.Body())
+}
+
+// Use kubectl exec to shutdown the ark container in pod.
+func (h *service) shutdownInPod(ctx context.Context, target ArkContainerRuntimeInfo, opts ShutdownOptions) error {
+	respBody, err := h.postInPod(ctx, target, EndpointShutdown, shutdownBody{Graceful: !opts.Immediate})
+	if err != nil {
+		return err
+	}
+
+	// curl doesn't fail on 404, the missing endpoint responds something other than an arklet response
+	shutdownResponse := &ArkResponseBase{}
+	if err := json.Unmarshal(respBody, shutdownResponse); err != nil || shutdownResponse.Code == "" {
+		return errShutdownNotSupported
+	}
+	if !shutdownResponse.Code.IsSuccess() {
+		return h.newResponseError("shutdown", shutdownResponse.Code, shutdownResponse.Message, respBody)
+	}
+	return nil
+}
+
+func (h *service) checkShutdownResponse(respBody []byte) error {
+	shutdownResponse := &ArkResponseBase{}
+	if err := json.Unmarshal(respBody, shutdownResponse); err != nil {
+		return err
+	}
+	if !shutdownResponse.Code.IsSuccess() {
+		return h.newResponseError("shutdown", shutdownResponse.Code, shutdownResponse.Message, respBody)
+	}
+	return nil
+}
+
+// Use kubectl to delete the pod.
+func (h *service) deletePod(ctx context.Context, target ArkContainerRuntimeInfo, opts ShutdownOptions) error {
+	namespace, podName, err := parsePodCoordinate(target.Coordinate)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-n", namespace, "delete", "pod", podName}
+	if opts.Immediate {
+		args = append(args, "--grace-period=0", "--force")
+	}
+	_, err = h.kubectl(ctx, target, args...)
+	return err
+}
+
+func (h *service) ShutdownContainer(ctx context.Context, target ArkContainerRuntimeInfo, opts ShutdownOptions) (err error) {
+	if !opts.Confirm {
+		return fmt.Errorf("%w: shutdown of ark container stops all the biz in it", ErrConfirmationRequired)
+	}
+	if target, err = targetOrDefault(ctx, target); err != nil {
+		return err
+	}
+
+	logger := contextutil.GetLogger(ctx).
+		WithFields(targetFields(target)).
+		WithField("immediate", opts.Immediate)
+	logger.Warn("shutdown ark container started")
+	defer func() {
+		if err != nil {
+			logger.Error(err)
+		} else {
+			logger.Warn("shutdown ark container completed")
+		}
+		// the container restarts with different capabilities and master biz
+		h.capabilities.Delete(containerCacheKey(target))
+		h.masterBizNames.Delete(containerCacheKey(target))
+		h.invalidateQueryAllBiz(target)
+	}()
+
+	switch target.RunType {
+	case ArkContainerRunTypeLocal:
+		return h.shutdownOnLocal(ctx, target, opts)
+	case ArkContainerRunTypeK8s:
+		err = h.shutdownInPod(ctx, target, opts)
+		if errors.Is(err, errShutdownNotSupported) && opts.DeletePodFallback {
+			logger.Warn("shutdown endpoint is not supported, delete the pod instead")
+			return h.deletePod(ctx, target, opts)
+		}
+		return err
+	default:
+		return fmt.Errorf("shutdown is not supported for run type: %s", target.RunType)
+	}
+}
@@ -0,0 +1,69 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownContainer_ConfirmationRequired(t *testing.T) {
+	ctx := context.Background()
+	err := BuildService(ctx).ShutdownContainer(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal}, ShutdownOptions{})
+	assert.True(t, errors.Is(err, ErrConfirmationRequired))
+}
+
+func TestShutdownContainer_Local(t *testing.T) {
+	ctx := context.Background()
+	var body string
+	port, cancel := mockHttpServer("/shutdown", func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		body = string(content)
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel()
+
+	err := BuildService(ctx).ShutdownContainer(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		ShutdownOptions{Confirm: true, Immediate: true})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"graceful":false}`, body)
+}
+
+func TestShutdownContainer_PodFallbackToDelete(t *testing.T) {
+	ctx := context.Background()
+	var commands [][]string
+	client := BuildService(ctx, WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		commands = append(commands, append([]string{cmd}, args...))
+		if args[2] == "exec" {
+			return []string{"<html>404 page not found</html>"}, nil
+		}
+		return []string{`pod "base-0" deleted`}, nil
+	}))
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"}
+
+	err := client.ShutdownContainer(ctx, target, ShutdownOptions{Confirm: true})
+	assert.True(t, errors.Is(err, errShutdownNotSupported))
+	assert.Len(t, commands, 1)
+
+	err = client.ShutdownContainer(ctx, target, ShutdownOptions{Confirm: true, DeletePodFallback: true})
+	assert.Nil(t, err)
+	assert.Len(t, commands, 3)
+	assert.Equal(t, []string{"kubectl", "-n", "default", "delete", "pod", "base-0"}, commands[2])
+}
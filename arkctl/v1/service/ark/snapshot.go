@@ -0,0 +1,199 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StateSnapshotSchemaVersion is the schema version of the StateSnapshot written by this client.
+const StateSnapshotSchemaVersion = 1
+
+// StateSnapshot is the biz state of an ark container captured by SnapshotState,
+// which is a self-contained json document for air-gapped debugging.
+type StateSnapshot struct {
+	// SchemaVersion is the version of the document schema, see StateSnapshotSchemaVersion.
+	SchemaVersion int `json:"schemaVersion"`
+
+	// CapturedAt is when the snapshot is captured.
+	CapturedAt time.Time `json:"capturedAt"`
+
+	// Source is the ark container the snapshot is captured from.
+	Source ArkContainerRuntimeInfo `json:"source"`
+
+	// ArkVersion is the version of the ark container, empty if arklet doesn't report it.
+	ArkVersion string `json:"arkVersion,omitempty"`
+
+	// ArkletVersion is the version of arklet, empty if arklet doesn't report it.
+	ArkletVersion string `json:"arkletVersion,omitempty"`
+
+	// MasterBiz is the master biz of the ark container, nil if arklet doesn't report it.
+	MasterBiz *ArkBizInfo `json:"masterBiz,omitempty"`
+
+	// Biz are the biz installed in the ark container except the master biz.
+	Biz []ArkBizInfo `json:"biz"`
+}
+
+// ParseStateSnapshot decode the snapshot document, the snapshots of newer schema versions are rejected.
+func ParseStateSnapshot(data []byte) (*StateSnapshot, error) {
+	snapshot := &StateSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.SchemaVersion < 1 || snapshot.SchemaVersion > StateSnapshotSchemaVersion {
+		return nil, fmt.Errorf("unsupported state snapshot schema version %d", snapshot.SchemaVersion)
+	}
+	return snapshot, nil
+}
+
+// ApplyOptions controls how ApplySnapshot re-creates the biz set of a snapshot.
+type ApplyOptions struct {
+	// RemoveExtraneous uninstalls the biz of the target not in the snapshot, the master biz is always kept.
+	RemoveExtraneous bool
+
+	// AllowFileUrls applies the biz installed from file urls, only if the target runs on the machine of the snapshot.
+	AllowFileUrls bool
+
+	// Plan only reports the actions without applying them.
+	Plan bool
+}
+
+// UnapplicableBiz is a biz of the snapshot which can't be re-created on another container.
+type UnapplicableBiz struct {
+	// BizInfo is the biz in the snapshot.
+	BizInfo ArkBizInfo
+
+	// Reason describes why the biz can't be applied.
+	Reason string
+}
+
+// SnapshotApplyResult is the result of ApplySnapshot.
+type SnapshotApplyResult struct {
+	// Report lists the actions re-creating the biz set with their outcome.
+	Report *SyncReport
+
+	// Unapplicable are the biz of the snapshot skipped, they are neither installed nor uninstalled.
+	// Only one version of a biz is applied, the active one if any, the other versions are listed here.
+	Unapplicable []UnapplicableBiz
+}
+
+func (h *service) SnapshotState(ctx context.Context, target ArkContainerRuntimeInfo) (*StateSnapshot, error) {
+	target, err := targetOrDefault(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	health, err := h.queryHealth(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	allBiz, err := h.queryAllBizOf(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &StateSnapshot{
+		SchemaVersion: StateSnapshotSchemaVersion,
+		CapturedAt:    time.Now().UTC(),
+		Source:        target,
+		ArkVersion:    health.HealthData.ArkVersion,
+		ArkletVersion: health.HealthData.ArkletVersion,
+		MasterBiz:     health.HealthData.MasterBizInfo,
+		Biz:           []ArkBizInfo{},
+	}
+	for _, info := range allBiz {
+		if snapshot.MasterBiz != nil && info.BizName == snapshot.MasterBiz.BizName {
+			continue
+		}
+		snapshot.Biz = append(snapshot.Biz, info)
+	}
+	return snapshot, nil
+}
+
+// unapplicableReason return why the biz can't be re-created on another container, empty if it can.
+func unapplicableReason(info ArkBizInfo, opts ApplyOptions) string {
+	switch {
+	case info.BizUrl == "":
+		return "biz url isn't reported by arklet"
+	case strings.HasPrefix(string(info.BizUrl), "file://") && !opts.AllowFileUrls:
+		return "biz url is a file local to the machine of the snapshot"
+	default:
+		return ""
+	}
+}
+
+func (h *service) ApplySnapshot(ctx context.Context, target ArkContainerRuntimeInfo, snapshot *StateSnapshot, opts ApplyOptions) (*SnapshotApplyResult, error) {
+	target, err := targetOrDefault(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.SchemaVersion > StateSnapshotSchemaVersion {
+		return nil, fmt.Errorf("unsupported state snapshot schema version %d", snapshot.SchemaVersion)
+	}
+
+	// a biz may be installed with several versions, only the active one is applied
+	active := map[string]ArkBizInfo{}
+	for _, info := range snapshot.Biz {
+		if current, ok := active[info.BizName]; !ok || (current.BizState != BizStateActivated && info.BizState == BizStateActivated) {
+			active[info.BizName] = info
+		}
+	}
+
+	result := &SnapshotApplyResult{}
+	var desired []BizModel
+	kept := map[string]bool{}
+	for _, info := range snapshot.Biz {
+		if active[info.BizName].BizVersion != info.BizVersion {
+			result.Unapplicable = append(result.Unapplicable, UnapplicableBiz{BizInfo: info, Reason: "another version of the biz is applied"})
+			continue
+		}
+		if reason := unapplicableReason(info, opts); reason != "" {
+			result.Unapplicable = append(result.Unapplicable, UnapplicableBiz{BizInfo: info, Reason: reason})
+			kept[info.BizName] = true
+			continue
+		}
+		desired = append(desired, BizModel{
+			BizName:        info.BizName,
+			BizVersion:     info.BizVersion,
+			BizUrl:         info.BizUrl,
+			WebContextPath: info.WebContextPath,
+		})
+	}
+
+	plan, err := h.Reconcile(ctx, target, desired, SyncOptions{RemoveExtraneous: opts.RemoveExtraneous})
+	if err != nil {
+		return nil, err
+	}
+	if opts.RemoveExtraneous {
+		// the skipped biz are not extraneous, the master biz is already excluded by Reconcile
+		actions := plan.Actions[:0]
+		for _, action := range plan.Actions {
+			if action.Type != SyncActionUninstall || !kept[action.BizName] {
+				actions = append(actions, action)
+			}
+		}
+		plan.Actions = actions
+	}
+
+	if opts.Plan {
+		result.Report = &SyncReport{Actions: plan.Actions, Unchanged: plan.Unchanged}
+		return result, nil
+	}
+	result.Report, err = h.applyPlan(ctx, plan)
+	return result, err
+}
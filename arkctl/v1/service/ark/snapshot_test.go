@@ -0,0 +1,140 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveWithMasterBiz serve the arklet whose master biz is base, which is reported by health.
+func serveWithMasterBiz(arklet *fakeArklet) (int, func()) {
+	return mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"healthData":{"arkVersion":"2.2.5",` +
+				`"masterBizInfo":{"bizName":"base","bizVersion":"1.0.0","bizState":"ACTIVATED"}}}}`))
+			return
+		}
+		arklet.serve(w, r)
+	})
+}
+
+func TestSnapshotState_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	source := &fakeArklet{biz: []ArkBizInfo{
+		{BizName: "base", BizVersion: "1.0.0", BizState: BizStateActivated},
+		{BizName: "biz1", BizVersion: "0.0.1", BizState: BizStateActivated, BizUrl: "http://repo/biz1.jar", WebContextPath: "/biz1"},
+		{BizName: "biz2", BizVersion: "0.0.2", BizState: BizStateActivated, BizUrl: "file:///tmp/biz2.jar"},
+	}}
+	sourcePort, cancel := serveWithMasterBiz(source)
+	defer cancel()
+	target := &fakeArklet{biz: []ArkBizInfo{
+		{BizName: "base", BizVersion: "1.0.0", BizState: BizStateActivated},
+		{BizName: "stale", BizVersion: "0.0.1", BizState: BizStateActivated},
+	}}
+	targetPort, cancel := serveWithMasterBiz(target)
+	defer cancel()
+
+	snapshot, err := client.SnapshotState(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &sourcePort})
+	assert.Nil(t, err)
+	assert.Equal(t, StateSnapshotSchemaVersion, snapshot.SchemaVersion)
+	assert.Equal(t, "2.2.5", snapshot.ArkVersion)
+	assert.Equal(t, "base", snapshot.MasterBiz.BizName)
+	assert.Equal(t, source.biz[1:], snapshot.Biz)
+
+	// the snapshot survives the json document
+	document, err := json.Marshal(snapshot)
+	assert.Nil(t, err)
+	parsed, err := ParseStateSnapshot(document)
+	assert.Nil(t, err)
+	assert.Equal(t, snapshot.Biz, parsed.Biz)
+	assert.True(t, snapshot.CapturedAt.Equal(parsed.CapturedAt))
+
+	targetInfo := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &targetPort}
+	result, err := client.ApplySnapshot(ctx, targetInfo, parsed, ApplyOptions{RemoveExtraneous: true, AllowFileUrls: true})
+	assert.Nil(t, err)
+	assert.Empty(t, result.Unapplicable)
+	// the master biz of the target is kept
+	assert.Equal(t, []string{"uninstall stale:0.0.1", "install biz1:0.0.1", "install biz2:0.0.2"}, target.calls)
+
+	applied, err := client.SnapshotState(ctx, targetInfo)
+	assert.Nil(t, err)
+	assert.Equal(t, snapshot.Biz, applied.Biz)
+	assert.Equal(t, snapshot.MasterBiz, applied.MasterBiz)
+}
+
+func TestApplySnapshot_Unapplicable(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	target := &fakeArklet{biz: []ArkBizInfo{
+		{BizName: "base", BizVersion: "1.0.0", BizState: BizStateActivated},
+		{BizName: "local", BizVersion: "0.0.1", BizState: BizStateActivated},
+	}}
+	port, cancel := serveWithMasterBiz(target)
+	defer cancel()
+
+	snapshot := &StateSnapshot{SchemaVersion: StateSnapshotSchemaVersion, Biz: []ArkBizInfo{
+		{BizName: "remote", BizVersion: "0.0.1", BizUrl: "http://repo/remote.jar"},
+		{BizName: "local", BizVersion: "0.0.2", BizUrl: "file:///tmp/local.jar"},
+		{BizName: "unknown", BizVersion: "0.0.1"},
+	}}
+	result, err := client.ApplySnapshot(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}, snapshot,
+		ApplyOptions{RemoveExtraneous: true, Plan: true})
+	assert.Nil(t, err)
+	assert.Empty(t, target.calls)
+	assert.Equal(t, []UnapplicableBiz{
+		{BizInfo: snapshot.Biz[1], Reason: "biz url is a file local to the machine of the snapshot"},
+		{BizInfo: snapshot.Biz[2], Reason: "biz url isn't reported by arklet"},
+	}, result.Unapplicable)
+	// the skipped biz installed in the target isn't removed
+	assert.Equal(t, "+ remote 0.0.1 (planned)", result.Report.String())
+}
+
+func TestApplySnapshot_MultipleVersions(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	target := &fakeArklet{biz: []ArkBizInfo{
+		{BizName: "base", BizVersion: "1.0.0", BizState: BizStateActivated},
+		{BizName: "biz1", BizVersion: "0.0.1", BizState: BizStateActivated},
+	}}
+	port, cancel := serveWithMasterBiz(target)
+	defer cancel()
+
+	snapshot := &StateSnapshot{SchemaVersion: StateSnapshotSchemaVersion, Biz: []ArkBizInfo{
+		{BizName: "biz1", BizVersion: "0.0.1", BizState: BizStateDeactivated, BizUrl: "http://repo/biz1-0.0.1.jar"},
+		{BizName: "biz1", BizVersion: "0.0.2", BizState: BizStateActivated, BizUrl: "http://repo/biz1-0.0.2.jar"},
+	}}
+	result, err := client.ApplySnapshot(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}, snapshot,
+		ApplyOptions{RemoveExtraneous: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []UnapplicableBiz{
+		{BizInfo: snapshot.Biz[0], Reason: "another version of the biz is applied"},
+	}, result.Unapplicable)
+	assert.Equal(t, "~ biz1 0.0.1 -> 0.0.2 (succeeded)", result.Report.String())
+	assert.Equal(t, []string{"uninstall biz1:0.0.1", "install biz1:0.0.2"}, target.calls)
+}
+
+func TestParseStateSnapshot_SchemaVersion(t *testing.T) {
+	_, err := ParseStateSnapshot([]byte(`{"schemaVersion":2,"biz":[]}`))
+	assert.EqualError(t, err, "unsupported state snapshot schema version 2")
+
+	_, err = ParseStateSnapshot([]byte(`{"biz":[]}`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,98 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// dialFunc is the DialContext of http.Transport.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// socketRegistry maps the placeholder hosts to the unix sockets of arklets.
+// Each socket gets its own host, so that the pooled connections of sockets and tcp ports never mix.
+type socketRegistry struct {
+	paths sync.Map
+}
+
+// host return the placeholder host of the unix socket at socketPath.
+func (r *socketRegistry) host(socketPath string) string {
+	sum := sha256.Sum256([]byte(socketPath))
+	host := fmt.Sprintf("unix-%x.arklet", sum[:8])
+	r.paths.Store(host, socketPath)
+	return host
+}
+
+// lookup return the unix socket of host, false if host isn't a placeholder.
+func (r *socketRegistry) lookup(host string) (string, bool) {
+	path, ok := r.paths.Load(host)
+	if !ok {
+		return "", false
+	}
+	return path.(string), true
+}
+
+// dialer dial the unix socket for the placeholder hosts, and dial others with next.
+func (r *socketRegistry) dialer(next dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err == nil {
+			if path, ok := r.lookup(host); ok {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			}
+		}
+		return next(ctx, network, addr)
+	}
+}
+
+// localHost return the host to reach the local arklet of target, which is a placeholder if it's served on a unix socket.
+func (h *service) localHost(target ArkContainerRuntimeInfo) string {
+	return h.hostOf(localHostName(target), target.SocketPath)
+}
+
+// localHostName return the host the local arklet of target is bound to, which is the Coordinate if it's an address like ::1,
+// or 127.0.0.1 by default. The brackets of IPv6 literals are trimmed.
+func localHostName(target ArkContainerRuntimeInfo) string {
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(target.Coordinate), "["), "]")
+	if host == "" || host == "localhost" {
+		return "127.0.0.1"
+	}
+	return host
+}
+
+// hostOf return the placeholder host of socketPath if it's given, otherwise the host itself.
+func (h *service) hostOf(host, socketPath string) string {
+	if socketPath == "" {
+		return host
+	}
+	return h.sockets.host(socketPath)
+}
+
+// bypassProxy never send the requests of unix sockets through the proxy.
+func (r *socketRegistry) bypassProxy(next func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if _, ok := r.lookup(req.URL.Hostname()); ok {
+			return nil, nil
+		}
+		return next(req)
+	}
+}
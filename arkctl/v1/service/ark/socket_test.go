@@ -0,0 +1,97 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockUnixSocketServer serve handler on a unix socket, and return the socket path.
+func mockUnixSocketServer(t *testing.T, handler http.HandlerFunc) string {
+	// the unix socket path is limited to about 100 bytes, t.TempDir might be too long
+	dir, err := os.MkdirTemp("", "arklet")
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	socketPath := filepath.Join(dir, "arklet.sock")
+	listener, err := net.Listen("unix", socketPath)
+	assert.Nil(t, err)
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return socketPath
+}
+
+func TestInstallBiz_UnixSocket(t *testing.T) {
+	clearProxyEnv(t)
+	// requests of unix sockets never go through the proxy
+	t.Setenv("HTTP_PROXY", "http://127.0.0.1:1")
+
+	var calls []string
+	socketPath := mockUnixSocketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+
+	ctx := context.Background()
+	client := BuildService(ctx)
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, SocketPath: socketPath}
+	err := client.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: target,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/health", "/queryAllBiz", "/installBiz"}, calls)
+
+	_, err = client.QueryAllBiz(ctx, QueryAllArkBizRequest{SocketPath: socketPath})
+	assert.Nil(t, err)
+	assert.Equal(t, "/queryAllBiz", calls[len(calls)-1])
+}
+
+func TestUnixSocket_DoesNotShareTcpConnections(t *testing.T) {
+	clearProxyEnv(t)
+
+	var socketCalls, tcpCalls int
+	socketPath := mockUnixSocketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		socketCalls++
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	port, cancel := mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		tcpCalls++
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel()
+
+	ctx := context.Background()
+	client := BuildService(ctx)
+	for i := 0; i < 2; i++ {
+		_, err := client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+		assert.Nil(t, err)
+		_, err = client.QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port, SocketPath: socketPath})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 2, tcpCalls)
+	assert.Equal(t, 2, socketCalls)
+}
@@ -0,0 +1,77 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type operationStatsKey struct{}
+
+// OperationStats is the latency and payload size of the arklet calls made by an operation, e.g. for pipeline dashboards.
+// Both the succeeded and the failed calls are counted, including the retries and the checks before the operation.
+type OperationStats struct {
+	mu sync.Mutex
+
+	// Calls is the count of the arklet calls.
+	Calls int
+
+	// Duration is the total time spent in the arklet calls.
+	Duration time.Duration
+
+	// ResponseBytes is the total size of the response bodies.
+	ResponseBytes int64
+}
+
+// WithOperationStats return a context that collects the stats of the operations using it into stats.
+func WithOperationStats(ctx context.Context, stats *OperationStats) context.Context {
+	return context.WithValue(ctx, operationStatsKey{}, stats)
+}
+
+func (s *OperationStats) add(duration time.Duration, responseBytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Calls++
+	s.Duration += duration
+	s.ResponseBytes += responseBytes
+}
+
+// recordStats add the call to the stats of ctx if any.
+func recordStats(ctx context.Context, duration time.Duration, responseBytes int64) {
+	if stats, ok := ctx.Value(operationStatsKey{}).(*OperationStats); ok {
+		stats.add(duration, responseBytes)
+	}
+}
+
+// recordResponseStats record the stats of the responded calls, used as a resty response middleware.
+func recordResponseStats(_ *resty.Client, resp *resty.Response) error {
+	recordStats(resp.Request.Context(), resp.Time(), resp.Size())
+	return nil
+}
+
+// recordErrorStats record the stats of the calls failed without response, used as a resty error hook.
+func recordErrorStats(req *resty.Request, err error) {
+	responseErr := &resty.ResponseError{}
+	if (errors.As(err, &responseErr) && responseErr.Response.RawResponse != nil) || req.Time.IsZero() {
+		// recorded by recordResponseStats, or never sent
+		return
+	}
+	recordStats(req.Context(), time.Since(req.Time), 0)
+}
@@ -0,0 +1,94 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationStats(t *testing.T) {
+	ctx := context.Background()
+	succeeded := `{"code":"SUCCESS","data":[{"bizName":"biz","bizVersion":"0.0.1","bizState":"ACTIVATED"}]}`
+	failed := `{"code":"FAILED","message":"install biz failed"}`
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		switch r.URL.Path {
+		case "/queryAllBiz":
+			_, _ = w.Write([]byte(succeeded))
+		case "/installBiz":
+			_, _ = w.Write([]byte(failed))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer cancel()
+	client := BuildService(ctx)
+
+	stats := &OperationStats{}
+	_, err := client.QueryAllBiz(WithOperationStats(ctx, stats), QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stats.Calls)
+	assert.True(t, stats.Duration >= 5*time.Millisecond)
+	assert.Equal(t, int64(len(succeeded)), stats.ResponseBytes)
+
+	// the failed operation is measured too, including the checks before it
+	stats = &OperationStats{}
+	err = client.InstallBiz(WithOperationStats(ctx, stats), InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz2", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz2.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	})
+	assert.NotNil(t, err)
+	// health, query all biz and install biz
+	assert.Equal(t, 3, stats.Calls)
+	assert.True(t, stats.Duration >= 15*time.Millisecond)
+	assert.Equal(t, int64(len(succeeded)+len(failed)), stats.ResponseBytes)
+}
+
+func TestOperationStats_ConnectionFailed(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {})
+	cancel()
+
+	stats := &OperationStats{}
+	_, err := BuildService(ctx).QueryAllBiz(WithOperationStats(ctx, stats), QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, stats.Calls)
+	assert.True(t, stats.Duration > 0)
+	assert.Equal(t, int64(0), stats.ResponseBytes)
+}
+
+func TestOperationStats_Pod(t *testing.T) {
+	ctx := context.Background()
+	body := `{"code":"SUCCESS","data":[]}`
+	client := BuildService(ctx, WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		time.Sleep(time.Millisecond)
+		return []string{body}, nil
+	}))
+
+	stats := &OperationStats{}
+	_, err := client.(*service).queryAllBizInPod(WithOperationStats(ctx, stats), ArkContainerRuntimeInfo{
+		RunType:    ArkContainerRunTypeK8s,
+		Coordinate: "default/base-0",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stats.Calls)
+	assert.True(t, stats.Duration > 0)
+	assert.Equal(t, int64(len(body)), stats.ResponseBytes)
+}
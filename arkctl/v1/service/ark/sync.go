@@ -0,0 +1,396 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+)
+
+// SyncOptions controls how SyncBiz applies the diff.
+type SyncOptions struct {
+	// RemoveExtraneous uninstalls the biz not in the desired set, except the master biz of the target.
+	RemoveExtraneous bool
+
+	// Plan only reports the actions without applying them, it's ignored by Reconcile.
+	Plan bool
+
+	// AllowVersionReuse keeps the biz installed with the desired version as unchanged even if its checksum differs,
+	// otherwise VersionReusedError is returned. The checksums are compared only if both are known.
+	AllowVersionReuse bool
+}
+
+// SyncActionType is the type of action to reconcile a biz.
+type SyncActionType string
+
+const (
+	SyncActionInstall   SyncActionType = "install"
+	SyncActionUninstall SyncActionType = "uninstall"
+	SyncActionReplace   SyncActionType = "replace"
+)
+
+// SyncOutcome is the outcome of a sync action.
+type SyncOutcome string
+
+const (
+	SyncOutcomePlanned   SyncOutcome = "planned"
+	SyncOutcomeSucceeded SyncOutcome = "succeeded"
+	SyncOutcomeFailed    SyncOutcome = "failed"
+)
+
+// SyncAction is a single change made by SyncBiz.
+type SyncAction struct {
+	// Type is the type of the action.
+	Type SyncActionType
+
+	// BizName is the name of the biz.
+	BizName string
+
+	// FromVersions are the versions before the action, empty for install.
+	FromVersions []string
+
+	// ToVersion is the version after the action, empty for uninstall.
+	ToVersion string
+
+	// BizUrl is the url to install the biz from, empty for uninstall.
+	BizUrl fileutil.FileUrl
+
+	// WebContextPath is the web context path to install the biz with, empty for the default one.
+	WebContextPath string
+
+	// FromWebContextPath is the web context path before the action, only set if it's changed by a replace,
+	// in which case the biz is reinstalled even if the version is the same.
+	FromWebContextPath string
+
+	// BizModel is the desired biz installed by the action with its env, args, main class and checksum,
+	// empty for uninstall.
+	BizModel BizModel
+
+	// Outcome is the outcome of the action.
+	Outcome SyncOutcome
+
+	// Err is the error of the failed action.
+	Err error
+}
+
+// change describe the change of the action without its outcome.
+func (a SyncAction) change() string {
+	switch {
+	case a.Type == SyncActionInstall:
+		return fmt.Sprintf("+ %s %s", a.BizName, a.ToVersion)
+	case a.Type == SyncActionUninstall:
+		return fmt.Sprintf("- %s %s", a.BizName, strings.Join(a.FromVersions, ","))
+	case a.FromWebContextPath != "":
+		return fmt.Sprintf("~ %s %s -> %s (%s -> %s)", a.BizName, strings.Join(a.FromVersions, ","), a.ToVersion,
+			a.FromWebContextPath, a.WebContextPath)
+	default:
+		return fmt.Sprintf("~ %s %s -> %s", a.BizName, strings.Join(a.FromVersions, ","), a.ToVersion)
+	}
+}
+
+func (a SyncAction) String() string {
+	change := a.change()
+	if a.Err != nil {
+		return fmt.Sprintf("%s (%s: %v)", change, a.Outcome, a.Err)
+	}
+	return fmt.Sprintf("%s (%s)", change, a.Outcome)
+}
+
+// SyncReport is the result of SyncBiz.
+type SyncReport struct {
+	// Actions are the changes in the order they are applied.
+	Actions []SyncAction
+
+	// Unchanged are the desired biz already installed.
+	Unchanged []BizModel
+}
+
+// Failed return the failed actions.
+func (r *SyncReport) Failed() []SyncAction {
+	var failed []SyncAction
+	for _, action := range r.Actions {
+		if action.Outcome == SyncOutcomeFailed {
+			failed = append(failed, action)
+		}
+	}
+	return failed
+}
+
+// String return a diff like summary of the report.
+func (r *SyncReport) String() string {
+	if len(r.Actions) == 0 {
+		return "no changes"
+	}
+
+	lines := make([]string, 0, len(r.Actions))
+	for _, action := range r.Actions {
+		lines = append(lines, action.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Plan is the actions reconciling the biz in an ark container to the desired biz set, computed by Reconcile.
+type Plan struct {
+	// Target is the ark container the plan is computed for.
+	Target ArkContainerRuntimeInfo
+
+	// Actions are the changes to apply in order, all of them are planned.
+	Actions []SyncAction
+
+	// Unchanged are the desired biz already installed.
+	Unchanged []BizModel
+}
+
+// IsEmpty return true if nothing needs to change.
+func (p *Plan) IsEmpty() bool {
+	return len(p.Actions) == 0
+}
+
+// String return a diff like summary of the plan.
+func (p *Plan) String() string {
+	return (&SyncReport{Actions: p.Actions}).String()
+}
+
+// planSync compute the actions to reconcile actual biz to the desired biz.
+// The master biz is never extraneous, it's empty if unknown.
+func planSync(actual []ArkBizInfo, desired []BizModel, masterBizName string, opts SyncOptions) ([]SyncAction, []BizModel, error) {
+	desiredByName := map[string]BizModel{}
+	for _, bizModel := range desired {
+		if existing, ok := desiredByName[bizModel.BizName]; ok {
+			return nil, nil, fmt.Errorf("biz %s is desired with multiple versions: %s, %s",
+				bizModel.BizName, existing.BizVersion, bizModel.BizVersion)
+		}
+		desiredByName[bizModel.BizName] = bizModel
+	}
+
+	actualVersions := map[string][]string{}
+	actualContextPaths := map[string]string{}
+	actualInfos := map[string]ArkBizInfo{}
+	for _, info := range actual {
+		actualVersions[info.BizName] = append(actualVersions[info.BizName], info.BizVersion)
+		actualContextPaths[info.BizName+":"+info.BizVersion] = info.WebContextPath
+		actualInfos[info.BizName+":"+info.BizVersion] = info
+	}
+
+	var actions []SyncAction
+	var unchanged []BizModel
+
+	// uninstall first to release the resources for the new biz
+	if opts.RemoveExtraneous {
+		names := make([]string, 0, len(actualVersions))
+		for name := range actualVersions {
+			if _, ok := desiredByName[name]; !ok && (masterBizName == "" || name != masterBizName) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			actions = append(actions, SyncAction{
+				Type:         SyncActionUninstall,
+				BizName:      name,
+				FromVersions: actualVersions[name],
+			})
+		}
+	}
+
+	for _, bizModel := range desired {
+		versions := actualVersions[bizModel.BizName]
+		// the context path is compared only if both are known, as older arklets don't report it
+		actualContextPath := actualContextPaths[bizModel.BizName+":"+bizModel.BizVersion]
+		fromContextPath := ""
+		if bizModel.WebContextPath != "" && actualContextPath != "" && actualContextPath != bizModel.WebContextPath {
+			fromContextPath = actualContextPath
+		}
+		switch {
+		case len(versions) == 0:
+			actions = append(actions, SyncAction{
+				Type:           SyncActionInstall,
+				BizName:        bizModel.BizName,
+				ToVersion:      bizModel.BizVersion,
+				BizUrl:         bizModel.BizUrl,
+				WebContextPath: bizModel.WebContextPath,
+				BizModel:       bizModel,
+			})
+		case slices.Contains(versions, bizModel.BizVersion) && fromContextPath == "":
+			if !opts.AllowVersionReuse {
+				if err := checkArtifactReused(actualInfos[bizModel.BizName+":"+bizModel.BizVersion], bizModel); err != nil {
+					return nil, nil, err
+				}
+			}
+			unchanged = append(unchanged, bizModel)
+			// the desired version is kept, only the other versions are uninstalled
+			var others []string
+			for _, version := range versions {
+				if version != bizModel.BizVersion {
+					others = append(others, version)
+				}
+			}
+			if len(others) > 0 {
+				actions = append(actions, SyncAction{
+					Type:         SyncActionUninstall,
+					BizName:      bizModel.BizName,
+					FromVersions: others,
+				})
+			}
+		default:
+			actions = append(actions, SyncAction{
+				Type:               SyncActionReplace,
+				BizName:            bizModel.BizName,
+				FromVersions:       versions,
+				ToVersion:          bizModel.BizVersion,
+				BizUrl:             bizModel.BizUrl,
+				WebContextPath:     bizModel.WebContextPath,
+				FromWebContextPath: fromContextPath,
+				BizModel:           bizModel,
+			})
+		}
+	}
+	return actions, unchanged, nil
+}
+
+// queryAllBizOf query all biz in the ark container of any run type.
+func (h *service) queryAllBizOf(ctx context.Context, target ArkContainerRuntimeInfo) ([]ArkBizInfo, error) {
+	switch target.RunType {
+	case ArkContainerRunTypeLocal:
+		port, err := localPort(target)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := h.QueryAllBiz(ctx, QueryAllArkBizRequest{
+			HostName:     localHostName(target),
+			Port:         port,
+			SocketPath:   target.SocketPath,
+			ForceRefresh: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Data, nil
+	case ArkContainerRunTypeK8s:
+		return h.queryAllBizInPod(WithCacheBypass(ctx), target)
+	default:
+		return nil, fmt.Errorf("query all biz is not supported for run type: %s", target.RunType)
+	}
+}
+
+func (h *service) applySyncAction(ctx context.Context, target ArkContainerRuntimeInfo, action SyncAction) error {
+	for _, version := range action.FromVersions {
+		if err := h.UnInstallBiz(ctx, UnInstallBizRequest{
+			BizModel: BizModel{
+				BizName:    action.BizName,
+				BizVersion: version,
+			},
+			TargetContainer: target,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if action.Type == SyncActionUninstall {
+		return nil
+	}
+	return h.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        action.BizModel,
+		TargetContainer: target,
+	})
+}
+
+func (h *service) Reconcile(ctx context.Context, target ArkContainerRuntimeInfo, desired []BizModel, opts SyncOptions) (*Plan, error) {
+	target, err := targetOrDefault(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := h.queryAllBizOf(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	masterBizName := ""
+	if opts.RemoveExtraneous {
+		masterBizName = h.masterBizName(ctx, target)
+	}
+	actions, unchanged, err := planSync(actual, desired, masterBizName, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range actions {
+		actions[i].Outcome = SyncOutcomePlanned
+	}
+	return &Plan{Target: target, Actions: actions, Unchanged: unchanged}, nil
+}
+
+func (h *service) Apply(ctx context.Context, plan *Plan) (report *SyncReport, err error) {
+	logger := contextutil.GetLogger(ctx).WithFields(targetFields(plan.Target))
+	logger.WithField("actions", len(plan.Actions)).Info("apply plan started")
+	defer func() {
+		if err != nil {
+			logger.Error(err)
+		} else {
+			logger.Info("apply plan completed")
+		}
+	}()
+	return h.applyPlan(ctx, plan)
+}
+
+// applyPlan apply the actions of plan in order, the failed ones don't stop the others.
+func (h *service) applyPlan(ctx context.Context, plan *Plan) (*SyncReport, error) {
+	ctx = WithPodPortCache(ctx)
+	report := &SyncReport{Unchanged: plan.Unchanged}
+	for _, action := range plan.Actions {
+		if action.Err = h.applySyncAction(ctx, plan.Target, action); action.Err != nil {
+			action.Outcome = SyncOutcomeFailed
+		} else {
+			action.Outcome = SyncOutcomeSucceeded
+		}
+		report.Actions = append(report.Actions, action)
+	}
+
+	results := make([]TargetResult, 0, len(report.Actions))
+	for _, action := range report.Actions {
+		results = append(results, TargetResult{Target: action.change(), Err: action.Err})
+	}
+	return report, newMultiTargetError("sync biz", results)
+}
+
+func (h *service) SyncBiz(ctx context.Context, target ArkContainerRuntimeInfo, desired []BizModel, opts SyncOptions) (report *SyncReport, err error) {
+	if target, err = targetOrDefault(ctx, target); err != nil {
+		return nil, err
+	}
+	logger := contextutil.GetLogger(ctx).WithFields(targetFields(target))
+	logger.WithField("desired", len(desired)).Info("sync biz started")
+	defer func() {
+		if err != nil {
+			logger.Error(err)
+		} else {
+			logger.Info("sync biz completed")
+		}
+	}()
+
+	ctx = WithPodPortCache(ctx)
+	plan, err := h.Reconcile(ctx, target, desired, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Plan {
+		return &SyncReport{Actions: plan.Actions, Unchanged: plan.Unchanged}, nil
+	}
+	return h.applyPlan(ctx, plan)
+}
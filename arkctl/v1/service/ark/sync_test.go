@@ -0,0 +1,528 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordedRequest is a request received by the fake arklet.
+type recordedRequest struct {
+	method      string
+	path        string
+	contentType string
+	body        string
+}
+
+// fakeArklet keeps the installed biz and their states in memory, and records the requests.
+// The biz of failNames fail to install and uninstall, the uninstall of a biz not installed responds NOT_FOUND_BIZ.
+// OPTIONS is not answered, so the capabilities are detected by help.
+type fakeArklet struct {
+	lock      sync.Mutex
+	biz       []ArkBizInfo
+	failNames map[string]bool
+	calls     []string
+	installed []BizModel
+	requests  []recordedRequest
+
+	// handlers override the fake endpoints of the paths per test, they are called with the lock held.
+	handlers map[string]http.HandlerFunc
+}
+
+func (a *fakeArklet) serve(w http.ResponseWriter, r *http.Request) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	a.requests = append(a.requests, recordedRequest{
+		method:      r.Method,
+		path:        r.URL.Path,
+		contentType: r.Header.Get("Content-Type"),
+		body:        string(body),
+	})
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if handler, ok := a.handlers[r.URL.Path]; ok {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		handler(w, r)
+		return
+	}
+
+	bizModel := BizModel{}
+	_ = json.Unmarshal(body, &bizModel)
+
+	switch r.URL.Path {
+	case "/queryBiz":
+		var data interface{}
+		if i := a.indexOf(bizModel); i >= 0 {
+			data = a.biz[i]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+			"data": data,
+		})
+		return
+	case "/activateBiz", "/deactivateBiz":
+		desired, operation := BizStateActivated, "activate "
+		if r.URL.Path == "/deactivateBiz" {
+			desired, operation = BizStateDeactivated, "deactivate "
+		}
+		a.calls = append(a.calls, operation+bizModel.BizName+":"+bizModel.BizVersion)
+		i := a.indexOf(bizModel)
+		if i < 0 {
+			_, _ = w.Write([]byte(`{"code":"FAILED","data":{"code":"NOT_FOUND_BIZ"}}`))
+			return
+		}
+		if a.biz[i].BizState == desired {
+			_, _ = w.Write([]byte(`{"code":"FAILED","message":"biz is already in the state","data":{"code":"ILLEGAL_STATE_BIZ"}}`))
+			return
+		}
+		a.biz[i].BizState = desired
+	case "/queryAllBiz":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+			"data": a.biz,
+		})
+		return
+	case "/installBiz":
+		a.calls = append(a.calls, "install "+bizModel.BizName+":"+bizModel.BizVersion)
+		a.installed = append(a.installed, bizModel)
+		if a.failNames[bizModel.BizName] {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code":    "FAILED",
+				"message": "install failed",
+			})
+			return
+		}
+		a.biz = append(a.biz, ArkBizInfo{
+			BizName:        bizModel.BizName,
+			BizVersion:     bizModel.BizVersion,
+			BizState:       BizStateActivated,
+			WebContextPath: bizModel.WebContextPath,
+			BizUrl:         bizModel.BizUrl,
+			Checksum:       bizModel.Checksum,
+		})
+	case "/uninstallBiz":
+		a.calls = append(a.calls, "uninstall "+bizModel.BizName+":"+bizModel.BizVersion)
+		if a.failNames[bizModel.BizName] {
+			_, _ = w.Write([]byte(`{"code":"FAILED","message":"uninstall failed"}`))
+			return
+		}
+		var remained []ArkBizInfo
+		for _, info := range a.biz {
+			if info.BizName != bizModel.BizName || info.BizVersion != bizModel.BizVersion {
+				remained = append(remained, info)
+			}
+		}
+		if len(remained) == len(a.biz) {
+			_, _ = w.Write([]byte(`{"code":"FAILED","data":{"code":"NOT_FOUND_BIZ"}}`))
+			return
+		}
+		a.biz = remained
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"code": "SUCCESS",
+	})
+}
+
+// indexOf return the index of the installed biz of bizModel, -1 if it's not installed.
+func (a *fakeArklet) indexOf(bizModel BizModel) int {
+	for i, info := range a.biz {
+		if info.BizName == bizModel.BizName && info.BizVersion == bizModel.BizVersion {
+			return i
+		}
+	}
+	return -1
+}
+
+// requestPaths return the paths of the requests received in order.
+func (a *fakeArklet) requestPaths() []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	paths := []string{}
+	for _, request := range a.requests {
+		paths = append(paths, request.path)
+	}
+	return paths
+}
+
+// requestCount return the count of the requests received by "method path".
+func (a *fakeArklet) requestCount(key string) int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	count := 0
+	for _, request := range a.requests {
+		if request.method+" "+request.path == key {
+			count++
+		}
+	}
+	return count
+}
+
+// lastRequest return the last request received of the path.
+func (a *fakeArklet) lastRequest(path string) (recordedRequest, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for i := len(a.requests) - 1; i >= 0; i-- {
+		if a.requests[i].path == path {
+			return a.requests[i], true
+		}
+	}
+	return recordedRequest{}, false
+}
+
+// respondBody return the handler responding body, to override an endpoint of fakeArklet.
+func respondBody(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func newFakeArklet() *fakeArklet {
+	return &fakeArklet{
+		biz: []ArkBizInfo{
+			{BizName: "keep", BizVersion: "1.0.0", BizState: BizStateActivated},
+			{BizName: "upgrade", BizVersion: "1.0.0", BizState: BizStateActivated},
+			{BizName: "extra", BizVersion: "1.0.0", BizState: BizStateActivated},
+		},
+		failNames: map[string]bool{},
+	}
+}
+
+var syncDesired = []BizModel{
+	{BizName: "keep", BizVersion: "1.0.0", BizUrl: "file:///tmp/keep.jar"},
+	{BizName: "upgrade", BizVersion: "2.0.0", BizUrl: "file:///tmp/upgrade.jar"},
+	{BizName: "new", BizVersion: "1.0.0", BizUrl: "file:///tmp/new.jar"},
+}
+
+func TestSyncBiz_Plan(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := newFakeArklet()
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	report, err := client.SyncBiz(ctx, ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}, syncDesired, SyncOptions{RemoveExtraneous: true, Plan: true})
+	assert.Nil(t, err)
+	assert.Empty(t, arklet.calls)
+	assert.Equal(t, []BizModel{syncDesired[0]}, report.Unchanged)
+	assert.Equal(t, "- extra 1.0.0 (planned)\n"+
+		"~ upgrade 1.0.0 -> 2.0.0 (planned)\n"+
+		"+ new 1.0.0 (planned)", report.String())
+}
+
+func TestSyncBiz_Apply(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := newFakeArklet()
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	target := ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}
+	report, err := client.SyncBiz(ctx, target, syncDesired, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "~ upgrade 1.0.0 -> 2.0.0 (succeeded)\n"+
+		"+ new 1.0.0 (succeeded)", report.String())
+	assert.Equal(t, []string{
+		"uninstall upgrade:1.0.0",
+		"install upgrade:2.0.0",
+		"install new:1.0.0",
+	}, arklet.calls)
+
+	// sync again is a no-op, the extraneous biz is kept
+	report, err = client.SyncBiz(ctx, target, syncDesired, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "no changes", report.String())
+	assert.Equal(t, 3, len(report.Unchanged))
+}
+
+func TestSyncBiz_ActionFailed(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := newFakeArklet()
+	arklet.failNames["new"] = true
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	report, err := client.SyncBiz(ctx, ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}, syncDesired, SyncOptions{RemoveExtraneous: true})
+	assert.NotNil(t, err)
+	assert.Equal(t, "sync biz: 1/3 targets failed: + new 1.0.0: install biz failed: install failed", err.Error())
+	assert.Equal(t, "- extra 1.0.0 (succeeded)\n"+
+		"~ upgrade 1.0.0 -> 2.0.0 (succeeded)\n"+
+		"+ new 1.0.0 (failed: install biz failed: install failed)", report.String())
+}
+
+func TestSyncBiz_DuplicatedDesired(t *testing.T) {
+	_, _, err := planSync(nil, []BizModel{
+		{BizName: "biz", BizVersion: "1.0.0"},
+		{BizName: "biz", BizVersion: "2.0.0"},
+	}, "", SyncOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestSyncBiz_WebContextPathChanged(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := &fakeArklet{
+		biz: []ArkBizInfo{
+			{BizName: "web", BizVersion: "1.0.0", BizState: BizStateActivated, WebContextPath: "/web"},
+		},
+		failNames: map[string]bool{},
+	}
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	target := ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}
+	desired := []BizModel{{BizName: "web", BizVersion: "1.0.0", BizUrl: "file:///tmp/web.jar", WebContextPath: "/web-canary"}}
+	report, err := client.SyncBiz(ctx, target, desired, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "~ web 1.0.0 -> 1.0.0 (/web -> /web-canary) (succeeded)", report.String())
+	assert.Equal(t, []string{
+		"uninstall web:1.0.0",
+		"install web:1.0.0",
+	}, arklet.calls)
+
+	report, err = client.SyncBiz(ctx, target, desired, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "no changes", report.String())
+
+	// the context path isn't compared if it's not desired
+	desired[0].WebContextPath = ""
+	report, err = client.SyncBiz(ctx, target, desired, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "no changes", report.String())
+}
+
+func TestReconcile_AddOnly(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := newFakeArklet()
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	desired := []BizModel{
+		{BizName: "keep", BizVersion: "1.0.0"},
+		{BizName: "upgrade", BizVersion: "1.0.0"},
+		{BizName: "extra", BizVersion: "1.0.0"},
+		{BizName: "new", BizVersion: "1.0.0", BizUrl: "file:///tmp/new.jar"},
+	}
+	plan, err := client.Reconcile(ctx, target, desired, SyncOptions{RemoveExtraneous: true})
+	assert.Nil(t, err)
+	assert.Empty(t, arklet.calls)
+	assert.Equal(t, target, plan.Target)
+	assert.Equal(t, desired[:3], plan.Unchanged)
+	assert.Equal(t, "+ new 1.0.0 (planned)", plan.String())
+
+	report, err := client.Apply(ctx, plan)
+	assert.Nil(t, err)
+	assert.Equal(t, "+ new 1.0.0 (succeeded)", report.String())
+	assert.Equal(t, []string{"install new:1.0.0"}, arklet.calls)
+
+	// the desired state is reached
+	plan, err = client.Reconcile(ctx, target, desired, SyncOptions{RemoveExtraneous: true})
+	assert.Nil(t, err)
+	assert.True(t, plan.IsEmpty())
+}
+
+func TestReconcile_RemoveOnly(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := newFakeArklet()
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	desired := []BizModel{{BizName: "keep", BizVersion: "1.0.0"}}
+	plan, err := client.Reconcile(ctx, target, desired, SyncOptions{RemoveExtraneous: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "- extra 1.0.0 (planned)\n- upgrade 1.0.0 (planned)", plan.String())
+
+	// the extraneous biz are kept unless they are asked to be removed
+	kept, err := client.Reconcile(ctx, target, desired, SyncOptions{})
+	assert.Nil(t, err)
+	assert.True(t, kept.IsEmpty())
+
+	report, err := client.Apply(ctx, plan)
+	assert.Nil(t, err)
+	assert.Len(t, report.Actions, 2)
+	assert.Equal(t, []string{"uninstall extra:1.0.0", "uninstall upgrade:1.0.0"}, arklet.calls)
+}
+
+func TestReconcile_KeepMasterBiz(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := &fakeArklet{biz: []ArkBizInfo{
+		{BizName: "base", BizVersion: "1.0.0", BizState: BizStateActivated},
+		{BizName: "extra", BizVersion: "1.0.0", BizState: BizStateActivated},
+	}}
+	port, cancel := serveWithMasterBiz(arklet)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	report, err := client.SyncBiz(ctx, target, nil, SyncOptions{RemoveExtraneous: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "- extra 1.0.0 (succeeded)", report.String())
+	assert.Equal(t, []string{"uninstall extra:1.0.0"}, arklet.calls)
+}
+
+func TestReconcile_Upgrade(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := newFakeArklet()
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	plan, err := client.Reconcile(ctx, target, []BizModel{
+		{BizName: "keep", BizVersion: "1.0.0"},
+		{BizName: "upgrade", BizVersion: "2.0.0", BizUrl: "file:///tmp/upgrade.jar"},
+		{BizName: "extra", BizVersion: "1.0.0"},
+	}, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, plan.Actions, 1)
+	action := plan.Actions[0]
+	assert.Equal(t, SyncActionReplace, action.Type)
+	assert.Equal(t, []string{"1.0.0"}, action.FromVersions)
+	assert.Equal(t, "2.0.0", action.ToVersion)
+
+	report, err := client.Apply(ctx, plan)
+	assert.Nil(t, err)
+	assert.Equal(t, "~ upgrade 1.0.0 -> 2.0.0 (succeeded)", report.String())
+	assert.Equal(t, []string{"uninstall upgrade:1.0.0", "install upgrade:2.0.0"}, arklet.calls)
+}
+
+func TestReconcile_InstallFullBizModel(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := newFakeArklet()
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	desired := []BizModel{
+		{
+			BizName:    "upgrade",
+			BizVersion: "2.0.0",
+			BizUrl:     "file:///tmp/upgrade.jar",
+			MainClass:  "com.example.Upgrade",
+			Env:        map[string]string{"profile": "prod"},
+			Args:       []string{"--debug"},
+			Checksum:   "sha256:upgrade",
+		},
+		{
+			BizName:        "new",
+			BizVersion:     "1.0.0",
+			BizUrl:         "file:///tmp/new.jar",
+			MainClass:      "com.example.New",
+			Env:            map[string]string{"region": "cn"},
+			Args:           []string{"--port", "8080"},
+			WebContextPath: "/new",
+			Checksum:       "sha256:new",
+		},
+	}
+	plan, err := client.Reconcile(ctx, target, desired, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, plan.Actions, 2)
+	assert.Equal(t, desired[0], plan.Actions[0].BizModel)
+	assert.Equal(t, desired[1], plan.Actions[1].BizModel)
+
+	_, err = client.Apply(ctx, plan)
+	assert.Nil(t, err)
+	assert.Equal(t, desired, arklet.installed)
+}
+
+func TestReconcile_DesiredVersionAmongOthers(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	arklet := newFakeArklet()
+	arklet.biz = append(arklet.biz, ArkBizInfo{BizName: "upgrade", BizVersion: "2.0.0", BizState: BizStateDeactivated})
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	desired := BizModel{BizName: "upgrade", BizVersion: "2.0.0", BizUrl: "file:///tmp/upgrade.jar"}
+	plan, err := client.Reconcile(ctx, target, []BizModel{desired}, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []BizModel{desired}, plan.Unchanged)
+	assert.Equal(t, "- upgrade 1.0.0 (planned)", plan.String())
+
+	_, err = client.Apply(ctx, plan)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"uninstall upgrade:1.0.0"}, arklet.calls)
+}
+
+func TestReconcile_VersionReused(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	tests := []struct {
+		name           string
+		activeChecksum string
+		checksum       string
+		opts           SyncOptions
+		reused         bool
+	}{
+		{name: "same artifact", activeChecksum: "abc", checksum: "abc"},
+		{name: "different artifact", activeChecksum: "abc", checksum: "def", reused: true},
+		{name: "different artifact allowed", activeChecksum: "abc", checksum: "def", opts: SyncOptions{AllowVersionReuse: true}},
+		{name: "active checksum unavailable", checksum: "def"},
+		{name: "checksum unavailable", activeChecksum: "abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			arklet := newFakeArklet()
+			arklet.biz[0].Checksum = test.activeChecksum
+			port, cancel := mockHttpServer("/", arklet.serve)
+			defer cancel()
+			target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+			plan, err := client.Reconcile(ctx, target, []BizModel{
+				{BizName: "keep", BizVersion: "1.0.0", Checksum: test.checksum},
+			}, test.opts)
+			if test.reused {
+				assert.True(t, errors.Is(err, ErrVersionReusedWithDifferentArtifact))
+				assert.Nil(t, plan)
+				return
+			}
+			assert.Nil(t, err)
+			assert.True(t, plan.IsEmpty())
+			assert.Len(t, plan.Unchanged, 1)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+)
+
+type targetKey struct{}
+
+// WithTarget return a context carrying the default target of the operations, for the embedders performing
+// a sequence of operations against the same ark container.
+// The operations fall back to it when their target, or the TargetContainer of their request, is zero-valued,
+// the target given to the operation always wins.
+func WithTarget(ctx context.Context, target ArkContainerRuntimeInfo) context.Context {
+	return context.WithValue(ctx, targetKey{}, target)
+}
+
+// targetOrDefault return target if it's given, otherwise the target of ctx set by WithTarget.
+// ErrNoTarget is returned if neither is present.
+func targetOrDefault(ctx context.Context, target ArkContainerRuntimeInfo) (ArkContainerRuntimeInfo, error) {
+	if target != (ArkContainerRuntimeInfo{}) {
+		return target, nil
+	}
+	if target, ok := ctx.Value(targetKey{}).(ArkContainerRuntimeInfo); ok && target != (ArkContainerRuntimeInfo{}) {
+		return target, nil
+	}
+	return ArkContainerRuntimeInfo{}, ErrNoTarget
+}
@@ -0,0 +1,106 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTarget(t *testing.T) {
+	ctx := context.Background()
+	var installed1, installed2 int
+	port1, cancel1 := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		installed1++
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel1()
+	port2, cancel2 := mockHttpServer("/installBiz", func(w http.ResponseWriter, r *http.Request) {
+		installed2++
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	})
+	defer cancel2()
+	target1 := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port1}
+	target2 := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port2}
+
+	client := BuildService(ctx)
+	install := func(ctx context.Context, target ArkContainerRuntimeInfo) error {
+		return client.InstallBiz(ctx, InstallBizRequest{
+			BizModel:              BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+			TargetContainer:       target,
+			AllowMultipleVersions: true,
+		})
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		target     ArkContainerRuntimeInfo
+		installed1 int
+		installed2 int
+	}{
+		{name: "request target", ctx: ctx, target: target1, installed1: 1},
+		{name: "context target", ctx: WithTarget(ctx, target2), installed2: 1},
+		{name: "request target wins", ctx: WithTarget(ctx, target2), target: target1, installed1: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			installed1, installed2 = 0, 0
+			assert.Nil(t, install(test.ctx, test.target))
+			assert.Equal(t, test.installed1, installed1)
+			assert.Equal(t, test.installed2, installed2)
+		})
+	}
+
+	t.Run("no target", func(t *testing.T) {
+		assert.ErrorIs(t, install(ctx, ArkContainerRuntimeInfo{}), ErrNoTarget)
+		assert.ErrorIs(t, client.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: BizModel{BizName: "biz", BizVersion: "0.0.1"}}), ErrNoTarget)
+		assert.Equal(t, ExitCodeValidation, ExitCode(ErrNoTarget))
+	})
+}
+
+func TestWithTarget_Query(t *testing.T) {
+	ctx := context.Background()
+	arklet := &fakeArklet{biz: []ArkBizInfo{{BizName: "biz", BizVersion: "0.0.1", BizState: BizStateActivated}}}
+	// the biz is queried by queryAllBiz as the arklet doesn't support queryBiz
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/queryBiz" {
+			http.NotFound(w, r)
+			return
+		}
+		arklet.serve(w, r)
+	})
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	client := BuildService(ctx)
+	state, err := client.QueryBizState(WithTarget(ctx, target), ArkContainerRuntimeInfo{}, "biz", "0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, BizStateActivated, state)
+
+	report, err := client.SyncBiz(WithTarget(ctx, target), ArkContainerRuntimeInfo{}, []BizModel{{BizName: "biz", BizVersion: "0.0.1"}}, SyncOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Actions)
+
+	_, err = client.QueryBiz(ctx, ArkContainerRuntimeInfo{}, "biz", "0.0.1")
+	assert.ErrorIs(t, err, ErrNoTarget)
+	_, err = client.GCFailedBiz(ctx, ArkContainerRuntimeInfo{}, GCOptions{})
+	assert.ErrorIs(t, err, ErrNoTarget)
+	_, err = client.SnapshotState(ctx, ArkContainerRuntimeInfo{})
+	assert.ErrorIs(t, err, ErrNoTarget)
+}
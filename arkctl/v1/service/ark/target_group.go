@@ -0,0 +1,299 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// TargetGroup is a named set of ark containers, like the "staging" environment.
+// The group lists its Targets, or resolves them from the Provider or by selecting the pods by label at call time.
+type TargetGroup struct {
+	// Targets are the ark containers of the group.
+	Targets []ArkContainerRuntimeInfo `json:"targets,omitempty"`
+
+	// Provider provides the ark containers of the group at call time, e.g. from a registry.
+	Provider EndpointProvider `json:"-"`
+
+	// Namespace is the namespace of the selected pods.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Selector is the label selector of the pods, e.g. app=base.
+	Selector string `json:"selector,omitempty"`
+
+	// Port is the ark api port of the selected pods, the default port is used if not given.
+	Port *int `json:"port,omitempty"`
+
+	// Kubeconfig is the kubeconfig file of the cluster the selected pods are running in.
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+
+	// KubeContext is the kube context of the cluster the selected pods are running in.
+	KubeContext string `json:"kubeContext,omitempty"`
+
+	// PerContainer expands each selected pod into a target per container annotated by
+	// ArkletContainerPortAnnotationPrefix, e.g. the pods running an arklet in both the main and the sidecar container.
+	// The pods without such annotations are expanded into a single target as usual.
+	PerContainer bool `json:"perContainer,omitempty"`
+}
+
+// Targets address the ark containers of an operation, either a single Target, a target group by name,
+// or the ark containers of a Provider. The ark containers of the client's EndpointProvider are addressed if none is given.
+type Targets struct {
+	// Target is the single ark container.
+	Target *ArkContainerRuntimeInfo
+
+	// Group is the name of the target group registered by WithTargetGroups.
+	Group string
+
+	// Provider provides the ark containers at call time.
+	Provider EndpointProvider
+
+	// Filter selects the subset of the addressed ark containers to operate on, all of them are selected if it's nil.
+	Filter func(target ArkContainerRuntimeInfo) bool
+}
+
+// SingleTarget address the single ark container.
+func SingleTarget(target ArkContainerRuntimeInfo) Targets {
+	return Targets{Target: &target}
+}
+
+// GroupTargets address the target group by name.
+func GroupTargets(name string) Targets {
+	return Targets{Group: name}
+}
+
+// ProviderTargets address the ark containers of the provider.
+func ProviderTargets(provider EndpointProvider) Targets {
+	return Targets{Provider: provider}
+}
+
+// AllTargets address the ark containers of the EndpointProvider of the client.
+func AllTargets() Targets {
+	return Targets{}
+}
+
+func (t Targets) String() string {
+	if t.Group != "" {
+		return "group " + t.Group
+	}
+	if t.Target != nil {
+		return targetString(*t.Target)
+	}
+	if t.Provider != nil {
+		return "provider"
+	}
+	return "all targets"
+}
+
+// TargetsResult is the result of an operation on Targets.
+type TargetsResult struct {
+	// Group is the name of the target group, empty for a single target.
+	Group string
+
+	// Targets are the ark containers the operation is applied to, as the group is expanded at call time.
+	Targets []ArkContainerRuntimeInfo
+
+	// Results are the results of each target in the order of Targets.
+	Results []TargetResult
+}
+
+// ResolveTargets expand the targets into ark containers, the providers are asked and the pods of a group
+// with a selector are listed at call time. Only the ark containers selected by the Filter are returned.
+func (h *service) ResolveTargets(ctx context.Context, targets Targets) ([]ArkContainerRuntimeInfo, error) {
+	resolved, err := h.expandTargets(ctx, targets)
+	if err != nil || targets.Filter == nil {
+		return resolved, err
+	}
+
+	selected := []ArkContainerRuntimeInfo{}
+	for _, target := range resolved {
+		if targets.Filter(target) {
+			selected = append(selected, target)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("none of the %d targets of %s is selected", len(resolved), targets)
+	}
+	return selected, nil
+}
+
+// expandTargets expand the targets into all the ark containers they address.
+func (h *service) expandTargets(ctx context.Context, targets Targets) ([]ArkContainerRuntimeInfo, error) {
+	addressed := 0
+	for _, given := range []bool{targets.Target != nil, targets.Group != "", targets.Provider != nil} {
+		if given {
+			addressed++
+		}
+	}
+	switch {
+	case addressed > 1:
+		return nil, fmt.Errorf("only one of target, group and provider is expected")
+	case targets.Target != nil:
+		return []ArkContainerRuntimeInfo{*targets.Target}, nil
+	case targets.Provider != nil:
+		return providedTargets(ctx, targets.Provider)
+	case targets.Group == "" && h.options.EndpointProvider != nil:
+		return providedTargets(ctx, h.options.EndpointProvider)
+	case targets.Group == "":
+		return nil, fmt.Errorf("no target is given")
+	}
+
+	group, ok := h.options.TargetGroups[targets.Group]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTargetGroupNotFound, targets.Group)
+	}
+
+	resolved := append([]ArkContainerRuntimeInfo{}, group.Targets...)
+	if group.Provider != nil {
+		endpoints, err := group.Provider.Endpoints(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("expand target group %s failed: %w", targets.Group, err)
+		}
+		resolved = append(resolved, endpoints...)
+	}
+	if group.Selector != "" {
+		pods, err := h.listGroupPods(ctx, group)
+		if err != nil {
+			return nil, fmt.Errorf("expand target group %s failed: %w", targets.Group, err)
+		}
+		resolved = append(resolved, pods...)
+	}
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("target group %s has no targets", targets.Group)
+	}
+	return resolved, nil
+}
+
+// listGroupPods list the pods matching the selector of group.
+func (h *service) listGroupPods(ctx context.Context, group TargetGroup) ([]ArkContainerRuntimeInfo, error) {
+	namespace := group.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	cluster := ArkContainerRuntimeInfo{
+		RunType:     ArkContainerRunTypeK8s,
+		Kubeconfig:  group.Kubeconfig,
+		KubeContext: group.KubeContext,
+	}
+	if group.PerContainer {
+		return h.listGroupContainers(ctx, group, cluster, namespace)
+	}
+	lines, err := h.kubectl(ctx, cluster,
+		"-n", namespace,
+		"get", "pods",
+		"-l", group.Selector,
+		"-o", "jsonpath={.items[*].metadata.name}",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	podNames := strings.Fields(strings.Join(lines, " "))
+	sort.Strings(podNames)
+	pods := make([]ArkContainerRuntimeInfo, 0, len(podNames))
+	for _, podName := range podNames {
+		pod := cluster
+		pod.Coordinate = namespace + "/" + podName
+		pod.Port = group.Port
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// listGroupContainers list the pods matching the selector of group, and expand each of them into a target per
+// container annotated with its arklet port.
+func (h *service) listGroupContainers(ctx context.Context, group TargetGroup, cluster ArkContainerRuntimeInfo, namespace string) ([]ArkContainerRuntimeInfo, error) {
+	lines, err := h.kubectl(ctx, cluster, "-n", namespace, "get", "pods", "-l", group.Selector, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	list := struct {
+		Items []podObject `json:"items"`
+	}{}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &list); err != nil {
+		return nil, fmt.Errorf("decode pods of %s failed: %w", group.Selector, err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Metadata.Name < list.Items[j].Metadata.Name
+	})
+
+	targets := []ArkContainerRuntimeInfo{}
+	for i := range list.Items {
+		pod := &list.Items[i]
+		ports, err := containerArkletPorts(pod)
+		if err != nil {
+			return nil, fmt.Errorf("pod %s/%s: %w", namespace, pod.Metadata.Name, err)
+		}
+		target := cluster
+		target.Coordinate = namespace + "/" + pod.Metadata.Name
+		if len(ports) == 0 {
+			target.Port = group.Port
+			targets = append(targets, target)
+			continue
+		}
+
+		containers := make([]string, 0, len(ports))
+		for container := range ports {
+			containers = append(containers, container)
+		}
+		sort.Strings(containers)
+		for _, container := range containers {
+			port := ports[container]
+			target.Container = container
+			target.Port = &port
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// forEachTarget apply op to each resolved target, the failed targets don't stop the others.
+func (h *service) forEachTarget(ctx context.Context, operation string, targets Targets, op func(target ArkContainerRuntimeInfo) error) (*TargetsResult, error) {
+	ctx = WithPodPortCache(ctx)
+	resolved, err := h.ResolveTargets(ctx, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := contextutil.GetLogger(ctx)
+	logger.WithField("targets", targets.String()).WithField("resolved", len(resolved)).Info(operation + " on targets")
+	result := &TargetsResult{
+		Group:   targets.Group,
+		Targets: resolved,
+		Results: make([]TargetResult, 0, len(resolved)),
+	}
+	for _, target := range resolved {
+		result.Results = append(result.Results, TargetResult{Target: targetString(target), Err: op(target)})
+	}
+	return result, newMultiTargetError(operation, result.Results)
+}
+
+func (h *service) InstallBizOnTargets(ctx context.Context, targets Targets, bizModel BizModel) (*TargetsResult, error) {
+	return h.forEachTarget(ctx, "install biz", targets, func(target ArkContainerRuntimeInfo) error {
+		return h.InstallBiz(ctx, InstallBizRequest{BizModel: bizModel, TargetContainer: target})
+	})
+}
+
+func (h *service) UnInstallBizOnTargets(ctx context.Context, targets Targets, bizModel BizModel) (*TargetsResult, error) {
+	return h.forEachTarget(ctx, "uninstall biz", targets, func(target ArkContainerRuntimeInfo) error {
+		return h.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: bizModel, TargetContainer: target})
+	})
+}
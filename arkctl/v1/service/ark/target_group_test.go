@@ -0,0 +1,163 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallBizOnTargets_StaticGroup(t *testing.T) {
+	ctx := context.Background()
+	succeeded := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+	}
+	port1, cancel1 := mockHttpServer("/", succeeded)
+	defer cancel1()
+	port2, cancel2 := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/installBiz" {
+			_, _ = w.Write([]byte(`{"code":"FAILED","message":"boom"}`))
+			return
+		}
+		succeeded(w, r)
+	})
+	defer cancel2()
+
+	staging := TargetGroup{Targets: []ArkContainerRuntimeInfo{
+		{RunType: ArkContainerRunTypeLocal, Port: &port1},
+		{RunType: ArkContainerRunTypeLocal, Port: &port2},
+	}}
+	client := BuildService(ctx, WithTargetGroups(map[string]TargetGroup{"staging": staging}))
+
+	result, err := client.InstallBizOnTargets(ctx, GroupTargets("staging"), BizModel{
+		BizName:    "biz",
+		BizVersion: "0.0.1",
+		BizUrl:     "file:///tmp/biz.jar",
+	})
+	multiErr := &MultiTargetError{}
+	assert.True(t, errors.As(err, &multiErr))
+	assert.Equal(t, 1, len(multiErr.Failed()))
+	assert.Equal(t, "staging", result.Group)
+	assert.Equal(t, staging.Targets, result.Targets)
+	assert.Equal(t, 2, len(result.Results))
+	assert.Nil(t, result.Results[0].Err)
+	assert.NotNil(t, result.Results[1].Err)
+}
+
+func TestResolveTargets_Selector(t *testing.T) {
+	ctx := context.Background()
+	port := 1239
+	var commands [][]string
+	client := BuildService(ctx,
+		WithTargetGroups(map[string]TargetGroup{
+			"staging": {Namespace: "staging", Selector: "app=base", Port: &port},
+		}),
+		WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+			commands = append(commands, append([]string{cmd}, args...))
+			if strings.Contains(strings.Join(args, " "), "get pods") {
+				return []string{"base-1 base-0"}, nil
+			}
+			return []string{`{"code":"SUCCESS"}`}, nil
+		}),
+	)
+
+	result, err := client.UnInstallBizOnTargets(ctx, GroupTargets("staging"), BizModel{BizName: "biz", BizVersion: "0.0.1"})
+	assert.Nil(t, err)
+	assert.Equal(t, []ArkContainerRuntimeInfo{
+		{RunType: ArkContainerRunTypeK8s, Coordinate: "staging/base-0", Port: &port},
+		{RunType: ArkContainerRunTypeK8s, Coordinate: "staging/base-1", Port: &port},
+	}, result.Targets)
+	assert.Equal(t, []string{
+		"kubectl", "-n", "staging", "get", "pods", "-l", "app=base", "-o", "jsonpath={.items[*].metadata.name}",
+	}, commands[0])
+
+	// the pods are listed again at each call
+	_, err = client.ResolveTargets(ctx, GroupTargets("staging"))
+	assert.Nil(t, err)
+	assert.Equal(t, "get", commands[len(commands)-1][3])
+}
+
+func TestResolveTargets_Invalid(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+
+	_, err := client.ResolveTargets(ctx, GroupTargets("staging"))
+	assert.True(t, errors.Is(err, ErrTargetGroupNotFound))
+
+	_, err = client.ResolveTargets(ctx, Targets{})
+	assert.NotNil(t, err)
+
+	port := 1238
+	targets, err := client.ResolveTargets(ctx, SingleTarget(ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(targets))
+}
+
+func TestResolveTargets_PerContainer(t *testing.T) {
+	ctx := context.Background()
+	pods := `{"items":[` +
+		`{"metadata":{"name":"base-0","annotations":{` +
+		`"serverless.alipay.com/arklet-port.sidecar":"1239","serverless.alipay.com/arklet-port.main":"1238"}},` +
+		`"spec":{"containers":[{"name":"main"},{"name":"sidecar"}]}},` +
+		`{"metadata":{"name":"base-1"},"spec":{"containers":[{"name":"main"}]}}]}`
+	var execs [][]string
+	client := BuildService(ctx,
+		WithTargetGroups(map[string]TargetGroup{
+			"staging": {Namespace: "staging", Selector: "app=base", PerContainer: true},
+		}),
+		WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+			switch args[2] {
+			case "get":
+				assert.Equal(t, "json", args[len(args)-1])
+				return strings.Split(pods, "\n"), nil
+			case "exec":
+				execs = append(execs, args)
+			}
+			return []string{`{"code":"SUCCESS"}`}, nil
+		}),
+	)
+
+	main, sidecar := 1238, 1239
+	targets, err := client.ResolveTargets(ctx, GroupTargets("staging"))
+	assert.Nil(t, err)
+	assert.Equal(t, []ArkContainerRuntimeInfo{
+		{RunType: ArkContainerRunTypeK8s, Coordinate: "staging/base-0", Container: "main", Port: &main},
+		{RunType: ArkContainerRunTypeK8s, Coordinate: "staging/base-0", Container: "sidecar", Port: &sidecar},
+		{RunType: ArkContainerRunTypeK8s, Coordinate: "staging/base-1"},
+	}, targets)
+
+	execs = nil
+	result, err := client.UnInstallBizOnTargets(ctx, GroupTargets("staging"), BizModel{BizName: "biz", BizVersion: "0.0.1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "staging/base-0/main:1238", result.Results[0].Target)
+	assert.Equal(t, "staging/base-0/sidecar:1239", result.Results[1].Target)
+
+	var uninstalls []string
+	for _, args := range execs {
+		if url := args[len(args)-1]; strings.HasSuffix(url, "/uninstallBiz") {
+			uninstalls = append(uninstalls, strings.Join(args[2:6], " ")+" "+url)
+		}
+	}
+	assert.Equal(t, []string{
+		"exec base-0 -c main http://127.0.0.1:1238/uninstallBiz",
+		"exec base-0 -c sidecar http://127.0.0.1:1239/uninstallBiz",
+		"exec base-1 -- curl http://127.0.0.1:1238/uninstallBiz",
+	}, uninstalls)
+}
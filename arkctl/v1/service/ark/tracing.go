@@ -0,0 +1,67 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	attributeBizName      = attribute.Key("ark.biz.name")
+	attributeBizVersion   = attribute.Key("ark.biz.version")
+	attributeRunType      = attribute.Key("ark.run_type")
+	attributeResponseCode = attribute.Key("ark.response_code")
+	attributeStatusCode   = attribute.Key("http.status_code")
+)
+
+// startSpan start a span of the operation on the biz, it's a no-op span if no tracer is configured.
+func (h *service) startSpan(ctx context.Context, operation string, bizModel BizModel, target ArkContainerRuntimeInfo) (context.Context, trace.Span) {
+	if h.options.Tracer == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return h.options.Tracer.Start(ctx, operation, trace.WithAttributes(
+		attributeBizName.String(bizModel.BizName),
+		attributeBizVersion.String(bizModel.BizVersion),
+		attributeRunType.String(string(target.RunType)),
+	))
+}
+
+// endSpan record the error of the operation and end the span, it's a no-op if no tracer is configured.
+func (h *service) endSpan(span trace.Span, err error) {
+	if h.options.Tracer == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// recordResponseCode record the code responded by arklet to the current span.
+func recordResponseCode(ctx context.Context, code ResponseCode) {
+	trace.SpanFromContext(ctx).SetAttributes(attributeResponseCode.String(string(code)))
+}
+
+// recordStatusCode record the http status code to the span of the request, used as a resty response middleware.
+func recordStatusCode(_ *resty.Client, resp *resty.Response) error {
+	trace.SpanFromContext(resp.Request.Context()).SetAttributes(attributeStatusCode.Int(resp.StatusCode()))
+	return nil
+}
@@ -0,0 +1,92 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func TestTracer(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/installBiz":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "SUCCESS"})
+		case "/uninstallBiz":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "FAILED", "message": "boom"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer cancel()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := BuildService(ctx, WithTracer(provider.Tracer("arkctl")))
+	target := ArkContainerRuntimeInfo{
+		RunType: ArkContainerRunTypeLocal,
+		Port:    &port,
+	}
+	bizModel := BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://serverless.alipay.com/biz.jar"}
+
+	assert.Nil(t, client.InstallBiz(ctx, InstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+	assert.NotNil(t, client.UnInstallBiz(ctx, UnInstallBizRequest{BizModel: bizModel, TargetContainer: target}))
+
+	spans := recorder.Ended()
+	assert.Equal(t, 2, len(spans))
+
+	assert.Equal(t, "InstallBiz", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, map[attribute.Key]attribute.Value{
+		attributeBizName:      attribute.StringValue("biz"),
+		attributeBizVersion:   attribute.StringValue("0.0.1"),
+		attributeRunType:      attribute.StringValue("local"),
+		attributeStatusCode:   attribute.IntValue(200),
+		attributeResponseCode: attribute.StringValue("SUCCESS"),
+	}, spanAttributes(spans[0]))
+
+	assert.Equal(t, "UnInstallBiz", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, attribute.StringValue("FAILED"), spanAttributes(spans[1])[attributeResponseCode])
+	assert.Equal(t, 1, len(spans[1].Events()))
+	assert.Equal(t, "exception", spans[1].Events()[0].Name)
+}
+
+func TestTracer_Disabled(t *testing.T) {
+	ctx := context.Background()
+	h := BuildService(ctx).(*service)
+
+	spanCtx, span := h.startSpan(ctx, "InstallBiz", BizModel{}, ArkContainerRuntimeInfo{})
+	assert.Equal(t, ctx, spanCtx)
+	assert.False(t, span.IsRecording())
+	h.endSpan(span, nil)
+}
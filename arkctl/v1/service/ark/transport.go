@@ -0,0 +1,184 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// TruncatedResponseError is returned when the response body is shorter than its Content-Length,
+// usually caused by a flaky proxy. It's a transient transport error and the request could be retried.
+type TruncatedResponseError struct {
+	// Read is the bytes actually read.
+	Read int64
+
+	// Expected is the Content-Length of the response.
+	Expected int64
+}
+
+func (e *TruncatedResponseError) Error() string {
+	return fmt.Sprintf("response truncated at %d of %d bytes", e.Read, e.Expected)
+}
+
+// lengthCheckingTransport verify the response body against its Content-Length.
+type lengthCheckingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *lengthCheckingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.ContentLength < 0 {
+		return resp, err
+	}
+	resp.Body = &lengthCheckingBody{ReadCloser: resp.Body, expected: resp.ContentLength}
+	return resp, nil
+}
+
+// lengthCheckingBody convert the early EOF of the body to TruncatedResponseError.
+type lengthCheckingBody struct {
+	io.ReadCloser
+	expected int64
+	read     int64
+}
+
+func (b *lengthCheckingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if errors.Is(err, io.ErrUnexpectedEOF) || (err == io.EOF && b.read < b.expected) {
+		return n, &TruncatedResponseError{Read: b.read, Expected: b.expected}
+	}
+	return n, err
+}
+
+// deadlineTransport bound the requests without deadline by timeout, so that they never hang on a dead connection.
+// The deadline of the caller is always respected.
+type deadlineTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := withDefaultTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return resp, err
+	}
+	// the body is still read after RoundTrip returns
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnCloseBody cancel the context of the request once the body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// withDefaultTimeout return ctx bounded by timeout if ctx has no deadline and timeout is positive, otherwise ctx itself.
+func withDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// redirectPolicy return the CheckRedirect of http.Client following options.
+func redirectPolicy(options RedirectOptions) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if options.MaxRedirects <= 0 {
+			// the redirect response is returned as is and fails the request with its status code
+			return http.ErrUseLastResponse
+		}
+		if len(via) > options.MaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", options.MaxRedirects)
+		}
+
+		// http.Client has switched POST redirected by 301 and 302 to GET and dropped the body
+		original := via[0]
+		if !options.PreservePost || original.Method != http.MethodPost || req.Method == http.MethodPost ||
+			original.GetBody == nil {
+			return nil
+		}
+		body, err := original.GetBody()
+		if err != nil {
+			return err
+		}
+		req.Method = http.MethodPost
+		req.Body = body
+		req.GetBody = original.GetBody
+		req.ContentLength = original.ContentLength
+		if contentType := original.Header.Get("Content-Type"); contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return nil
+	}
+}
+
+// closeUnsentBody close the body which is not sent, so that the writer goroutine of a pipe-backed body ends.
+func closeUnsentBody(body io.Reader, err error) {
+	switch closer := body.(type) {
+	case *io.PipeReader:
+		_ = closer.CloseWithError(err)
+	case io.Closer:
+		_ = closer.Close()
+	}
+}
+
+// postStreaming post the body by the http client under resty, which reads the reader bodies into memory as a whole.
+// The body is sent once without retries, the response body is unwrapped like the responses of resty.
+// The body is closed if it's a closer, even if the request isn't sent.
+func (h *service) postStreaming(ctx context.Context, endpointUrl string, header http.Header, body io.Reader) (*http.Response, []byte, error) {
+	if h.closed.Load() {
+		closeUnsentBody(body, ErrClientClosed)
+		return nil, nil, ErrClientClosed
+	}
+	if h.limiter != nil {
+		if err := h.limiter.wait(ctx); err != nil {
+			closeUnsentBody(body, err)
+			return nil, nil, err
+		}
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointUrl, body)
+	if err != nil {
+		closeUnsentBody(body, err)
+		return nil, nil, err
+	}
+	request.Header = header
+	request.Header.Set("User-Agent", h.options.UserAgent)
+
+	start := time.Now()
+	resp, err := h.client.GetClient().Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	recordStats(ctx, time.Since(start), int64(len(respBody)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, h.options.Dialect.toArk(unwrapEnvelope(h.options.ResponseEnvelope, respBody)), nil
+}
@@ -0,0 +1,189 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockTruncatingArklet serve /queryAllBiz, the first truncated responses send only part of the declared body.
+func mockTruncatingArklet(t *testing.T, truncated int) (int, func()) {
+	body := `{"code":"SUCCESS","data":[{"bizName":"biz","bizVersion":"0.0.1"}]}`
+	calls := 0
+	return mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls > truncated {
+			_, _ = w.Write([]byte(body))
+			return
+		}
+
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 100\r\n\r\n")
+		_, _ = buf.WriteString(body[:20])
+		_ = buf.Flush()
+	})
+}
+
+func TestTruncatedResponse(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockTruncatingArklet(t, 1)
+	defer cancel()
+
+	_, err := BuildService(ctx).QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	truncatedErr := &TruncatedResponseError{}
+	assert.True(t, errors.As(err, &truncatedErr))
+	assert.Equal(t, "response truncated at 20 of 100 bytes", err.Error())
+	assert.True(t, IsRetriable(err))
+}
+
+func TestTruncatedResponse_Retried(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockTruncatingArklet(t, 1)
+	defer cancel()
+
+	resp, err := BuildService(ctx, WithRetry(1, 10*time.Millisecond)).
+		QueryAllBiz(ctx, QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	assert.Equal(t, "biz", resp.Data[0].BizName)
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	port, cancel := mockHttpServer("/queryAllBiz", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[]}`))
+	})
+	defer cancel()
+	req := QueryAllArkBizRequest{HostName: "127.0.0.1", Port: port}
+
+	// the default timeout is applied to the context without deadline
+	client := BuildService(context.Background(), WithDefaultTimeout(50*time.Millisecond))
+	start := time.Now()
+	_, err := client.QueryAllBiz(context.Background(), req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.True(t, time.Since(start) < 200*time.Millisecond)
+
+	// the deadline of the caller is respected
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelCtx()
+	resp, err := client.QueryAllBiz(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, ResponseCodeSuccess, resp.Code)
+
+	// disabled
+	_, err = BuildService(context.Background(), WithDefaultTimeout(0)).QueryAllBiz(context.Background(), req)
+	assert.Nil(t, err)
+}
+
+// mockRedirectingArklet redirect /installBiz to /regional/installBiz by status and record the redirected requests.
+func mockRedirectingArklet(status int, redirected *[]BizModel, methods *[]string) (int, func()) {
+	return mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/installBiz":
+			http.Redirect(w, r, "/regional/installBiz", status)
+		case "/regional/installBiz":
+			bizModel := BizModel{}
+			_ = json.NewDecoder(r.Body).Decode(&bizModel)
+			*redirected = append(*redirected, bizModel)
+			*methods = append(*methods, r.Method)
+			_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func TestRedirect(t *testing.T) {
+	ctx := context.Background()
+	bizModel := BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"}
+
+	tests := []struct {
+		status          int
+		opts            []Option
+		expectedErr     bool
+		expectedMethods []string
+		expectedBiz     []BizModel
+	}{
+		{status: http.StatusFound, expectedMethods: []string{http.MethodPost}, expectedBiz: []BizModel{bizModel}},
+		{status: http.StatusMovedPermanently, expectedMethods: []string{http.MethodPost}, expectedBiz: []BizModel{bizModel}},
+		{status: http.StatusTemporaryRedirect, expectedMethods: []string{http.MethodPost}, expectedBiz: []BizModel{bizModel}},
+		{
+			// switched to GET without body following the browsers
+			status:          http.StatusFound,
+			opts:            []Option{WithRedirect(RedirectOptions{MaxRedirects: 10})},
+			expectedMethods: []string{http.MethodGet},
+			expectedBiz:     []BizModel{{}},
+		},
+		{
+			status:      http.StatusTemporaryRedirect,
+			opts:        []Option{WithRedirect(RedirectOptions{})},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		var redirected []BizModel
+		var methods []string
+		port, cancel := mockRedirectingArklet(test.status, &redirected, &methods)
+
+		err := BuildService(ctx, test.opts...).InstallBiz(ctx, InstallBizRequest{
+			BizModel:        bizModel,
+			TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		})
+		cancel()
+		assert.Equal(t, test.expectedErr, err != nil, "status %d: %v", test.status, err)
+		assert.Equal(t, test.expectedMethods, methods, "status %d", test.status)
+		assert.Equal(t, test.expectedBiz, redirected, "status %d", test.status)
+	}
+}
+
+func TestRedirect_MaxRedirects(t *testing.T) {
+	ctx := context.Background()
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/installBiz", http.StatusTemporaryRedirect)
+	})
+	defer cancel()
+
+	err := BuildService(ctx, WithRedirect(RedirectOptions{MaxRedirects: 2})).InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "stopped after 2 redirects")
+}
+
+func TestPostStreaming_ClosesUnsentBody(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx).(*service)
+	_ = client.Close()
+
+	body, _ := newMultipartFormBody(nil, "biz.jar", strings.NewReader("content"))
+	_, _, err := client.postStreaming(ctx, "http://127.0.0.1:1238/installBiz", http.Header{}, body)
+	assert.Equal(t, ErrClientClosed, err)
+	// the pipe is closed, so the writer goroutine isn't blocked forever
+	_, err = body.Read(make([]byte, 1))
+	assert.Equal(t, io.ErrClosedPipe, err)
+}
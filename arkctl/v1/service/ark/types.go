@@ -14,7 +14,16 @@
 
 package ark
 
-import "serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/runtime"
+)
 
 type ArkContainerRunType string
 
@@ -25,17 +34,49 @@ const (
 )
 
 // ArkResponseData is the response data of ark api.
+// Some arklets respond the data as a list of biz infos rather than an object, in which case only the raw data is kept,
+// use DataAsObject and DataAsBizList of ArkResponseBase to decode it by its shape.
 type ArkResponseData struct {
-	Code         string        `json:"code"`
+	Code         ResponseCode  `json:"code"`
 	Message      string        `json:"message"`
-	ElapsedSpace int           `json:"elapsedSpace"`
+	ElapsedSpace Milliseconds  `json:"elapsedSpace"`
 	BizInfos     []interface{} `json:"bizInfos"`
+
+	raw json.RawMessage
+}
+
+// UnmarshalJSON keep the raw data, and decode the fields only if the data is an object.
+func (data *ArkResponseData) UnmarshalJSON(raw []byte) error {
+	data.raw = append(json.RawMessage{}, raw...)
+	if jsonShapeOf(raw) != '{' {
+		return nil
+	}
+	type arkResponseDataAlias ArkResponseData
+	return json.Unmarshal(raw, (*arkResponseDataAlias)(data))
+}
+
+// String print the data like %v of the struct without the raw data, with the elapsed space in milliseconds as arklet reports.
+// The data of other shapes is printed as is.
+func (data ArkResponseData) String() string {
+	if shape := jsonShapeOf(data.raw); shape != '{' && shape != 0 {
+		return string(data.raw)
+	}
+	return fmt.Sprintf("{%s %s %d %v}", data.Code, data.Message, data.ElapsedSpace.Duration().Milliseconds(), data.BizInfos)
+}
+
+// jsonShapeOf return the first non space byte of the json value, e.g. '{' for object and '[' for array.
+func jsonShapeOf(raw []byte) byte {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[0]
 }
 
 // GenericArkResponseBase is the base response of ark api.
 type GenericArkResponseBase[T any] struct {
 	// Code is the response code
-	Code string `json:"code"`
+	Code ResponseCode `json:"code"`
 
 	// Data is the response data
 	Data T `json:"data"`
@@ -47,7 +88,7 @@ type GenericArkResponseBase[T any] struct {
 // ArkResponseBase is the base response of ark api.
 type ArkResponseBase struct {
 	// Code is the response code
-	Code string `json:"code"`
+	Code ResponseCode `json:"code"`
 
 	// Data is the response data
 	Data ArkResponseData `json:"data"`
@@ -56,29 +97,86 @@ type ArkResponseBase struct {
 	Message string `json:"message"`
 }
 
+// DataAsObject return the data if it's an object, false if it's of another shape like a list of biz infos.
+func (resp ArkResponseBase) DataAsObject() (ArkResponseData, bool) {
+	if resp.Data.raw == nil {
+		// not decoded from json
+		return resp.Data, true
+	}
+	return resp.Data, jsonShapeOf(resp.Data.raw) == '{'
+}
+
+// DataAsBizList return the biz infos of the data, which is either the data itself as a list or the bizInfos of the object.
+func (resp ArkResponseBase) DataAsBizList() ([]ArkBizInfo, error) {
+	raw := resp.Data.raw
+	switch jsonShapeOf(raw) {
+	case '[':
+	case '{', 0:
+		raw = runtime.Must(json.Marshal(resp.Data.BizInfos))
+	default:
+		return nil, fmt.Errorf("unexpected data of %s response: %s", resp.Code, resp.Data.raw)
+	}
+
+	bizList := []ArkBizInfo{}
+	if err := json.Unmarshal(raw, &bizList); err != nil {
+		return nil, fmt.Errorf("decode biz list of %s response failed: %w", resp.Code, err)
+	}
+	return bizList, nil
+}
+
 // ArkContainerRuntimeInfo contains necessary info of an ark container.
 type ArkContainerRuntimeInfo struct {
 	// RunType is the type of ark container, like local, vm server, pod, etc.
 	RunType ArkContainerRunType `json:"runType"`
 
 	// Coordinate is the exact location of ark container.
-	// If the RunType is local, then it's localhost.
+	// If the RunType is local, then it's localhost, or the address the arklet is bound to, like ::1.
 	// If the RunType is vm server, then it's ip.
 	// If the RunType is pod, then it's the {namespace}/{podName}
 	Coordinate string `json:"coordinate"`
 
 	// Port is the ark api port of ark container.
+	// It's required if the RunType is local unless SocketPath is given, otherwise 1238 by default,
+	// or detected from the pod if the client enables DetectPodPort.
 	Port *int `json:"port"`
+
+	// SocketPath is the unix socket the arklet is served on, the Port is ignored if it's given.
+	// If the RunType is pod, then it's the path inside the pod.
+	SocketPath string `json:"socketPath,omitempty"`
+
+	// Kubeconfig is the kubeconfig file of the cluster the pod is running in, overriding the client's kube config.
+	// It's only used if the RunType is pod.
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+
+	// KubeContext is the kube context of the cluster the pod is running in, overriding the client's kube context.
+	// It's only used if the RunType is pod.
+	KubeContext string `json:"kubeContext,omitempty"`
+
+	// Container is the container of the pod the arklet runs in, for the pods running an arklet in each of their containers
+	// like a sidecar along with the main container. The default container of the pod is used if it's empty.
+	// It's only used if the RunType is pod.
+	Container string `json:"container,omitempty"`
 }
 
+// GetPort return the port of the ark container, the default port if it's not given, even if info is nil.
 func (info *ArkContainerRuntimeInfo) GetPort() int {
-	if info.Port == nil {
+	if info == nil || info.Port == nil {
 		// default port
 		return 1238
 	}
 	return *info.Port
 }
 
+// Clone return a deep copy of the info, so that the Port of the copy could be changed without affecting the original,
+// e.g. when building the requests of multiple targets from a shared one.
+func (info ArkContainerRuntimeInfo) Clone() ArkContainerRuntimeInfo {
+	if info.Port != nil {
+		port := *info.Port
+		info.Port = &port
+	}
+	return info
+}
+
 // BizModel contains necessary metadata info of an ark biz.
 // Usually this BizModel is generated automatically from bundle like jarfile.
 type BizModel struct {
@@ -90,6 +188,23 @@ type BizModel struct {
 
 	// BizUrl is the location of source code.
 	BizUrl fileutil.FileUrl `json:"bizUrl,omitempty"`
+
+	// MainClass overrides the main class declared in the biz bundle.
+	MainClass string `json:"mainClass,omitempty"`
+
+	// Env is passed to the biz as environment properties at install time.
+	Env map[string]string `json:"envs,omitempty"`
+
+	// Args is passed to the main method of the biz at install time.
+	Args []string `json:"args,omitempty"`
+
+	// WebContextPath overrides the web context path of the biz at install time, e.g. /biz1-canary,
+	// so that the same biz could be installed with different context paths. It's only supported by some arklets.
+	WebContextPath string `json:"webContextPath,omitempty"`
+
+	// Checksum is the hex encoded sha256 checksum of the biz bundle, used to tell different artifacts of the same version.
+	// It's sent to the arklet along with the biz, which could report it back in ArkBizInfo.
+	Checksum string `json:"checksum,omitempty"`
 }
 
 // InstallBizRequest is the request for installing biz module to ark container.
@@ -109,6 +224,44 @@ type InstallBizRequest struct {
 	// If not given, we will use {tmp}/arkBiz/ dir instead.
 	// This will only be used when install biz module from local filesystem.
 	BizHomeDir *string `json:"bizHomeDir"`
+
+	// IdempotencyNonce is provided by caller to distinguish logical install requests of the same biz.
+	// It's only used when the idempotency key is enabled, a random one is generated if not given.
+	IdempotencyNonce string `json:"idempotencyNonce,omitempty"`
+
+	// ExtraParams is merged into the install body sent to arklet, e.g. async, installStrategy of newer arklets.
+	// The core fields of BizModel are reserved and can't be overridden.
+	ExtraParams map[string]interface{} `json:"extraParams,omitempty"`
+
+	// AllowMultipleVersions skips the check of other active versions of the same biz.
+	AllowMultipleVersions bool `json:"allowMultipleVersions,omitempty"`
+
+	// AllowMasterBiz skips the protection of the master biz, only for advanced users.
+	AllowMasterBiz bool `json:"allowMasterBiz,omitempty"`
+
+	// Preflight runs PreflightCheck on the biz jar before install, and fails the install if any error is found.
+	// The biz url must be accessible by arkctl.
+	Preflight bool `json:"preflight,omitempty"`
+
+	// ProbeBizUrl verifies the biz url is downloadable from where the arklet runs before install,
+	// by a HEAD request from this host for the local run type or inside the pod for the pod run type.
+	ProbeBizUrl bool `json:"probeBizUrl,omitempty"`
+
+	// RecordOnPod records the installed biz in BizStateAnnotation of the pod, only for the pod run type.
+	// The install doesn't fail if the annotation can't be patched.
+	RecordOnPod bool `json:"recordOnPod,omitempty"`
+
+	// AutoGC collects the failed biz of the same name and version by GCFailedBiz if the install fails,
+	// and retries the install once if any is collected.
+	AutoGC bool `json:"autoGC,omitempty"`
+
+	// AllowVersionReuse skips the check of the biz active with the same version but another checksum,
+	// which is only done for the local run type when both checksums are known.
+	AllowVersionReuse bool `json:"allowVersionReuse,omitempty"`
+
+	// VerifyProbe is polled after the biz is installed, and fails the install if it doesn't succeed before its timeout.
+	// The biz is left installed for investigation when the probe fails.
+	VerifyProbe *VerifyProbe `json:"verifyProbe,omitempty"`
 }
 
 // InstallBizResponse is the response for installing biz module to ark container.
@@ -116,6 +269,35 @@ type InstallBizResponse struct {
 	ArkResponseBase
 }
 
+// UploadBizRequest is the request for uploading biz bundle to ark container.
+type UploadBizRequest struct {
+	// BizModel is the metadata a given biz module, the BizUrl is ignored.
+	BizModel BizModel `json:"bizModel"`
+
+	// TargetContainer is the target ark container we want to upload a biz bundle to.
+	TargetContainer ArkContainerRuntimeInfo `json:"targetContainer"`
+
+	// FileName is the file name of the biz bundle.
+	FileName string `json:"fileName"`
+
+	// Content is the content of the biz bundle.
+	Content io.Reader `json:"-"`
+
+	// Size is the size of Content in bytes, -1 if it's unknown.
+	Size int64 `json:"size"`
+}
+
+// UploadBizResult is the data of UploadBizResponse.
+type UploadBizResult struct {
+	// BizUrl is the location of the uploaded biz bundle inside the ark container.
+	BizUrl fileutil.FileUrl `json:"bizUrl"`
+}
+
+// UploadBizResponse is the response for uploading biz bundle to ark container.
+type UploadBizResponse struct {
+	GenericArkResponseBase[UploadBizResult]
+}
+
 // UnInstallBizRequest is the request for installing biz module to ark container.
 type UnInstallBizRequest struct {
 	// BizModel is the metadata a given biz module.
@@ -123,6 +305,34 @@ type UnInstallBizRequest struct {
 
 	// TargetContainer is the target ark container we want to install a biz module to.
 	TargetContainer ArkContainerRuntimeInfo `json:"targetContainer"`
+
+	// DrainFirst drains the traffic of the biz before uninstalling it, if the arklet supports it.
+	DrainFirst bool `json:"drainFirst,omitempty"`
+
+	// AllowMasterBiz skips the protection of the master biz, only for advanced users.
+	AllowMasterBiz bool `json:"allowMasterBiz,omitempty"`
+
+	// RecordOnPod removes the uninstalled biz from BizStateAnnotation of the pod, only for the pod run type.
+	// The uninstall doesn't fail if the annotation can't be patched.
+	RecordOnPod bool `json:"recordOnPod,omitempty"`
+}
+
+// ActivateBizRequest is the request for activating a biz module installed in ark container.
+type ActivateBizRequest struct {
+	// BizModel is the metadata a given biz module.
+	BizModel BizModel `json:"bizModel"`
+
+	// TargetContainer is the ark container the biz module is installed in.
+	TargetContainer ArkContainerRuntimeInfo `json:"targetContainer"`
+}
+
+// DeactivateBizRequest is the request for deactivating a biz module, which stops serving traffic but stays installed.
+type DeactivateBizRequest struct {
+	// BizModel is the metadata a given biz module.
+	BizModel BizModel `json:"bizModel"`
+
+	// TargetContainer is the ark container the biz module is installed in.
+	TargetContainer ArkContainerRuntimeInfo `json:"targetContainer"`
 }
 
 // UnInstallBizResponse is the response for installing biz module to ark container.
@@ -130,6 +340,49 @@ type UnInstallBizResponse struct {
 	ArkResponseBase
 }
 
+const (
+	PhaseDrain     = "drain"
+	PhaseUninstall = "uninstall"
+)
+
+// PhaseResult is the result of a phase of an operation.
+type PhaseResult struct {
+	// Name is the name of the phase, like drain, uninstall.
+	Name string
+
+	// Duration is how long the phase takes.
+	Duration time.Duration
+
+	// Skipped is true if the phase is not executed, the reason is in Message.
+	Skipped bool
+
+	// Message describes the result of the phase.
+	Message string
+
+	// Err is the error of the failed phase.
+	Err error
+}
+
+// UnInstallResult is the result of uninstalling biz.
+type UnInstallResult struct {
+	// Existed is false if the biz was already absent, i.e. arklet reports NOT_FOUND_BIZ.
+	Existed bool
+
+	// Phases are the executed phases in order.
+	Phases []PhaseResult
+}
+
+// DrainBizResult is the data of DrainBizResponse.
+type DrainBizResult struct {
+	// InFlightRequests is the requests still being processed by the biz, nil if arklet doesn't report it.
+	InFlightRequests *int `json:"inFlightRequests"`
+}
+
+// DrainBizResponse is the response for draining the traffic of biz.
+type DrainBizResponse struct {
+	GenericArkResponseBase[DrainBizResult]
+}
+
 // QueryAllArkBizRequest is the request for querying all biz module in a given ark container.
 type QueryAllArkBizRequest struct {
 	// HostName is where the ark container is running
@@ -137,18 +390,125 @@ type QueryAllArkBizRequest struct {
 
 	// Port is where the ark container is serving
 	Port int
+
+	// SocketPath is the unix socket the ark container is serving on, HostName and Port are ignored if it's given.
+	SocketPath string `json:"-"`
+
+	// ForceRefresh skips the cached result and queries the ark container.
+	ForceRefresh bool `json:"-"`
 }
 
 // ArkBizInfo is the response for querying all biz module in a given ark container.
 type ArkBizInfo struct {
-	BizName        string `json:"bizName"`
-	BizState       string `json:"bizState"`
-	BizVersion     string `json:"bizVersion"`
-	MainClass      string `json:"mainClass"`
-	WebContextPath string `json:"webContextPath"`
+	BizName        string   `json:"bizName"`
+	BizState       BizState `json:"bizState"`
+	BizVersion     string   `json:"bizVersion"`
+	MainClass      string   `json:"mainClass"`
+	WebContextPath string   `json:"webContextPath"`
+
+	// BizUrl is the url the biz is installed from, empty if arklet doesn't report it.
+	BizUrl fileutil.FileUrl `json:"bizUrl,omitempty"`
+
+	// Checksum is the checksum of the installed biz bundle, empty if arklet doesn't report it.
+	Checksum string `json:"checksum,omitempty"`
 }
 
 // QueryAllArkBizResponse is the response for querying all biz module in a given ark container.
 type QueryAllArkBizResponse struct {
 	GenericArkResponseBase[[]ArkBizInfo]
 }
+
+// BizDetail is the detail info of a single biz module.
+// Some arklets expose more fields than ArkBizInfo, the unknown fields are kept in Extra.
+type BizDetail struct {
+	ArkBizInfo
+
+	// ClassLoader is the classloader of the biz module.
+	ClassLoader string `json:"classLoader,omitempty"`
+
+	// Dependencies is the declared dependencies of the biz module.
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// InstalledTime is the unix milliseconds when the biz module is installed.
+	InstalledTime int64 `json:"installedTime,omitempty"`
+
+	// ActivatedTime is the unix milliseconds when the biz module is activated.
+	ActivatedTime int64 `json:"activatedTime,omitempty"`
+
+	// Extra contains the fields not known by this client.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+var bizDetailKnownFields = []string{
+	"bizName", "bizState", "bizVersion", "mainClass", "webContextPath", "bizUrl", "checksum",
+	"classLoader", "dependencies", "installedTime", "activatedTime",
+}
+
+// UnmarshalJSON decode the known fields, and keep the unknown fields in Extra.
+func (detail *BizDetail) UnmarshalJSON(data []byte) error {
+	type bizDetailAlias BizDetail
+	alias := (*bizDetailAlias)(detail)
+	if err := json.Unmarshal(data, alias); err != nil {
+		return err
+	}
+
+	extra := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return err
+	}
+	for _, field := range bizDetailKnownFields {
+		delete(extra, field)
+	}
+	detail.Extra = nil
+	if len(extra) > 0 {
+		detail.Extra = extra
+	}
+	return nil
+}
+
+// MarshalJSON encode both the known fields and the unknown fields in Extra.
+func (detail BizDetail) MarshalJSON() ([]byte, error) {
+	type bizDetailAlias BizDetail
+	data, err := json.Marshal(bizDetailAlias(detail))
+	if err != nil || len(detail.Extra) == 0 {
+		return data, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range detail.Extra {
+		if _, exist := fields[key]; !exist {
+			fields[key] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// QueryBizResponse is the response for querying the detail of a biz module.
+type QueryBizResponse struct {
+	GenericArkResponseBase[*BizDetail]
+}
+
+// HealthData is the health data reported by arklet.
+type HealthData struct {
+	// MasterBizInfo is the master biz, aka the host application of the ark container.
+	MasterBizInfo *ArkBizInfo `json:"masterBizInfo"`
+
+	// ArkVersion is the version of the ark container, empty if arklet doesn't report it.
+	ArkVersion string `json:"arkVersion,omitempty"`
+
+	// ArkletVersion is the version of arklet, empty if arklet doesn't report it.
+	ArkletVersion string `json:"arkletVersion,omitempty"`
+}
+
+// HealthResult is the data of HealthResponse.
+type HealthResult struct {
+	HealthData HealthData `json:"healthData"`
+}
+
+// HealthResponse is the response for querying the health of ark container.
+type HealthResponse struct {
+	GenericArkResponseBase[HealthResult]
+}
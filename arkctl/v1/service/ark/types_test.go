@@ -0,0 +1,46 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArkContainerRuntimeInfo_GetPort(t *testing.T) {
+	port := 1239
+	assert.Equal(t, 1239, (&ArkContainerRuntimeInfo{Port: &port}).GetPort())
+	assert.Equal(t, 1238, (&ArkContainerRuntimeInfo{}).GetPort())
+	assert.Equal(t, 1238, (*ArkContainerRuntimeInfo)(nil).GetPort())
+}
+
+func TestArkContainerRuntimeInfo_Clone(t *testing.T) {
+	port := 1238
+	original := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0", Port: &port}
+
+	clone := original.Clone()
+	assert.Equal(t, original, clone)
+	*clone.Port = 1239
+	clone.Coordinate = "default/base-1"
+	assert.Equal(t, 1238, original.GetPort())
+	assert.Equal(t, 1238, port)
+	assert.Equal(t, "default/base-0", original.Coordinate)
+	assert.Equal(t, 1239, clone.GetPort())
+
+	// the default port is kept
+	clone = ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal}.Clone()
+	assert.Nil(t, clone.Port)
+}
@@ -0,0 +1,91 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// UnInstallAllOptions controls UnInstallAllBiz.
+type UnInstallAllOptions struct {
+	// IncludeMasterBiz uninstalls the master biz as well, which breaks the ark container.
+	// The master biz is skipped by default.
+	IncludeMasterBiz bool
+}
+
+// BatchResult is the result of a biz in a batch operation.
+type BatchResult struct {
+	// BizModel is the biz operated.
+	BizModel BizModel
+
+	// Skipped is true if the biz is left untouched, like the master biz.
+	Skipped bool
+
+	// Err is the error of the failed operation, nil if it succeeded or skipped.
+	Err error
+}
+
+// UnInstallAllBiz uninstall every biz installed in the ark container, e.g. to tear down a test environment.
+// All the biz are tried even if some fail, the failures are reported by MultiTargetError along with the results.
+// The biz already uninstalled meanwhile are tolerated.
+func (h *service) UnInstallAllBiz(ctx context.Context, target ArkContainerRuntimeInfo, opts UnInstallAllOptions) ([]BatchResult, error) {
+	target, err := targetOrDefault(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	ctx = WithPodPortCache(ctx)
+	logger := contextutil.GetLogger(ctx).WithFields(targetFields(target))
+	logger.Info("uninstall all biz started")
+
+	installed, err := h.queryAllBizOf(ctx, target)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	masterBizName := ""
+	if !opts.IncludeMasterBiz {
+		masterBizName = h.masterBizName(ctx, target)
+	}
+
+	results := []BatchResult{}
+	targets := []TargetResult{}
+	for _, info := range installed {
+		bizModel := BizModel{BizName: info.BizName, BizVersion: info.BizVersion}
+		if masterBizName != "" && info.BizName == masterBizName {
+			logger.WithFields(bizFields(bizModel)).Info("skip master biz")
+			results = append(results, BatchResult{BizModel: bizModel, Skipped: true})
+			continue
+		}
+
+		uninstallErr := h.UnInstallBiz(ctx, UnInstallBizRequest{
+			BizModel:        bizModel,
+			TargetContainer: target,
+			// the master biz is either skipped or explicitly included
+			AllowMasterBiz: true,
+		})
+		results = append(results, BatchResult{BizModel: bizModel, Err: uninstallErr})
+		targets = append(targets, TargetResult{Target: info.BizName + ":" + info.BizVersion, Err: uninstallErr})
+	}
+
+	if err := newMultiTargetError("uninstall all biz", targets); err != nil {
+		logger.Error(err)
+		return results, err
+	}
+	logger.WithField("count", len(results)).Info("uninstall all biz completed")
+	return results, nil
+}
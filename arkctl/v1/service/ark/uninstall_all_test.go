@@ -0,0 +1,105 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// masterBizHealth is the health of the arklet whose master biz is base.
+const masterBizHealth = `{"code":"SUCCESS","data":{"healthData":{"masterBizInfo":{"bizName":"base","bizVersion":"1.0.0"}}}}`
+
+func TestUnInstallAllBiz(t *testing.T) {
+	ctx := context.Background()
+	arklet := &fakeArklet{
+		biz: []ArkBizInfo{
+			{BizName: "base", BizVersion: "1.0.0", BizState: BizStateActivated},
+			{BizName: "biz1", BizVersion: "0.0.1", BizState: BizStateActivated},
+			{BizName: "biz2", BizVersion: "0.0.2", BizState: BizStateDeactivated},
+		},
+		handlers: map[string]http.HandlerFunc{
+			"/health": respondBody(masterBizHealth),
+			// gone is listed, but uninstalled by someone else meanwhile
+			"/queryAllBiz": respondBody(`{"code":"SUCCESS","data":[` +
+				`{"bizName":"base","bizVersion":"1.0.0","bizState":"ACTIVATED"},` +
+				`{"bizName":"biz1","bizVersion":"0.0.1","bizState":"ACTIVATED"},` +
+				`{"bizName":"gone","bizVersion":"0.0.1","bizState":"ACTIVATED"},` +
+				`{"bizName":"biz2","bizVersion":"0.0.2","bizState":"DEACTIVATED"}]}`),
+		},
+	}
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+	target := ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}
+
+	results, err := BuildService(ctx).UnInstallAllBiz(ctx, target, UnInstallAllOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"uninstall biz1:0.0.1", "uninstall gone:0.0.1", "uninstall biz2:0.0.2"}, arklet.calls)
+	assert.Equal(t, []BatchResult{
+		{BizModel: BizModel{BizName: "base", BizVersion: "1.0.0"}, Skipped: true},
+		{BizModel: BizModel{BizName: "biz1", BizVersion: "0.0.1"}},
+		{BizModel: BizModel{BizName: "gone", BizVersion: "0.0.1"}},
+		{BizModel: BizModel{BizName: "biz2", BizVersion: "0.0.2"}},
+	}, results)
+
+	// include the master biz
+	arklet.calls = nil
+	_, err = BuildService(ctx).UnInstallAllBiz(ctx, target, UnInstallAllOptions{IncludeMasterBiz: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"uninstall base:1.0.0", "uninstall biz1:0.0.1", "uninstall gone:0.0.1", "uninstall biz2:0.0.2"}, arklet.calls)
+}
+
+func TestUnInstallAllBiz_Empty(t *testing.T) {
+	ctx := context.Background()
+	arklet := &fakeArklet{handlers: map[string]http.HandlerFunc{"/health": respondBody(masterBizHealth)}}
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	results, err := BuildService(ctx).UnInstallAllBiz(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}, UnInstallAllOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+	assert.Empty(t, arklet.calls)
+}
+
+func TestUnInstallAllBiz_PartialFailure(t *testing.T) {
+	ctx := context.Background()
+	arklet := &fakeArklet{
+		biz: []ArkBizInfo{
+			{BizName: "broken", BizVersion: "0.0.1", BizState: BizStateActivated},
+			{BizName: "biz1", BizVersion: "0.0.1", BizState: BizStateActivated},
+		},
+		failNames: map[string]bool{"broken": true},
+		handlers:  map[string]http.HandlerFunc{"/health": respondBody(masterBizHealth)},
+	}
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	results, err := BuildService(ctx).UnInstallAllBiz(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port}, UnInstallAllOptions{})
+	multiErr := &MultiTargetError{}
+	assert.True(t, errors.As(err, &multiErr))
+	assert.Equal(t, []string{"uninstall broken:0.0.1", "uninstall biz1:0.0.1"}, arklet.calls)
+	assert.NotNil(t, results[0].Err)
+	assert.Nil(t, results[1].Err)
+
+	// the failure of each biz is recoverable from the aggregate
+	responseErr := &ResponseError{}
+	assert.True(t, errors.As(err, &responseErr))
+	assert.Equal(t, ResponseCodeFailed, responseErr.Code)
+	assert.Equal(t, []error{results[0].Err}, multiErr.Unwrap())
+}
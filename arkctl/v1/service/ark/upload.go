@@ -0,0 +1,313 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/progressutil"
+)
+
+// progressInterval is the min interval between two progress callbacks,
+// so that a slow callback won't slow down the transfer.
+const progressInterval = 100 * time.Millisecond
+
+// progressReader report the bytes read through it to the callback.
+type progressReader struct {
+	reader     io.Reader
+	sent       int64
+	lastReport time.Time
+	progress   progressutil.ProgressReporter
+}
+
+// newProgressReader return the reader updating progress with the bytes read, progress should be started by the caller.
+func newProgressReader(reader io.Reader, progress progressutil.ProgressReporter) io.Reader {
+	if progress == nil {
+		return reader
+	}
+	return &progressReader{
+		reader:   reader,
+		progress: progress,
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.sent += int64(n)
+
+	now := time.Now()
+	if err == io.EOF || now.Sub(r.lastReport) >= progressInterval {
+		r.lastReport = now
+		r.progress.Update(r.sent, "")
+	}
+	return n, err
+}
+
+// newMultipartBody stream the content as a multipart file field named "file".
+// It returns the body reader and its content type.
+func newMultipartBody(fileName string, content io.Reader) (io.Reader, string) {
+	return newMultipartFormBody(nil, fileName, content)
+}
+
+// newMultipartFormBody stream the fields in the order of their names followed by the content as the file field named "file".
+// It returns the body reader and its content type.
+func newMultipartFormBody(fields url.Values, fileName string, content io.Reader) (io.Reader, string) {
+	pipeReader, pipeWriter := io.Pipe()
+	multipartWriter := multipart.NewWriter(pipeWriter)
+
+	go func() {
+		var err error
+		for _, key := range sortedKeys(fields) {
+			for _, value := range fields[key] {
+				if err == nil {
+					err = multipartWriter.WriteField(key, value)
+				}
+			}
+		}
+		var part io.Writer
+		if err == nil {
+			part, err = multipartWriter.CreateFormFile("file", fileName)
+		}
+		if err == nil {
+			_, err = io.Copy(part, content)
+		}
+		if err == nil {
+			err = multipartWriter.Close()
+		}
+		pipeWriter.CloseWithError(err)
+	}()
+
+	return pipeReader, multipartWriter.FormDataContentType()
+}
+
+// CompressionMode controls whether the biz bundles are compressed when uploading.
+type CompressionMode string
+
+const (
+	// CompressionOff never compresses the uploads.
+	CompressionOff CompressionMode = "off"
+
+	// CompressionAuto compresses the uploads if the arklet advertises the support by Accept-Encoding.
+	CompressionAuto CompressionMode = "auto"
+
+	// CompressionAlways compresses the uploads regardless of the advertisement.
+	CompressionAlways CompressionMode = "always"
+)
+
+// errCompressionNotAccepted is returned when arklet rejects the compressed upload with 415.
+var errCompressionNotAccepted = errors.New("compressed upload is not accepted by arklet")
+
+// shouldCompressUpload return true if the upload should be gzip compressed.
+func (h *service) shouldCompressUpload(ctx context.Context, req UploadBizRequest) bool {
+	switch h.options.UploadCompression {
+	case CompressionAlways:
+		return true
+	case CompressionAuto:
+		capabilities, err := h.DetectCapabilities(ctx, req.TargetContainer)
+		if err != nil {
+			contextutil.GetLogger(ctx).WithError(err).Warn("upload without compression")
+			return false
+		}
+		return capabilities.GzipUpload
+	default:
+		return false
+	}
+}
+
+// newGzipReader return a reader of the gzip compressed content.
+// The content is closed once it's consumed or the reader is closed, if it's a closer.
+func newGzipReader(content io.Reader) io.Reader {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		if closer, ok := content.(io.Closer); ok {
+			defer closer.Close()
+		}
+		gzipWriter := gzip.NewWriter(pipeWriter)
+		_, err := io.Copy(gzipWriter, content)
+		if closeErr := gzipWriter.Close(); err == nil {
+			err = closeErr
+		}
+		pipeWriter.CloseWithError(err)
+	}()
+	return pipeReader
+}
+
+// rewinder remember the current offset of the content, and return a function seeking back to it.
+// The function fails if the content isn't seekable.
+func rewinder(content io.Reader) func() error {
+	seeker, ok := content.(io.Seeker)
+	if !ok {
+		return func() error {
+			return errors.New("the content is not seekable")
+		}
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	return func() error {
+		if err != nil {
+			return err
+		}
+		_, err := seeker.Seek(offset, io.SeekStart)
+		return err
+	}
+}
+
+// readerSize return the size of the content if it's known without reading it, -1 otherwise.
+func readerSize(content io.Reader) int64 {
+	switch r := content.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		if info, err := r.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+// ReaderInstallOptions controls how the biz bundle read from a reader is staged before install.
+type ReaderInstallOptions struct {
+	// MaxSize is the max bytes of the biz bundle, ErrBizTooLarge is returned if it's exceeded.
+	// The size is not limited if it's not positive.
+	MaxSize int64
+
+	// ExpectedChecksum is the hex encoded sha256 checksum the biz bundle must match, the check is skipped if it's empty.
+	ExpectedChecksum string
+
+	// OnProgress is called periodically with the staged bytes and the total bytes, the total is -1 if it's unknown.
+	//
+	// Deprecated: use Progress, the callback is reported through it along with Progress.
+	OnProgress func(staged, total int64)
+
+	// Progress is started with the total bytes, -1 if it's unknown, and updated with the staged bytes.
+	Progress progressutil.ProgressReporter
+
+	// TempDir is where the biz bundle is staged, the default temp dir is used if it's empty.
+	TempDir string
+}
+
+// stagedBiz is the biz bundle staged to a temp file.
+type stagedBiz struct {
+	path     string
+	size     int64
+	checksum string
+}
+
+// stageBiz stream the content to a temp file, computing the checksum on the fly.
+// The temp file is removed if the staging fails.
+func stageBiz(content io.Reader, opts ReaderInstallOptions) (staged *stagedBiz, err error) {
+	file, err := os.CreateTemp(opts.TempDir, "ark-biz-*.jar")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	progress := progressutil.Multi(opts.Progress, progressutil.FromFunc(opts.OnProgress))
+	if progress != nil {
+		progress.Start(readerSize(content))
+		defer func() {
+			progress.Finish(err)
+		}()
+	}
+	reader := newProgressReader(content, progress)
+	if opts.MaxSize > 0 {
+		// read one more byte to tell whether the limit is exceeded
+		reader = io.LimitReader(reader, opts.MaxSize+1)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), reader)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxSize > 0 && size > opts.MaxSize {
+		return nil, fmt.Errorf("%w: exceeds the limit of %d bytes", ErrBizTooLarge, opts.MaxSize)
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if opts.ExpectedChecksum != "" && !strings.EqualFold(checksum, opts.ExpectedChecksum) {
+		return nil, fmt.Errorf("%w: expected %s, got %s", fileutil.ErrChecksumMismatch, opts.ExpectedChecksum, checksum)
+	}
+	return &stagedBiz{path: file.Name(), size: size, checksum: checksum}, nil
+}
+
+// InstallBizFromReader stage the biz bundle read from content to a temp file, then upload it to the ark container and install it.
+// The content is streamed, its length is not required to be known. The temp file is removed afterward unless KeepTempFiles is set.
+func (h *service) InstallBizFromReader(ctx context.Context, bizModel BizModel, content io.Reader, target ArkContainerRuntimeInfo, opts ReaderInstallOptions) error {
+	target, err := targetOrDefault(ctx, target)
+	if err != nil {
+		return err
+	}
+	logger := contextutil.GetLogger(ctx)
+	staged, err := stageBiz(content, opts)
+	if err != nil {
+		return err
+	}
+	logger.WithField("path", staged.path).
+		WithField("size", staged.size).
+		WithField("checksum", staged.checksum).
+		Info("biz bundle is staged")
+	defer func() {
+		if h.options.KeepTempFiles {
+			logger.WithField("path", staged.path).Info("temp file of biz bundle is kept")
+			return
+		}
+		if err := os.Remove(staged.path); err != nil {
+			logger.WithField("path", staged.path).Warn("failed to remove temp file of biz bundle: ", err)
+		}
+	}()
+
+	file, err := os.Open(staged.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bizUrl, err := h.UploadBiz(ctx, UploadBizRequest{
+		BizModel:        bizModel,
+		TargetContainer: target,
+		FileName:        fmt.Sprintf("%s-%s-ark-biz.jar", bizModel.BizName, bizModel.BizVersion),
+		Content:         file,
+		Size:            staged.size,
+	})
+	if err != nil {
+		return err
+	}
+
+	bizModel.BizUrl = bizUrl
+	return h.InstallBiz(ctx, InstallBizRequest{
+		BizModel:        bizModel,
+		TargetContainer: target,
+	})
+}
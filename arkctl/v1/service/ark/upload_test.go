@@ -0,0 +1,373 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/fileutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/progressutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowReader return at most 1 byte per read and sleep a while.
+type slowReader struct {
+	reader io.Reader
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(5 * time.Millisecond)
+	return r.reader.Read(p[:1])
+}
+
+func TestProgressReader_MonotonicallyIncreasing(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 64)
+
+	var reported []int64
+	progress := progressutil.FromFunc(func(bytesSent, total int64) {
+		assert.Equal(t, int64(len(content)), total)
+		reported = append(reported, bytesSent)
+	})
+	progress.Start(int64(len(content)))
+	reader := newProgressReader(&slowReader{reader: bytes.NewReader(content)}, progress)
+
+	read, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, content, read)
+
+	// callbacks are throttled, but fire more than once for a slow transfer
+	assert.True(t, len(reported) > 1)
+	assert.True(t, len(reported) < len(content))
+	for i := 1; i < len(reported); i++ {
+		assert.True(t, reported[i] > reported[i-1])
+	}
+	assert.Equal(t, int64(len(content)), reported[len(reported)-1])
+}
+
+func TestUploadBiz_Success(t *testing.T) {
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("biz"), 1024)
+
+	var lastSent int64
+	client := BuildService(ctx, WithUploadProgress(func(bytesSent, total int64) {
+		lastSent = bytesSent
+	}))
+
+	var uploaded []byte
+	var query string
+	port, cancel := mockHttpServer("/uploadBiz", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		file, header, err := r.FormFile("file")
+		assert.Nil(t, err)
+		assert.Equal(t, "biz-ark-biz.jar", header.Filename)
+		uploaded, _ = io.ReadAll(file)
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": "SUCCESS",
+			"data": map[string]interface{}{
+				"bizUrl": "file:///tmp/biz-ark-biz.jar",
+			},
+		})
+	})
+	defer cancel()
+
+	bizUrl, err := client.UploadBiz(ctx, UploadBizRequest{
+		BizModel: BizModel{
+			BizName:    "biz",
+			BizVersion: "0.0.1-SNAPSHOT",
+		},
+		TargetContainer: ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		},
+		FileName: "biz-ark-biz.jar",
+		Content:  bytes.NewReader(content),
+		Size:     int64(len(content)),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, fileutil.FileUrl("file:///tmp/biz-ark-biz.jar"), bizUrl)
+	assert.Equal(t, content, uploaded)
+	assert.Equal(t, "bizName=biz&bizVersion=0.0.1-SNAPSHOT", query)
+	assert.Equal(t, int64(len(content)), lastSent)
+}
+
+func TestUploadBiz_ProgressFollowsServer(t *testing.T) {
+	ctx := context.Background()
+	const size, chunk = 32 << 20, 1 << 20
+	var received, reported atomic.Int64
+	var aheadMax int64
+	client := BuildService(ctx, WithUploadProgress(func(bytesSent, total int64) {
+		reported.Store(bytesSent)
+	}))
+
+	port, cancel := mockHttpServer("/uploadBiz", func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		assert.Nil(t, err)
+		part, err := reader.NextPart()
+		assert.Nil(t, err)
+		buf := make([]byte, chunk)
+		for {
+			// read slowly, the progress shouldn't run ahead by more than what the buffers in between hold
+			time.Sleep(5 * time.Millisecond)
+			n, err := io.ReadFull(part, buf)
+			received.Add(int64(n))
+			if ahead := reported.Load() - received.Load(); ahead > aheadMax {
+				aheadMax = ahead
+			}
+			if err != nil {
+				break
+			}
+		}
+		_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"bizUrl":"file:///tmp/biz-ark-biz.jar"}}`))
+	})
+	defer cancel()
+
+	_, err := client.UploadBiz(ctx, UploadBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		FileName:        "biz-ark-biz.jar",
+		Content:         &zeroReader{n: size},
+		Size:            size,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(size), received.Load())
+	assert.Equal(t, int64(size), reported.Load())
+	assert.Less(t, aheadMax, int64(size/2))
+}
+
+func TestUploadBiz_MissingPortNoLeak(t *testing.T) {
+	ctx := context.Background()
+	client := BuildService(ctx)
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		_, err := client.UploadBiz(ctx, UploadBizRequest{
+			BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+			TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal},
+			FileName:        "biz-ark-biz.jar",
+			Content:         bytes.NewReader([]byte("biz")),
+			Size:            3,
+		})
+		assert.ErrorIs(t, err, ErrMissingPort)
+	}
+	// the writer goroutine of the body isn't started
+	assert.Less(t, runtime.NumGoroutine(), before+5)
+}
+
+func TestInstallBizFromReader(t *testing.T) {
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("biz"), 1024)
+
+	var calls []string
+	var uploaded []byte
+	var installed map[string]interface{}
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		switch r.URL.Path {
+		case "/uploadBiz":
+			file, header, err := r.FormFile("file")
+			assert.Nil(t, err)
+			assert.Equal(t, "biz-0.0.1-ark-biz.jar", header.Filename)
+			uploaded, _ = io.ReadAll(file)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": "SUCCESS",
+				"data": map[string]interface{}{"bizUrl": "file:///tmp/biz-0.0.1-ark-biz.jar"},
+			})
+		case "/installBiz":
+			_ = json.NewDecoder(r.Body).Decode(&installed)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "SUCCESS"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer cancel()
+
+	for reader, expectedTotal := range map[io.Reader]int64{
+		bytes.NewReader(content): int64(len(content)),
+		// the length of the stream is unknown
+		io.MultiReader(bytes.NewReader(content)): -1,
+	} {
+		calls, uploaded, installed = nil, nil, nil
+		var stagedTotal, uploadTotal int64
+		reporter := &recordingReporter{}
+		client := BuildService(ctx, WithUploadProgress(func(bytesSent, size int64) {
+			uploadTotal = size
+		}))
+		tempDir := t.TempDir()
+		err := client.InstallBizFromReader(ctx, BizModel{BizName: "biz", BizVersion: "0.0.1"}, reader, ArkContainerRuntimeInfo{
+			RunType: ArkContainerRunTypeLocal,
+			Port:    &port,
+		}, ReaderInstallOptions{
+			TempDir: tempDir,
+			OnProgress: func(staged, total int64) {
+				stagedTotal = total
+			},
+			Progress: reporter,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"/uploadBiz", "/health", "/queryAllBiz", "/installBiz"}, calls)
+		assert.Equal(t, content, uploaded)
+		assert.Equal(t, expectedTotal, stagedTotal)
+		assert.Equal(t, fmt.Sprintf("start %d", expectedTotal), reporter.events[0])
+		assert.Equal(t, fmt.Sprintf("update %d ", len(content)), reporter.events[len(reporter.events)-2])
+		assert.Equal(t, "finish <nil>", reporter.events[len(reporter.events)-1])
+		// the size of the staged bundle is always known
+		assert.Equal(t, int64(len(content)), uploadTotal)
+		assert.Equal(t, "file:///tmp/biz-0.0.1-ark-biz.jar", installed["bizUrl"])
+
+		// the staged temp file is removed
+		entries, _ := os.ReadDir(tempDir)
+		assert.Empty(t, entries)
+	}
+}
+
+func TestInstallBizFromReader_StagingFailed(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("biz")
+	sum := sha256.Sum256(content)
+
+	tests := []struct {
+		opts     ReaderInstallOptions
+		expected error
+	}{
+		{opts: ReaderInstallOptions{MaxSize: 2}, expected: ErrBizTooLarge},
+		{opts: ReaderInstallOptions{ExpectedChecksum: "abc"}, expected: fileutil.ErrChecksumMismatch},
+	}
+	for _, test := range tests {
+		tempDir := t.TempDir()
+		test.opts.TempDir = tempDir
+		err := BuildService(ctx).InstallBizFromReader(ctx, BizModel{BizName: "biz", BizVersion: "0.0.1"},
+			bytes.NewReader(content), ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal}, test.opts)
+		assert.True(t, errors.Is(err, test.expected), err)
+
+		entries, _ := os.ReadDir(tempDir)
+		assert.Empty(t, entries)
+	}
+
+	staged, err := stageBiz(bytes.NewReader(content), ReaderInstallOptions{
+		MaxSize:          3,
+		ExpectedChecksum: hex.EncodeToString(sum[:]),
+		TempDir:          t.TempDir(),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), staged.size)
+}
+
+func TestReaderSize(t *testing.T) {
+	assert.Equal(t, int64(3), readerSize(bytes.NewReader([]byte("biz"))))
+	assert.Equal(t, int64(3), readerSize(strings.NewReader("biz")))
+	assert.Equal(t, int64(-1), readerSize(io.MultiReader(strings.NewReader("biz"))))
+}
+
+// mockGzipArklet mock an arklet accepting uploads, the compressed uploads are decompressed if acceptGzip is true,
+// or rejected with 415 otherwise. The encodings of the uploads are recorded.
+func mockGzipArklet(t *testing.T, acceptGzip bool, encodings *[]string, uploaded *[]byte) (int, func()) {
+	return mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/help":
+			if acceptGzip {
+				w.Header().Set("Accept-Encoding", "gzip, deflate")
+			}
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":[{"id":"uploadBiz"}]}`))
+		case "/uploadBiz":
+			encoding := r.Header.Get("Content-Encoding")
+			*encodings = append(*encodings, encoding)
+			if encoding == "gzip" {
+				if !acceptGzip {
+					_, _ = io.Copy(io.Discard, r.Body)
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				gzipReader, err := gzip.NewReader(r.Body)
+				assert.Nil(t, err)
+				r.Body = gzipReader
+			}
+			file, _, err := r.FormFile("file")
+			assert.Nil(t, err)
+			*uploaded, _ = io.ReadAll(file)
+			_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"bizUrl":"file:///tmp/biz-ark-biz.jar"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func uploadTestBiz(client Service, port int, content io.Reader) (fileutil.FileUrl, error) {
+	return client.UploadBiz(context.Background(), UploadBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		FileName:        "biz-ark-biz.jar",
+		Content:         content,
+		Size:            -1,
+	})
+}
+
+func TestUploadBiz_Gzip(t *testing.T) {
+	content := bytes.Repeat([]byte("biz"), 1024)
+	tests := []struct {
+		mode              CompressionMode
+		acceptGzip        bool
+		expectedEncodings []string
+	}{
+		{mode: CompressionOff, acceptGzip: true, expectedEncodings: []string{""}},
+		{mode: CompressionAuto, acceptGzip: true, expectedEncodings: []string{"gzip"}},
+		{mode: CompressionAuto, acceptGzip: false, expectedEncodings: []string{""}},
+		{mode: CompressionAlways, acceptGzip: true, expectedEncodings: []string{"gzip"}},
+		// fall back to raw upload
+		{mode: CompressionAlways, acceptGzip: false, expectedEncodings: []string{"gzip", ""}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.mode), func(t *testing.T) {
+			var encodings []string
+			var uploaded []byte
+			port, cancel := mockGzipArklet(t, test.acceptGzip, &encodings, &uploaded)
+			defer cancel()
+
+			client := BuildService(context.Background(), WithUploadCompression(test.mode))
+			bizUrl, err := uploadTestBiz(client, port, bytes.NewReader(content))
+			assert.Nil(t, err)
+			assert.Equal(t, fileutil.FileUrl("file:///tmp/biz-ark-biz.jar"), bizUrl)
+			assert.Equal(t, test.expectedEncodings, encodings)
+			assert.Equal(t, content, uploaded)
+		})
+	}
+}
+
+func TestUploadBiz_GzipNotAcceptedAndNotSeekable(t *testing.T) {
+	var encodings []string
+	var uploaded []byte
+	port, cancel := mockGzipArklet(t, false, &encodings, &uploaded)
+	defer cancel()
+
+	client := BuildService(context.Background(), WithUploadCompression(CompressionAlways))
+	_, err := uploadTestBiz(client, port, io.MultiReader(strings.NewReader("biz")))
+	assert.True(t, errors.Is(err, errCompressionNotAccepted))
+	assert.Equal(t, []string{"gzip"}, encodings)
+}
@@ -0,0 +1,135 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/pollutil"
+)
+
+// defaultVerifyProbeTimeout bounds the polling of the verify probe if its timeout isn't given.
+const defaultVerifyProbeTimeout = 30 * time.Second
+
+// VerifyProbe is the readiness url of the biz polled after it's installed, to confirm the biz actually serves traffic.
+type VerifyProbe struct {
+	// URL is the readiness url served by the biz, requested from this host for the local run type
+	// or inside the pod for the pod run type, like ProbeBizUrl.
+	URL string `json:"url"`
+
+	// ExpectedStatus is the status code of the ready biz, any 2xx status if it's zero.
+	ExpectedStatus int `json:"expectedStatus,omitempty"`
+
+	// Timeout is the max time to wait for the probe to succeed, default to 30s.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// Interval is the interval of polling the probe, default to 1s.
+	Interval time.Duration `json:"interval,omitempty"`
+}
+
+// accepts return nil if the status code tells the biz is ready.
+func (p VerifyProbe) accepts(statusCode int) error {
+	if p.ExpectedStatus == 0 && statusCode >= 200 && statusCode < 300 {
+		return nil
+	}
+	if p.ExpectedStatus != 0 && statusCode == p.ExpectedStatus {
+		return nil
+	}
+	return fmt.Errorf("responded with code %d", statusCode)
+}
+
+// verifyInstall poll the verify probe of the installed biz until it succeeds,
+// ErrVerifyProbeFailed is returned with the last failure if it doesn't succeed before the deadline.
+func (h *service) verifyInstall(ctx context.Context, req InstallBizRequest) error {
+	probe := *req.VerifyProbe
+	timeout := probe.Timeout
+	if timeout <= 0 {
+		timeout = defaultVerifyProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	logger := contextutil.GetLogger(ctx).WithField("url", probe.URL)
+	err := pollutil.Poll(ctx, pollutil.Config{Interval: probe.Interval}, func(ctx context.Context) (bool, error) {
+		var statusCode int
+		var err error
+		switch req.TargetContainer.RunType {
+		case ArkContainerRunTypeLocal:
+			statusCode, err = verifyOnLocal(ctx, probe.URL)
+		case ArkContainerRunTypeK8s:
+			statusCode, err = h.verifyInPod(ctx, req.TargetContainer, probe.URL)
+		default:
+			return false, pollutil.Fatal(fmt.Errorf("verify probe is not supported for run type: %s", req.TargetContainer.RunType))
+		}
+		if err == nil {
+			err = probe.accepts(statusCode)
+		}
+		if err != nil {
+			logger.WithError(err).Debug("biz isn't verified yet")
+			return false, err
+		}
+		return true, nil
+	})
+
+	pollErr := &pollutil.Error{}
+	if errors.As(err, &pollErr) {
+		return fmt.Errorf("%w: %s of %s:%s: %v",
+			ErrVerifyProbeFailed, probe.URL, req.BizModel.BizName, req.BizModel.BizVersion, err)
+	}
+	if err == nil {
+		logger.Info("biz verified")
+	}
+	return err
+}
+
+// verifyOnLocal request the verify probe from this host, where the local arklet runs.
+func verifyOnLocal(ctx context.Context, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, pollutil.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// verifyInPod request the verify probe inside the pod by kubectl exec with curl.
+func (h *service) verifyInPod(ctx context.Context, target ArkContainerRuntimeInfo, url string) (int, error) {
+	namespace, podName, err := parsePodCoordinate(target.Coordinate)
+	if err != nil {
+		return 0, pollutil.Fatal(err)
+	}
+	args := podExecArgs(target, namespace, podName, "curl", "-sS", "-o", "/dev/null", "-w", "%{http_code}", url)
+	lines, err := h.kubectl(ctx, target, args...)
+	output := strings.TrimSpace(strings.Join(lines, "\n"))
+	if err != nil {
+		return 0, err
+	}
+	statusCode, err := strconv.Atoi(output)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected curl output %q", output)
+	}
+	return statusCode, nil
+}
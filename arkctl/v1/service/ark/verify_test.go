@@ -0,0 +1,97 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallBiz_VerifyProbeOnLocal(t *testing.T) {
+	ctx := context.Background()
+	var probed atomic.Int32
+	port, cancel := mockHttpServer("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/installBiz":
+			_, _ = w.Write([]byte(`{"code":"SUCCESS"}`))
+		case "/ready":
+			// the biz gets ready at the third probe
+			if probed.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/starting":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer cancel()
+	baseUrl := "http://127.0.0.1:" + strconv.Itoa(port)
+	client := BuildService(ctx)
+	req := InstallBizRequest{
+		BizModel:              BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer:       ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		AllowMultipleVersions: true,
+	}
+
+	req.VerifyProbe = &VerifyProbe{URL: baseUrl + "/ready", Interval: 10 * time.Millisecond, Timeout: time.Second}
+	assert.Nil(t, client.InstallBiz(ctx, req))
+	assert.Equal(t, int32(3), probed.Load())
+
+	req.VerifyProbe = &VerifyProbe{URL: baseUrl + "/starting", Interval: 10 * time.Millisecond, Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	err := client.InstallBiz(ctx, req)
+	assert.ErrorIs(t, err, ErrVerifyProbeFailed)
+	assert.Contains(t, err.Error(), "code 503")
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the expected status is matched exactly
+	req.VerifyProbe = &VerifyProbe{URL: baseUrl + "/starting", ExpectedStatus: http.StatusServiceUnavailable, Timeout: time.Second}
+	assert.Nil(t, client.InstallBiz(ctx, req))
+}
+
+func TestInstallBiz_VerifyProbeInPod(t *testing.T) {
+	ctx := context.Background()
+
+	var probes [][]string
+	curlOutput := "200"
+	client := BuildService(ctx, WithCommandRunner(func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+		if args[6] == "-sS" {
+			probes = append(probes, args)
+			return []string{curlOutput}, nil
+		}
+		return []string{`{"code":"SUCCESS"}`}, nil
+	}))
+	req := InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "http://10.0.0.1/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeK8s, Coordinate: "default/base-0"},
+		VerifyProbe:     &VerifyProbe{URL: "http://127.0.0.1:8080/biz/ready", Interval: 10 * time.Millisecond, Timeout: 100 * time.Millisecond},
+	}
+
+	assert.Nil(t, client.InstallBiz(ctx, req))
+	assert.Equal(t, [][]string{{"-n", "default", "exec", "base-0", "--",
+		"curl", "-sS", "-o", "/dev/null", "-w", "%{http_code}", "http://127.0.0.1:8080/biz/ready"}}, probes)
+
+	curlOutput = "404"
+	err := client.InstallBiz(ctx, req)
+	assert.ErrorIs(t, err, ErrVerifyProbeFailed)
+	assert.Contains(t, err.Error(), "code 404")
+}
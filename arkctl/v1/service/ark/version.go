@@ -0,0 +1,115 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+)
+
+// operationMinVersions are the min arklet versions of the operations not supported by older arklets.
+var operationMinVersions = map[string]string{
+	operationAsyncInstall: "2.2.9",
+}
+
+const operationAsyncInstall = "async install"
+
+// QueryVersion return the arklet version of target, empty if arklet doesn't report it.
+// The ark version is returned for the arklets reporting only it, as arklet is released with ark.
+func (h *service) QueryVersion(ctx context.Context, target ArkContainerRuntimeInfo) (string, error) {
+	target, err := targetOrDefault(ctx, target)
+	if err != nil {
+		return "", err
+	}
+	health, err := h.queryHealth(ctx, target)
+	if err != nil {
+		return "", err
+	}
+	if health.HealthData.ArkletVersion != "" {
+		return health.HealthData.ArkletVersion, nil
+	}
+	return health.HealthData.ArkVersion, nil
+}
+
+// requireVersion return IncompatibleVersionError if the arklet of target is older than the min version of operation.
+// The check is skipped if the version can't be queried or parsed, the operation will tell by itself.
+func (h *service) requireVersion(ctx context.Context, target ArkContainerRuntimeInfo, operation string) error {
+	required, ok := operationMinVersions[operation]
+	if !ok {
+		return nil
+	}
+
+	logger := contextutil.GetLogger(ctx)
+	actual, err := h.QueryVersion(ctx, target)
+	if err != nil {
+		logger.WithError(err).Warnf("skip version check of %s", operation)
+		return nil
+	}
+	compared, ok := compareVersions(actual, required)
+	if !ok {
+		logger.Warnf("skip version check of %s, the arklet version %q is unknown", operation, actual)
+		return nil
+	}
+	if compared < 0 {
+		return &IncompatibleVersionError{Operation: operation, Required: required, Actual: actual}
+	}
+	return nil
+}
+
+// parseVersion parse the numbers of a version like 2.2.3-SNAPSHOT, the qualifier after '-' is ignored.
+func parseVersion(version string) ([]int, bool) {
+	version, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(version), "v"), "-")
+	if version == "" {
+		return nil, false
+	}
+	numbers := []int{}
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, true
+}
+
+// compareVersions return -1, 0, 1 if a is older than, same as, newer than b, ok is false if any of them can't be parsed.
+// The missing numbers are taken as 0, e.g. 2.2 is the same as 2.2.0.
+func compareVersions(a, b string) (int, bool) {
+	numbersA, okA := parseVersion(a)
+	numbersB, okB := parseVersion(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	for i := 0; i < len(numbersA) || i < len(numbersB); i++ {
+		var x, y int
+		if i < len(numbersA) {
+			x = numbersA[i]
+		}
+		if i < len(numbersB) {
+			y = numbersB[i]
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+	}
+	return 0, true
+}
@@ -0,0 +1,92 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+		ok       bool
+	}{
+		{a: "2.2.9", b: "2.2.9", expected: 0, ok: true},
+		{a: "2.2.10", b: "2.2.9", expected: 1, ok: true},
+		{a: "2.2", b: "2.2.0", expected: 0, ok: true},
+		{a: "2.2.8-SNAPSHOT", b: "2.2.9", expected: -1, ok: true},
+		{a: "v3.0.0", b: "2.2.9", expected: 1, ok: true},
+		{a: "", b: "2.2.9", ok: false},
+		{a: "2.x", b: "2.2.9", ok: false},
+	}
+	for _, test := range tests {
+		compared, ok := compareVersions(test.a, test.b)
+		assert.Equal(t, test.ok, ok, test.a)
+		assert.Equal(t, test.expected, compared, test.a)
+	}
+}
+
+// versionHealth is the health of the arklet of version.
+func versionHealth(version string) http.HandlerFunc {
+	return respondBody(`{"code":"SUCCESS","data":{"healthData":{"arkVersion":"2.2.0","arkletVersion":"` + version + `"}}}`)
+}
+
+func TestQueryVersion(t *testing.T) {
+	ctx := context.Background()
+	arklet := &fakeArklet{handlers: map[string]http.HandlerFunc{"/health": versionHealth("2.2.9")}}
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	version, err := BuildService(ctx).QueryVersion(ctx, ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port})
+	assert.Nil(t, err)
+	assert.Equal(t, "2.2.9", version)
+}
+
+func TestInstallBiz_AsyncCompatibleVersion(t *testing.T) {
+	ctx := context.Background()
+	arklet := &fakeArklet{handlers: map[string]http.HandlerFunc{"/health": versionHealth("2.3.0")}}
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	err := BuildService(ctx).InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		ExtraParams:     map[string]interface{}{"async": true},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"install biz:0.0.1"}, arklet.calls)
+}
+
+func TestInstallBiz_AsyncIncompatibleVersion(t *testing.T) {
+	ctx := context.Background()
+	arklet := &fakeArklet{handlers: map[string]http.HandlerFunc{"/health": versionHealth("2.2.8")}}
+	port, cancel := mockHttpServer("/", arklet.serve)
+	defer cancel()
+
+	err := BuildService(ctx).InstallBiz(ctx, InstallBizRequest{
+		BizModel:        BizModel{BizName: "biz", BizVersion: "0.0.1", BizUrl: "file:///tmp/biz.jar"},
+		TargetContainer: ArkContainerRuntimeInfo{RunType: ArkContainerRunTypeLocal, Port: &port},
+		ExtraParams:     map[string]interface{}{"async": true},
+	})
+	assert.True(t, errors.Is(err, ErrIncompatibleVersion))
+	assert.Equal(t, "incompatible arklet version: async install requires arklet 2.2.9 or newer, but the target runs 2.2.8", err.Error())
+	assert.Empty(t, arklet.calls)
+}
@@ -0,0 +1,297 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/common/cmdutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/contextutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/k8sutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/pollutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/common/progressutil"
+	"serverless.alipay.com/sofa-serverless/arkctl/v1/service/ark"
+)
+
+const (
+	defaultPollInterval    = 2 * time.Second
+	defaultActivateTimeout = 2 * time.Minute
+)
+
+// PodLister list the pods matching the label selector.
+type PodLister interface {
+	ListPods(ctx context.Context, namespace, selector string) ([]string, error)
+}
+
+// KubectlPodLister list the pods with kubectl.
+type KubectlPodLister struct {
+	// Runner runs kubectl, cmdutil.RunCommand is used if not given.
+	Runner cmdutil.Runner
+
+	// KubeConfig selects the cluster, kubectl defaults are used if not given.
+	KubeConfig *k8sutil.Config
+}
+
+func (l *KubectlPodLister) ListPods(ctx context.Context, namespace, selector string) ([]string, error) {
+	runner := l.Runner
+	if runner == nil {
+		runner = cmdutil.RunCommand
+	}
+
+	lines, err := runner(ctx, "kubectl", l.KubeConfig.KubectlArgs(
+		"-n", namespace,
+		"get", "pods",
+		"-l", selector,
+		"-o", "jsonpath={.items[*].metadata.name}",
+	)...)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := strings.Fields(strings.Join(lines, " "))
+	sort.Strings(pods)
+	return pods, nil
+}
+
+// Request is the request of rolling out a biz to the pods matching the selector.
+type Request struct {
+	// Namespace is the namespace of the pods.
+	Namespace string
+
+	// Selector is the label selector of the pods, e.g. app=base.
+	Selector string
+
+	// BizModel is the biz to install, the BizUrl must be accessible inside the pods.
+	BizModel ark.BizModel
+
+	// Port is the ark api port of the pods, the default port is used if not given.
+	Port *int
+}
+
+// Result is the result of a rollout.
+type Result struct {
+	// Updated are the pods installed with the biz by this rollout.
+	Updated []string
+
+	// Skipped are the pods already running the target version when resuming.
+	Skipped []string
+
+	// PodVersions is the activated version of the biz per pod after the rollout, empty if the biz is not activated.
+	PodVersions map[string]string
+}
+
+// HaltedError is returned when the rollout halts on a failed batch.
+type HaltedError struct {
+	// Batch is the index of the failed batch, starting from 0.
+	Batch int
+
+	// Failures are the failed pods of the batch and their errors.
+	Failures map[string]error
+
+	// Err has the results of all pods in the failed batch.
+	Err *ark.MultiTargetError
+}
+
+func (e *HaltedError) Error() string {
+	return fmt.Sprintf("rollout halted at batch %d: %v", e.Batch, e.Err)
+}
+
+func (e *HaltedError) Unwrap() error {
+	return e.Err
+}
+
+// Executor installs a biz to the pods in batches, and waits for each batch to be activated before the next one.
+type Executor struct {
+	// Service is used to install and query biz in pods.
+	Service ark.Service
+
+	// Lister lists the pods of the rollout.
+	Lister PodLister
+
+	// MaxUnavailable is the max pods installed at the same time, 1 if not positive.
+	MaxUnavailable int
+
+	// PollInterval is the interval of querying biz state after install.
+	PollInterval time.Duration
+
+	// ActivateTimeout is the max time waiting a pod to activate the biz.
+	ActivateTimeout time.Duration
+
+	// Progress is started with the pods to roll out of each run, and updated as each of them is done.
+	Progress progressutil.ProgressReporter
+}
+
+// Run rollout the biz to all pods matching the selector.
+// The rollout halts on the first batch that fails, and HaltedError is returned with the Result.
+func (e *Executor) Run(ctx context.Context, req Request) (*Result, error) {
+	return e.run(ctx, req, false)
+}
+
+// Resume continue a halted rollout, the pods already running the target version are skipped.
+func (e *Executor) Resume(ctx context.Context, req Request) (*Result, error) {
+	return e.run(ctx, req, true)
+}
+
+func (e *Executor) run(ctx context.Context, req Request, resume bool) (_ *Result, err error) {
+	logger := contextutil.GetLogger(ctx)
+	progress := progressutil.OrNop(e.Progress)
+	defer func() {
+		progress.Finish(err)
+	}()
+
+	pods, err := e.Lister.ListPods(ctx, req.Namespace, req.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no pods match selector %q in namespace %s", req.Selector, req.Namespace)
+	}
+
+	result := &Result{}
+	var todo []string
+	for _, pod := range pods {
+		if resume && e.isActivated(ctx, req, pod) {
+			result.Skipped = append(result.Skipped, pod)
+			continue
+		}
+		todo = append(todo, pod)
+	}
+
+	batchSize := e.MaxUnavailable
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	progress.Start(int64(len(todo)))
+	var done int64
+	onPodDone := func(pod string) {
+		progress.Update(atomic.AddInt64(&done, 1), pod)
+	}
+
+	for start := 0; start < len(todo); start += batchSize {
+		end := start + batchSize
+		if end > len(todo) {
+			end = len(todo)
+		}
+		batch := todo[start:end]
+
+		logger.WithField("batch", start/batchSize).WithField("pods", batch).Info("rollout batch started")
+		if batchErr := e.rolloutBatch(ctx, req, batch, onPodDone); len(batchErr.Failed()) != 0 {
+			failures := map[string]error{}
+			for _, failed := range batchErr.Failed() {
+				failures[failed.Target] = failed.Err
+			}
+			result.PodVersions = e.podVersions(ctx, req, pods)
+			return result, &HaltedError{Batch: start / batchSize, Failures: failures, Err: batchErr}
+		}
+		result.Updated = append(result.Updated, batch...)
+	}
+
+	result.PodVersions = e.podVersions(ctx, req, pods)
+	return result, nil
+}
+
+// rolloutBatch install the biz to the pods concurrently and wait them to be activated, onDone is called as each pod is done.
+// The results are in the order of pods.
+func (e *Executor) rolloutBatch(ctx context.Context, req Request, pods []string, onDone func(pod string)) *ark.MultiTargetError {
+	results := make([]ark.TargetResult, len(pods))
+	wg := sync.WaitGroup{}
+	for i, pod := range pods {
+		wg.Add(1)
+		go func(i int, pod string) {
+			defer wg.Done()
+			results[i] = ark.TargetResult{Target: pod, Err: e.rolloutPod(ctx, req, pod)}
+			onDone(pod)
+		}(i, pod)
+	}
+	wg.Wait()
+	return &ark.MultiTargetError{Results: results}
+}
+
+func (e *Executor) rolloutPod(ctx context.Context, req Request, pod string) error {
+	if err := e.Service.InstallBiz(ctx, ark.InstallBizRequest{
+		BizModel:        req.BizModel,
+		TargetContainer: e.target(req, pod),
+	}); err != nil {
+		return err
+	}
+	return e.waitActivated(ctx, req, pod)
+}
+
+func (e *Executor) waitActivated(ctx context.Context, req Request, pod string) error {
+	timeout := e.ActivateTimeout
+	if timeout <= 0 {
+		timeout = defaultActivateTimeout
+	}
+	interval := e.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var state ark.BizState
+	err := pollutil.Poll(ctx, pollutil.Config{Interval: interval}, func(ctx context.Context) (bool, error) {
+		detail, err := e.Service.QueryBiz(ark.WithCacheBypass(ctx), e.target(req, pod), req.BizModel.BizName, req.BizModel.BizVersion)
+		if err != nil {
+			return false, err
+		}
+		state = detail.BizState
+		return state == ark.BizStateActivated, nil
+	})
+	if err != nil {
+		return fmt.Errorf("biz %s:%s is not activated in %v, last state is %q: %w",
+			req.BizModel.BizName, req.BizModel.BizVersion, timeout, state, err)
+	}
+	return nil
+}
+
+func (e *Executor) isActivated(ctx context.Context, req Request, pod string) bool {
+	detail, err := e.Service.QueryBiz(ark.WithCacheBypass(ctx), e.target(req, pod), req.BizModel.BizName, req.BizModel.BizVersion)
+	return err == nil && detail.BizState == ark.BizStateActivated
+}
+
+// podVersions report the activated version of the biz per pod.
+func (e *Executor) podVersions(ctx context.Context, req Request, pods []string) map[string]string {
+	versions := map[string]string{}
+	for _, pod := range pods {
+		if e.isActivated(ctx, req, pod) {
+			versions[pod] = req.BizModel.BizVersion
+			continue
+		}
+
+		versions[pod] = ""
+		detail, err := e.Service.QueryBiz(ark.WithCacheBypass(ctx), e.target(req, pod), req.BizModel.BizName, "")
+		if err == nil && detail.BizState == ark.BizStateActivated {
+			versions[pod] = detail.BizVersion
+		}
+	}
+	return versions
+}
+
+func (e *Executor) target(req Request, pod string) ark.ArkContainerRuntimeInfo {
+	return ark.ArkContainerRuntimeInfo{
+		RunType:    ark.ArkContainerRunTypeK8s,
+		Coordinate: req.Namespace + "/" + pod,
+		Port:       req.Port,
+	}
+}
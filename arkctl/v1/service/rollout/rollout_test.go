@@ -0,0 +1,175 @@
+/**
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"serverless.alipay.com/sofa-serverless/arkctl/v1/service/ark"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLister []string
+
+func (l fakeLister) ListPods(_ context.Context, _, _ string) ([]string, error) {
+	return l, nil
+}
+
+// fakeService keeps the activated biz version of each pod in memory.
+type fakeService struct {
+	ark.Service
+
+	lock      sync.Mutex
+	versions  map[string]string
+	broken    map[string]bool
+	installed []string
+}
+
+func newFakeService(versions map[string]string) *fakeService {
+	return &fakeService{
+		versions: versions,
+		broken:   map[string]bool{},
+	}
+}
+
+func (s *fakeService) InstallBiz(_ context.Context, req ark.InstallBizRequest) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pod := strings.Split(req.TargetContainer.Coordinate, "/")[1]
+	s.installed = append(s.installed, pod)
+	if !s.broken[pod] {
+		s.versions[pod] = req.BizModel.BizVersion
+	}
+	return nil
+}
+
+func (s *fakeService) QueryBiz(_ context.Context, target ark.ArkContainerRuntimeInfo, bizName, bizVersion string) (*ark.BizDetail, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pod := strings.Split(target.Coordinate, "/")[1]
+	version, ok := s.versions[pod]
+	if !ok || (bizVersion != "" && bizVersion != version) {
+		return nil, fmt.Errorf("%w: %s:%s", ark.ErrBizNotFound, bizName, bizVersion)
+	}
+	return &ark.BizDetail{ArkBizInfo: ark.ArkBizInfo{
+		BizName:    bizName,
+		BizVersion: version,
+		BizState:   ark.BizStateActivated,
+	}}, nil
+}
+
+func newRequest() Request {
+	return Request{
+		Namespace: "default",
+		Selector:  "app=base",
+		BizModel: ark.BizModel{
+			BizName:    "biz",
+			BizVersion: "2.0.0",
+			BizUrl:     "file:///tmp/biz.jar",
+		},
+	}
+}
+
+func TestRollout_AllBatchesSucceed(t *testing.T) {
+	service := newFakeService(map[string]string{})
+	executor := &Executor{
+		Service:        service,
+		Lister:         fakeLister{"pod-0", "pod-1", "pod-2", "pod-3", "pod-4"},
+		MaxUnavailable: 2,
+		PollInterval:   time.Millisecond,
+	}
+
+	result, err := executor.Run(context.Background(), newRequest())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pod-0", "pod-1", "pod-2", "pod-3", "pod-4"}, result.Updated)
+	for _, version := range result.PodVersions {
+		assert.Equal(t, "2.0.0", version)
+	}
+}
+
+func TestRollout_HaltOnFailedBatch(t *testing.T) {
+	service := newFakeService(map[string]string{
+		"pod-0": "1.0.0",
+		"pod-1": "1.0.0",
+		"pod-2": "1.0.0",
+		"pod-3": "1.0.0",
+		"pod-4": "1.0.0",
+	})
+	service.broken["pod-3"] = true
+	executor := &Executor{
+		Service:         service,
+		Lister:          fakeLister{"pod-0", "pod-1", "pod-2", "pod-3", "pod-4"},
+		MaxUnavailable:  2,
+		PollInterval:    time.Millisecond,
+		ActivateTimeout: 20 * time.Millisecond,
+	}
+
+	result, err := executor.Run(context.Background(), newRequest())
+	haltedErr := &HaltedError{}
+	assert.True(t, errors.As(err, &haltedErr))
+	assert.Equal(t, 1, haltedErr.Batch)
+	assert.Contains(t, haltedErr.Failures, "pod-3")
+	assert.Equal(t, 1, len(haltedErr.Failures))
+
+	multiErr := &ark.MultiTargetError{}
+	assert.True(t, errors.As(err, &multiErr))
+	assert.Equal(t, "pod-2", multiErr.Succeeded()[0].Target)
+	assert.Equal(t, "pod-3", multiErr.Failed()[0].Target)
+
+	// the third batch is never installed
+	assert.NotContains(t, service.installed, "pod-4")
+	assert.Equal(t, []string{"pod-0", "pod-1"}, result.Updated)
+	assert.Equal(t, map[string]string{
+		"pod-0": "2.0.0",
+		"pod-1": "2.0.0",
+		"pod-2": "2.0.0",
+		"pod-3": "1.0.0",
+		"pod-4": "1.0.0",
+	}, result.PodVersions)
+
+	// resume after fixing the pod, the pods on target version are skipped
+	service.broken["pod-3"] = false
+	service.installed = nil
+	result, err = executor.Resume(context.Background(), newRequest())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pod-0", "pod-1", "pod-2"}, result.Skipped)
+	assert.Equal(t, []string{"pod-3", "pod-4"}, result.Updated)
+	assert.ElementsMatch(t, []string{"pod-3", "pod-4"}, service.installed)
+}
+
+func TestKubectlPodLister(t *testing.T) {
+	var command []string
+	lister := &KubectlPodLister{
+		Runner: func(ctx context.Context, cmd string, args ...string) ([]string, error) {
+			command = append([]string{cmd}, args...)
+			return []string{"pod-b pod-a"}, nil
+		},
+	}
+
+	pods, err := lister.ListPods(context.Background(), "default", "app=base")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pod-a", "pod-b"}, pods)
+	assert.Equal(t, []string{"kubectl", "-n", "default", "get", "pods", "-l", "app=base",
+		"-o", "jsonpath={.items[*].metadata.name}"}, command)
+}